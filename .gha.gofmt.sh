@@ -1,6 +1,6 @@
 #!/bin/bash
 # code from https://github.com/Seklfreak/Robyul2
-which goimports || go get -v golang.org/x/tools/cmd/goimports
+which goimports || go install golang.org/x/tools/cmd/goimports@v0.1.12
 
 unset dirs files
 dirs=$(go list -f {{.Dir}} ./... | grep -v /vendor/)
@@ -20,6 +20,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ontio/ontology/cmd/utils"
 	"github.com/ontio/ontology/common"
@@ -42,6 +43,9 @@ func SetOntologyConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
 	setRestfulConfig(ctx, cfg.Restful)
 	setGraphQLConfig(ctx, cfg.GraphQL)
 	setWebSocketConfig(ctx, cfg.Ws)
+	setMetricsConfig(ctx, cfg.Metrics)
+	setTracingConfig(ctx, cfg.Tracing)
+	setAdminRpcConfig(ctx, cfg.AdminRpc)
 	if cfg.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
 		cfg.Ws.EnableHttpWs = true
 		cfg.Restful.EnableHttpRestful = true
@@ -119,7 +123,7 @@ func setGenesis(ctx *cli.Context, cfg *config.OntologyConfig) error {
 		if cfg.Genesis.DBFT.GenBlockTime <= 0 {
 			cfg.Genesis.DBFT.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
 		}
-	case config.CONSENSUS_TYPE_VBFT:
+	case config.CONSENSUS_TYPE_VBFT, config.CONSENSUS_TYPE_SBFT:
 		err = governance.CheckVBFTConfig(cfg.Genesis.VBFT)
 		if err != nil {
 			return fmt.Errorf("VBFT config error %v", err)
@@ -140,13 +144,23 @@ func setCommonConfig(ctx *cli.Context, cfg *config.CommonConfig) {
 	cfg.MinGasLimit = ctx.Uint64(utils.GetFlagName(utils.GasLimitFlag))
 	cfg.GasPrice = ctx.Uint64(utils.GetFlagName(utils.GasPriceFlag))
 	cfg.DataDir = ctx.String(utils.GetFlagName(utils.DataDirFlag))
+	cfg.DBBackend = ctx.String(utils.GetFlagName(utils.DBBackendFlag))
 	//add new flag for ethgaslimit
 	cfg.ETHTxGasLimit = ctx.Uint64(utils.GetFlagName(utils.ETHTxGasLimitFlag))
+	cfg.EnableStateProof = ctx.Bool(utils.GetFlagName(utils.EnableStateProofFlag))
+	cfg.EnableArchive = ctx.Bool(utils.GetFlagName(utils.EnableArchiveFlag))
+	cfg.EnableAddressIndex = ctx.Bool(utils.GetFlagName(utils.EnableAddressIndexFlag))
+	cfg.EnableContractEventIndex = ctx.Bool(utils.GetFlagName(utils.EnableContractEventIndexFlag))
+	cfg.StateUndoDepth = ctx.Uint(utils.GetFlagName(utils.StateUndoDepthFlag))
+	cfg.TxPoolCapacity = ctx.Uint(utils.GetFlagName(utils.TxPoolCapacityFlag))
+	cfg.TxPoolPayerSlots = ctx.Uint(utils.GetFlagName(utils.TxPoolPayerSlotsFlag))
+	cfg.TxPoolQueueLifetime = ctx.Uint(utils.GetFlagName(utils.TxPoolQueueLifetimeFlag))
 }
 
 func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) {
 	cfg.EnableConsensus = ctx.Bool(utils.GetFlagName(utils.EnableConsensusFlag))
 	cfg.MaxTxInBlock = ctx.Uint(utils.GetFlagName(utils.MaxTxInBlockFlag))
+	cfg.EnablePipeline = ctx.Bool(utils.GetFlagName(utils.EnablePipelineFlag))
 }
 
 func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
@@ -162,6 +176,7 @@ func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
 	cfg.MaxConnInBoundForSingleIP = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundForSingleIPFlag))
 
 	rsvfile := ctx.String(utils.GetFlagName(utils.ReservedPeersFileFlag))
+	cfg.ReservedPeersFile = rsvfile
 	if cfg.ReservedPeersOnly {
 		if !common.FileExisted(rsvfile) {
 			log.Infof("file %s not exist\n", rsvfile)
@@ -187,6 +202,16 @@ func setRpcConfig(ctx *cli.Context, cfg *config.RpcConfig) {
 	cfg.HttpJsonPort = ctx.Uint(utils.GetFlagName(utils.RPCPortFlag))
 	cfg.HttpLocalPort = ctx.Uint(utils.GetFlagName(utils.RPCLocalProtFlag))
 	cfg.EthJsonPort = ctx.Uint(utils.GetFlagName(utils.ETHRPCPortFlag))
+	if ctx.Bool(utils.GetFlagName(utils.ETHWSEnableFlag)) {
+		cfg.EthWsPort = ctx.Uint(utils.GetFlagName(utils.ETHWSPortFlag))
+	}
+	for _, origin := range strings.Split(ctx.String(utils.GetFlagName(utils.ETHWSOriginsFlag)), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.EthWsOrigins = append(cfg.EthWsOrigins, origin)
+		}
+	}
+	cfg.EthLogsMaxBlockRange = ctx.Uint(utils.GetFlagName(utils.ETHLogsMaxBlockRangeFlag))
+	cfg.EnableEthDebugApi = ctx.Bool(utils.GetFlagName(utils.ETHDebugApiEnableFlag))
 }
 
 func setRestfulConfig(ctx *cli.Context, cfg *config.RestfulConfig) {
@@ -199,6 +224,11 @@ func setGraphQLConfig(ctx *cli.Context, cfg *config.GraphQLConfig) {
 	cfg.EnableGraphQL = ctx.Bool(utils.GetFlagName(utils.GraphQLEnableFlag))
 	cfg.GraphQLPort = ctx.Uint(utils.GetFlagName(utils.GraphQLPortFlag))
 	cfg.MaxConnections = ctx.Uint(utils.GetFlagName(utils.GraphQLMaxConnsFlag))
+	for _, origin := range strings.Split(ctx.String(utils.GetFlagName(utils.GraphQLWsOriginsFlag)), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.WsOrigins = append(cfg.WsOrigins, origin)
+		}
+	}
 }
 
 func setWebSocketConfig(ctx *cli.Context, cfg *config.WebSocketConfig) {
@@ -206,6 +236,25 @@ func setWebSocketConfig(ctx *cli.Context, cfg *config.WebSocketConfig) {
 	cfg.HttpWsPort = ctx.Uint(utils.GetFlagName(utils.WsPortFlag))
 }
 
+func setMetricsConfig(ctx *cli.Context, cfg *config.MetricsConfig) {
+	cfg.EnableMetrics = ctx.Bool(utils.GetFlagName(utils.MetricsEnableFlag))
+	cfg.MetricsPort = ctx.Uint(utils.GetFlagName(utils.MetricsPortFlag))
+}
+
+func setTracingConfig(ctx *cli.Context, cfg *config.TracingConfig) {
+	cfg.EnableTracing = ctx.Bool(utils.GetFlagName(utils.TracingEnableFlag))
+	cfg.Endpoint = ctx.String(utils.GetFlagName(utils.TracingEndpointFlag))
+	cfg.TraceFile = ctx.String(utils.GetFlagName(utils.TracingFileFlag))
+}
+
+func setAdminRpcConfig(ctx *cli.Context, cfg *config.AdminRpcConfig) {
+	cfg.EnableAdminRpc = ctx.Bool(utils.GetFlagName(utils.AdminRpcEnableFlag))
+	cfg.IpcPath = ctx.String(utils.GetFlagName(utils.AdminRpcIpcFlag))
+	cfg.HttpAddr = ctx.String(utils.GetFlagName(utils.AdminRpcAddrFlag))
+	cfg.HttpPort = ctx.Uint(utils.GetFlagName(utils.AdminRpcPortFlag))
+	cfg.TokenFile = ctx.String(utils.GetFlagName(utils.AdminRpcTokenFileFlag))
+}
+
 func SetRpcPort(ctx *cli.Context) {
 	if ctx.IsSet(utils.GetFlagName(utils.RPCPortFlag)) {
 		config.DefConfig.Rpc.HttpJsonPort = ctx.Uint(utils.GetFlagName(utils.RPCPortFlag))
@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/store/ledgerstore"
+)
+
+var DBCommand = cli.Command{
+	Name:      "db",
+	Action:    cli.ShowSubcommandHelp,
+	Usage:     "Check and repair the ledger database",
+	ArgsUsage: "[arguments...]",
+	Subcommands: []cli.Command{
+		{
+			Action: checkDB,
+			Name:   "check",
+			Usage:  "Check the consistency of ledger database",
+			Flags: []cli.Flag{
+				utils.DBRepairFlag,
+				utils.DBStateHashFlag,
+				utils.DataDirFlag,
+				utils.DBBackendFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+			},
+			Description: "Walk the header index, blocks, transactions, state merkle roots and events of a stopped node " +
+				"and report the inconsistencies between them. With --repair, the stores are rolled back to the last consistent height.",
+		},
+		{
+			Action: rollbackDB,
+			Name:   "rollback",
+			Usage:  "Roll the ledger database back to a block height",
+			Flags: []cli.Flag{
+				utils.DBRollbackHeightFlag,
+				utils.DataDirFlag,
+				utils.DBBackendFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+			},
+			Description: "Remove the blocks, transactions, events, cross chain messages and state changes above the height " +
+				"from the ledger of a stopped node. The state is rolled back with the undo logs kept for the latest blocks, " +
+				"see --state-undo-depth.",
+		},
+	},
+}
+
+//openLedgerChecker open the ledger database of the node configured by ctx
+func openLedgerChecker(ctx *cli.Context) (*ledgerstore.LedgerChecker, string, error) {
+	cfg, err := SetOntologyConfig(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("SetOntologyConfig error:%s", err)
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	if _, err := os.Stat(dbDir); err != nil {
+		return nil, "", fmt.Errorf("data dir %s error:%s", dbDir, err)
+	}
+	checker, err := ledgerstore.NewLedgerChecker(dbDir, config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId))
+	if err != nil {
+		return nil, "", fmt.Errorf("NewLedgerChecker error:%s", err)
+	}
+	return checker, dbDir, nil
+}
+
+func checkDB(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	checker, dbDir, err := openLedgerChecker(ctx)
+	if err != nil {
+		return err
+	}
+	defer checker.Close()
+	genesisBlock, err := buildGenesisBlock()
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool(utils.GetFlagName(utils.DBStateHashFlag)) {
+		checker.EnableStateHash()
+	}
+	PrintInfoMsg("Start checking ledger in %s.", dbDir)
+	result, err := checker.Check(genesisBlock.Hash())
+	if err != nil {
+		return fmt.Errorf("check ledger error:%s", err)
+	}
+	PrintInfoMsg("BlockHeight:%d", result.BlockHeight)
+	PrintInfoMsg("StateHeight:%d", result.StateHeight)
+	PrintInfoMsg("EventHeight:%d", result.EventHeight)
+	PrintInfoMsg("PrunedHeight:%d", result.PrunedHeight)
+	if result.StateHash != common.UINT256_EMPTY {
+		PrintInfoMsg("StateHash:%s", result.StateHash.ToHexString())
+	}
+	if result.Consistent() {
+		PrintInfoMsg("Ledger is consistent.")
+		return nil
+	}
+	for _, issue := range result.Issues {
+		PrintWarnMsg("%s", issue)
+	}
+	if result.Truncated {
+		PrintWarnMsg("Too many issues, the check is stopped.")
+	}
+	PrintWarnMsg("Ledger is inconsistent, the last consistent height is %d.", result.ConsistentHeight)
+	if !ctx.Bool(utils.GetFlagName(utils.DBRepairFlag)) {
+		PrintInfoMsg("Use --%s to roll back the ledger to the last consistent height.", utils.DBRepairFlag.Name)
+		return nil
+	}
+	err = checker.Repair(result)
+	if err != nil {
+		return fmt.Errorf("repair ledger error:%s", err)
+	}
+	PrintInfoMsg("Repair complete, the ledger is rolled back to height %d.", result.ConsistentHeight)
+	return nil
+}
+
+func rollbackDB(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	if !ctx.IsSet(utils.GetFlagName(utils.DBRollbackHeightFlag)) {
+		PrintErrorMsg("Missing --%s argument.", utils.DBRollbackHeightFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	height := uint32(ctx.Uint(utils.GetFlagName(utils.DBRollbackHeightFlag)))
+	checker, dbDir, err := openLedgerChecker(ctx)
+	if err != nil {
+		return err
+	}
+	defer checker.Close()
+
+	PrintInfoMsg("Start rolling back ledger in %s to height %d.", dbDir, height)
+	err = checker.Rollback(height)
+	if err != nil {
+		return fmt.Errorf("rollback ledger error:%s", err)
+	}
+	PrintInfoMsg("Rollback complete, the ledger is rolled back to height %d.", height)
+	return nil
+}
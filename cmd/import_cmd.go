@@ -46,6 +46,7 @@ var ImportCommand = cli.Command{
 		utils.ImportFileFlag,
 		utils.ImportEndHeightFlag,
 		utils.DataDirFlag,
+		utils.DBBackendFlag,
 		utils.ConfigFlag,
 		utils.NetworkIdFlag,
 		utils.DisableEventLogFlag,
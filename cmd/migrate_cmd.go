@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/store/kvstore"
+	"github.com/ontio/ontology/core/store/ledgerstore"
+)
+
+//number of keys written in one batch while migrating
+const MIGRATE_BATCH_SIZE = 10000
+
+var MigrateCommand = cli.Command{
+	Name:      "migrate",
+	Usage:     "Migrate ledger DB to another key-value backend",
+	ArgsUsage: "",
+	Action:    migrateDB,
+	Flags: []cli.Flag{
+		utils.DBBackendFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+	},
+	Description: "Copy every key-value store of ledger into the backend given by --db-backend. " +
+		"The old stores are kept as <dir>.<backend>.bak and can be removed after the node starts with the new backend. " +
+		"Note that the node must be stopped while migrating",
+}
+
+func migrateDB(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	_, err := SetOntologyConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOntologyConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	target := config.DefConfig.Common.DBBackend
+	if !kvstore.IsValidBackend(target) {
+		PrintErrorMsg("Invalid %s argument:%s", utils.DBBackendFlag.Name, target)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+
+	PrintInfoMsg("Start migrate %s to %s backend.", dbDir, target)
+	for _, dir := range ledgerstore.PersistStoreDirs(dbDir) {
+		if err := kvstore.RecoverMigration(dir); err != nil {
+			return fmt.Errorf("recover migration of %s error:%s", dir, err)
+		}
+		source, err := kvstore.DetectBackend(dir)
+		if err != nil {
+			return fmt.Errorf("detect backend of %s error:%s", dir, err)
+		}
+		if source == "" {
+			PrintInfoMsg("%s does not exist, skip.", dir)
+			continue
+		}
+		if source == target {
+			PrintInfoMsg("%s is already %s, skip.", dir, target)
+			continue
+		}
+		count, err := migrateStore(dir, source, target)
+		if err != nil {
+			return fmt.Errorf("migrate %s error:%s", dir, err)
+		}
+		PrintInfoMsg("Migrated %s from %s to %s, %d keys.", dir, source, target, count)
+	}
+	PrintInfoMsg("Migrate finished.")
+	return nil
+}
+
+//migrateStore copies the store at dir into a new store of target backend, then swaps them
+//and keeps the old one as backup. A crash between the two renames of the swap is recovered by
+//kvstore.RecoverMigration when the store is opened again
+func migrateStore(dir, source, target string) (uint64, error) {
+	backup := kvstore.BackupPath(dir, source)
+	if _, err := os.Stat(backup); err == nil {
+		return 0, fmt.Errorf("backup %s already exists", backup)
+	}
+	tmp := kvstore.MigratingPath(dir)
+	if err := os.RemoveAll(tmp); err != nil {
+		return 0, err
+	}
+
+	src, err := kvstore.NewPersistStore(source, dir)
+	if err != nil {
+		return 0, fmt.Errorf("open %s error:%s", dir, err)
+	}
+	dst, err := kvstore.NewPersistStore(target, tmp)
+	if err != nil {
+		src.Close()
+		return 0, fmt.Errorf("open %s error:%s", tmp, err)
+	}
+	count, err := kvstore.Copy(dst, src, MIGRATE_BATCH_SIZE)
+	dstErr := dst.Close()
+	srcErr := src.Close()
+	if err != nil {
+		return count, err
+	}
+	if dstErr != nil {
+		return count, dstErr
+	}
+	if srcErr != nil {
+		return count, srcErr
+	}
+
+	if err := os.Rename(dir, backup); err != nil {
+		return count, err
+	}
+	return count, os.Rename(tmp, dir)
+}
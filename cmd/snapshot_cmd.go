@@ -0,0 +1,200 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/store/ledgerstore"
+	"github.com/ontio/ontology/core/types"
+)
+
+var SnapshotCommand = cli.Command{
+	Name:      "snapshot",
+	Action:    cli.ShowSubcommandHelp,
+	Usage:     "Export or import ledger snapshot for fast node bootstrap",
+	ArgsUsage: "[arguments...]",
+	Subcommands: []cli.Command{
+		{
+			Action: exportSnapshot,
+			Name:   "export",
+			Usage:  "Export ledger snapshot to a file",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.SnapshotHeightFlag,
+				utils.DataDirFlag,
+				utils.DBBackendFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+			},
+			Description: "Export the state, the block merkle tree and the headers up to the snapshot height of a stopped node.",
+		},
+		{
+			Action: importSnapshot,
+			Name:   "import",
+			Usage:  "Import ledger snapshot from a file into an empty data dir",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.SnapshotTrustedHashFlag,
+				utils.SnapshotStateHashFlag,
+				utils.DataDirFlag,
+				utils.DBBackendFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+			},
+			Description: "Verify the snapshot with the trusted block hash and state hash of the snapshot height and import it, " +
+				"then the node starts syncing from the next block. Blocks, transactions and events below the snapshot height are not available.",
+		},
+	},
+}
+
+func buildGenesisBlock() (*types.Block, error) {
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return nil, fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		return nil, fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	return genesisBlock, nil
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	cfg, err := SetOntologyConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOntologyConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	genesisBlock, err := buildGenesisBlock()
+	if err != nil {
+		return err
+	}
+	bookKeepers, _ := config.DefConfig.GetBookkeepers()
+	store, err := ledgerstore.NewLedgerStore(dbDir, config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId))
+	if err != nil {
+		return fmt.Errorf("NewLedgerStore error:%s", err)
+	}
+	defer store.Close()
+	err = store.InitLedgerStoreWithGenesisBlock(genesisBlock, bookKeepers)
+	if err != nil {
+		return fmt.Errorf("InitLedgerStore error:%s", err)
+	}
+	height := store.GetCurrentBlockHeight()
+	if ctx.IsSet(utils.GetFlagName(utils.SnapshotHeightFlag)) {
+		height = uint32(ctx.Uint(utils.GetFlagName(utils.SnapshotHeightFlag)))
+	}
+
+	file, err := os.OpenFile(snapshotFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return fmt.Errorf("OpenFile error:%s", err)
+	}
+	PrintInfoMsg("Start export snapshot of height %d.", height)
+	info, err := store.ExportSnapshot(file, height)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(snapshotFile)
+		return fmt.Errorf("export snapshot error:%s", err)
+	}
+	PrintInfoMsg("Export snapshot complete.")
+	PrintInfoMsg("Height:%d", info.Height)
+	PrintInfoMsg("BlockHash:%s", info.BlockHash.ToHexString())
+	PrintInfoMsg("StateMerkleRoot:%s", info.StateMerkleRoot.ToHexString())
+	PrintInfoMsg("StateHash:%s", info.StateHash.ToHexString())
+	PrintInfoMsg("File:%s", snapshotFile)
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	cfg, err := SetOntologyConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOntologyConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	trustedHash, err := common.Uint256FromHexString(ctx.String(utils.GetFlagName(utils.SnapshotTrustedHashFlag)))
+	if err != nil {
+		PrintErrorMsg("Invalid %s argument:%s", utils.SnapshotTrustedHashFlag.Name, err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	stateHash, err := common.Uint256FromHexString(ctx.String(utils.GetFlagName(utils.SnapshotStateHashFlag)))
+	if err != nil {
+		PrintErrorMsg("Invalid %s argument:%s", utils.SnapshotStateHashFlag.Name, err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	if _, err := os.Stat(dbDir); err == nil {
+		return fmt.Errorf("data dir %s already exists, snapshot can only be imported into an empty data dir", dbDir)
+	}
+	genesisBlock, err := buildGenesisBlock()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(snapshotFile)
+	if err != nil {
+		return fmt.Errorf("Open error:%s", err)
+	}
+	defer file.Close()
+	PrintInfoMsg("Start import snapshot.")
+	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	bookKeepers, _ := config.DefConfig.GetBookkeepers()
+	info, err := ledgerstore.ImportSnapshot(dbDir, file, stateHashHeight, bookKeepers, genesisBlock.Hash(),
+		trustedHash, stateHash)
+	if err != nil {
+		os.RemoveAll(dbDir)
+		return fmt.Errorf("import snapshot error:%s", err)
+	}
+	PrintInfoMsg("Import snapshot complete, the node will sync from height %d.", info.Height+1)
+	PrintInfoMsg("BlockHash:%s", info.BlockHash.ToHexString())
+	PrintInfoMsg("StateMerkleRoot:%s", info.StateMerkleRoot.ToHexString())
+	PrintInfoMsg("StateHash:%s", info.StateHash.ToHexString())
+	return nil
+}
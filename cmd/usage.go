@@ -100,10 +100,18 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.ConfigFlag,
 			utils.LogLevelFlag,
+			utils.ModuleLogLevelFlag,
+			utils.LogFormatFlag,
 			utils.LogDirFlag,
 			utils.DisableLogFileFlag,
 			utils.DisableEventLogFlag,
+			utils.EnableStateProofFlag,
+			utils.EnableArchiveFlag,
+			utils.EnableAddressIndexFlag,
+			utils.EnableContractEventIndexFlag,
+			utils.StateUndoDepthFlag,
 			utils.DataDirFlag,
+			utils.DBBackendFlag,
 			utils.ETHTxGasLimitFlag,
 			utils.WasmVerifyMethodFlag,
 		},
@@ -136,6 +144,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.EnableConsensusFlag,
 			utils.MaxTxInBlockFlag,
+			utils.EnablePipelineFlag,
 		},
 	},
 	{
@@ -143,9 +152,14 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GasPriceFlag,
 			utils.GasLimitFlag,
+			utils.TxPoolCapacityFlag,
+			utils.TxPoolPayerSlotsFlag,
+			utils.TxPoolQueueLifetimeFlag,
 			utils.TxpoolPreExecDisableFlag,
 			utils.DisableSyncVerifyTxFlag,
 			utils.DisableBroadcastNetTxFlag,
+			utils.EnableTxPoolJournalFlag,
+			utils.TxPoolRejournalFlag,
 		},
 	},
 	{
@@ -169,6 +183,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCLocalEnableFlag,
 			utils.RPCLocalProtFlag,
 			utils.ETHRPCPortFlag,
+			utils.ETHWSEnableFlag,
+			utils.ETHWSPortFlag,
+			utils.ETHWSOriginsFlag,
+			utils.ETHLogsMaxBlockRangeFlag,
+			utils.ETHDebugApiEnableFlag,
 		},
 	},
 	{
@@ -185,6 +204,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.GraphQLEnableFlag,
 			utils.GraphQLPortFlag,
 			utils.GraphQLMaxConnsFlag,
+			utils.GraphQLWsOriginsFlag,
 		},
 	},
 	{
@@ -194,6 +214,31 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WsPortFlag,
 		},
 	},
+	{
+		Name: "METRICS",
+		Flags: []cli.Flag{
+			utils.MetricsEnableFlag,
+			utils.MetricsPortFlag,
+		},
+	},
+	{
+		Name: "TRACING",
+		Flags: []cli.Flag{
+			utils.TracingEnableFlag,
+			utils.TracingEndpointFlag,
+			utils.TracingFileFlag,
+		},
+	},
+	{
+		Name: "ADMIN RPC",
+		Flags: []cli.Flag{
+			utils.AdminRpcEnableFlag,
+			utils.AdminRpcIpcFlag,
+			utils.AdminRpcAddrFlag,
+			utils.AdminRpcPortFlag,
+			utils.AdminRpcTokenFileFlag,
+		},
+	},
 	{
 		Name: "TEST MODE",
 		Flags: []cli.Flag{
@@ -265,6 +310,27 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ImportEndHeightFlag,
 		},
 	},
+	{
+		Name: "SNAPSHOT",
+		Flags: []cli.Flag{
+			utils.SnapshotFileFlag,
+			utils.SnapshotHeightFlag,
+			utils.SnapshotTrustedHashFlag,
+			utils.SnapshotStateHashFlag,
+		},
+	},
+	{
+		Name: "DB CHECK",
+		Flags: []cli.Flag{
+			utils.DBRepairFlag,
+		},
+	},
+	{
+		Name: "DB ROLLBACK",
+		Flags: []cli.Flag{
+			utils.DBRollbackHeightFlag,
+		},
+	},
 	{
 		Name: "MISC",
 	},
@@ -29,6 +29,7 @@ import (
 
 const (
 	DEFAULT_EXPORT_FILE   = "./OntBlocks.dat"
+	DEFAULT_SNAPSHOT_FILE = "./OntSnapshot.dat"
 	DEFAULT_ABI_PATH      = "./abi"
 	DEFAULT_EXPORT_HEIGHT = 0
 	DEFAULT_WALLET_PATH   = "./wallet_data"
@@ -45,6 +46,15 @@ var (
 		Usage: "Set the log level to `<level>` (0~6). 0:Trace 1:Debug 2:Info 3:Warn 4:Error 5:Fatal 6:MaxLevel",
 		Value: config.DEFAULT_LOG_LEVEL,
 	}
+	ModuleLogLevelFlag = cli.StringFlag{
+		Name:  "module-loglevel",
+		Usage: "Set the log level of modules p2p, vbft, sbft, txnpool, ledger and http, overriding loglevel, e.g. `<p2p=1,vbft=1>`",
+	}
+	LogFormatFlag = cli.StringFlag{
+		Name:  "log-format",
+		Usage: "Log output format `<text|json>`",
+		Value: log.TEXT_FORMAT,
+	}
 	DisableLogFileFlag = cli.BoolFlag{
 		Name:  "disable-log-file",
 		Usage: "Discard log output to file",
@@ -58,6 +68,27 @@ var (
 		Name:  "disable-event-log",
 		Usage: "Discard event log output by smart contract execution",
 	}
+	EnableStateProofFlag = cli.BoolFlag{
+		Name:  "enable-state-proof",
+		Usage: "Record state history to serve state proofs, eg. eth_getProof",
+	}
+	EnableArchiveFlag = cli.BoolFlag{
+		Name:  "enable-archive",
+		Usage: "Run as archive node, retain state history to query the state of past blocks",
+	}
+	EnableAddressIndexFlag = cli.BoolFlag{
+		Name:  "enable-address-index",
+		Usage: "Index transactions by the addresses they touch to query the transaction history of an address",
+	}
+	EnableContractEventIndexFlag = cli.BoolFlag{
+		Name:  "enable-contract-event-index",
+		Usage: "Index smart contract events by contract address and event name to query the events of a contract",
+	}
+	StateUndoDepthFlag = cli.UintFlag{
+		Name:  "state-undo-depth",
+		Usage: "Keep state undo logs of the latest `<number>` blocks, the ledger can be rolled back so many blocks by db rollback command. 0 means no undo log",
+		Value: config.DEFAULT_STATE_UNDO_DEPTH,
+	}
 	WasmVerifyMethodFlag = cli.BoolFlag{
 		Name:  "enable-wasmjit-verifier",
 		Usage: "Enable wasmjit verifier to verify wasm contract",
@@ -82,6 +113,11 @@ var (
 		Usage: "Block data storage `<path>`",
 		Value: config.DEFAULT_DATA_DIR,
 	}
+	DBBackendFlag = cli.StringFlag{
+		Name:  "db-backend",
+		Usage: "Key-value store `<backend>` of ledger, leveldb or pebble. Use migrate command to convert an existing DB",
+		Value: config.DEFAULT_DB_BACKEND,
+	}
 	ETHTxGasLimitFlag = cli.Uint64Flag{
 		Name:  "eth-tx-gaslimit",
 		Usage: "ETH block total gas limit",
@@ -97,6 +133,10 @@ var (
 		Usage: "Max transaction `<number>` in block",
 		Value: config.DEFAULT_MAX_TX_IN_BLOCK,
 	}
+	EnablePipelineFlag = cli.BoolFlag{
+		Name:  "enable-pipeline",
+		Usage: "Execute the committed block and propose the next block before the block sealed",
+	}
 	GasLimitFlag = cli.Uint64Flag{
 		Name:  "gaslimit",
 		Usage: "Min gas limit `<value>` of transaction to be accepted by tx pool.",
@@ -107,6 +147,21 @@ var (
 		Usage: "Min gas price `<value>` of transaction to be accepted by tx pool.",
 		Value: config.DEFAULT_GAS_PRICE,
 	}
+	TxPoolCapacityFlag = cli.UintFlag{
+		Name:  "txpool-capacity",
+		Usage: "Max transaction `<number>` in tx pool, the transaction with the lowest gas price is evicted when full. 0 means unlimited",
+		Value: config.DEFAULT_TX_POOL_CAPACITY,
+	}
+	TxPoolPayerSlotsFlag = cli.UintFlag{
+		Name:  "txpool-payer-slots",
+		Usage: "Max EIP155 transaction `<number>` of a payer in tx pool. 0 means unlimited",
+		Value: config.DEFAULT_TX_POOL_PAYER_SLOTS,
+	}
+	TxPoolQueueLifetimeFlag = cli.UintFlag{
+		Name:  "txpool-queue-lifetime",
+		Usage: "Max `<time>`(s) the EIP155 transactions with nonce gap are queued in tx pool. 0 means unlimited",
+		Value: config.DEFAULT_TX_POOL_QUEUE_LIFETIME,
+	}
 	//Test Mode setting
 	EnableTestModeFlag = cli.BoolFlag{
 		Name:  "testmode",
@@ -173,6 +228,28 @@ var (
 		Usage: "Eth json rpc server listening port `<number>`",
 		Value: config.DEFAULT_ETH_RPC_PORT,
 	}
+	ETHWSEnableFlag = cli.BoolFlag{
+		Name:  "ethws",
+		Usage: "Enable eth json rpc websocket server",
+	}
+	ETHWSPortFlag = cli.UintFlag{
+		Name:  "ethwsport",
+		Usage: "Eth json rpc websocket server listening port `<number>`",
+		Value: config.DEFAULT_ETH_WS_PORT,
+	}
+	ETHWSOriginsFlag = cli.StringFlag{
+		Name:  "eth-ws-origins",
+		Usage: "Comma separated `<origins>` allowed to connect the eth websocket server from browser, \"*\" allows any origin, default only localhost",
+	}
+	ETHLogsMaxBlockRangeFlag = cli.UintFlag{
+		Name:  "eth-logs-max-block-range",
+		Usage: "Max block range `<number>` of eth_getLogs query, 0 means no limit",
+		Value: config.DEFAULT_ETH_LOGS_MAX_BLOCK_RANGE,
+	}
+	ETHDebugApiEnableFlag = cli.BoolFlag{
+		Name:  "eth-debug-api",
+		Usage: "Enable the debug namespace of eth json rpc, which re-executes transactions to trace them",
+	}
 	RPCLocalEnableFlag = cli.BoolFlag{
 		Name:  "localrpc",
 		Usage: "Enable local rpc server",
@@ -225,6 +302,61 @@ var (
 		Usage: "GraphQL server maximum connections `<number>`",
 		Value: config.DEFAULT_HTTP_MAX_CONN,
 	}
+	GraphQLWsOriginsFlag = cli.StringFlag{
+		Name:  "graphql-ws-origins",
+		Usage: "Comma separated `<origins>` allowed to connect the graphql subscription websocket from browser, \"*\" allows any origin, default only localhost",
+	}
+
+	//Metrics setting
+	MetricsEnableFlag = cli.BoolFlag{
+		Name:  "metrics",
+		Usage: "Enable prometheus metrics server",
+	}
+	MetricsPortFlag = cli.UintFlag{
+		Name:  "metrics-port",
+		Usage: "Prometheus metrics server listening port `<number>`",
+		Value: config.DEFAULT_METRICS_PORT,
+	}
+
+	//Tracing setting
+	TracingEnableFlag = cli.BoolFlag{
+		Name:  "tracing",
+		Usage: "Enable opentelemetry tracing of transaction lifecycle",
+	}
+	TracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing-endpoint",
+		Usage: "OTLP/HTTP traces endpoint `<url>`, empty to disable exporting over OTLP",
+		Value: config.DEFAULT_TRACING_ENDPOINT,
+	}
+	TracingFileFlag = cli.StringFlag{
+		Name:  "tracing-file",
+		Usage: "Write spans to `<file>` in json for offline debugging",
+	}
+
+	//Admin rpc setting
+	AdminRpcEnableFlag = cli.BoolFlag{
+		Name:  "admin",
+		Usage: "Enable admin rpc for runtime node management",
+	}
+	AdminRpcIpcFlag = cli.StringFlag{
+		Name:  "admin-ipc",
+		Usage: "Admin rpc unix socket `<path>`, empty to disable the socket",
+		Value: config.DEFAULT_ADMIN_IPC_PATH,
+	}
+	AdminRpcAddrFlag = cli.StringFlag{
+		Name:  "admin-addr",
+		Usage: "Admin rpc http server listening `<address>`, the token is sent in plain http, expose it to trusted networks only",
+		Value: config.DEFAULT_ADMIN_HTTP_ADDR,
+	}
+	AdminRpcPortFlag = cli.UintFlag{
+		Name:  "admin-port",
+		Usage: "Admin rpc http server listening port `<number>`, requests must carry the bearer token. 0 to disable",
+	}
+	AdminRpcTokenFileFlag = cli.StringFlag{
+		Name:  "admin-token-file",
+		Usage: "Admin rpc bearer token `<file>`, generated if not exists",
+		Value: config.DEFAULT_ADMIN_TOKEN_FILE,
+	}
 
 	//Account setting
 	AccountPassFlag = cli.StringFlag{
@@ -503,6 +635,39 @@ var (
 		Value: "m",
 	}
 
+	//Snapshot setting
+	SnapshotFileFlag = cli.StringFlag{
+		Name:  "snapshot-file",
+		Usage: "Snapshot `<file>` path",
+		Value: DEFAULT_SNAPSHOT_FILE,
+	}
+	SnapshotHeightFlag = cli.UintFlag{
+		Name:  "height",
+		Usage: "Block `<height>` of the snapshot, it must be within the state undo depth below current block height. Default is current block height",
+	}
+	SnapshotTrustedHashFlag = cli.StringFlag{
+		Name:  "trusted-hash",
+		Usage: "Trusted block `<hash>` of the snapshot height, eg. from a block explorer or a trusted node",
+	}
+	SnapshotStateHashFlag = cli.StringFlag{
+		Name:  "state-hash",
+		Usage: "Trusted state `<hash>` of the snapshot height, eg. StateHash of db check --calc-state-hash or snapshot export on a trusted node",
+	}
+
+	//DB check setting
+	DBRepairFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Roll the ledger stores back to the last consistent height if any inconsistency is found",
+	}
+	DBStateHashFlag = cli.BoolFlag{
+		Name:  "calc-state-hash",
+		Usage: "Calculate the hash of current world state, eg. as the trusted state hash of snapshot import. It reads the whole state",
+	}
+	DBRollbackHeightFlag = cli.UintFlag{
+		Name:  "height",
+		Usage: "Block `<height>` the ledger is rolled back to",
+	}
+
 	//PreExecute switcher
 	TxpoolPreExecDisableFlag = cli.BoolFlag{
 		Name:  "disable-tx-pool-pre-exec",
@@ -520,6 +685,17 @@ var (
 		Usage: "Disable broadcast tx from network in tx pool",
 	}
 
+	EnableTxPoolJournalFlag = cli.BoolFlag{
+		Name:  "enable-txpool-journal",
+		Usage: "Save local transactions of tx pool to disk, and resubmit them after restart",
+	}
+
+	TxPoolRejournalFlag = cli.UintFlag{
+		Name:  "txpool-rejournal",
+		Usage: "Time `<interval>`(s) to regenerate the tx pool journal",
+		Value: config.DEFAULT_TX_POOL_REJOURNAL,
+	}
+
 	NonOptionFlag = cli.StringFlag{
 		Name:  "option",
 		Usage: "this command does not need option, please run directly",
@@ -55,15 +55,18 @@ const (
 	CONSENSUS_TYPE_DBFT = "dbft"
 	CONSENSUS_TYPE_SOLO = "solo"
 	CONSENSUS_TYPE_VBFT = "vbft"
+	CONSENSUS_TYPE_SBFT = "sbft" // chained hotstuff upon the vbft governance config
 
 	DEFAULT_LOG_LEVEL                       = log.InfoLog
 	DEFAULT_ETH_RPC_PORT                    = 20339
+	DEFAULT_ETH_WS_PORT                     = 20340
 	DEFAULT_NODE_PORT                       = 20338
 	DEFAULT_RPC_PORT                        = 20336
 	DEFAULT_RPC_LOCAL_PORT                  = 20337
 	DEFAULT_GRAPHQL_PORT                    = 20333
 	DEFAULT_REST_PORT                       = 20334
 	DEFAULT_WS_PORT                         = 20335
+	DEFAULT_METRICS_PORT                    = 20341
 	DEFAULT_HTTP_MAX_CONN                   = 1024
 	DEFAULT_MAX_CONN_IN_BOUND               = 1024
 	DEFAULT_MAX_CONN_OUT_BOUND              = 1024
@@ -76,14 +79,28 @@ const (
 	DEFUALT_CLI_RPC_ADDRESS                 = "127.0.0.1"
 	DEFAULT_MIN_GAS_LIMIT                   = 20000
 	DEFAULT_GAS_PRICE                       = 500
+	DEFAULT_TX_POOL_CAPACITY                = 100140
+	DEFAULT_TX_POOL_PAYER_SLOTS             = 256
+	DEFAULT_TX_POOL_REJOURNAL               = 3600
+	DEFAULT_TX_POOL_QUEUE_LIFETIME          = 3 * 3600
 	DEFAULT_WASM_GAS_FACTOR                 = uint64(10)
 	DEFAULT_WASM_MAX_STEPCOUNT              = uint64(8000000)
 
 	DEFAULT_DATA_DIR      = "./Chain/"
 	DEFAULT_RESERVED_FILE = "./peers.rsv"
+	DEFAULT_DB_BACKEND    = "leveldb"
+
+	DEFAULT_TRACING_ENDPOINT = "http://127.0.0.1:4318/v1/traces"
+
+	DEFAULT_ADMIN_IPC_PATH   = "./admin.ipc"
+	DEFAULT_ADMIN_HTTP_ADDR  = "127.0.0.1"
+	DEFAULT_ADMIN_TOKEN_FILE = "./admin.token"
+
+	DEFAULT_STATE_UNDO_DEPTH = 0
 
 	//DEFAULT_ETH_BLOCK_GAS_LIMIT = 800000000
-	DEFAULT_ETH_TX_MAX_GAS_LIMIT = 6000000
+	DEFAULT_ETH_TX_MAX_GAS_LIMIT     = 6000000
+	DEFAULT_ETH_LOGS_MAX_BLOCK_RANGE = 10000
 )
 
 const (
@@ -634,14 +651,28 @@ type CommonConfig struct {
 	MinGasLimit    uint64
 	GasPrice       uint64
 	DataDir        string
+	DBBackend      string // key-value store backend of ledger, leveldb or pebble
 	ETHTxGasLimit  uint64
 	//NGasLimit        uint64
 	WasmVerifyMethod VerifyMethod
+	EnableStateProof bool
+	EnableArchive    bool
+	// index transactions by the addresses they touch, only blocks saved after enabling are indexed
+	EnableAddressIndex bool
+	// index notifications by contract address and event name, only blocks saved after enabling are indexed
+	EnableContractEventIndex bool
+	TxPoolCapacity           uint // max number of verified txs in the tx pool, 0 means unlimited
+	TxPoolPayerSlots         uint // max number of eip155 txs of a payer in the tx pool, 0 means unlimited
+	// max time in seconds the queued eip155 txs of a payer wait for the missing nonce, 0 means unlimited
+	TxPoolQueueLifetime uint
+	// number of latest blocks whose state undo logs are kept for db rollback, 0 means no undo log
+	StateUndoDepth uint
 }
 
 type ConsensusConfig struct {
 	EnableConsensus bool
 	MaxTxInBlock    uint
+	EnablePipeline  bool
 }
 
 type P2PRsvConfig struct {
@@ -651,6 +682,7 @@ type P2PRsvConfig struct {
 
 type P2PNodeConfig struct {
 	ReservedPeersOnly         bool
+	ReservedPeersFile         string
 	ReservedCfg               *P2PRsvConfig
 	NetworkMagic              uint32
 	NetworkId                 uint32
@@ -669,10 +701,14 @@ type P2PNodeConfig struct {
 }
 
 type RpcConfig struct {
-	EnableHttpJsonRpc bool
-	HttpJsonPort      uint
-	HttpLocalPort     uint
-	EthJsonPort       uint
+	EnableHttpJsonRpc    bool
+	HttpJsonPort         uint
+	HttpLocalPort        uint
+	EthJsonPort          uint
+	EthWsPort            uint     // 0 means disabled, the eth websocket server is started by --ethws
+	EthWsOrigins         []string // origins allowed to open the eth websocket from browser, empty means only localhost
+	EthLogsMaxBlockRange uint
+	EnableEthDebugApi    bool // debug_traceTransaction and debug_traceCall re-execute transactions, off by default
 }
 
 type RestfulConfig struct {
@@ -687,6 +723,7 @@ type GraphQLConfig struct {
 	EnableGraphQL  bool
 	GraphQLPort    uint
 	MaxConnections uint
+	WsOrigins      []string // origins allowed to open the subscription websocket from browser, empty means only localhost
 }
 
 type WebSocketConfig struct {
@@ -696,6 +733,25 @@ type WebSocketConfig struct {
 	HttpKeyPath  string
 }
 
+type MetricsConfig struct {
+	EnableMetrics bool
+	MetricsPort   uint
+}
+
+type TracingConfig struct {
+	EnableTracing bool
+	Endpoint      string // OTLP/HTTP traces endpoint, empty to disable the OTLP exporter
+	TraceFile     string // file to write spans in json, empty to disable the file exporter
+}
+
+type AdminRpcConfig struct {
+	EnableAdminRpc bool
+	IpcPath        string // unix socket path, empty to disable the socket
+	HttpAddr       string // address the http server listens on, loopback by default since the token is sent in plain http
+	HttpPort       uint   // token protected http port, 0 to disable the http server
+	TokenFile      string // file of the bearer token, generated if not exists
+}
+
 type OntologyConfig struct {
 	Genesis   *GenesisConfig
 	Common    *CommonConfig
@@ -705,19 +761,27 @@ type OntologyConfig struct {
 	Restful   *RestfulConfig
 	GraphQL   *GraphQLConfig
 	Ws        *WebSocketConfig
+	Metrics   *MetricsConfig
+	Tracing   *TracingConfig
+	AdminRpc  *AdminRpcConfig
 }
 
 func NewOntologyConfig() *OntologyConfig {
 	return &OntologyConfig{
 		Genesis: MainNetConfig,
 		Common: &CommonConfig{
-			LogLevel:         DEFAULT_LOG_LEVEL,
-			EnableEventLog:   DEFAULT_ENABLE_EVENT_LOG,
-			SystemFee:        make(map[string]int64),
-			MinGasLimit:      DEFAULT_MIN_GAS_LIMIT,
-			DataDir:          DEFAULT_DATA_DIR,
-			WasmVerifyMethod: InterpVerifyMethod,
-			ETHTxGasLimit:    DEFAULT_ETH_TX_MAX_GAS_LIMIT,
+			LogLevel:            DEFAULT_LOG_LEVEL,
+			EnableEventLog:      DEFAULT_ENABLE_EVENT_LOG,
+			SystemFee:           make(map[string]int64),
+			MinGasLimit:         DEFAULT_MIN_GAS_LIMIT,
+			DataDir:             DEFAULT_DATA_DIR,
+			DBBackend:           DEFAULT_DB_BACKEND,
+			StateUndoDepth:      DEFAULT_STATE_UNDO_DEPTH,
+			WasmVerifyMethod:    InterpVerifyMethod,
+			ETHTxGasLimit:       DEFAULT_ETH_TX_MAX_GAS_LIMIT,
+			TxPoolCapacity:      DEFAULT_TX_POOL_CAPACITY,
+			TxPoolPayerSlots:    DEFAULT_TX_POOL_PAYER_SLOTS,
+			TxPoolQueueLifetime: DEFAULT_TX_POOL_QUEUE_LIFETIME,
 		},
 		Consensus: &ConsensusConfig{
 			EnableConsensus: true,
@@ -742,9 +806,11 @@ func NewOntologyConfig() *OntologyConfig {
 			MaxConnInBoundForSingleIP: DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP,
 		},
 		Rpc: &RpcConfig{
-			EnableHttpJsonRpc: true,
-			HttpJsonPort:      DEFAULT_RPC_PORT,
-			HttpLocalPort:     DEFAULT_RPC_LOCAL_PORT,
+			EnableHttpJsonRpc:    true,
+			HttpJsonPort:         DEFAULT_RPC_PORT,
+			HttpLocalPort:        DEFAULT_RPC_LOCAL_PORT,
+			EthJsonPort:          DEFAULT_ETH_RPC_PORT,
+			EthLogsMaxBlockRange: DEFAULT_ETH_LOGS_MAX_BLOCK_RANGE,
 		},
 		Restful: &RestfulConfig{
 			EnableHttpRestful: true,
@@ -758,13 +824,27 @@ func NewOntologyConfig() *OntologyConfig {
 			EnableHttpWs: true,
 			HttpWsPort:   DEFAULT_WS_PORT,
 		},
+		Metrics: &MetricsConfig{
+			EnableMetrics: false,
+			MetricsPort:   DEFAULT_METRICS_PORT,
+		},
+		Tracing: &TracingConfig{
+			EnableTracing: false,
+			Endpoint:      DEFAULT_TRACING_ENDPOINT,
+		},
+		AdminRpc: &AdminRpcConfig{
+			EnableAdminRpc: false,
+			IpcPath:        DEFAULT_ADMIN_IPC_PATH,
+			HttpAddr:       DEFAULT_ADMIN_HTTP_ADDR,
+			TokenFile:      DEFAULT_ADMIN_TOKEN_FILE,
+		},
 	}
 }
 
 func (this *OntologyConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
 	var bookKeepers []string
 	switch this.Genesis.ConsensusType {
-	case CONSENSUS_TYPE_VBFT:
+	case CONSENSUS_TYPE_VBFT, CONSENSUS_TYPE_SBFT:
 		for _, peer := range this.Genesis.VBFT.Peers {
 			bookKeepers = append(bookKeepers, peer.PeerPubkey)
 		}
@@ -818,7 +898,7 @@ func (this *OntologyConfig) getDefNetworkIDFromGenesisConfig(genCfg *GenesisConf
 	var configData []byte
 	var err error
 	switch this.Genesis.ConsensusType {
-	case CONSENSUS_TYPE_VBFT:
+	case CONSENSUS_TYPE_VBFT, CONSENSUS_TYPE_SBFT:
 		configData, err = json.Marshal(genCfg.VBFT)
 	case CONSENSUS_TYPE_DBFT:
 		configData, err = json.Marshal(genCfg.DBFT)
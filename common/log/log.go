@@ -26,10 +26,8 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
 	"runtime"
 	"strconv"
-	"strings"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -114,14 +112,16 @@ func LevelName(level int) string {
 }
 
 type Logger struct {
-	level   int
+	level   int32
+	format  string
 	logger  *log.Logger
 	logFile *os.File
 }
 
 func New(out io.Writer, prefix string, flag, level int, file *os.File) *Logger {
 	return &Logger{
-		level:   level,
+		level:   int32(level),
+		format:  TEXT_FORMAT,
 		logger:  log.New(out, prefix, flag),
 		logFile: file,
 	}
@@ -132,30 +132,25 @@ func (l *Logger) SetDebugLevel(level int) error {
 		return errors.New("invalid Debug Level")
 	}
 
-	l.level = level
+	atomic.StoreInt32(&l.level, int32(level))
 	return nil
 }
 
-func (l *Logger) Output(level int, a ...interface{}) error {
-	if level >= l.level {
-		gid := GetGID()
-		gidStr := strconv.FormatUint(gid, 10)
-
-		a = append([]interface{}{LevelName(level), "GID",
-			gidStr + ","}, a...)
+// GetDebugLevel returns the global log level
+func (l *Logger) GetDebugLevel() int {
+	return int(atomic.LoadInt32(&l.level))
+}
 
-		return l.logger.Output(CALL_DEPTH, fmt.Sprintln(a...))
+func (l *Logger) Output(level int, a ...interface{}) error {
+	if level >= l.GetDebugLevel() {
+		return l.write(&record{level: level, args: a})
 	}
 	return nil
 }
 
 func (l *Logger) Outputf(level int, format string, v ...interface{}) error {
-	if level >= l.level {
-		gid := GetGID()
-		v = append([]interface{}{LevelName(level), "GID",
-			gid}, v...)
-
-		return l.logger.Output(CALL_DEPTH, fmt.Sprintf("%s %s %d, "+format+"\n", v...))
+	if level >= l.GetDebugLevel() {
+		return l.write(&record{level: level, format: format, args: v})
 	}
 	return nil
 }
@@ -209,107 +204,51 @@ func (l *Logger) Fatalf(format string, a ...interface{}) {
 }
 
 func Trace(a ...interface{}) {
-	if TraceLog < Log().level {
-		return
-	}
-
-	pc := make([]uintptr, 10)
-	runtime.Callers(2, pc)
-	f := runtime.FuncForPC(pc[0])
-	file, line := f.FileLine(pc[0])
-	fileName := filepath.Base(file)
-
-	nameFull := f.Name()
-	nameEnd := filepath.Ext(nameFull)
-	funcName := strings.TrimPrefix(nameEnd, ".")
-
-	a = append([]interface{}{funcName + "()", fileName + ":" + strconv.Itoa(line)}, a...)
-
-	Log().Trace(a...)
+	output(TraceLog, nil, a...)
 }
 
 func Tracef(format string, a ...interface{}) {
-	if TraceLog < Log().level {
-		return
-	}
-
-	pc := make([]uintptr, 10)
-	runtime.Callers(2, pc)
-	f := runtime.FuncForPC(pc[0])
-	file, line := f.FileLine(pc[0])
-	fileName := filepath.Base(file)
-
-	nameFull := f.Name()
-	nameEnd := filepath.Ext(nameFull)
-	funcName := strings.TrimPrefix(nameEnd, ".")
-
-	a = append([]interface{}{funcName, fileName, line}, a...)
-
-	Log().Tracef("%s() %s:%d "+format, a...)
+	outputf(TraceLog, nil, format, a...)
 }
 
 func Debug(a ...interface{}) {
-	if DebugLog < Log().level {
-		return
-	}
-
-	pc := make([]uintptr, 10)
-	runtime.Callers(2, pc)
-	f := runtime.FuncForPC(pc[0])
-	file, line := f.FileLine(pc[0])
-	fileName := filepath.Base(file)
-
-	a = append([]interface{}{f.Name(), fileName + ":" + strconv.Itoa(line)}, a...)
-
-	Log().Debug(a...)
+	output(DebugLog, nil, a...)
 }
 
 func Debugf(format string, a ...interface{}) {
-	if DebugLog < Log().level {
-		return
-	}
-
-	pc := make([]uintptr, 10)
-	runtime.Callers(2, pc)
-	f := runtime.FuncForPC(pc[0])
-	file, line := f.FileLine(pc[0])
-	fileName := filepath.Base(file)
-
-	a = append([]interface{}{f.Name(), fileName, line}, a...)
-
-	Log().Debugf("%s %s:%d "+format, a...)
+	outputf(DebugLog, nil, format, a...)
 }
 
 func Info(a ...interface{}) {
-	Log().Info(a...)
+	output(InfoLog, nil, a...)
 }
 
 func Warn(a ...interface{}) {
-	Log().Warn(a...)
+	output(WarnLog, nil, a...)
 }
 
 func Error(a ...interface{}) {
-	Log().Error(a...)
+	output(ErrorLog, nil, a...)
 }
 
 func Fatal(a ...interface{}) {
-	Log().Fatal(a...)
+	output(FatalLog, nil, a...)
 }
 
 func Infof(format string, a ...interface{}) {
-	Log().Infof(format, a...)
+	outputf(InfoLog, nil, format, a...)
 }
 
 func Warnf(format string, a ...interface{}) {
-	Log().Warnf(format, a...)
+	outputf(WarnLog, nil, format, a...)
 }
 
 func Errorf(format string, a ...interface{}) {
-	Log().Errorf(format, a...)
+	outputf(ErrorLog, nil, format, a...)
 }
 
 func Fatalf(format string, a ...interface{}) {
-	Log().Fatalf(format, a...)
+	outputf(FatalLog, nil, format, a...)
 }
 
 // used for develop stage and not allowed in production enforced by CI
@@ -374,7 +313,14 @@ func createLog(logLevel int, a ...interface{}) *Logger {
 	}
 	fileAndStdoutWrite := io.MultiWriter(writers...)
 
-	logger := New(fileAndStdoutWrite, "", log.LUTC|log.Ldate|log.Lmicroseconds, logLevel, logFile)
+	format := getFormat()
+	flag := log.LUTC | log.Ldate | log.Lmicroseconds
+	if format == JSON_FORMAT {
+		// the time is a field of json log
+		flag = 0
+	}
+	logger := New(fileAndStdoutWrite, "", flag, logLevel, logFile)
+	logger.format = format
 
 	return logger
 }
@@ -428,7 +374,7 @@ func ClosePrintLog() error {
 func CheckRotateLogFile() {
 	isNeedNewFile := checkIfNeedNewFile()
 	if isNeedNewFile {
-		old := swapGlobalLogger(createLog(Log().level, PATH, Stdout))
+		old := swapGlobalLogger(createLog(Log().GetDebugLevel(), PATH, Stdout))
 		if old.logFile != nil {
 			_ = old.logFile.Close()
 		}
@@ -18,9 +18,12 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -100,3 +103,82 @@ func TestDataRace(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestFuncModule(t *testing.T) {
+	assert.Equal(t, MODULE_VBFT, funcModule("github.com/ontio/ontology/consensus/vbft.(*Server).sealBlock.func1"))
+	assert.Equal(t, MODULE_SBFT, funcModule("github.com/ontio/ontology/consensus/sbft.(*SbftService).Start"))
+	assert.Equal(t, MODULE_P2P, funcModule("github.com/ontio/ontology/p2pserver/net/netserver.(*NetServer).Start"))
+	assert.Equal(t, MODULE_LEDGER, funcModule("github.com/ontio/ontology/core/store/ledgerstore.NewLedgerStore"))
+	assert.Equal(t, MODULE_HTTP, funcModule("github.com/ontio/ontology/http/base/rpc.Handle"))
+	assert.Equal(t, MODULE_OTHER, funcModule("github.com/ontio/ontology/httpx.Handle"))
+	assert.Equal(t, MODULE_OTHER, funcModule("github.com/ontio/ontology/consensus/dbft.NewDbftService"))
+	assert.Equal(t, MODULE_OTHER, funcModule("main.main"))
+}
+
+func TestModuleLevel(t *testing.T) {
+	var buf bytes.Buffer
+	old := swapGlobalLogger(New(&buf, "", 0, WarnLog, nil))
+	defer swapGlobalLogger(old)
+	defer ResetModuleLevel(MODULE_P2P)
+
+	assert.NotNil(t, SetModuleLevel("unknown", DebugLog))
+	assert.NotNil(t, SetModuleLevel(MODULE_P2P, MaxLevelLog+1))
+	assert.NotNil(t, SetModuleLevels("p2p:1"))
+	assert.Nil(t, SetModuleLevels("p2p=1, vbft=4"))
+	assert.Equal(t, map[string]int{MODULE_P2P: DebugLog, MODULE_VBFT: ErrorLog}, ModuleLevels())
+	assert.Nil(t, ResetModuleLevel(MODULE_VBFT))
+
+	// the test is of module other, which logs with the global level
+	Info("info")
+	Debugf("debug %d", 1)
+	assert.Equal(t, "", buf.String())
+	Warn("warn")
+	assert.Contains(t, buf.String(), "warn")
+
+	buf.Reset()
+	rec := Log().newRecord(DebugLog, 1, nil)
+	assert.Nil(t, rec)
+	Log().SetDebugLevel(DebugLog)
+	rec = Log().newRecord(DebugLog, 1, nil)
+	assert.NotNil(t, rec)
+	assert.Equal(t, MODULE_OTHER, rec.module)
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "", 0, InfoLog, nil)
+	logger.format = JSON_FORMAT
+	old := swapGlobalLogger(logger)
+	defer swapGlobalLogger(old)
+
+	WithFields(Fields{FIELD_HEIGHT: 10, FIELD_TXHASH: "abcd", "msg": "ignored"}).Infof("sealed %d", 1)
+	Debug("debug")
+	Error("error", 2)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], `{"time":`))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, MODULE_OTHER, entry["module"])
+	assert.Equal(t, "sealed 1", entry["msg"])
+	assert.Equal(t, float64(10), entry[FIELD_HEIGHT])
+	assert.Equal(t, "abcd", entry[FIELD_TXHASH])
+
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Equal(t, "error 2", entry["msg"])
+}
+
+func TestTextFields(t *testing.T) {
+	var buf bytes.Buffer
+	old := swapGlobalLogger(New(&buf, "", 0, InfoLog, nil))
+	defer swapGlobalLogger(old)
+
+	WithFields(Fields{FIELD_TXHASH: "abcd", FIELD_HEIGHT: 10}).Info("tx", "added")
+	assert.True(t, strings.HasPrefix(buf.String(), LevelName(InfoLog)+" GID "))
+	// the fields are only written in json format
+	assert.True(t, strings.HasSuffix(buf.String(), ", tx added\n"))
+}
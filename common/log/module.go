@@ -0,0 +1,423 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// log output formats
+const (
+	TEXT_FORMAT = "text"
+	JSON_FORMAT = "json"
+)
+
+// modules which log level can be set separately, the logs of other packages are of MODULE_OTHER
+const (
+	MODULE_P2P     = "p2p"
+	MODULE_VBFT    = "vbft"
+	MODULE_SBFT    = "sbft"
+	MODULE_TXNPOOL = "txnpool"
+	MODULE_LEDGER  = "ledger"
+	MODULE_HTTP    = "http"
+	MODULE_OTHER   = "other"
+)
+
+// stable field names of structured logs
+const (
+	FIELD_HEIGHT = "height"
+	FIELD_TXHASH = "txhash" // hex string of the transaction hash
+)
+
+const (
+	PKG_PREFIX = "github.com/ontio/ontology/"
+	// call depth from newRecord to the caller of the package level log functions
+	RECORD_CALL_DEPTH = 3
+)
+
+var modulePackages = []struct {
+	pkg    string
+	module string
+}{
+	{"p2pserver", MODULE_P2P},
+	{"consensus/vbft", MODULE_VBFT},
+	{"consensus/sbft", MODULE_SBFT},
+	{"txnpool", MODULE_TXNPOOL},
+	{"validator", MODULE_TXNPOOL},
+	{"core/ledger", MODULE_LEDGER},
+	{"core/store", MODULE_LEDGER},
+	{"http", MODULE_HTTP},
+}
+
+var jsonLevels = map[int]string{
+	TraceLog: "trace",
+	DebugLog: "debug",
+	InfoLog:  "info",
+	WarnLog:  "warn",
+	ErrorLog: "error",
+	FatalLog: "fatal",
+}
+
+var (
+	logFormat atomic.Value // string
+
+	moduleLock     sync.Mutex
+	moduleLevels   atomic.Value // map[string]int, replaced on update
+	minModuleLevel int32        = MaxLevelLog
+
+	callers sync.Map // pc => *callerFunc
+)
+
+// Fields are the structured fields of a log, eg. FIELD_HEIGHT and FIELD_TXHASH. They are only written in
+// JSON format, the text format keeps the message as it is.
+type Fields map[string]interface{}
+
+// Entry logs with the structured fields
+type Entry struct {
+	fields Fields
+}
+
+func WithFields(fields Fields) *Entry {
+	return &Entry{fields: fields}
+}
+
+func (e *Entry) Debug(a ...interface{}) {
+	output(DebugLog, e.fields, a...)
+}
+
+func (e *Entry) Debugf(format string, a ...interface{}) {
+	outputf(DebugLog, e.fields, format, a...)
+}
+
+func (e *Entry) Info(a ...interface{}) {
+	output(InfoLog, e.fields, a...)
+}
+
+func (e *Entry) Infof(format string, a ...interface{}) {
+	outputf(InfoLog, e.fields, format, a...)
+}
+
+func (e *Entry) Warn(a ...interface{}) {
+	output(WarnLog, e.fields, a...)
+}
+
+func (e *Entry) Warnf(format string, a ...interface{}) {
+	outputf(WarnLog, e.fields, format, a...)
+}
+
+func (e *Entry) Error(a ...interface{}) {
+	output(ErrorLog, e.fields, a...)
+}
+
+func (e *Entry) Errorf(format string, a ...interface{}) {
+	outputf(ErrorLog, e.fields, format, a...)
+}
+
+// SetFormat sets the output format of the loggers created by InitLog afterwards
+func SetFormat(format string) error {
+	switch format {
+	case TEXT_FORMAT, JSON_FORMAT:
+	default:
+		return fmt.Errorf("invalid log format %s", format)
+	}
+	logFormat.Store(format)
+	return nil
+}
+
+func getFormat() string {
+	if format, ok := logFormat.Load().(string); ok {
+		return format
+	}
+	return TEXT_FORMAT
+}
+
+// Modules returns the modules which log level can be set
+func Modules() []string {
+	return []string{MODULE_P2P, MODULE_VBFT, MODULE_SBFT, MODULE_TXNPOOL, MODULE_LEDGER, MODULE_HTTP}
+}
+
+func isModule(module string) bool {
+	for _, m := range Modules() {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+func getModuleLevels() map[string]int {
+	levels, _ := moduleLevels.Load().(map[string]int)
+	return levels
+}
+
+// ModuleLevels returns the modules which log level is set separately from the global level
+func ModuleLevels() map[string]int {
+	levels := make(map[string]int)
+	for module, level := range getModuleLevels() {
+		levels[module] = level
+	}
+	return levels
+}
+
+// SetModuleLevel sets the log level of module, which overrides the global level
+func SetModuleLevel(module string, level int) error {
+	if level > MaxLevelLog || level < 0 {
+		return fmt.Errorf("invalid log level %d", level)
+	}
+	return updateModuleLevel(module, func(levels map[string]int) {
+		levels[module] = level
+	})
+}
+
+// ResetModuleLevel makes module log with the global level again
+func ResetModuleLevel(module string) error {
+	return updateModuleLevel(module, func(levels map[string]int) {
+		delete(levels, module)
+	})
+}
+
+func updateModuleLevel(module string, update func(levels map[string]int)) error {
+	if !isModule(module) {
+		return fmt.Errorf("unknown log module %s", module)
+	}
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	levels := ModuleLevels()
+	update(levels)
+	min := MaxLevelLog
+	for _, level := range levels {
+		if level < min {
+			min = level
+		}
+	}
+	moduleLevels.Store(levels)
+	atomic.StoreInt32(&minModuleLevel, int32(min))
+	return nil
+}
+
+// SetModuleLevels sets the log levels of modules in the form of "p2p=1,vbft=0"
+func SetModuleLevels(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid module log level %s", item)
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return fmt.Errorf("invalid module log level %s", item)
+		}
+		if err := SetModuleLevel(strings.TrimSpace(kv[0]), level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type callerFunc struct {
+	name   string
+	module string
+}
+
+func getCallerFunc(pc uintptr) *callerFunc {
+	if fn, ok := callers.Load(pc); ok {
+		return fn.(*callerFunc)
+	}
+	fn := &callerFunc{module: MODULE_OTHER}
+	if f := runtime.FuncForPC(pc); f != nil {
+		fn.name = f.Name()
+		fn.module = funcModule(fn.name)
+	}
+	callers.Store(pc, fn)
+	return fn
+}
+
+// funcModule returns the module of the package of a function name like
+// github.com/ontio/ontology/consensus/vbft.(*Server).sealBlock
+func funcModule(name string) string {
+	if !strings.HasPrefix(name, PKG_PREFIX) {
+		return MODULE_OTHER
+	}
+	name = name[len(PKG_PREFIX):]
+	for _, mp := range modulePackages {
+		if strings.HasPrefix(name, mp.pkg) {
+			rest := name[len(mp.pkg):]
+			if rest == "" || rest[0] == '/' || rest[0] == '.' {
+				return mp.module
+			}
+		}
+	}
+	return MODULE_OTHER
+}
+
+type record struct {
+	level  int
+	module string
+	fn     string // function of the caller
+	caller string // file:line of the caller
+	fields Fields
+	format string // printf format, the args are printed like println if empty
+	args   []interface{}
+}
+
+func (rec *record) message() string {
+	if rec.format == "" {
+		return strings.TrimSuffix(fmt.Sprintln(rec.args...), "\n")
+	}
+	return fmt.Sprintf(rec.format, rec.args...)
+}
+
+// newRecord returns nil if level is disabled for the module of the caller skip frames above
+func (l *Logger) newRecord(level int, skip int, fields Fields) *record {
+	global := l.GetDebugLevel()
+	if level < global && level < int(atomic.LoadInt32(&minModuleLevel)) {
+		return nil
+	}
+	rec := &record{level: level, fields: fields}
+	levels := getModuleLevels()
+	if len(levels) == 0 && level > DebugLog && l.format != JSON_FORMAT {
+		// no need to find the caller
+		return rec
+	}
+
+	pc, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		rec.module = MODULE_OTHER
+	} else {
+		fn := getCallerFunc(pc)
+		rec.module, rec.fn = fn.module, fn.name
+		rec.caller = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	if moduleLevel, ok := levels[rec.module]; ok {
+		global = moduleLevel
+	}
+	if level < global {
+		return nil
+	}
+	return rec
+}
+
+func output(level int, fields Fields, a ...interface{}) {
+	logger := Log()
+	if rec := logger.newRecord(level, RECORD_CALL_DEPTH, fields); rec != nil {
+		rec.args = a
+		_ = logger.write(rec)
+	}
+}
+
+func outputf(level int, fields Fields, format string, a ...interface{}) {
+	logger := Log()
+	if rec := logger.newRecord(level, RECORD_CALL_DEPTH, fields); rec != nil {
+		rec.format, rec.args = format, a
+		_ = logger.write(rec)
+	}
+}
+
+func (l *Logger) write(rec *record) error {
+	if l.format == JSON_FORMAT {
+		return l.writeJSON(rec)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s GID %d, ", LevelName(rec.level), GetGID())
+	if rec.level <= DebugLog && rec.fn != "" {
+		if rec.level == TraceLog {
+			buf.WriteString(strings.TrimPrefix(filepath.Ext(rec.fn), ".") + "() ")
+		} else {
+			buf.WriteString(rec.fn + " ")
+		}
+		buf.WriteString(rec.caller + " ")
+	}
+	buf.WriteString(rec.message())
+	buf.WriteByte('\n')
+	return l.logger.Output(CALL_DEPTH, buf.String())
+}
+
+func (l *Logger) writeJSON(rec *record) error {
+	module := rec.module
+	if module == "" {
+		module = MODULE_OTHER
+	}
+	level, ok := jsonLevels[rec.level]
+	if !ok {
+		level = strings.ToLower(NAME_PREFIX) + strconv.Itoa(rec.level)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeJSONField(&buf, "time", time.Now().UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
+	buf.WriteByte(',')
+	writeJSONField(&buf, "level", level)
+	buf.WriteByte(',')
+	writeJSONField(&buf, "module", module)
+	buf.WriteByte(',')
+	writeJSONField(&buf, "gid", GetGID())
+	if rec.caller != "" {
+		buf.WriteByte(',')
+		writeJSONField(&buf, "caller", rec.caller)
+	}
+	buf.WriteByte(',')
+	writeJSONField(&buf, "msg", rec.message())
+	for _, key := range sortedKeys(rec.fields) {
+		switch key {
+		case "time", "level", "module", "gid", "caller", "msg":
+			continue
+		}
+		buf.WriteByte(',')
+		writeJSONField(&buf, key, rec.fields[key])
+	}
+	buf.WriteString("}\n")
+	return l.logger.Output(CALL_DEPTH, buf.String())
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(v)
+}
+
+func sortedKeys(fields Fields) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
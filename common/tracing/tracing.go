@@ -0,0 +1,242 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package tracing records the opentelemetry spans of the transaction lifecycle. The trace id of a
+// transaction is derived from its hash, so the spans recorded by rpc, tx pool, validators, consensus
+// and ledger of every node for the same transaction are correlated in one trace without passing a
+// context between modules.
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName      = "github.com/ontio/ontology"
+	shutdownTimeout = 5 * time.Second
+)
+
+// span attributes shared by the modules
+const (
+	ATTR_BLOCK_HEIGHT = attribute.Key("ontology.block.height")
+	ATTR_PROPOSER     = attribute.Key("ontology.vbft.proposer")
+	ATTR_SENDER       = attribute.Key("ontology.tx.sender")
+	ATTR_ERROR_CODE   = attribute.Key("ontology.error.code")
+)
+
+var (
+	lock     sync.RWMutex             // guards provider, tracer and files
+	provider *sdktrace.TracerProvider // nil if tracing is disabled
+	tracer   trace.Tracer
+	files    []*os.File
+
+	noopSpan = trace.SpanFromContext(context.Background())
+)
+
+type txHashKey struct{}
+
+// Start installs the span exporters of cfg. It must be called before the services which record spans are started.
+func Start(cfg *config.TracingConfig) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if !cfg.EnableTracing || provider != nil {
+		return nil
+	}
+	if cfg.Endpoint == "" && cfg.TraceFile == "" {
+		return fmt.Errorf("neither tracing endpoint nor tracing file is set")
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String("ontology"),
+		semconv.ServiceVersionKey.String(config.Version),
+	)
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithIDGenerator(newTxIDGenerator()),
+	}
+	if cfg.Endpoint != "" {
+		exporter, err := newOtlpExporter(cfg.Endpoint)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	if cfg.TraceFile != "" {
+		file, err := os.OpenFile(cfg.TraceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("open tracing file: %s", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return err
+		}
+		files = append(files, file)
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider = sdktrace.NewTracerProvider(opts...)
+	tracer = provider.Tracer(tracerName)
+	return nil
+}
+
+func newOtlpExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid tracing endpoint %s", endpoint)
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("unsupported tracing endpoint scheme %s", u.Scheme)
+	}
+	if u.Path != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	// the exporter connects lazily, an unreachable collector only fails the upload of spans
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+// Stop flushes the pending spans and closes the exporters
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Warnf("shutdown tracing error: %s", err)
+	}
+	for _, file := range files {
+		file.Close()
+	}
+	provider, tracer, files = nil, nil, nil
+}
+
+// Enabled returns whether spans are recorded
+func Enabled() bool {
+	return getTracer() != nil
+}
+
+// getTracer returns the tracer, nil if tracing is disabled
+func getTracer() trace.Tracer {
+	lock.RLock()
+	defer lock.RUnlock()
+	if provider == nil {
+		return nil
+	}
+	return tracer
+}
+
+// TxTraceID returns the trace id of the transaction, which is the first half of the hex string of its hash
+func TxTraceID(hash common.Uint256) trace.TraceID {
+	var id trace.TraceID
+	copy(id[:], common.ToArrayReverse(hash[:]))
+	return id
+}
+
+// StartTxSpan starts a span in the trace of the transaction, the caller must end it with EndTxSpan
+func StartTxSpan(hash common.Uint256, name string, attrs ...attribute.KeyValue) trace.Span {
+	tracer := getTracer()
+	if tracer == nil {
+		return noopSpan
+	}
+	ctx := context.WithValue(context.Background(), txHashKey{}, hash)
+	_, span := tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return span
+}
+
+// EndTxSpan ends the span started by StartTxSpan, marks it failed if errCode is not ErrNoError
+func EndTxSpan(span trace.Span, errCode errors.ErrCode) {
+	if !span.IsRecording() {
+		return
+	}
+	if errCode != errors.ErrNoError {
+		span.SetAttributes(ATTR_ERROR_CODE.Int64(int64(errCode)))
+		span.SetStatus(codes.Error, errCode.Error())
+	}
+	span.End()
+}
+
+// RecordTxSpan records a span started at start and ended now in the trace of the transaction
+func RecordTxSpan(hash common.Uint256, name string, start time.Time, attrs ...attribute.KeyValue) {
+	tracer := getTracer()
+	if tracer == nil {
+		return
+	}
+	ctx := context.WithValue(context.Background(), txHashKey{}, hash)
+	_, span := tracer.Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	span.End()
+}
+
+// txIDGenerator generates the trace id of the spans started by StartTxSpan and RecordTxSpan from the
+// transaction hash, and random ids for the others.
+type txIDGenerator struct {
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+func newTxIDGenerator() *txIDGenerator {
+	var seed int64
+	binary.Read(crand.Reader, binary.LittleEndian, &seed)
+	return &txIDGenerator{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (gen *txIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	var traceID trace.TraceID
+	if hash, ok := ctx.Value(txHashKey{}).(common.Uint256); ok {
+		traceID = TxTraceID(hash)
+	} else {
+		gen.lock.Lock()
+		gen.rand.Read(traceID[:])
+		gen.lock.Unlock()
+	}
+	return traceID, gen.NewSpanID(ctx, traceID)
+}
+
+func (gen *txIDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	gen.lock.Lock()
+	gen.rand.Read(spanID[:])
+	gen.lock.Unlock()
+	return spanID
+}
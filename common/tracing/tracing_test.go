@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package tracing
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxTraceID(t *testing.T) {
+	hash := common.Uint256{1, 2, 3}
+	hash[31] = 0xff
+	id := TxTraceID(hash)
+	assert.Equal(t, hash.ToHexString()[:32], id.String())
+}
+
+func TestTxSpans(t *testing.T) {
+	span := StartTxSpan(common.Uint256{}, "disabled")
+	assert.False(t, span.IsRecording())
+	EndTxSpan(span, errors.ErrNoError)
+
+	file := filepath.Join(t.TempDir(), "trace.json")
+	err := Start(&config.TracingConfig{EnableTracing: true, TraceFile: file})
+	assert.Nil(t, err)
+	assert.True(t, Enabled())
+
+	hash := common.Uint256{}
+	hash[30], hash[31] = 0xab, 0xcd
+	span = StartTxSpan(hash, "txpool.VerifyTx", ATTR_SENDER.String("http"))
+	assert.True(t, span.IsRecording())
+	EndTxSpan(span, errors.ErrDuplicatedTx)
+	RecordTxSpan(hash, "ledger.SubmitBlock", time.Now(), ATTR_BLOCK_HEIGHT.Int64(10))
+	Stop()
+	assert.False(t, Enabled())
+
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	output := string(data)
+	assert.Equal(t, 2, strings.Count(output, `"TraceID":"`+hash.ToHexString()[:32]+`"`))
+	assert.Contains(t, output, "txpool.VerifyTx")
+	assert.Contains(t, output, "ledger.SubmitBlock")
+	assert.Contains(t, output, errors.ErrDuplicatedTx.Error())
+}
+
+func TestStartError(t *testing.T) {
+	err := Start(&config.TracingConfig{EnableTracing: true})
+	assert.NotNil(t, err)
+	err = Start(&config.TracingConfig{EnableTracing: true, Endpoint: "127.0.0.1:4318"})
+	assert.NotNil(t, err)
+	assert.False(t, Enabled())
+}
+
+func TestStopWhileRecording(t *testing.T) {
+	err := Start(&config.TracingConfig{EnableTracing: true, TraceFile: filepath.Join(t.TempDir(), "trace.json")})
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				EndTxSpan(StartTxSpan(common.Uint256{}, "txpool.VerifyTx"), errors.ErrNoError)
+				RecordTxSpan(common.Uint256{}, "ledger.SubmitBlock", time.Now())
+			}
+		}()
+	}
+	Stop()
+	wg.Wait()
+	assert.False(t, Enabled())
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/ontio/ontology/account"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/consensus/dbft"
+	"github.com/ontio/ontology/consensus/sbft"
 	"github.com/ontio/ontology/consensus/solo"
 	"github.com/ontio/ontology/consensus/vbft"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
@@ -38,6 +39,7 @@ const (
 	CONSENSUS_DBFT = "dbft"
 	CONSENSUS_SOLO = "solo"
 	CONSENSUS_VBFT = "vbft"
+	CONSENSUS_SBFT = "sbft"
 )
 
 func NewConsensusService(consensusType string, account *account.Account, txpool *actor.PID, ledger *actor.PID, p2p p2p.P2P) (ConsensusService, error) {
@@ -53,6 +55,8 @@ func NewConsensusService(consensusType string, account *account.Account, txpool
 		consensus, err = solo.NewSoloService(account, txpool)
 	case CONSENSUS_VBFT:
 		consensus, err = vbft.NewVbftServer(account, txpool, p2p)
+	case CONSENSUS_SBFT:
+		consensus, err = sbft.NewSbftService(account, txpool, p2p)
 	}
 	log.Infof("ConsensusType:%s", consensusType)
 	return consensus, err
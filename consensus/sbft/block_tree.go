@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+)
+
+// blockTree keeps the blocks proposed upon the last committed block. Every
+// block is certified by the justify of its children, so the tree of parents
+// is the tree of certificates of chained hotstuff.
+type blockTree struct {
+	root   *Block      // last committed block
+	rootQC *QuorumCert // certificate of the root
+	highQC *QuorumCert // certificate of the highest view known
+	blocks map[common.Uint256]*Block
+}
+
+func newBlockTree(root *Block, rootQC *QuorumCert) *blockTree {
+	return &blockTree{
+		root:   root,
+		rootQC: rootQC,
+		highQC: rootQC,
+		blocks: make(map[common.Uint256]*Block),
+	}
+}
+
+// getBlock returns the block of hash in the tree, including the root
+func (tree *blockTree) getBlock(hash common.Uint256) *Block {
+	if hash == tree.root.hash() {
+		return tree.root
+	}
+	return tree.blocks[hash]
+}
+
+func (tree *blockTree) getParent(blk *Block) *Block {
+	if blk == tree.root {
+		return nil
+	}
+	return tree.getBlock(blk.getPrevBlockHash())
+}
+
+// addBlock adds block upon its parent in the tree
+func (tree *blockTree) addBlock(blk *Block) error {
+	parent := tree.getBlock(blk.getPrevBlockHash())
+	if parent == nil {
+		return fmt.Errorf("parent of block %d not found", blk.getBlockNum())
+	}
+	if parent.getBlockNum()+1 != blk.getBlockNum() {
+		return fmt.Errorf("block %d upon parent %d", blk.getBlockNum(), parent.getBlockNum())
+	}
+	if parent.getView() >= blk.getView() {
+		return fmt.Errorf("block view %d not higher than parent %d", blk.getView(), parent.getView())
+	}
+	tree.blocks[blk.hash()] = blk
+	return nil
+}
+
+// updateHighQC keeps the certificate of the highest view
+func (tree *blockTree) updateHighQC(qc *QuorumCert) {
+	if qc.View > tree.highQC.View {
+		tree.highQC = qc
+	}
+}
+
+// uncommitted returns the blocks from the child of root to blk
+func (tree *blockTree) uncommitted(blk *Block) []*Block {
+	var blocks []*Block
+	for b := blk; b != nil && b != tree.root; b = tree.getParent(b) {
+		blocks = append(blocks, b)
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+// commitCandidate returns the block committed by the certificate of blk, the
+// head of a three-chain of direct parents in consecutive views, with the
+// certificate of the committed block.
+func (tree *blockTree) commitCandidate(blk *Block) (*Block, *QuorumCert) {
+	b1 := tree.getParent(blk)
+	if b1 == nil || b1 == tree.root {
+		return nil, nil
+	}
+	b0 := tree.getParent(b1)
+	if b0 == nil || b0 == tree.root {
+		return nil, nil
+	}
+	if b0.getView()+1 != b1.getView() || b1.getView()+1 != blk.getView() {
+		return nil, nil
+	}
+	return b0, b1.getJustify()
+}
+
+// prune makes the committed blk the root, only its descendants are kept
+func (tree *blockTree) prune(blk *Block, qc *QuorumCert) {
+	height := blk.getBlockNum()
+	hash := blk.hash()
+	var pruned []common.Uint256
+	for h, b := range tree.blocks {
+		a := b
+		for a != nil && a.getBlockNum() > height {
+			a = tree.getParent(a)
+		}
+		if a == nil || a.hash() != hash || b == blk {
+			pruned = append(pruned, h)
+		}
+	}
+	for _, h := range pruned {
+		delete(tree.blocks, h)
+	}
+	tree.root = blk
+	tree.rootQC = qc
+	if tree.highQC.View < qc.View || tree.getBlock(tree.highQC.BlockHash) == nil {
+		tree.highQC = qc
+	}
+}
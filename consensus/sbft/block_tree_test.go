@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"testing"
+)
+
+func TestBlockTreeAddBlock(t *testing.T) {
+	root := newTestBlock(t, nil, 0)
+	tree := newBlockTree(root, &QuorumCert{BlockHash: root.hash()})
+	b1 := newTestBlock(t, root, 1)
+	if err := tree.addBlock(b1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.addBlock(newTestBlock(t, newTestBlock(t, root, 2), 3)); err == nil {
+		t.Error("block without parent added")
+	}
+	stale := newTestBlock(t, b1, 1)
+	if err := tree.addBlock(stale); err == nil {
+		t.Error("block of parent view added")
+	}
+	if tree.getParent(b1) != root || tree.getParent(root) != nil {
+		t.Error("parents of blocks")
+	}
+}
+
+func TestBlockTreeCommitCandidate(t *testing.T) {
+	root := newTestBlock(t, nil, 0)
+	tree := newBlockTree(root, &QuorumCert{BlockHash: root.hash()})
+	b1 := newTestBlock(t, root, 1)
+	b2 := newTestBlock(t, b1, 2)
+	b3 := newTestBlock(t, b2, 3)
+	b5 := newTestBlock(t, b3, 5)
+	b6 := newTestBlock(t, b5, 6)
+	for _, b := range []*Block{b1, b2, b3, b5, b6} {
+		if err := tree.addBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b0, _ := tree.commitCandidate(b2); b0 != nil {
+		t.Errorf("root committed again by block %d", b0.getBlockNum())
+	}
+	b0, qc := tree.commitCandidate(b3)
+	if b0 != b1 || qc.BlockHash != b1.hash() {
+		t.Fatalf("three-chain of b1, b2, b3 not committed")
+	}
+	if b0, _ := tree.commitCandidate(b6); b0 != nil {
+		t.Errorf("block %d of view %d committed by views not consecutive", b0.getBlockNum(), b0.getView())
+	}
+
+	if uncommitted := tree.uncommitted(b3); len(uncommitted) != 3 || uncommitted[0] != b1 {
+		t.Fatalf("uncommitted blocks of b3: %d", len(uncommitted))
+	}
+	// the fork of b1 is pruned with committing b2
+	fork := newTestBlock(t, b1, 4)
+	if err := tree.addBlock(fork); err != nil {
+		t.Fatal(err)
+	}
+	tree.highQC = b6.getJustify()
+	tree.prune(b2, b3.getJustify())
+	if tree.root != b2 || tree.getBlock(b1.hash()) != nil || tree.getBlock(fork.hash()) != nil {
+		t.Fatal("blocks not descendant of b2 kept")
+	}
+	if tree.getBlock(b6.hash()) == nil || tree.highQC != b6.getJustify() {
+		t.Fatal("descendants of b2 pruned")
+	}
+	if uncommitted := tree.uncommitted(b6); len(uncommitted) != 3 || uncommitted[0] != b3 {
+		t.Fatalf("uncommitted blocks of b6: %d", len(uncommitted))
+	}
+}
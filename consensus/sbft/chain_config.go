@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/consensus/vbft"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	gover "github.com/ontio/ontology/smartcontract/service/native/governance"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+// ledgerChainConfig returns the chain config of the blocks upon the current
+// block of ledger, with the number of the block carrying the config.
+func ledgerChainConfig(ldg *ledger.Ledger) (*vconfig.ChainConfig, uint32, error) {
+	height := ldg.GetCurrentBlockHeight()
+	header, err := ldg.GetHeaderByHeight(height)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get header %d: %s", height, err)
+	}
+	info, err := SbftBlock(header)
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.NewChainConfig != nil {
+		return info.NewChainConfig, height, nil
+	}
+	cfgHeader, err := ldg.GetHeaderByHeight(info.LastConfigBlockNum)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get config header %d: %s", info.LastConfigBlockNum, err)
+	}
+	cfgInfo, err := SbftBlock(cfgHeader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if cfgInfo.NewChainConfig == nil {
+		return nil, 0, fmt.Errorf("no chain config in block %d", info.LastConfigBlockNum)
+	}
+	return cfgInfo.NewChainConfig, info.LastConfigBlockNum, nil
+}
+
+// governanceChainConfig returns the chain config of the consensus peers in
+// current governance view, selected by stake the same way as vbft.
+func governanceChainConfig(ldg *ledger.Ledger, blkNum uint32) (*vconfig.ChainConfig, error) {
+	conf, err := vbft.GetVbftConfigInfo(nil, ldg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chainconfig from leveldb: %s", err)
+	}
+	peers, err := vbft.GetPeersConfig(nil, ldg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peersinfo from leveldb: %s", err)
+	}
+	if len(peers) < int(conf.K) {
+		return nil, fmt.Errorf("%d peers less than K %d", len(peers), conf.K)
+	}
+	view, err := vbft.GetGovernanceView(nil, ldg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get governanceview failed:%s", err)
+	}
+	cfg, err := vconfig.GenesisChainConfig(conf, peers, view.TxHash, blkNum)
+	if err != nil {
+		return nil, fmt.Errorf("GenesisChainConfig failed: %s", err)
+	}
+	cfg.View = view.View
+	return cfg, nil
+}
+
+// commitDposTransaction invokes commitDpos of governance contract to update
+// the consensus peers, the only transaction of its block.
+func commitDposTransaction(blkNum uint32) (*types.Transaction, error) {
+	mutable := utils.BuildNativeTransaction(nutils.GovernanceContractAddress, gover.COMMIT_DPOS, []byte{})
+	mutable.Nonce = blkNum
+	return mutable.IntoImmutable()
+}
+
+func isCommitDposTransaction(tx *types.Transaction, blkNum uint32) bool {
+	sysTx, err := commitDposTransaction(blkNum)
+	return err == nil && sysTx.Hash() == tx.Hash()
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	viewMetric = prom.NewGauge(prom.GaugeOpts{
+		Name: "ontology_sbft_view",
+		Help: "ontology current hotstuff view of consensus",
+	})
+
+	committedBlocksMetric = prom.NewCounter(prom.CounterOpts{
+		Name: "ontology_sbft_committed_blocks_total",
+		Help: "ontology blocks committed by consensus",
+	})
+
+	viewTimeoutsMetric = prom.NewCounter(prom.CounterOpts{
+		Name: "ontology_sbft_view_timeouts_total",
+		Help: "ontology consensus views timed out without certificate",
+	})
+)
+
+func init() {
+	prom.MustRegister(viewMetric, committedBlocksMetric, viewTimeoutsMetric)
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+type MsgType uint8
+
+const (
+	ProposalMessage MsgType = iota
+	VoteMessage
+	NewViewMessage
+	BlockFetchMessage
+	BlockFetchRespMessage
+)
+
+type ConsensusMsg interface {
+	Type() MsgType
+	Serialize() ([]byte, error)
+}
+
+// ConsensusMsgPayload is the data of p2p consensus payload
+type ConsensusMsgPayload struct {
+	Type    MsgType `json:"type"`
+	Len     uint32  `json:"len"`
+	Payload []byte  `json:"payload"`
+}
+
+// proposalMsg is broadcast by the leader of the view, the block is signed by
+// the leader in header sigdata.
+type proposalMsg struct {
+	Block []byte `json:"block"`
+}
+
+func (msg *proposalMsg) Type() MsgType {
+	return ProposalMessage
+}
+
+func (msg *proposalMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// voteMsg is sent to the leader of the next view
+type voteMsg struct {
+	View      uint64         `json:"view"`
+	BlockNum  uint32         `json:"block_num"`
+	BlockHash common.Uint256 `json:"block_hash"`
+	Sig       []byte         `json:"sig"`
+}
+
+func (msg *voteMsg) Type() MsgType {
+	return VoteMessage
+}
+
+func (msg *voteMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// newViewMsg is sent to the leader of the view entered on timeout, with the
+// highest certificate of the sender.
+type newViewMsg struct {
+	View   uint64      `json:"view"`
+	HighQC *QuorumCert `json:"high_qc"`
+}
+
+func (msg *newViewMsg) Type() MsgType {
+	return NewViewMessage
+}
+
+func (msg *newViewMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// blockFetchMsg requests the block certified or referred by a msg from its sender
+type blockFetchMsg struct {
+	BlockHash common.Uint256 `json:"block_hash"`
+}
+
+func (msg *blockFetchMsg) Type() MsgType {
+	return BlockFetchMessage
+}
+
+func (msg *blockFetchMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+type blockFetchRespMsg struct {
+	Block []byte `json:"block"`
+}
+
+func (msg *blockFetchRespMsg) Type() MsgType {
+	return BlockFetchRespMessage
+}
+
+func (msg *blockFetchRespMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func SerializeSbftMsg(msg ConsensusMsg) ([]byte, error) {
+	payload, err := msg.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&ConsensusMsgPayload{
+		Type:    msg.Type(),
+		Len:     uint32(len(payload)),
+		Payload: payload,
+	})
+}
+
+func DeserializeSbftMsg(msgPayload []byte) (ConsensusMsg, error) {
+	m := &ConsensusMsgPayload{}
+	if err := json.Unmarshal(msgPayload, m); err != nil {
+		return nil, fmt.Errorf("unmarshal consensus msg payload: %s", err)
+	}
+	if m.Len < uint32(len(m.Payload)) {
+		return nil, fmt.Errorf("invalid payload length: %d", m.Len)
+	}
+
+	var msg ConsensusMsg
+	switch m.Type {
+	case ProposalMessage:
+		msg = &proposalMsg{}
+	case VoteMessage:
+		msg = &voteMsg{}
+	case NewViewMessage:
+		msg = &newViewMsg{}
+	case BlockFetchMessage:
+		msg = &blockFetchMsg{}
+	case BlockFetchRespMessage:
+		msg = &blockFetchRespMsg{}
+	default:
+		return nil, fmt.Errorf("unknown msg type: %d", m.Type)
+	}
+	if err := json.Unmarshal(m.Payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal msg (type: %d): %s", m.Type, err)
+	}
+	return msg, nil
+}
+
+func blockFromBytes(data []byte) (*Block, error) {
+	blk, err := types.BlockFromRawBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("deserialize block: %s", err)
+	}
+	return initBlock(blk)
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSbftMsgSerialization(t *testing.T) {
+	root := newTestBlock(t, nil, 0)
+	blk := newTestBlock(t, root, 1)
+	msgs := []ConsensusMsg{
+		&proposalMsg{Block: blk.Block.ToArray()},
+		&voteMsg{View: 1, BlockNum: 1, BlockHash: blk.hash(), Sig: []byte{1, 2, 3}},
+		&newViewMsg{View: 2, HighQC: blk.getJustify()},
+		&blockFetchMsg{BlockHash: blk.hash()},
+		&blockFetchRespMsg{Block: blk.Block.ToArray()},
+	}
+	for _, msg := range msgs {
+		data, err := SerializeSbftMsg(msg)
+		if err != nil {
+			t.Fatalf("serialize msg of type %d: %s", msg.Type(), err)
+		}
+		msg2, err := DeserializeSbftMsg(data)
+		if err != nil {
+			t.Fatalf("deserialize msg of type %d: %s", msg.Type(), err)
+		}
+		if !reflect.DeepEqual(msg, msg2) {
+			t.Errorf("msg of type %d changed after serialization", msg.Type())
+		}
+	}
+
+	parsed, err := blockFromBytes(msgs[0].(*proposalMsg).Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.hash() != blk.hash() || parsed.getJustify().BlockHash != root.hash() {
+		t.Error("proposed block changed after serialization")
+	}
+	if _, err := DeserializeSbftMsg([]byte(`{"type":9,"len":2,"payload":"e30="}`)); err == nil {
+		t.Error("msg of unknown type deserialized")
+	}
+}
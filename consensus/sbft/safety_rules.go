@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const SBFT_SAFETY_FILE = "sbft.safety"
+
+// safetyRules are the voting rules of chained hotstuff. They are saved before
+// a vote sent, so the restarted node never votes against its previous votes.
+type safetyRules struct {
+	path          string // in memory only if empty
+	LastVotedView uint64 `json:"last_voted_view"`
+	LockedView    uint64 `json:"locked_view"` // view of the block locked by a two-chain
+}
+
+func loadSafetyRules(path string) (*safetyRules, error) {
+	rules := &safetyRules{path: path}
+	if path == "" {
+		return rules, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %s", path, err)
+	}
+	return rules, nil
+}
+
+// safeToVote accepts the block of new view which extends the locked block, or
+// justified by a certificate newer than the lock.
+func (self *safetyRules) safeToVote(blk *Block) bool {
+	justify := blk.getJustify()
+	return blk.getView() > self.LastVotedView && justify != nil && justify.View >= self.LockedView
+}
+
+// updateLock locks on the parent of a certified block
+func (self *safetyRules) updateLock(view uint64) error {
+	if view <= self.LockedView {
+		return nil
+	}
+	self.LockedView = view
+	return self.save()
+}
+
+func (self *safetyRules) voted(view uint64) error {
+	self.LastVotedView = view
+	return self.save()
+}
+
+func (self *safetyRules) save() error {
+	if self.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(self)
+	if err != nil {
+		return err
+	}
+	tmp := self.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, self.path)
+}
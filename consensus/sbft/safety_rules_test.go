@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafetyRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbft-safety")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, SBFT_SAFETY_FILE)
+
+	rules, err := loadSafetyRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := newTestBlock(t, nil, 0)
+	b1 := newTestBlock(t, root, 1)
+	b2 := newTestBlock(t, b1, 2)
+	if !rules.safeToVote(b1) {
+		t.Fatal("not safe to vote the first block")
+	}
+	if err := rules.voted(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := rules.updateLock(1); err != nil {
+		t.Fatal(err)
+	}
+	if rules.safeToVote(b2) {
+		t.Error("safe to vote the view voted")
+	}
+	// the fork of view 3 upon root conflicts with the lock of b1
+	if rules.safeToVote(newTestBlock(t, root, 3)) {
+		t.Error("safe to vote the fork against lock")
+	}
+	if !rules.safeToVote(newTestBlock(t, b2, 3)) {
+		t.Error("not safe to vote the block extending lock")
+	}
+
+	reloaded, err := loadSafetyRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.LastVotedView != 2 || reloaded.LockedView != 1 {
+		t.Fatalf("reloaded safety rules: %+v", reloaded)
+	}
+}
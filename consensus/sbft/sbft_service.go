@@ -18,13 +18,1146 @@
 
 package sbft
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology-eventbus/actor"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	actorTypes "github.com/ontio/ontology/consensus/actor"
+	"github.com/ontio/ontology/consensus/vbft"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/events"
+	"github.com/ontio/ontology/events/message"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
+	msgpack "github.com/ontio/ontology/p2pserver/message/msg_pack"
+	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
+	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+)
+
+const (
+	CAP_EVENT_CHANNEL   = 4096
+	MAX_PEER_PENDING    = 64 // msgs of each validator waiting for blocks
+	PENDING_MSG_TIMEOUT = 10 * time.Second
+	VIEW_TIMEOUT_MARGIN = 2 * time.Second
+	MAX_TIMEOUT_BACKOFF = 6  // view timeout doubles on consecutive timeouts, up to 64 times
+	MAX_TIMESTAMP_DRIFT = 30 // seconds of block timestamp ahead of local time
+	TX_POLL_INTERVAL    = time.Second
+	BLOCK_FETCH_TIMEOUT = time.Second
+)
+
+const noIndex uint32 = math.MaxUint32
+
+type peerMsgEvent struct {
+	payload *p2pmsg.ConsensusPayload
+}
+
+type viewTimeoutEvent struct {
+	view uint64
+}
+
+type proposeEvent struct {
+	view uint64
+}
+
+// blockVerifiedEvent is the result of verifying the transactions of block by txpool
+type blockVerifiedEvent struct {
+	from     uint32
+	blk      *Block
+	proposal bool
+	err      error
+}
+
+type ledgerEvent struct{}
+
+type pendingMsg struct {
+	from     uint32
+	msg      ConsensusMsg
+	received time.Time
+}
+
+// SbftService runs chained hotstuff: the leader of each view proposes a block
+// upon the highest certificate, validators send their votes to the leader of
+// next view, who aggregates them into the certificate carried by the next
+// block. A block is committed once it heads a three-chain of certified blocks
+// in consecutive views. On view timeout the validators send their highest
+// certificates to the leader of next view, so the view change is linear too.
 type SbftService struct {
+	account   *account.Account
+	poolActor *actorTypes.TxPoolActor
+	p2p       p2p.P2P
+	ledger    *ledger.Ledger
+	clock     vbft.Clock
+	pid       *actor.PID
+	sub       *events.ActorSubscriber
+
+	// states below are only accessed in the event loop
+	peers              *validators // validators of current chain config
+	lastConfigBlockNum uint32
+	index              uint32 // index of the node in validators, noIndex if not a validator
+	tree               *blockTree
+	safety             *safetyRules
+	view               uint64
+	timeouts           uint32 // consecutive view timeouts
+	viewTimer          vbft.Timer
+	proposeTimer       vbft.Timer
+	proposeTimerView   uint64
+	proposedView       uint64
+	votes              map[common.Uint256]map[uint32][]byte // votes received as leader of next view
+	newViews           map[uint64]map[uint32]bool           // new view msgs received as leader of the view
+	verifying          map[common.Uint256]bool
+	fetching           map[common.Uint256]time.Time
+	pending            map[common.Uint256][]*pendingMsg // msgs waiting for the block
+	pendingCount       map[uint32]int                   // count of pending msgs by sender
+	p2pIds             map[uint32]p2pcommon.PeerId
+
+	eventC   chan interface{}
+	quitC    chan struct{}
+	quitOnce sync.Once
+	quitWg   sync.WaitGroup
+}
+
+func NewSbftService(account *account.Account, txpool *actor.PID, p2p p2p.P2P) (*SbftService, error) {
+	dataDir := filepath.Join(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	service := newSbftService(account, txpool, p2p, ledger.DefLedger, vbft.SystemClock)
+
+	pid, err := actor.SpawnNamed(service.props(), "consensus_sbft")
+	if err != nil {
+		return nil, err
+	}
+	service.pid = pid
+	service.sub = events.NewActorSubscriber(pid)
+
+	if err := service.initialize(filepath.Join(dataDir, SBFT_SAFETY_FILE)); err != nil {
+		return nil, fmt.Errorf("sbft service init failed: %s", err)
+	}
+	return service, nil
+}
+
+// newSbftService creates the service on the ledger, with the timers created
+// from clock. The actor of service should be spawned before initialized.
+func newSbftService(account *account.Account, txpool *actor.PID, p2p p2p.P2P, ldg *ledger.Ledger,
+	clock vbft.Clock) *SbftService {
+	return &SbftService{
+		account:      account,
+		poolActor:    &actorTypes.TxPoolActor{Pool: txpool},
+		p2p:          p2p,
+		ledger:       ldg,
+		clock:        clock,
+		votes:        make(map[common.Uint256]map[uint32][]byte),
+		newViews:     make(map[uint64]map[uint32]bool),
+		verifying:    make(map[common.Uint256]bool),
+		fetching:     make(map[common.Uint256]time.Time),
+		pending:      make(map[common.Uint256][]*pendingMsg),
+		pendingCount: make(map[uint32]int),
+		p2pIds:       make(map[uint32]p2pcommon.PeerId),
+		eventC:       make(chan interface{}, CAP_EVENT_CHANNEL),
+		quitC:        make(chan struct{}),
+	}
+}
+
+// initialize loads the chain config and committed block from ledger, and the
+// safety rules saved in path.
+func (self *SbftService) initialize(path string) error {
+	var err error
+	if self.safety, err = loadSafetyRules(path); err != nil {
+		return fmt.Errorf("load safety rules: %s", err)
+	}
+	if _, err := self.loadChainConfig(); err != nil {
+		return err
+	}
+	root, err := self.ledgerBlock(self.ledger.GetCurrentBlockHeight())
+	if err != nil {
+		return err
+	}
+	self.tree = newBlockTree(root, rootQuorumCert(root, self.peers))
+	self.view = self.tree.highQC.View + 1
+	if self.safety.LastVotedView >= self.view {
+		self.view = self.safety.LastVotedView + 1
+	}
+	log.Infof("sbft service %d initialized, block %d, view %d", self.index, root.getBlockNum(), self.view)
+	return nil
+}
+
+func (self *SbftService) props() *actor.Props {
+	return actor.FromProducer(func() actor.Actor {
+		return self
+	})
+}
+
+func (self *SbftService) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *actor.Restarting:
+		log.Info("sbft actor restarting")
+	case *actor.Stopping:
+		log.Info("sbft actor stopping")
+	case *actor.Stopped:
+		log.Info("sbft actor stopped")
+	case *actor.Started:
+		log.Info("sbft actor started")
+	case *actor.Restart:
+		log.Info("sbft actor restart")
+	case *actorTypes.StartConsensus:
+		log.Info("sbft actor start consensus")
+	case *actorTypes.StopConsensus:
+		self.stop()
+	case *message.SaveBlockCompleteMsg:
+		self.sendEvent(&ledgerEvent{})
+	case *p2pmsg.ConsensusPayload:
+		self.sendEvent(&peerMsgEvent{payload: msg})
+	default:
+		log.Info("sbft actor: Unknown msg ", msg, "type", reflect.TypeOf(msg))
+	}
 }
 
-func (ss *SbftService) Halt() error {
+func (self *SbftService) GetPID() *actor.PID {
+	return self.pid
+}
+
+func (self *SbftService) Start() error {
+	if self.sub != nil {
+		self.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	}
+	self.spawn(self.run)
 	return nil
 }
 
-func (ds *SbftService) Start() error {
+func (self *SbftService) Halt() error {
+	self.pid.Tell(&actorTypes.StopConsensus{})
 	return nil
 }
+
+// spawn runs f in a goroutine, which stop waits for after quitC closed
+func (self *SbftService) spawn(f func()) {
+	self.quitWg.Add(1)
+	go func() {
+		defer self.quitWg.Done()
+		f()
+	}()
+}
+
+func (self *SbftService) stop() {
+	self.quitOnce.Do(func() {
+		close(self.quitC)
+		self.quitWg.Wait()
+		if self.sub != nil {
+			self.sub.Unsubscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+		}
+	})
+}
+
+func (self *SbftService) sendEvent(evt interface{}) {
+	select {
+	case self.eventC <- evt:
+	case <-self.quitC:
+	}
+}
+
+// run is the event loop, all the consensus states are only changed in it
+func (self *SbftService) run() {
+	self.enterView(self.view, true)
+	for {
+		select {
+		case evt := <-self.eventC:
+			self.syncWithLedger()
+			switch e := evt.(type) {
+			case *peerMsgEvent:
+				self.onPayload(e.payload)
+			case *viewTimeoutEvent:
+				self.onViewTimeout(e.view)
+			case *proposeEvent:
+				self.onPropose(e.view)
+			case *blockVerifiedEvent:
+				self.onBlockVerified(e)
+			case *ledgerEvent:
+			}
+		case <-self.quitC:
+			if self.viewTimer != nil {
+				self.viewTimer.Stop()
+			}
+			if self.proposeTimer != nil {
+				self.proposeTimer.Stop()
+			}
+			log.Infof("sbft service %d quit", self.index)
+			return
+		}
+	}
+}
+
+func (self *SbftService) ledgerBlock(height uint32) (*Block, error) {
+	block, err := self.ledger.GetBlockByHeight(height)
+	if err != nil {
+		return nil, fmt.Errorf("get block %d: %s", height, err)
+	}
+	return initBlock(block)
+}
+
+// loadChainConfig loads the validators from ledger, returns whether changed
+func (self *SbftService) loadChainConfig() (bool, error) {
+	cfg, lastConfigBlockNum, err := ledgerChainConfig(self.ledger)
+	if err != nil {
+		return false, fmt.Errorf("load chain config: %s", err)
+	}
+	if self.peers != nil && self.lastConfigBlockNum == lastConfigBlockNum {
+		return false, nil
+	}
+	peers, err := newValidators(cfg)
+	if err != nil {
+		return false, err
+	}
+	self.peers = peers
+	self.lastConfigBlockNum = lastConfigBlockNum
+	self.index = noIndex
+	if index, present := peers.index(vconfig.PubkeyID(self.account.PublicKey)); present {
+		self.index = index
+	}
+	log.Infof("sbft service %d loaded chain config of block %d, view %d, %d validators",
+		self.index, lastConfigBlockNum, cfg.View, len(cfg.Peers))
+	return true, nil
+}
+
+// syncWithLedger restarts the block tree from the ledger if blocks are saved
+// by block syncing, instead of committed by the service.
+func (self *SbftService) syncWithLedger() {
+	height := self.ledger.GetCurrentBlockHeight()
+	if height <= self.tree.root.getBlockNum() {
+		return
+	}
+	root, err := self.ledgerBlock(height)
+	if err != nil {
+		log.Errorf("sbft service %d sync with ledger: %s", self.index, err)
+		return
+	}
+	qc := rootQuorumCert(root, self.peers)
+	changed, err := self.loadChainConfig()
+	if err != nil {
+		log.Errorf("sbft service %d sync with ledger: %s", self.index, err)
+		return
+	}
+	if !changed && self.tree.getBlock(root.hash()) != nil {
+		self.tree.prune(root, qc)
+	} else {
+		self.resetTree(root, qc)
+	}
+	log.Infof("sbft service %d synced with ledger block %d", self.index, height)
+}
+
+func (self *SbftService) resetTree(root *Block, qc *QuorumCert) {
+	self.tree = newBlockTree(root, qc)
+	self.votes = make(map[common.Uint256]map[uint32][]byte)
+	self.newViews = make(map[uint64]map[uint32]bool)
+	self.pending = make(map[common.Uint256][]*pendingMsg)
+	self.pendingCount = make(map[uint32]int)
+}
+
+func (self *SbftService) onPayload(payload *p2pmsg.ConsensusPayload) {
+	from, present := self.peers.index(vconfig.PubkeyID(payload.Owner))
+	if !present {
+		log.Debugf("sbft msg from non validator: %s", vconfig.PubkeyID(payload.Owner))
+		return
+	}
+	self.p2pIds[from] = payload.PeerId
+	msg, err := DeserializeSbftMsg(payload.Data)
+	if err != nil {
+		log.Errorf("sbft service %d failed to deserialize msg from %d: %s", self.index, from, err)
+		return
+	}
+	self.onConsensusMsg(from, msg)
+}
+
+func (self *SbftService) onConsensusMsg(from uint32, msg ConsensusMsg) {
+	switch m := msg.(type) {
+	case *proposalMsg:
+		self.onProposal(from, m)
+	case *voteMsg:
+		self.onVote(from, m)
+	case *newViewMsg:
+		self.onNewView(from, m)
+	case *blockFetchMsg:
+		self.onBlockFetch(from, m)
+	case *blockFetchRespMsg:
+		self.onBlockFetchResp(from, m)
+	}
+}
+
+func (self *SbftService) onProposal(from uint32, msg *proposalMsg) {
+	blk, err := blockFromBytes(msg.Block)
+	if err != nil {
+		log.Errorf("sbft service %d invalid proposal from %d: %s", self.index, from, err)
+		return
+	}
+	if blk.Info.Proposer != from || self.peers.leader(blk.getView()) != from {
+		log.Errorf("sbft service %d proposal of view %d from %d is not the leader",
+			self.index, blk.getView(), from)
+		return
+	}
+	header := blk.Block.Header
+	hash := blk.hash()
+	if len(header.SigData) == 0 || signature.Verify(self.peers.pubkey(from), hash[:], header.SigData[0]) != nil {
+		log.Errorf("sbft service %d invalid proposal sig from %d", self.index, from)
+		return
+	}
+	self.processBlock(from, blk, msg, true)
+}
+
+func (self *SbftService) onBlockFetchResp(from uint32, msg *blockFetchRespMsg) {
+	blk, err := blockFromBytes(msg.Block)
+	if err != nil {
+		log.Errorf("sbft service %d invalid fetched block from %d: %s", self.index, from, err)
+		return
+	}
+	// only the blocks referred by certificates are fetched
+	if _, present := self.fetching[blk.hash()]; !present {
+		return
+	}
+	delete(self.fetching, blk.hash())
+	self.processBlock(from, blk, msg, false)
+}
+
+// processBlock verifies the proposed or fetched block, msg is kept until the
+// parent of block fetched.
+func (self *SbftService) processBlock(from uint32, blk *Block, msg ConsensusMsg, proposal bool) {
+	hash := blk.hash()
+	if blk.getBlockNum() <= self.tree.root.getBlockNum() || self.tree.getBlock(hash) != nil || self.verifying[hash] {
+		return
+	}
+	justify := blk.getJustify()
+	if justify == nil || justify.BlockHash != blk.getPrevBlockHash() || justify.BlockNum+1 != blk.getBlockNum() ||
+		justify.View >= blk.getView() {
+		log.Errorf("sbft service %d invalid justify of block %d from %d", self.index, blk.getBlockNum(), from)
+		return
+	}
+	parent := self.tree.getBlock(justify.BlockHash)
+	if parent == nil {
+		self.waitBlock(from, justify.BlockHash, msg)
+		return
+	}
+	if err := self.verifyQC(justify); err != nil {
+		log.Errorf("sbft service %d invalid justify of block %d from %d: %s", self.index, blk.getBlockNum(), from, err)
+		return
+	}
+	txs, err := self.checkBlock(blk, parent)
+	if err != nil {
+		log.Errorf("sbft service %d invalid block %d from %d: %s", self.index, blk.getBlockNum(), from, err)
+		return
+	}
+	if len(txs) == 0 {
+		self.acceptBlock(from, blk, proposal)
+		return
+	}
+	// verify txs in new routine, the block is accepted on verified event
+	self.verifying[hash] = true
+	height := self.ledger.GetCurrentBlockHeight()
+	self.spawn(func() {
+		err := self.poolActor.VerifyBlock(txs, height)
+		if err == actor.ErrTimeout {
+			log.Errorf("sbft verify block %d from %d timedout", blk.getBlockNum(), from)
+			err = nil
+		}
+		self.sendEvent(&blockVerifiedEvent{from: from, blk: blk, proposal: proposal, err: err})
+	})
+}
+
+func (self *SbftService) onBlockVerified(evt *blockVerifiedEvent) {
+	delete(self.verifying, evt.blk.hash())
+	if evt.err != nil {
+		log.Errorf("sbft service %d verify txs of block %d from %d failed: %s",
+			self.index, evt.blk.getBlockNum(), evt.from, evt.err)
+		return
+	}
+	if evt.blk.getBlockNum() <= self.tree.root.getBlockNum() || self.tree.getBlock(evt.blk.getPrevBlockHash()) == nil {
+		return
+	}
+	self.acceptBlock(evt.from, evt.blk, evt.proposal)
+}
+
+// checkBlock checks the block upon parent, returns the user transactions to
+// be verified by txpool.
+func (self *SbftService) checkBlock(blk *Block, parent *Block) ([]*types.Transaction, error) {
+	header := blk.Block.Header
+	if header.Timestamp <= parent.getTimestamp() {
+		return nil, fmt.Errorf("timestamp %d not after parent %d", header.Timestamp, parent.getTimestamp())
+	}
+	if header.Timestamp > uint32(self.clock.Now().Unix())+MAX_TIMESTAMP_DRIFT {
+		return nil, fmt.Errorf("timestamp %d ahead of local time", header.Timestamp)
+	}
+	if blk.Info.LastConfigBlockNum != self.lastConfigBlockNum {
+		return nil, fmt.Errorf("last config block %d, expected %d", blk.Info.LastConfigBlockNum, self.lastConfigBlockNum)
+	}
+	if blk.Info.ExecBlockNum >= blk.getBlockNum() {
+		return nil, fmt.Errorf("executed block %d not before block %d", blk.Info.ExecBlockNum, blk.getBlockNum())
+	}
+
+	ancestors := self.tree.uncommitted(parent)
+	ancestorTxs := make(map[common.Uint256]bool)
+	upon := false
+	for _, b := range ancestors {
+		if b.getNewChainConfig() != nil {
+			upon = true
+		}
+		for _, tx := range b.Block.Transactions {
+			ancestorTxs[tx.Hash()] = true
+		}
+	}
+	if upon && !blk.isEmpty() {
+		return nil, errors.New("non empty block upon uncommitted chain config")
+	}
+
+	var userTxs []*types.Transaction
+	hashes := make([]common.Uint256, 0, len(blk.Block.Transactions))
+	for _, tx := range blk.Block.Transactions {
+		hash := tx.Hash()
+		if ancestorTxs[hash] {
+			return nil, fmt.Errorf("tx %s duplicated", hash.ToHexString())
+		}
+		ancestorTxs[hash] = true
+		hashes = append(hashes, hash)
+		if isCommitDposTransaction(tx, blk.getBlockNum()) {
+			if len(blk.Block.Transactions) != 1 {
+				return nil, errors.New("commitDpos with other transactions")
+			}
+			continue
+		}
+		userTxs = append(userTxs, tx)
+	}
+	txRoot := common.ComputeMerkleRoot(hashes)
+	if header.TransactionsRoot != txRoot {
+		return nil, errors.New("transactions root mismatch")
+	}
+	if header.BlockRoot != self.blockRoot(ancestors, txRoot) {
+		return nil, errors.New("block root mismatch")
+	}
+
+	if cfg := blk.getNewChainConfig(); cfg != nil {
+		if len(blk.Block.Transactions) != 0 {
+			return nil, errors.New("transactions in chain config block")
+		}
+		expected, err := governanceChainConfig(self.ledger, blk.getBlockNum())
+		if err != nil {
+			return nil, err
+		}
+		if expected.View != cfg.View {
+			return nil, fmt.Errorf("chain config of view %d, local governance view %d", cfg.View, expected.View)
+		}
+		data1, _ := json.Marshal(expected)
+		data2, _ := json.Marshal(cfg)
+		if string(data1) != string(data2) {
+			return nil, errors.New("chain config mismatch")
+		}
+	}
+	return userTxs, nil
+}
+
+// blockRoot returns the block root with the tx roots of uncommitted ancestors
+func (self *SbftService) blockRoot(ancestors []*Block, txRoot common.Uint256) common.Uint256 {
+	height := self.ledger.GetCurrentBlockHeight()
+	var txRoots []common.Uint256
+	for _, b := range ancestors {
+		if b.getBlockNum() > height {
+			txRoots = append(txRoots, b.Block.Header.TransactionsRoot)
+		}
+	}
+	txRoots = append(txRoots, txRoot)
+	return self.ledger.GetBlockRootWithNewTxRoots(height+1, txRoots)
+}
+
+// verifyQC verifies the certificate against the certified block in tree, the
+// certificate of root is rebuilt from its header and not verified again.
+func (self *SbftService) verifyQC(qc *QuorumCert) error {
+	blk := self.tree.getBlock(qc.BlockHash)
+	if blk == nil {
+		return fmt.Errorf("block %d of certificate not found", qc.BlockNum)
+	}
+	if blk == self.tree.root {
+		if qc.View != blk.getView() || qc.BlockNum != blk.getBlockNum() {
+			return fmt.Errorf("certificate of view %d block %d mismatch committed block %d",
+				qc.View, qc.BlockNum, blk.getBlockNum())
+		}
+		return nil
+	}
+	return qc.verify(self.peers, blk)
+}
+
+func (self *SbftService) acceptBlock(from uint32, blk *Block, proposal bool) {
+	if err := self.tree.addBlock(blk); err != nil {
+		log.Errorf("sbft service %d add block %d from %d: %s", self.index, blk.getBlockNum(), from, err)
+		return
+	}
+	log.Debugf("sbft service %d accepted block %d of view %d from %d", self.index, blk.getBlockNum(), blk.getView(), from)
+	self.processQC(blk.getJustify())
+	// the block is dropped if chain config changed by the block committed
+	if self.tree.getBlock(blk.hash()) == nil {
+		return
+	}
+	if proposal {
+		if blk.getView() > self.view {
+			self.enterView(blk.getView(), true)
+		}
+		self.vote(blk)
+	}
+	self.resolvePending(blk.hash())
+}
+
+func (self *SbftService) vote(blk *Block) {
+	if self.index == noIndex || blk.getView() != self.view || !self.safety.safeToVote(blk) {
+		return
+	}
+	if err := self.checkExecRoot(blk); err != nil {
+		log.Errorf("sbft service %d not vote block %d of view %d: %s", self.index, blk.getBlockNum(), blk.getView(), err)
+		return
+	}
+	if err := self.safety.voted(blk.getView()); err != nil {
+		log.Errorf("sbft service %d save safety rules: %s", self.index, err)
+		return
+	}
+	hash := blk.hash()
+	sig, err := signature.Sign(self.account, hash[:])
+	if err != nil {
+		log.Errorf("sbft service %d sign vote: %s", self.index, err)
+		return
+	}
+	self.sendToPeer(self.peers.leader(blk.getView()+1), &voteMsg{
+		View:      blk.getView(),
+		BlockNum:  blk.getBlockNum(),
+		BlockHash: hash,
+		Sig:       sig,
+	})
+}
+
+// checkExecRoot checks the state root carried by blk against the local ledger,
+// so the certificate of blk is also the agreement of validators on the state
+// root. The executed block was committed before blk proposed, the justify of blk
+// commits it on validators not lagging behind.
+func (self *SbftService) checkExecRoot(blk *Block) error {
+	height := blk.Info.ExecBlockNum
+	if height > self.ledger.GetCurrentBlockHeight() {
+		return fmt.Errorf("executed block %d not committed", height)
+	}
+	root, err := self.ledger.GetStateMerkleRoot(height)
+	if err != nil {
+		return fmt.Errorf("state merkle root of block %d: %s", height, err)
+	}
+	if root != blk.Info.ExecMerkleRoot {
+		return fmt.Errorf("state merkle root of block %d is %s, local %s", height,
+			blk.Info.ExecMerkleRoot.ToHexString(), root.ToHexString())
+	}
+	return nil
+}
+
+func (self *SbftService) onVote(from uint32, msg *voteMsg) {
+	if self.peers.leader(msg.View+1) != self.index || msg.View <= self.tree.highQC.View {
+		return
+	}
+	if err := signature.Verify(self.peers.pubkey(from), msg.BlockHash[:], msg.Sig); err != nil {
+		log.Errorf("sbft service %d invalid vote from %d: %s", self.index, from, err)
+		return
+	}
+	blk := self.tree.getBlock(msg.BlockHash)
+	if blk == nil {
+		self.waitBlock(from, msg.BlockHash, msg)
+		return
+	}
+	if blk.getView() != msg.View || blk.getBlockNum() != msg.BlockNum {
+		return
+	}
+	votes := self.votes[msg.BlockHash]
+	if votes == nil {
+		votes = make(map[uint32][]byte)
+		self.votes[msg.BlockHash] = votes
+	}
+	votes[from] = msg.Sig
+	if len(votes) >= self.peers.quorum() {
+		delete(self.votes, msg.BlockHash)
+		self.processQC(newQuorumCert(blk, votes))
+	}
+}
+
+func (self *SbftService) onNewView(from uint32, msg *newViewMsg) {
+	if msg.View < self.view || self.peers.leader(msg.View) != self.index {
+		return
+	}
+	if qc := msg.HighQC; qc != nil && qc.View > self.tree.highQC.View {
+		if self.tree.getBlock(qc.BlockHash) == nil {
+			self.waitBlock(from, qc.BlockHash, msg)
+			return
+		}
+		if err := self.verifyQC(qc); err != nil {
+			log.Errorf("sbft service %d invalid new view from %d: %s", self.index, from, err)
+			return
+		}
+		self.processQC(qc)
+	}
+	// the certificate of new view msg may have entered a higher view
+	if msg.View < self.view {
+		return
+	}
+	senders := self.newViews[msg.View]
+	if senders == nil {
+		senders = make(map[uint32]bool)
+		self.newViews[msg.View] = senders
+	}
+	senders[from] = true
+	if len(senders) >= self.peers.quorum() && msg.View > self.view {
+		self.enterView(msg.View, false)
+	}
+	self.schedulePropose()
+}
+
+// processQC updates the high certificate and lock, commits the three-chain
+// and enters the next view of the certificate.
+func (self *SbftService) processQC(qc *QuorumCert) {
+	blk := self.tree.getBlock(qc.BlockHash)
+	if blk == nil {
+		return
+	}
+	self.tree.updateHighQC(qc)
+	if justify := blk.getJustify(); justify != nil && blk != self.tree.root {
+		if err := self.safety.updateLock(justify.View); err != nil {
+			log.Errorf("sbft service %d save safety rules: %s", self.index, err)
+		}
+	}
+	if b0, b0QC := self.tree.commitCandidate(blk); b0 != nil {
+		self.commit(b0, b0QC)
+	}
+	if qc.View >= self.view {
+		self.enterView(qc.View+1, true)
+	} else {
+		self.schedulePropose()
+	}
+}
+
+// commit saves the block and its uncommitted ancestors to ledger. Blocks upon
+// a chain config block are dropped, the next block is proposed in new config.
+func (self *SbftService) commit(blk *Block, qc *QuorumCert) {
+	path := self.tree.uncommitted(blk)
+	for i, b := range path {
+		if b.getNewChainConfig() != nil && i+1 < len(path) {
+			blk, qc = b, path[i+1].getJustify()
+			path = path[:i+1]
+			break
+		}
+	}
+	for i, b := range path {
+		bQC := qc
+		if i+1 < len(path) {
+			bQC = path[i+1].getJustify()
+		}
+		if err := self.submitBlock(b, bQC); err != nil {
+			log.Errorf("sbft service %d failed to commit block %d: %s", self.index, b.getBlockNum(), err)
+			return
+		}
+	}
+	self.tree.prune(blk, qc)
+	if blk.getNewChainConfig() == nil {
+		return
+	}
+	if _, err := self.loadChainConfig(); err != nil {
+		log.Errorf("sbft service %d: %s", self.index, err)
+	}
+	self.resetTree(blk, qc)
+}
+
+func (self *SbftService) submitBlock(blk *Block, qc *QuorumCert) error {
+	if blk.getBlockNum() <= self.ledger.GetCurrentBlockHeight() {
+		return nil
+	}
+	bookkeepers, err := qc.bookkeepers(self.peers)
+	if err != nil {
+		return err
+	}
+	header := blk.Block.Header
+	header.Bookkeepers = bookkeepers
+	header.SigData = qc.Sigs
+	result, err := self.ledger.ExecuteBlock(blk.Block)
+	if err != nil {
+		return fmt.Errorf("execute block: %s", err)
+	}
+	if err := self.ledger.SubmitBlock(blk.Block, nil, result); err != nil {
+		return fmt.Errorf("submit block: %s", err)
+	}
+	committedBlocksMetric.Inc()
+	log.Infof("sbft service %d committed block %d of view %d, txs %d",
+		self.index, blk.getBlockNum(), blk.getView(), len(blk.Block.Transactions))
+	return nil
+}
+
+// viewTimeout covers the leader waiting BlockMsgDelay to propose an empty
+// block, and the delivery of votes and proposal. A crashed leader of view v
+// times out both view v-1, whose votes are sent to it, and view v, so the
+// timeout only doubles from the second consecutive timeout on.
+func (self *SbftService) viewTimeout() time.Duration {
+	timeout := self.peers.config.BlockMsgDelay + VIEW_TIMEOUT_MARGIN
+	backoff := self.timeouts
+	if backoff > 0 {
+		backoff--
+	}
+	if backoff > MAX_TIMEOUT_BACKOFF {
+		backoff = MAX_TIMEOUT_BACKOFF
+	}
+	return timeout << backoff
+}
+
+// enterView restarts the view timer, progress resets the timeout backoff
+func (self *SbftService) enterView(view uint64, progress bool) {
+	if view < self.view {
+		return
+	}
+	self.view = view
+	if progress {
+		self.timeouts = 0
+	}
+	if self.viewTimer != nil {
+		self.viewTimer.Stop()
+	}
+	self.viewTimer = self.clock.AfterFunc(self.viewTimeout(), func() {
+		self.sendEvent(&viewTimeoutEvent{view: view})
+	})
+	viewMetric.Set(float64(view))
+	self.expirePending()
+	for v := range self.newViews {
+		if v < view {
+			delete(self.newViews, v)
+		}
+	}
+	for hash := range self.votes {
+		if blk := self.tree.getBlock(hash); blk == nil || blk.getView()+1 < view {
+			delete(self.votes, hash)
+		}
+	}
+	self.schedulePropose()
+}
+
+func (self *SbftService) onViewTimeout(view uint64) {
+	if view != self.view {
+		return
+	}
+	self.timeouts++
+	viewTimeoutsMetric.Inc()
+	log.Infof("sbft service %d view %d timeout, high qc of view %d", self.index, view, self.tree.highQC.View)
+	self.enterView(view+1, false)
+	if self.index != noIndex {
+		self.sendToPeer(self.peers.leader(view+1), &newViewMsg{View: view + 1, HighQC: self.tree.highQC})
+	}
+}
+
+// schedulePropose starts the proposal as the leader of current view, after
+// the certificate of previous view or the new view msgs of a quorum received.
+// Header timestamps are in seconds, so blocks are proposed a second apart.
+func (self *SbftService) schedulePropose() {
+	view := self.view
+	if self.index == noIndex || self.peers.leader(view) != self.index || self.proposedView >= view ||
+		self.proposeTimerView == view {
+		return
+	}
+	if self.tree.highQC.View+1 != view && len(self.newViews[view]) < self.peers.quorum() {
+		return
+	}
+	parent := self.tree.getBlock(self.tree.highQC.BlockHash)
+	if parent == nil {
+		return
+	}
+	self.setProposeTimer(time.Unix(int64(parent.getTimestamp())+1, 0).Sub(self.clock.Now()), view)
+}
+
+func (self *SbftService) setProposeTimer(d time.Duration, view uint64) {
+	if d < 0 {
+		d = 0
+	}
+	if self.proposeTimer != nil {
+		self.proposeTimer.Stop()
+	}
+	self.proposeTimerView = view
+	self.proposeTimer = self.clock.AfterFunc(d, func() {
+		self.sendEvent(&proposeEvent{view: view})
+	})
+}
+
+func (self *SbftService) onPropose(view uint64) {
+	if view != self.view || self.proposedView >= view || self.peers.leader(view) != self.index {
+		return
+	}
+	parent := self.tree.getBlock(self.tree.highQC.BlockHash)
+	if parent == nil {
+		return
+	}
+	blk, wait, err := self.makeProposal(parent, view)
+	if err != nil {
+		log.Errorf("sbft service %d failed to make proposal of view %d: %s", self.index, view, err)
+		return
+	}
+	if blk == nil {
+		self.setProposeTimer(wait, view)
+		return
+	}
+	self.proposedView = view
+	msg := &proposalMsg{Block: blk.Block.ToArray()}
+	self.broadcast(msg)
+	log.Infof("sbft service %d proposed block %d of view %d, txs %d",
+		self.index, blk.getBlockNum(), view, len(blk.Block.Transactions))
+	self.acceptBlock(self.index, blk, true)
+}
+
+// makeProposal builds the block upon parent, or returns the duration to wait
+// for transactions if there is nothing to propose. A chain config block is
+// proposed once the governance view changed, followed by empty blocks until
+// committed, and commitDpos is invoked every MaxBlockChangeView blocks.
+func (self *SbftService) makeProposal(parent *Block, view uint64) (*Block, time.Duration, error) {
+	blkNum := parent.getBlockNum() + 1
+	ancestors := self.tree.uncommitted(parent)
+	ancestorTxs := make(map[common.Uint256]bool)
+	eipPayers := make(map[common.Address]bool)
+	upon, pending, dpos := false, false, false
+	for _, b := range ancestors {
+		if b.getNewChainConfig() != nil {
+			upon = true
+		}
+		if !b.isEmpty() {
+			pending = true
+		}
+		for _, tx := range b.Block.Transactions {
+			ancestorTxs[tx.Hash()] = true
+			if tx.IsEipTx() {
+				eipPayers[tx.Payer] = true
+			}
+			if isCommitDposTransaction(tx, b.getBlockNum()) {
+				dpos = true
+			}
+		}
+	}
+
+	var txs []*types.Transaction
+	var newCfg *vconfig.ChainConfig
+	if !upon {
+		cfg := self.peers.config
+		govView, err := vbft.GetGovernanceView(nil, self.ledger)
+		if err != nil {
+			return nil, 0, err
+		}
+		if govView.View > cfg.View {
+			if newCfg, err = governanceChainConfig(self.ledger, blkNum); err != nil {
+				return nil, 0, err
+			}
+		} else if !dpos && cfg.MaxBlockChangeView > 0 && blkNum-govView.Height >= cfg.MaxBlockChangeView {
+			tx, err := commitDposTransaction(blkNum)
+			if err != nil {
+				return nil, 0, err
+			}
+			txs = append(txs, tx)
+		} else {
+			// nonces of eip155 txs are checked upon ledger, txs of payers in ancestors are delayed
+			for _, e := range self.poolActor.GetTxnPool(true, self.ledger.GetCurrentBlockHeight()) {
+				if ancestorTxs[e.Tx.Hash()] || (e.Tx.IsEipTx() && eipPayers[e.Tx.Payer]) {
+					continue
+				}
+				txs = append(txs, e.Tx)
+			}
+		}
+	}
+	if len(txs) == 0 && newCfg == nil && !pending {
+		// empty blocks are proposed every BlockMsgDelay if no block to commit
+		deadline := time.Unix(int64(parent.getTimestamp()), 0).Add(self.peers.config.BlockMsgDelay)
+		if wait := deadline.Sub(self.clock.Now()); wait > 0 {
+			if wait > TX_POLL_INTERVAL {
+				wait = TX_POLL_INTERVAL
+			}
+			return nil, wait, nil
+		}
+	}
+	blk, err := self.constructBlock(parent, ancestors, view, txs, newCfg)
+	return blk, 0, err
+}
+
+func (self *SbftService) constructBlock(parent *Block, ancestors []*Block, view uint64, txs []*types.Transaction,
+	newCfg *vconfig.ChainConfig) (*Block, error) {
+	hashes := make([]common.Uint256, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash())
+	}
+	txRoot := common.ComputeMerkleRoot(hashes)
+
+	next := self.peers
+	if newCfg != nil {
+		var err error
+		if next, err = newValidators(newCfg); err != nil {
+			return nil, err
+		}
+	}
+	nextBookkeeper, err := next.bookkeeperAddress()
+	if err != nil {
+		return nil, err
+	}
+	execBlockNum := self.ledger.GetCurrentBlockHeight()
+	execRoot, err := self.ledger.GetStateMerkleRoot(execBlockNum)
+	if err != nil {
+		return nil, fmt.Errorf("state merkle root of block %d: %s", execBlockNum, err)
+	}
+	info := &SbftBlockInfo{
+		View:               view,
+		Proposer:           self.index,
+		Justify:            self.tree.highQC,
+		LastConfigBlockNum: self.lastConfigBlockNum,
+		NewChainConfig:     newCfg,
+		ExecBlockNum:       execBlockNum,
+		ExecMerkleRoot:     execRoot,
+	}
+	payload, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := uint32(self.clock.Now().Unix())
+	if timestamp <= parent.getTimestamp() {
+		timestamp = parent.getTimestamp() + 1
+	}
+	header := &types.Header{
+		PrevBlockHash:    parent.hash(),
+		TransactionsRoot: txRoot,
+		BlockRoot:        self.blockRoot(ancestors, txRoot),
+		Timestamp:        timestamp,
+		Height:           parent.getBlockNum() + 1,
+		ConsensusData:    common.GetNonce(),
+		NextBookkeeper:   nextBookkeeper,
+		ConsensusPayload: payload,
+	}
+	block := &types.Block{
+		Header:       header,
+		Transactions: txs,
+	}
+	hash := block.Hash()
+	sig, err := signature.Sign(self.account, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign block failed, block hash:%s, error: %s", hash.ToHexString(), err)
+	}
+	header.Bookkeepers = []keypair.PublicKey{self.account.PublicKey}
+	header.SigData = [][]byte{sig}
+	return &Block{Block: block, Info: info}, nil
+}
+
+func (self *SbftService) onBlockFetch(from uint32, msg *blockFetchMsg) {
+	var block *types.Block
+	if blk := self.tree.getBlock(msg.BlockHash); blk != nil {
+		block = blk.Block
+	} else if b, err := self.ledger.GetBlockByHash(msg.BlockHash); err == nil && b != nil {
+		block = b
+	} else {
+		return
+	}
+	self.sendToPeer(from, &blockFetchRespMsg{Block: block.ToArray()})
+}
+
+// waitBlock keeps msg until the block of hash fetched from the sender, the
+// msgs of each sender are limited, so a faulty validator only drops its own.
+func (self *SbftService) waitBlock(from uint32, hash common.Uint256, msg ConsensusMsg) {
+	if self.pendingCount[from] >= MAX_PEER_PENDING {
+		log.Debugf("sbft service %d drop msg from %d, too many pending", self.index, from)
+		return
+	}
+	now := self.clock.Now()
+	self.pending[hash] = append(self.pending[hash], &pendingMsg{from: from, msg: msg, received: now})
+	self.pendingCount[from]++
+	if t, present := self.fetching[hash]; present && now.Sub(t) < BLOCK_FETCH_TIMEOUT {
+		return
+	}
+	self.fetching[hash] = now
+	self.sendToPeer(from, &blockFetchMsg{BlockHash: hash})
+}
+
+func (self *SbftService) resolvePending(hash common.Uint256) {
+	msgs := self.pending[hash]
+	delete(self.pending, hash)
+	for _, m := range msgs {
+		self.pendingCount[m.from]--
+	}
+	for _, m := range msgs {
+		self.onConsensusMsg(m.from, m.msg)
+	}
+}
+
+// expirePending drops the msgs waiting for blocks never fetched
+func (self *SbftService) expirePending() {
+	now := self.clock.Now()
+	for hash, msgs := range self.pending {
+		kept := msgs[:0]
+		for _, m := range msgs {
+			if now.Sub(m.received) < PENDING_MSG_TIMEOUT {
+				kept = append(kept, m)
+			} else {
+				self.pendingCount[m.from]--
+			}
+		}
+		if len(kept) == 0 {
+			delete(self.pending, hash)
+		} else {
+			self.pending[hash] = kept
+		}
+	}
+	for hash, t := range self.fetching {
+		if now.Sub(t) >= PENDING_MSG_TIMEOUT {
+			delete(self.fetching, hash)
+		}
+	}
+}
+
+func (self *SbftService) newPayload(msg ConsensusMsg) (*p2pmsg.ConsensusPayload, error) {
+	data, err := SerializeSbftMsg(msg)
+	if err != nil {
+		return nil, err
+	}
+	payload := &p2pmsg.ConsensusPayload{
+		Data:  data,
+		Owner: self.account.PublicKey,
+	}
+	sink := common.NewZeroCopySink(nil)
+	payload.SerializationUnsigned(sink)
+	if payload.Signature, err = signature.Sign(self.account, sink.Bytes()); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// sendToPeer sends msg to the validator, broadcast if its p2p id is unknown yet
+func (self *SbftService) sendToPeer(peerIdx uint32, msg ConsensusMsg) {
+	if peerIdx == self.index {
+		self.onConsensusMsg(self.index, msg)
+		return
+	}
+	if self.p2p == nil {
+		return
+	}
+	payload, err := self.newPayload(msg)
+	if err != nil {
+		log.Errorf("sbft service %d build msg: %s", self.index, err)
+		return
+	}
+	cons := msgpack.NewConsensus(payload)
+	if p2pid, present := self.p2pIds[peerIdx]; present {
+		self.spawn(func() { self.p2p.SendTo(p2pid, cons) })
+	} else {
+		self.spawn(func() { self.p2p.Broadcast(cons) })
+	}
+}
+
+func (self *SbftService) broadcast(msg ConsensusMsg) {
+	if self.p2p == nil {
+		return
+	}
+	payload, err := self.newPayload(msg)
+	if err != nil {
+		log.Errorf("sbft service %d build msg: %s", self.index, err)
+		return
+	}
+	cons := msgpack.NewConsensus(payload)
+	self.spawn(func() { self.p2p.Broadcast(cons) })
+}
@@ -0,0 +1,398 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology-eventbus/actor"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/consensus/vbft"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
+	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/p2pserver/mock"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	txpool "github.com/ontio/ontology/txnpool/common"
+)
+
+const (
+	testNodeNum = 7
+	// virtual time advanced in each step, and the real time waited for the
+	// services to handle the fired events
+	testStep     = 50 * time.Millisecond
+	testStepWait = 2 * time.Millisecond
+)
+
+type testNode struct {
+	account *account.Account
+	p2pId   p2pcommon.PeerId
+	ledger  *ledger.Ledger
+	service *SbftService
+}
+
+// testNetwork runs the sbft services of 7 validators in process, upon a
+// message level network driven by a simulated clock. The txpool serves a new transaction at
+// every request, so blocks are proposed without waiting BlockMsgDelay.
+type testNetwork struct {
+	t       *testing.T
+	dir     string
+	clock   *vbft.SimClock
+	network *mock.MsgNetwork
+	txpool  *actor.PID
+	nodes   []*testNode
+	genesis *config.GenesisConfig // replaced default genesis config
+	txNonce uint32                // nonce of the last transaction served
+}
+
+func newTestNetwork(t *testing.T) *testNetwork {
+	log.InitLog(log.FatalLog, log.Stdout)
+	dir, err := ioutil.TempDir("", "sbft-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	net := &testNetwork{t: t, dir: dir, clock: vbft.NewSimClock()}
+	net.network = mock.NewMsgNetwork(1, func(d time.Duration, f func()) {
+		net.clock.AfterFunc(d, f)
+	})
+	payer := account.NewAccount("")
+	net.txpool = actor.Spawn(testProps(nil, func(ctx actor.Context) {
+		switch ctx.Message().(type) {
+		case *txpool.GetTxnPoolReq:
+			rsp := &txpool.GetTxnPoolRsp{}
+			tx, err := net.newTransaction(payer)
+			if err != nil {
+				t.Errorf("build transaction: %s", err)
+			} else {
+				rsp.TxnPool = append(rsp.TxnPool, &txpool.VerifiedTx{Tx: tx})
+			}
+			ctx.Respond(rsp)
+		case *txpool.VerifyBlockReq:
+			ctx.Respond(&txpool.VerifyBlockRsp{})
+		}
+	}))
+
+	var bookkeepers []keypair.PublicKey
+	vbftConfig := *config.PolarisConfig.VBFT
+	vbftConfig.Peers = nil
+	for i := 0; i < testNodeNum; i++ {
+		acc := account.NewAccount("")
+		net.nodes = append(net.nodes, &testNode{
+			account: acc,
+			p2pId:   p2pcommon.PseudoPeerIdFromUint64(uint64(i + 1)),
+		})
+		bookkeepers = append(bookkeepers, acc.PublicKey)
+		vbftConfig.Peers = append(vbftConfig.Peers, &config.VBFTPeerStakeInfo{
+			Index:      uint32(i + 1),
+			PeerPubkey: vconfig.PubkeyID(acc.PublicKey),
+			Address:    acc.Address.ToBase58(),
+			InitPos:    10000,
+		})
+	}
+	genesisConfig := &config.GenesisConfig{
+		ConsensusType: config.CONSENSUS_TYPE_SBFT,
+		VBFT:          &vbftConfig,
+		DBFT:          &config.DBFTConfig{},
+		SOLO:          &config.SOLOConfig{},
+	}
+	net.genesis, config.DefConfig.Genesis = config.DefConfig.Genesis, genesisConfig
+	block, err := genesis.BuildGenesisBlock(bookkeepers, genesisConfig)
+	if err != nil {
+		net.close()
+		t.Fatalf("build genesis block: %s", err)
+	}
+
+	for i, node := range net.nodes {
+		nodeDir := filepath.Join(dir, strconv.Itoa(i))
+		if node.ledger, err = ledger.InitLedger(filepath.Join(nodeDir, "ledger"), 0, bookkeepers, block); err != nil {
+			net.close()
+			t.Fatalf("init ledger of node %d: %s", i, err)
+		}
+		node := node
+		p2p := net.network.NewNode(node.p2pId, func(from p2pcommon.PeerId, msg p2pmsg.Message) {
+			if cons, ok := msg.(*p2pmsg.Consensus); ok {
+				payload := cons.Cons
+				payload.PeerId = from
+				node.service.pid.Tell(&payload)
+			}
+		})
+		node.service = newSbftService(node.account, net.txpool, p2p, node.ledger, net.clock)
+		node.service.pid = actor.Spawn(testProps(node.service.stop, node.service.Receive))
+		if err := node.service.initialize(filepath.Join(nodeDir, SBFT_SAFETY_FILE)); err != nil {
+			net.close()
+			t.Fatalf("initialize service of node %d: %s", i, err)
+		}
+	}
+	for i, node := range net.nodes {
+		if err := node.service.Start(); err != nil {
+			net.close()
+			t.Fatalf("start service of node %d: %s", i, err)
+		}
+	}
+	return net
+}
+
+// newTransaction builds a native transaction never served before, the
+// transactions are only accessed by the txpool actor.
+func (self *testNetwork) newTransaction(payer *account.Account) (*types.Transaction, error) {
+	self.txNonce++
+	mutable := utils.BuildNativeTransaction(nutils.OntContractAddress, "name", []byte{})
+	mutable.GasLimit = 20000
+	mutable.Nonce = self.txNonce
+	mutable.Payer = payer.Address
+	txHash := mutable.Hash()
+	sig, err := signature.Sign(payer, txHash.ToArray())
+	if err != nil {
+		return nil, err
+	}
+	mutable.Sigs = []types.Sig{{
+		PubKeys: []keypair.PublicKey{payer.PublicKey},
+		M:       1,
+		SigData: [][]byte{sig},
+	}}
+	return mutable.IntoImmutable()
+}
+
+// testStop stops the actor from inside, done is closed once the actor stopped.
+type testStop struct {
+	done chan struct{}
+}
+
+// testProps runs receive as the actor, on testStop it calls stop before the actor
+// stops, as Halt does for the service. The actor is stopped by itself, as stopping
+// it from another goroutine races with its mailbox.
+func testProps(stop func(), receive func(ctx actor.Context)) *actor.Props {
+	var done chan struct{}
+	return actor.FromFunc(func(ctx actor.Context) {
+		switch msg := ctx.Message().(type) {
+		case *testStop:
+			if stop != nil {
+				stop()
+			}
+			done = msg.done
+			ctx.Self().Stop()
+		case *actor.Stopped:
+			if done != nil {
+				close(done)
+			}
+		default:
+			receive(ctx)
+		}
+	})
+}
+
+// stopActor stops an actor spawned with testProps and waits for it
+func (self *testNetwork) stopActor(pid *actor.PID) bool {
+	stop := &testStop{done: make(chan struct{})}
+	pid.Tell(stop)
+	select {
+	case <-stop.done:
+		return true
+	case <-time.After(time.Minute):
+		return false
+	}
+}
+
+func (self *testNetwork) close() {
+	// no more msgs delivered once stopped advancing the clock
+	for _, node := range self.nodes {
+		self.network.SetDown(node.p2pId, true)
+	}
+	// the ledgers are closed after all the services and their goroutines stopped
+	for i, node := range self.nodes {
+		if node.service == nil || node.service.pid == nil {
+			continue
+		}
+		if !self.stopActor(node.service.pid) {
+			self.t.Errorf("service of node %d not stopped", i)
+		}
+	}
+	for _, node := range self.nodes {
+		if node.ledger != nil {
+			_ = node.ledger.Close()
+		}
+	}
+	if !self.stopActor(self.txpool) {
+		self.t.Errorf("txpool not stopped")
+	}
+	if self.genesis != nil {
+		config.DefConfig.Genesis = self.genesis
+	}
+	os.RemoveAll(self.dir)
+}
+
+// waitHeight advances the clock until the ledgers of the nodes reach the height
+func (self *testNetwork) waitHeight(height uint32, timeout time.Duration, nodes ...int) {
+	reached := func() bool {
+		for _, i := range nodes {
+			if self.nodes[i].ledger.GetCurrentBlockHeight() < height {
+				return false
+			}
+		}
+		return true
+	}
+	for elapsed := time.Duration(0); !reached(); elapsed += testStep {
+		if elapsed >= timeout {
+			var heights []uint32
+			for _, node := range self.nodes {
+				heights = append(heights, node.ledger.GetCurrentBlockHeight())
+			}
+			self.t.Fatalf("liveness: nodes %v not reach height %d in %s, heights %v", nodes, height, timeout, heights)
+		}
+		self.clock.Advance(testStep)
+		time.Sleep(testStepWait)
+	}
+}
+
+// checkSafety checks no two nodes saved different blocks at the same height
+func (self *testNetwork) checkSafety() {
+	for height := uint32(1); ; height++ {
+		var hash common.Uint256
+		found := -1
+		for i, node := range self.nodes {
+			if node.ledger.GetCurrentBlockHeight() < height {
+				continue
+			}
+			h := node.ledger.GetBlockHash(height)
+			if found < 0 {
+				hash, found = h, i
+			} else if h != hash {
+				self.t.Fatalf("safety: block %d of node %d is %s, node %d is %s", height, found,
+					hash.ToHexString(), i, h.ToHexString())
+			}
+		}
+		if found < 0 {
+			return
+		}
+	}
+}
+
+func TestSbftConsensus(t *testing.T) {
+	net := newTestNetwork(t)
+	defer net.close()
+
+	net.waitHeight(4, 30*time.Second, 0, 1, 2, 3)
+	net.checkSafety()
+
+	block, err := net.nodes[0].ledger.GetBlockByHeight(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := initBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blk.getJustify() == nil || blk.getJustify().BlockNum != 2 {
+		t.Fatalf("block 3 not justified by block 2: %v", blk.getJustify())
+	}
+	if len(block.Header.Bookkeepers) < net.nodes[0].service.peers.quorum() {
+		t.Fatalf("block 3 signed by %d validators", len(block.Header.Bookkeepers))
+	}
+	if len(block.Transactions) == 0 {
+		t.Fatal("no transaction sealed in block 3")
+	}
+
+	// the certified blocks carry the state root of a committed block
+	ldg := net.nodes[0].ledger
+	blk, err = net.nodes[0].service.ledgerBlock(ldg.GetCurrentBlockHeight())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blk.Info.ExecBlockNum == 0 || blk.Info.ExecBlockNum >= blk.getBlockNum() {
+		t.Fatalf("block %d carries state root of block %d", blk.getBlockNum(), blk.Info.ExecBlockNum)
+	}
+	if root, _ := ldg.GetStateMerkleRoot(blk.Info.ExecBlockNum); root != blk.Info.ExecMerkleRoot {
+		t.Fatalf("state root of block %d mismatch", blk.Info.ExecBlockNum)
+	}
+	if err := net.nodes[1].service.checkExecRoot(blk); err != nil {
+		t.Fatal(err)
+	}
+	blk.Info.ExecMerkleRoot = common.Uint256{1}
+	if err := net.nodes[1].service.checkExecRoot(blk); err == nil {
+		t.Fatal("voted block of mismatched state root")
+	}
+	blk.Info.ExecBlockNum = blk.getBlockNum() + 1
+	if err := net.nodes[1].service.checkExecRoot(blk); err == nil {
+		t.Fatal("voted block of state root not committed")
+	}
+}
+
+func TestSbftViewChange(t *testing.T) {
+	net := newTestNetwork(t)
+	defer net.close()
+
+	net.waitHeight(2, 30*time.Second, 0, 1, 2, 3)
+	// the views of the crashed node time out, the others keep committing
+	crashed := 3
+	net.network.SetDown(net.nodes[crashed].p2pId, true)
+	height := net.nodes[0].ledger.GetCurrentBlockHeight()
+	net.waitHeight(height+4, 60*time.Second, 0, 1, 2)
+	net.checkSafety()
+
+	net.network.SetDown(net.nodes[crashed].p2pId, false)
+	height = net.nodes[0].ledger.GetCurrentBlockHeight()
+	net.waitHeight(height+2, 60*time.Second, 0, 1, 2)
+	net.checkSafety()
+}
+
+func TestSbftPendingMsgs(t *testing.T) {
+	clock := vbft.NewSimClock()
+	service := newSbftService(account.NewAccount(""), nil, nil, nil, clock)
+	service.index = 1
+
+	// votes of a faulty validator for unknown blocks only fill its own quota
+	for i := 0; i < MAX_PEER_PENDING+10; i++ {
+		service.waitBlock(2, common.Uint256{byte(i), byte(i >> 8)}, &voteMsg{View: uint64(i)})
+	}
+	if service.pendingCount[2] != MAX_PEER_PENDING || len(service.pending) != MAX_PEER_PENDING {
+		t.Fatalf("pending msgs of faulty validator: %d", service.pendingCount[2])
+	}
+	if len(service.fetching) != MAX_PEER_PENDING {
+		t.Fatalf("blocks fetched for dropped msgs: %d", len(service.fetching))
+	}
+	service.waitBlock(3, common.Uint256{0xff}, &voteMsg{})
+	if service.pendingCount[3] != 1 {
+		t.Fatal("msg of other validator dropped")
+	}
+
+	clock.Advance(PENDING_MSG_TIMEOUT / 2)
+	service.waitBlock(3, common.Uint256{0xfe}, &voteMsg{})
+	clock.Advance(PENDING_MSG_TIMEOUT / 2)
+	service.expirePending()
+	if service.pendingCount[2] != 0 || service.pendingCount[3] != 1 || len(service.pending) != 1 {
+		t.Fatalf("pending msgs after expired: %v", service.pendingCount)
+	}
+	if len(service.fetching) != 1 {
+		t.Fatalf("fetching blocks after expired: %d", len(service.fetching))
+	}
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+)
+
+// SbftBlockInfo is the consensus payload stored on each block header. The chain
+// config fields share the json names of vbft block payload, so the ledger
+// verifies the bookkeepers of sbft headers the same way as vbft.
+type SbftBlockInfo struct {
+	View               uint64               `json:"view"`   // hotstuff view the block proposed in
+	Proposer           uint32               `json:"leader"` // leader of the view
+	Justify            *QuorumCert          `json:"justify"`
+	LastConfigBlockNum uint32               `json:"last_config_block_num"`
+	NewChainConfig     *vconfig.ChainConfig `json:"new_chain_config"`
+	ExecBlockNum       uint32               `json:"exec_block_num"`   // committed block executed by the leader
+	ExecMerkleRoot     common.Uint256       `json:"exec_merkle_root"` // state merkle root of ExecBlockNum
+}
+
+// SbftBlock returns the consensus payload of header
+func SbftBlock(header *types.Header) (*SbftBlockInfo, error) {
+	blkInfo := &SbftBlockInfo{}
+	if err := json.Unmarshal(header.ConsensusPayload, blkInfo); err != nil {
+		return nil, fmt.Errorf("unmarshal blockInfo: %s", err)
+	}
+	return blkInfo, nil
+}
+
+// QuorumCert collects the votes of a quorum of validators for a block, each
+// vote is the signature of the validator on the block hash, so the certificate
+// of a committed block is saved as the sigdata of its header. It is a list of
+// signatures rather than an aggregated one, see README.
+type QuorumCert struct {
+	View      uint64         `json:"view"`
+	BlockNum  uint32         `json:"block_num"`
+	BlockHash common.Uint256 `json:"block_hash"`
+	Signers   []uint32       `json:"signers"` // validator indexes, sorted
+	Sigs      [][]byte       `json:"sigs"`
+}
+
+func newQuorumCert(blk *Block, votes map[uint32][]byte) *QuorumCert {
+	qc := &QuorumCert{
+		View:      blk.getView(),
+		BlockNum:  blk.getBlockNum(),
+		BlockHash: blk.hash(),
+	}
+	for signer := range votes {
+		qc.Signers = append(qc.Signers, signer)
+	}
+	sort.Slice(qc.Signers, func(i, j int) bool { return qc.Signers[i] < qc.Signers[j] })
+	for _, signer := range qc.Signers {
+		qc.Sigs = append(qc.Sigs, votes[signer])
+	}
+	return qc
+}
+
+// verify checks the certificate is of blk, with the signatures of a quorum of
+// validators on the block hash. The votes only sign the hash, so the view and
+// number of the certificate must be checked against the block.
+func (qc *QuorumCert) verify(vals *validators, blk *Block) error {
+	if qc.BlockHash != blk.hash() || qc.View != blk.getView() || qc.BlockNum != blk.getBlockNum() {
+		return fmt.Errorf("certificate of view %d block %d mismatch block of view %d block %d",
+			qc.View, qc.BlockNum, blk.getView(), blk.getBlockNum())
+	}
+	if len(qc.Signers) != len(qc.Sigs) {
+		return fmt.Errorf("%d signers with %d sigs", len(qc.Signers), len(qc.Sigs))
+	}
+	if len(qc.Signers) < vals.quorum() {
+		return fmt.Errorf("%d signers less than quorum %d", len(qc.Signers), vals.quorum())
+	}
+	signed := make(map[uint32]bool)
+	for i, signer := range qc.Signers {
+		if signed[signer] {
+			return fmt.Errorf("duplicated signer %d", signer)
+		}
+		signed[signer] = true
+		pub := vals.pubkey(signer)
+		if pub == nil {
+			return fmt.Errorf("signer %d is not validator", signer)
+		}
+		if err := signature.Verify(pub, qc.BlockHash[:], qc.Sigs[i]); err != nil {
+			return fmt.Errorf("signature of signer %d: %s", signer, err)
+		}
+	}
+	return nil
+}
+
+// bookkeepers returns the public keys of signers for the header of certified block
+func (qc *QuorumCert) bookkeepers(vals *validators) ([]keypair.PublicKey, error) {
+	pubs := make([]keypair.PublicKey, 0, len(qc.Signers))
+	for _, signer := range qc.Signers {
+		pub := vals.pubkey(signer)
+		if pub == nil {
+			return nil, fmt.Errorf("signer %d is not validator", signer)
+		}
+		pubs = append(pubs, pub)
+	}
+	return pubs, nil
+}
+
+// Block is the block proposed with its parsed consensus payload
+type Block struct {
+	Block *types.Block
+	Info  *SbftBlockInfo
+}
+
+func initBlock(blk *types.Block) (*Block, error) {
+	if blk == nil || blk.Header == nil {
+		return nil, errors.New("nil block")
+	}
+	info, err := SbftBlock(blk.Header)
+	if err != nil {
+		return nil, err
+	}
+	return &Block{
+		Block: blk,
+		Info:  info,
+	}, nil
+}
+
+func (blk *Block) getBlockNum() uint32 {
+	return blk.Block.Header.Height
+}
+
+func (blk *Block) getView() uint64 {
+	return blk.Info.View
+}
+
+func (blk *Block) hash() common.Uint256 {
+	return blk.Block.Hash()
+}
+
+func (blk *Block) getPrevBlockHash() common.Uint256 {
+	return blk.Block.Header.PrevBlockHash
+}
+
+func (blk *Block) getTimestamp() uint32 {
+	return blk.Block.Header.Timestamp
+}
+
+func (blk *Block) getJustify() *QuorumCert {
+	return blk.Info.Justify
+}
+
+func (blk *Block) getNewChainConfig() *vconfig.ChainConfig {
+	return blk.Info.NewChainConfig
+}
+
+func (blk *Block) isEmpty() bool {
+	return len(blk.Block.Transactions) == 0 && blk.Info.NewChainConfig == nil
+}
+
+// rootQuorumCert rebuilds the certificate of a committed block from its header,
+// signers out of the validators are skipped.
+func rootQuorumCert(blk *Block, vals *validators) *QuorumCert {
+	qc := &QuorumCert{
+		View:      blk.getView(),
+		BlockNum:  blk.getBlockNum(),
+		BlockHash: blk.hash(),
+	}
+	header := blk.Block.Header
+	if len(header.Bookkeepers) != len(header.SigData) {
+		return qc
+	}
+	for i, pub := range header.Bookkeepers {
+		if index, present := vals.index(vconfig.PubkeyID(pub)); present {
+			qc.Signers = append(qc.Signers, index)
+			qc.Sigs = append(qc.Sigs, header.SigData[i])
+		}
+	}
+	return qc
+}
+
+// validators are the consensus peers of a chain config, the leader of views
+// rotates over them in the order of chain config.
+type validators struct {
+	config  *vconfig.ChainConfig
+	pubkeys map[uint32]keypair.PublicKey
+	indexes map[string]uint32
+}
+
+func newValidators(cfg *vconfig.ChainConfig) (*validators, error) {
+	if len(cfg.Peers) == 0 {
+		return nil, errors.New("no peer in chain config")
+	}
+	vals := &validators{
+		config:  cfg,
+		pubkeys: make(map[uint32]keypair.PublicKey),
+		indexes: make(map[string]uint32),
+	}
+	for _, p := range cfg.Peers {
+		pub, err := vconfig.Pubkey(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer %d: %s", p.Index, err)
+		}
+		vals.pubkeys[p.Index] = pub
+		vals.indexes[p.ID] = p.Index
+	}
+	return vals, nil
+}
+
+func (vals *validators) leader(view uint64) uint32 {
+	return vals.config.Peers[view%uint64(len(vals.config.Peers))].Index
+}
+
+// quorum is the minimum count of votes certifying a block, 2f+1 in 3f+1 validators
+func (vals *validators) quorum() int {
+	n := len(vals.config.Peers)
+	return n - (n-1)/3
+}
+
+func (vals *validators) pubkey(index uint32) keypair.PublicKey {
+	return vals.pubkeys[index]
+}
+
+func (vals *validators) index(peerID string) (uint32, bool) {
+	index, present := vals.indexes[peerID]
+	return index, present
+}
+
+// bookkeeperAddress is the next bookkeeper of blocks signed by the validators
+func (vals *validators) bookkeeperAddress() (common.Address, error) {
+	pubs := make([]keypair.PublicKey, 0, len(vals.config.Peers))
+	for _, p := range vals.config.Peers {
+		pubs = append(pubs, vals.pubkeys[p.Index])
+	}
+	return types.AddressFromBookkeepers(pubs)
+}
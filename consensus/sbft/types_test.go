@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+)
+
+func newTestValidators(t *testing.T, n int) ([]*account.Account, *validators) {
+	cfg := &vconfig.ChainConfig{}
+	accs := make([]*account.Account, 0, n)
+	for i := 0; i < n; i++ {
+		acc := account.NewAccount("")
+		accs = append(accs, acc)
+		cfg.Peers = append(cfg.Peers, &vconfig.PeerConfig{
+			Index: uint32(i + 1),
+			ID:    vconfig.PubkeyID(acc.PublicKey),
+		})
+	}
+	vals, err := newValidators(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accs, vals
+}
+
+// newTestBlock builds an empty block of view upon parent, justified by the
+// certificate of parent.
+func newTestBlock(t *testing.T, parent *Block, view uint64) *Block {
+	info := &SbftBlockInfo{View: view}
+	if parent != nil {
+		info.Justify = &QuorumCert{
+			View:      parent.getView(),
+			BlockNum:  parent.getBlockNum(),
+			BlockHash: parent.hash(),
+		}
+	}
+	payload, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &types.Header{
+		TransactionsRoot: common.ComputeMerkleRoot(nil),
+		Timestamp:        uint32(view),
+		ConsensusData:    view,
+		ConsensusPayload: payload,
+	}
+	if parent != nil {
+		header.PrevBlockHash = parent.hash()
+		header.Height = parent.getBlockNum() + 1
+	}
+	return &Block{Block: &types.Block{Header: header}, Info: info}
+}
+
+func signVotes(t *testing.T, accs []*account.Account, blk *Block, signers ...uint32) map[uint32][]byte {
+	hash := blk.hash()
+	votes := make(map[uint32][]byte)
+	for _, i := range signers {
+		sig, err := signature.Sign(accs[i-1], hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes[i] = sig
+	}
+	return votes
+}
+
+func TestValidators(t *testing.T) {
+	_, vals := newTestValidators(t, 7)
+	if vals.quorum() != 5 {
+		t.Errorf("quorum of 7 validators: %d", vals.quorum())
+	}
+	if vals.leader(0) != 1 || vals.leader(8) != 2 {
+		t.Errorf("leaders of view 0 and 8: %d, %d", vals.leader(0), vals.leader(8))
+	}
+	if _, present := vals.index("unknown"); present {
+		t.Error("unknown peer found in validators")
+	}
+	_, vals = newTestValidators(t, 4)
+	if vals.quorum() != 3 {
+		t.Errorf("quorum of 4 validators: %d", vals.quorum())
+	}
+}
+
+func TestQuorumCertVerify(t *testing.T) {
+	accs, vals := newTestValidators(t, 4)
+	blk := newTestBlock(t, nil, 1)
+
+	qc := newQuorumCert(blk, signVotes(t, accs, blk, 3, 1, 2))
+	if qc.Signers[0] != 1 || qc.Signers[2] != 3 {
+		t.Fatalf("signers not sorted: %v", qc.Signers)
+	}
+	if err := qc.verify(vals, blk); err != nil {
+		t.Fatalf("verify quorum cert: %s", err)
+	}
+	pubs, err := qc.bookkeepers(vals)
+	if err != nil || len(pubs) != 3 {
+		t.Fatalf("bookkeepers of quorum cert: %v, %v", pubs, err)
+	}
+
+	if err := newQuorumCert(blk, signVotes(t, accs, blk, 1, 2)).verify(vals, blk); err == nil {
+		t.Error("quorum cert of 2 signers verified")
+	}
+	dup := newQuorumCert(blk, signVotes(t, accs, blk, 1, 2, 3))
+	dup.Signers[2], dup.Sigs[2] = 2, dup.Sigs[1]
+	if err := dup.verify(vals, blk); err == nil {
+		t.Error("quorum cert of duplicated signers verified")
+	}
+	forged := newQuorumCert(blk, signVotes(t, accs, blk, 1, 2, 3))
+	forged.Sigs[0] = forged.Sigs[1]
+	if err := forged.verify(vals, blk); err == nil {
+		t.Error("quorum cert of forged signature verified")
+	}
+	// the signatures of votes are reused with an inflated view
+	inflated := newQuorumCert(blk, signVotes(t, accs, blk, 1, 2, 3))
+	inflated.View = 100
+	if err := inflated.verify(vals, blk); err == nil {
+		t.Error("quorum cert of forged view verified")
+	}
+	other := newTestBlock(t, nil, 2)
+	if err := newQuorumCert(blk, signVotes(t, accs, blk, 1, 2, 3)).verify(vals, other); err == nil {
+		t.Error("quorum cert verified against other block")
+	}
+}
+
+func TestRootQuorumCert(t *testing.T) {
+	accs, vals := newTestValidators(t, 4)
+	blk := newTestBlock(t, nil, 1)
+	qc := newQuorumCert(blk, signVotes(t, accs, blk, 1, 2, 4))
+	pubs, err := qc.bookkeepers(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk.Block.Header.Bookkeepers = pubs
+	blk.Block.Header.SigData = qc.Sigs
+
+	root := rootQuorumCert(blk, vals)
+	if root.BlockHash != blk.hash() || root.View != 1 {
+		t.Fatalf("root quorum cert of block: %+v", root)
+	}
+	if err := root.verify(vals, blk); err != nil {
+		t.Fatalf("verify root quorum cert: %s", err)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/store"
 	"github.com/ontio/ontology/core/store/overlaydb"
 )
 
@@ -38,10 +39,11 @@ var errDupEndorse = errors.New("multi endorsement from same endorser")
 var errDupCommit = errors.New("multi commit from same committer")
 
 type CandidateEndorseSigInfo struct {
-	EndorsedProposer uint32
-	Signature        []byte
-	ForEmpty         bool
-	CrossChainMsgSig []byte
+	EndorsedProposer  uint32
+	EndorsedBlockHash common.Uint256 // the sigs are counted by block, a proposer may equivocate
+	Signature         []byte
+	ForEmpty          bool
+	CrossChainMsgSig  []byte
 }
 
 type CandidateInfo struct {
@@ -150,9 +152,10 @@ func (pool *BlockPool) newBlockProposal(msg *blockProposalMsg) error {
 	// add endorse-sig
 	proposer := msg.Block.getProposer()
 	eSig := &CandidateEndorseSigInfo{
-		EndorsedProposer: proposer,
-		Signature:        msg.BlockProposerSig,
-		ForEmpty:         false,
+		EndorsedProposer:  proposer,
+		EndorsedBlockHash: msg.Block.Block.Hash(),
+		Signature:         msg.BlockProposerSig,
+		ForEmpty:          false,
 	}
 	if msg.Block.Block.Header.Height > 1 && msg.Block.CrossChainMsg != nil {
 		eSig.CrossChainMsgSig = msg.Block.CrossChainMsg.SigData[0]
@@ -297,10 +300,11 @@ func (pool *BlockPool) newBlockEndorsement(msg *blockEndorseMsg) {
 	defer pool.lock.Unlock()
 
 	eSig := &CandidateEndorseSigInfo{
-		EndorsedProposer: msg.EndorsedProposer,
-		Signature:        msg.EndorserSig,
-		ForEmpty:         msg.EndorseForEmpty,
-		CrossChainMsgSig: msg.CrossChainMsgEndorserSig,
+		EndorsedProposer:  msg.EndorsedProposer,
+		EndorsedBlockHash: msg.EndorsedBlockHash,
+		Signature:         msg.EndorserSig,
+		ForEmpty:          msg.EndorseForEmpty,
+		CrossChainMsgSig:  msg.CrossChainMsgEndorserSig,
 	}
 	pool.addBlockEndorsementLocked(msg.GetBlockNum(), msg.Endorser, eSig, false)
 }
@@ -310,23 +314,24 @@ func (pool *BlockPool) newBlockEndorsement(msg *blockEndorseMsg) {
 //
 // return
 //		@ endorsable proposer
+//		@ endorsable block hash
 //		@ for empty commit
 //		@ endorsable
 //
-func (pool *BlockPool) endorseDone(blkNum uint32, C uint32) (uint32, bool, bool) {
+func (pool *BlockPool) endorseDone(blkNum uint32, C uint32) (uint32, common.Uint256, bool, bool) {
 	pool.lock.RLock()
 	defer pool.lock.RUnlock()
 
-	endorseCount := make(map[uint32]uint32)
+	endorseCount := make(map[common.Uint256]uint32)
 	emptyEndorseCount := 0
 
 	candidate := pool.candidateBlocks[blkNum]
 	if candidate == nil {
-		return math.MaxUint32, false, false
+		return math.MaxUint32, common.Uint256{}, false, false
 	}
 
 	if uint32(len(candidate.EndorseSigs)) < C+1 {
-		return math.MaxUint32, false, false
+		return math.MaxUint32, common.Uint256{}, false, false
 	}
 
 	for _, eSigs := range candidate.EndorseSigs {
@@ -335,26 +340,26 @@ func (pool *BlockPool) endorseDone(blkNum uint32, C uint32) (uint32, bool, bool)
 				emptyEndorseCount++
 				if emptyEndorseCount > int(C) {
 					// FIXME: endorsedProposer need fix
-					return esig.EndorsedProposer, true, true
+					return esig.EndorsedProposer, esig.EndorsedBlockHash, true, true
 				}
 			} else {
-				endorseCount[esig.EndorsedProposer] += 1
+				endorseCount[esig.EndorsedBlockHash] += 1
 				// check if endorse-consensus reached
-				if endorseCount[esig.EndorsedProposer] > C {
-					return esig.EndorsedProposer, false, true
+				if endorseCount[esig.EndorsedBlockHash] > C {
+					return esig.EndorsedProposer, esig.EndorsedBlockHash, false, true
 				}
 			}
 		}
 	}
 
-	return math.MaxUint32, false, false
+	return math.MaxUint32, common.Uint256{}, false, false
 }
 
 func (pool *BlockPool) endorseFailed(blkNum uint32, C uint32) bool {
 	pool.lock.RLock()
 	defer pool.lock.RUnlock()
 
-	proposalCount := make(map[uint32]uint32)
+	proposalCount := make(map[common.Uint256]uint32)
 	endorserCount := make(map[uint32]uint32)
 	candidate := pool.candidateBlocks[blkNum]
 	if candidate == nil {
@@ -369,8 +374,8 @@ func (pool *BlockPool) endorseFailed(blkNum uint32, C uint32) bool {
 	for endorser, eSigs := range candidate.EndorseSigs {
 		for _, esig := range eSigs {
 			if !esig.ForEmpty {
-				proposalCount[esig.EndorsedProposer] += 1
-				if proposalCount[esig.EndorsedProposer] > C+1 {
+				proposalCount[esig.EndorsedBlockHash] += 1
+				if proposalCount[esig.EndorsedBlockHash] > C+1 {
 					return false
 				}
 			} else {
@@ -469,9 +474,10 @@ func (pool *BlockPool) newBlockCommitment(msg *blockCommitMsg) error {
 	// add all endorse sigs
 	for endorser, sig := range msg.EndorsersSig {
 		eSig := &CandidateEndorseSigInfo{
-			EndorsedProposer: msg.BlockProposer,
-			Signature:        sig,
-			ForEmpty:         msg.CommitForEmpty,
+			EndorsedProposer:  msg.BlockProposer,
+			EndorsedBlockHash: msg.CommitBlockHash,
+			Signature:         sig,
+			ForEmpty:          msg.CommitForEmpty,
 		}
 		// old version of committer msg is nil, compatible old version
 		if msg.CrossChainMsgCommitterSig != nil {
@@ -485,10 +491,11 @@ func (pool *BlockPool) newBlockCommitment(msg *blockCommitMsg) error {
 
 	// add committer sig
 	pool.addBlockEndorsementLocked(blkNum, msg.Committer, &CandidateEndorseSigInfo{
-		EndorsedProposer: msg.BlockProposer,
-		Signature:        msg.CommitterSig,
-		ForEmpty:         msg.CommitForEmpty,
-		CrossChainMsgSig: msg.CrossChainMsgCommitterSig,
+		EndorsedProposer:  msg.BlockProposer,
+		EndorsedBlockHash: msg.CommitBlockHash,
+		Signature:         msg.CommitterSig,
+		ForEmpty:          msg.CommitForEmpty,
+		CrossChainMsgSig:  msg.CrossChainMsgCommitterSig,
 	}, true)
 
 	// add msg to commit-msgs
@@ -500,29 +507,30 @@ func (pool *BlockPool) newBlockCommitment(msg *blockCommitMsg) error {
 // check if has reached consensus on block-commit
 // return
 //		@ consensused proposer
+//		@ consensused block hash
 //		@ for empty commit
 //		@ consensused
 //
 // Note: Attentions on lock contention.
 // Only shared-lock for this function, because this function will also acquires shared-lock on peer-pool.
 //
-func (pool *BlockPool) commitDone(blkNum uint32, C uint32, N uint32) (uint32, bool, bool) {
+func (pool *BlockPool) commitDone(blkNum uint32, C uint32, N uint32) (uint32, common.Uint256, bool, bool) {
 	pool.lock.RLock()
 	defer pool.lock.RUnlock()
 	candidate := pool.candidateBlocks[blkNum]
 	if candidate == nil {
-		return math.MaxUint32, false, false
+		return math.MaxUint32, common.Uint256{}, false, false
 	}
 
 	// check consensus with commit msgs
-	proposer, forEmpty := getCommitConsensus(candidate.CommitMsgs, int(C), int(N))
+	proposer, blkHash, forEmpty := getCommitConsensus(candidate.CommitMsgs, int(C), int(N))
 
 	if proposer == math.MaxUint32 {
 		// check consensus with endorse sigs
 		// enforce signature quorum if checking commit-consensus base on signature count
 		C = N - (N-1)/3 - 1
 		var emptyCnt uint32
-		endorseCnt := make(map[uint32]uint32) // proposer -> endorsed-cnt
+		endorseCnt := make(map[common.Uint256]uint32) // block hash -> endorsed-cnt
 		for endorser, eSigs := range candidate.EndorseSigs {
 			// check if from endorser
 			if !pool.server.isEndorser(blkNum, endorser) {
@@ -537,9 +545,10 @@ func (pool *BlockPool) commitDone(blkNum uint32, C uint32, N uint32) (uint32, bo
 				if sig.ForEmpty {
 					emptyCnt++
 				} else {
-					endorseCnt[sig.EndorsedProposer] += 1
-					if endorseCnt[sig.EndorsedProposer] > C {
+					endorseCnt[sig.EndorsedBlockHash] += 1
+					if endorseCnt[sig.EndorsedBlockHash] > C {
 						proposer = sig.EndorsedProposer
+						blkHash = sig.EndorsedBlockHash
 						if !forEmpty {
 							forEmpty = emptyCnt > C
 						}
@@ -555,10 +564,10 @@ func (pool *BlockPool) commitDone(blkNum uint32, C uint32, N uint32) (uint32, bo
 	}
 
 	if proposer != math.MaxUint32 {
-		return proposer, forEmpty, true
+		return proposer, blkHash, forEmpty, true
 	}
 
-	return math.MaxUint32, false, false
+	return math.MaxUint32, common.Uint256{}, false, false
 }
 
 //
@@ -602,21 +611,24 @@ func (pool *BlockPool) addSignaturesToBlockLocked(block *Block, forEmpty bool) e
 	// add proposer sig
 	proposer := block.getProposer()
 	proposerPk := pool.server.peerPool.GetPeerPubKey(proposer)
+	var blkHash common.Uint256
 	if !forEmpty {
 		bookkeepers = append(bookkeepers, proposerPk)
 		sigData = append(sigData, block.Block.Header.SigData[0])
+		blkHash = block.Block.Hash()
 	} else {
 		if block.EmptyBlock == nil {
 			return fmt.Errorf("block has no empty candidate")
 		}
 		bookkeepers = append(bookkeepers, proposerPk)
 		sigData = append(sigData, block.EmptyBlock.Header.SigData[0])
+		blkHash = block.EmptyBlock.Hash()
 	}
 
 	// add endorsers' sig
 	for endorser, eSigs := range c.EndorseSigs {
 		for _, sig := range eSigs {
-			if sig.EndorsedProposer == proposer && sig.ForEmpty == forEmpty && endorser != proposer {
+			if sig.EndorsedBlockHash == blkHash && sig.ForEmpty == forEmpty && endorser != proposer {
 				endoresrPk := pool.server.peerPool.GetPeerPubKey(endorser)
 				if endoresrPk != nil {
 					bookkeepers = append(bookkeepers, endoresrPk)
@@ -657,6 +669,8 @@ func (pool *BlockPool) setBlockSealed(block *Block, forEmpty bool, sigdata bool)
 		return fmt.Errorf("double seal for block %d", blkNum)
 	}
 	if sigdata {
+		// the proposal msg may still be queued in msgSendC
+		block = block.copyHeaders()
 		if err := pool.addSignaturesToBlockLocked(block, forEmpty); err != nil {
 			return fmt.Errorf("failed to add sig to block: %s", err)
 		}
@@ -801,6 +815,12 @@ func (pool *BlockPool) getExecWriteSet(blkNum uint32) *overlaydb.MemDB {
 	return pool.chainStore.getExecWriteSet(blkNum)
 }
 
+func (pool *BlockPool) executeSpeculatively(block *Block) (*store.ExecuteResult, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	return pool.chainStore.executeSpeculatively(block)
+}
+
 func (pool *BlockPool) submitBlock(blkNum uint32) error {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
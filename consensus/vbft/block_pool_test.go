@@ -29,6 +29,7 @@ import (
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/signature"
 	"github.com/ontio/ontology/core/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func buildTestBlockPool(t *testing.T) (*BlockPool, error) {
@@ -114,3 +115,81 @@ func TestAddBlock(t *testing.T) {
 		t.Errorf("submitBlock err:%s", err)
 	}
 }
+
+func TestEndorseDoneEquivocation(t *testing.T) {
+	// the endorsements of an equivocating proposer are split over its two blocks
+	hash1, hash2 := common.Uint256{1}, common.Uint256{2}
+	pool := &BlockPool{candidateBlocks: make(map[uint32]*CandidateInfo)}
+	c := pool.getCandidateInfoLocked(1)
+	for i := uint32(2); i <= 5; i++ {
+		hash := hash1
+		if i > 3 {
+			hash = hash2
+		}
+		c.EndorseSigs[i] = []*CandidateEndorseSigInfo{{
+			EndorsedProposer:  1,
+			EndorsedBlockHash: hash,
+		}}
+	}
+	if proposer, _, _, done := pool.endorseDone(1, 2); done {
+		t.Fatalf("endorse done on split endorsements of proposer %d", proposer)
+	}
+
+	c.EndorseSigs[6] = []*CandidateEndorseSigInfo{{
+		EndorsedProposer:  1,
+		EndorsedBlockHash: hash2,
+	}}
+	proposer, hash, forEmpty, done := pool.endorseDone(1, 2)
+	if !done || proposer != 1 || hash != hash2 || forEmpty {
+		t.Fatalf("endorse done %v of proposer %d, block %s", done, proposer, hash.ToHexString())
+	}
+}
+
+func TestSpeculativeBlockRollback(t *testing.T) {
+	blockpool, err := buildTestBlockPool(t)
+	if err != nil {
+		t.Fatalf("buildTestBlockPool err:%s", err)
+	}
+	defer cleanTestChainStore()
+	store := blockpool.chainStore
+
+	lastBlock, _ := blockpool.getSealedBlock(0)
+	committed, err := buildTestBlock(t, lastBlock.Block, store.db)
+	if err != nil {
+		t.Fatalf("buildTestBlock err:%s", err)
+	}
+	sealed, err := buildTestBlock(t, lastBlock.Block, store.db)
+	if err != nil {
+		t.Fatalf("buildTestBlock err:%s", err)
+	}
+
+	// the committed block is executed before sealed, another block is sealed instead
+	if _, err := blockpool.executeSpeculatively(committed); err != nil {
+		t.Fatalf("executeSpeculatively err:%s", err)
+	}
+	reused := testutil.ToFloat64(speculativeBlocksMetric.WithLabelValues("reused"))
+	abandoned := testutil.ToFloat64(speculativeBlocksMetric.WithLabelValues("abandoned"))
+	if err := store.AddBlock(sealed); err != nil {
+		t.Fatalf("AddBlock err:%s", err)
+	}
+	if len(store.speculativeBlocks) != 0 {
+		t.Fatalf("%d speculative results not discarded", len(store.speculativeBlocks))
+	}
+	if testutil.ToFloat64(speculativeBlocksMetric.WithLabelValues("reused")) != reused {
+		t.Fatalf("speculative result of the committed block reused")
+	}
+	if testutil.ToFloat64(speculativeBlocksMetric.WithLabelValues("abandoned")) != abandoned+1 {
+		t.Fatalf("speculative result of the committed block not abandoned")
+	}
+	// the committed block is not next to the chained block anymore
+	if _, err := blockpool.executeSpeculatively(committed); err == nil {
+		t.Fatalf("executed the committed block upon the sealed block")
+	}
+
+	if err := blockpool.submitBlock(sealed.getBlockNum()); err != nil {
+		t.Fatalf("submitBlock err:%s", err)
+	}
+	if hash := store.db.GetBlockHash(sealed.getBlockNum()); hash != sealed.Block.Hash() {
+		t.Fatalf("block %s saved, sealed block %s", hash.ToHexString(), sealed.Block.Hash().ToHexString())
+	}
+}
@@ -41,15 +41,18 @@ type ChainStore struct {
 	db              *ledger.Ledger
 	chainedBlockNum uint32
 	pendingBlocks   map[uint32]*PendingBlock
-	pid             *actor.PID
+	// execution results of the blocks next to chained block before sealed, indexed by block hash
+	speculativeBlocks map[common.Uint256]*PendingBlock
+	pid               *actor.PID
 }
 
 func OpenBlockStore(db *ledger.Ledger, serverPid *actor.PID) (*ChainStore, error) {
 	chainstore := &ChainStore{
-		db:              db,
-		chainedBlockNum: db.GetCurrentBlockHeight(),
-		pendingBlocks:   make(map[uint32]*PendingBlock),
-		pid:             serverPid,
+		db:                db,
+		chainedBlockNum:   db.GetCurrentBlockHeight(),
+		pendingBlocks:     make(map[uint32]*PendingBlock),
+		speculativeBlocks: make(map[common.Uint256]*PendingBlock),
+		pid:               serverPid,
 	}
 	merkleRoot, err := db.GetStateMerkleRoot(chainstore.GetChainedBlockNum())
 	if err != nil {
@@ -131,6 +134,7 @@ func (self *ChainStore) ReloadFromLedger() {
 		log.Debug("chainstore ReloadFromLedger pendingBlocks")
 		// update pending blocks
 		self.pendingBlocks = newPending
+		self.dropSpeculativeBlocks(height)
 	}
 }
 
@@ -152,12 +156,21 @@ func (self *ChainStore) AddBlock(block *Block) error {
 	if err != nil {
 		log.Errorf("chainstore blkNum:%d, SubmitBlock: %s", blkNum-1, err)
 	}
-	execResult, err := self.db.ExecuteBlock(block.Block)
-	if err != nil {
-		log.Errorf("chainstore AddBlock GetBlockExecResult: %s", err)
-		return fmt.Errorf("chainstore AddBlock GetBlockExecResult: %s", err)
-	}
 	h := block.Block.Hash()
+	var execResult store.ExecuteResult
+	if speculated, present := self.speculativeBlocks[h]; present {
+		execResult = *speculated.execResult
+		delete(self.speculativeBlocks, h)
+		speculativeBlocksMetric.WithLabelValues("reused").Inc()
+	} else {
+		execResult, err = self.db.ExecuteBlock(block.Block)
+		if err != nil {
+			log.Errorf("chainstore AddBlock GetBlockExecResult: %s", err)
+			return fmt.Errorf("chainstore AddBlock GetBlockExecResult: %s", err)
+		}
+	}
+	// results of the other blocks at the height are abandoned
+	self.dropSpeculativeBlocks(blkNum)
 	log.Debugf("execResult:%+v, AddBlock execResult height:%d, hash: %s \n", execResult, block.Block.Header.Height, h.ToHexString())
 	log.Debugf("chainstore addblock pendingBlocks height:%d,block height:%d", blkNum, block.getBlockNum())
 	self.pendingBlocks[blkNum] = &PendingBlock{block: block, execResult: &execResult, hasSubmitted: false}
@@ -172,6 +185,40 @@ func (self *ChainStore) AddBlock(block *Block) error {
 	return nil
 }
 
+// executeSpeculatively executes the block next to chained block before it is sealed. The
+// result is kept until a block is sealed at the height, reused if the block is the one sealed.
+func (self *ChainStore) executeSpeculatively(block *Block) (*store.ExecuteResult, error) {
+	blkNum := block.getBlockNum()
+	if blkNum != self.GetChainedBlockNum()+1 {
+		return nil, fmt.Errorf("block %d is not next to chained block %d", blkNum, self.GetChainedBlockNum())
+	}
+	h := block.Block.Hash()
+	if speculated, present := self.speculativeBlocks[h]; present {
+		return speculated.execResult, nil
+	}
+	// the chained block is sealed, submit it to execute the block upon it
+	if err := self.submitBlock(blkNum - 1); err != nil {
+		return nil, err
+	}
+	execResult, err := self.db.ExecuteBlock(block.Block)
+	if err != nil {
+		return nil, fmt.Errorf("execute block %d: %s", blkNum, err)
+	}
+	self.speculativeBlocks[h] = &PendingBlock{block: block, execResult: &execResult, hasSubmitted: false}
+	return &execResult, nil
+}
+
+// dropSpeculativeBlocks removes the speculative results not higher than blkNum, which have
+// nothing written to ledger
+func (self *ChainStore) dropSpeculativeBlocks(blkNum uint32) {
+	for h, blk := range self.speculativeBlocks {
+		if blk.block.getBlockNum() <= blkNum {
+			delete(self.speculativeBlocks, h)
+			speculativeBlocksMetric.WithLabelValues("abandoned").Inc()
+		}
+	}
+}
+
 func (self *ChainStore) submitBlock(blkNum uint32) error {
 	if blkNum == 0 {
 		return nil
@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the server, all the consensus timers are
+// created from it, so the server can run with a simulated clock in tests.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after duration d
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the timer created by Clock
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the Clock of system time
+var SystemClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// SimClock is a Clock only advanced by simulations, timers due in the same
+// step are fired in the order of due time and creation.
+type SimClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers map[*simTimer]struct{}
+}
+
+type simTimer struct {
+	clock *SimClock
+	due   time.Time
+	seq   uint64
+	f     func()
+}
+
+func NewSimClock() *SimClock {
+	return &SimClock{
+		now:    time.Now(),
+		timers: make(map[*simTimer]struct{}),
+	}
+}
+
+func (self *SimClock) Now() time.Time {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.now
+}
+
+func (self *SimClock) AfterFunc(d time.Duration, f func()) Timer {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	t := &simTimer{clock: self, f: f}
+	self.scheduleLocked(t, d)
+	return t
+}
+
+func (self *SimClock) scheduleLocked(t *simTimer, d time.Duration) {
+	self.seq++
+	t.due = self.now.Add(d)
+	t.seq = self.seq
+	self.timers[t] = struct{}{}
+}
+
+// Advance moves the clock forward by d, and fires the due timers
+func (self *SimClock) Advance(d time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	end := self.now.Add(d)
+	for {
+		var next *simTimer
+		for t := range self.timers {
+			if t.due.After(end) {
+				continue
+			}
+			if next == nil || t.due.Before(next.due) || (t.due.Equal(next.due) && t.seq < next.seq) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		delete(self.timers, next)
+		if next.due.After(self.now) {
+			self.now = next.due
+		}
+		go next.f()
+	}
+	self.now = end
+}
+
+func (self *simTimer) Stop() bool {
+	self.clock.lock.Lock()
+	defer self.clock.lock.Unlock()
+
+	_, active := self.clock.timers[self]
+	delete(self.clock.timers, self)
+	return active
+}
+
+func (self *simTimer) Reset(d time.Duration) bool {
+	self.clock.lock.Lock()
+	defer self.clock.lock.Unlock()
+
+	_, active := self.clock.timers[self]
+	self.clock.scheduleLocked(self, d)
+	return active
+}
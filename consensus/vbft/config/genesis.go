@@ -198,7 +198,7 @@ func GenesisConsensusPayload(txhash common.Uint256, height uint32) ([]byte, erro
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
 
 	switch consensusType {
-	case "vbft":
+	case "vbft", "sbft":
 		return genConsensusPayload(config.DefConfig.Genesis.VBFT, txhash, height)
 	}
 	return nil, nil
@@ -66,11 +66,12 @@ type TimerEvent struct {
 	msg      ConsensusMsg
 }
 
-type perBlockTimer map[uint32]*time.Timer
+type perBlockTimer map[uint32]Timer
 
 type EventTimer struct {
 	lock   sync.Mutex
 	server *Server
+	clock  Clock
 	C      chan *TimerEvent
 	//timerQueue TimerQueue
 
@@ -78,28 +79,29 @@ type EventTimer struct {
 	eventTimers map[TimerEventType]perBlockTimer
 
 	// peer heartbeat tickers
-	peerTickers map[uint32]*time.Timer
+	peerTickers map[uint32]Timer
 	// other timers
-	normalTimers map[uint32]*time.Timer
+	normalTimers map[uint32]Timer
 }
 
 func NewEventTimer(server *Server) *EventTimer {
 	timer := &EventTimer{
 		server:       server,
+		clock:        server.clock,
 		C:            make(chan *TimerEvent, 64),
 		eventTimers:  make(map[TimerEventType]perBlockTimer),
-		peerTickers:  make(map[uint32]*time.Timer),
-		normalTimers: make(map[uint32]*time.Timer),
+		peerTickers:  make(map[uint32]Timer),
+		normalTimers: make(map[uint32]Timer),
 	}
 
 	for i := 0; i < int(EventMax); i++ {
-		timer.eventTimers[TimerEventType(i)] = make(map[uint32]*time.Timer)
+		timer.eventTimers[TimerEventType(i)] = make(map[uint32]Timer)
 	}
 
 	return timer
 }
 
-func stopAllTimers(timers map[uint32]*time.Timer) {
+func stopAllTimers(timers map[uint32]Timer) {
 	for _, t := range timers {
 		t.Stop()
 	}
@@ -112,12 +114,12 @@ func (self *EventTimer) stop() {
 	// clear timers by event timer
 	for i := 0; i < int(EventMax); i++ {
 		stopAllTimers(self.eventTimers[TimerEventType(i)])
-		self.eventTimers[TimerEventType(i)] = make(map[uint32]*time.Timer)
+		self.eventTimers[TimerEventType(i)] = make(map[uint32]Timer)
 	}
 
 	// clear normal timers
 	stopAllTimers(self.normalTimers)
-	self.normalTimers = make(map[uint32]*time.Timer)
+	self.normalTimers = make(map[uint32]Timer)
 }
 
 func (self *EventTimer) StartTimer(Idx uint32, timeout time.Duration) {
@@ -129,7 +131,7 @@ func (self *EventTimer) StartTimer(Idx uint32, timeout time.Duration) {
 		log.Infof("timer for %d got reset", Idx)
 	}
 
-	self.normalTimers[Idx] = time.AfterFunc(timeout, func() {
+	self.normalTimers[Idx] = self.clock.AfterFunc(timeout, func() {
 		// remove timer from map
 		self.lock.Lock()
 		defer self.lock.Unlock()
@@ -201,7 +203,7 @@ func (self *EventTimer) startEventTimer(evtType TimerEventType, blockNum uint32)
 		log.Errorf("invalid timeout for event %d, blkNum %d", evtType, blockNum)
 		return fmt.Errorf("invalid timeout for event %d, blkNum %d", evtType, blockNum)
 	}
-	timers[blockNum] = time.AfterFunc(timeout, func() {
+	timers[blockNum] = self.clock.AfterFunc(timeout, func() {
 		self.C <- &TimerEvent{
 			evtType:  evtType,
 			blockNum: blockNum,
@@ -358,7 +360,7 @@ func (self *EventTimer) startPeerTicker(peerIdx uint32) error {
 	}
 
 	timeout := self.getEventTimeout(EventPeerHeartbeat)
-	self.peerTickers[peerIdx] = time.AfterFunc(timeout, func() {
+	self.peerTickers[peerIdx] = self.clock.AfterFunc(timeout, func() {
 		self.C <- &TimerEvent{
 			evtType:  EventPeerHeartbeat,
 			blockNum: peerIdx,
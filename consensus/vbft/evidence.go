@@ -0,0 +1,244 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
+)
+
+const (
+	VBFT_EVIDENCE_FILE = "vbft.evidence"
+
+	EVIDENCE_PROPOSAL    = "proposal"
+	EVIDENCE_ENDORSEMENT = "endorsement"
+
+	// two hex encoded msgs in a json record
+	maxEvidenceRecordLen = 4*p2pcommon.MAX_MSG_LEN + 1024
+)
+
+// Evidence is a pair of conflicting msgs signed by the same consensus peer for
+// the same block num and role.
+type Evidence struct {
+	Type       string    `json:"type"`
+	BlockNum   uint32    `json:"block_num"`
+	PeerIndex  uint32    `json:"peer_index"`
+	PeerPubkey string    `json:"peer_pubkey"`
+	ForEmpty   bool      `json:"for_empty"`
+	Msgs       [2]string `json:"msgs"` // hex of the serialized signed msgs
+	Timestamp  int64     `json:"timestamp"`
+}
+
+type voteKey struct {
+	msgType  MsgType
+	blkNum   uint32
+	peerIdx  uint32
+	forEmpty bool
+	prevHash common.Uint256 // proposals upon different blocks are not conflicting
+}
+
+type peerVote struct {
+	blkHash  common.Uint256
+	payload  []byte
+	reported bool
+}
+
+// evidencePool keeps the first proposal and endorsement received from each peer
+// for the unsealed blocks, and records the evidence once a peer signs a
+// conflicting one.
+type evidencePool struct {
+	lock  sync.Mutex
+	path  string
+	votes map[voteKey]*peerVote
+}
+
+func EvidencePath() string {
+	return filepath.Join(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName, VBFT_EVIDENCE_FILE)
+}
+
+func newEvidencePool(path string) *evidencePool {
+	return &evidencePool{
+		path:  path,
+		votes: make(map[voteKey]*peerVote),
+	}
+}
+
+// getVoteKey returns the signer of msg and the block it voted for. Endorsements
+// relayed by other peers are skipped, only the endorser's own msg is counted.
+// A pipelined proposal is made again upon the block sealed if the previous
+// block it was made upon fails to be sealed, so proposals are keyed by their
+// previous block.
+func getVoteKey(peerIdx uint32, msg ConsensusMsg) (voteKey, common.Uint256, bool) {
+	switch m := msg.(type) {
+	case *blockProposalMsg:
+		if m.Block == nil || m.Block.Block == nil {
+			return voteKey{}, common.UINT256_EMPTY, false
+		}
+		key := voteKey{msgType: BlockProposalMessage, blkNum: m.GetBlockNum(), peerIdx: m.Block.getProposer(),
+			prevHash: m.Block.getPrevBlockHash()}
+		return key, m.Block.Block.Hash(), true
+	case *blockEndorseMsg:
+		if m.Endorser != peerIdx {
+			return voteKey{}, common.UINT256_EMPTY, false
+		}
+		key := voteKey{msgType: BlockEndorseMessage, blkNum: m.BlockNum, peerIdx: m.Endorser, forEmpty: m.EndorseForEmpty}
+		return key, m.EndorsedBlockHash, true
+	}
+	return voteKey{}, common.UINT256_EMPTY, false
+}
+
+// check returns the evidence if the verified msg from peer conflicts with the
+// one received before, the evidence of a vote is reported only once.
+func (self *evidencePool) check(peerIdx uint32, msg ConsensusMsg, getPubKey func(uint32) keypair.PublicKey) (*Evidence, error) {
+	if self == nil {
+		return nil, nil
+	}
+	key, blkHash, ok := getVoteKey(peerIdx, msg)
+	if !ok {
+		return nil, nil
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	prev := self.votes[key]
+	if prev != nil && (prev.blkHash == blkHash || prev.reported) {
+		return nil, nil
+	}
+	payload, err := SerializeVbftMsg(msg)
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil {
+		self.votes[key] = &peerVote{blkHash: blkHash, payload: payload}
+		return nil, nil
+	}
+
+	peerPk := getPubKey(key.peerIdx)
+	if peerPk == nil {
+		return nil, fmt.Errorf("failed to get peer %d pubkey", key.peerIdx)
+	}
+	evidence := &Evidence{
+		Type:       EVIDENCE_ENDORSEMENT,
+		BlockNum:   key.blkNum,
+		PeerIndex:  key.peerIdx,
+		PeerPubkey: vconfig.PubkeyID(peerPk),
+		ForEmpty:   key.forEmpty,
+		Msgs:       [2]string{hex.EncodeToString(prev.payload), hex.EncodeToString(payload)},
+		Timestamp:  time.Now().Unix(),
+	}
+	if key.msgType == BlockProposalMessage {
+		evidence.Type = EVIDENCE_PROPOSAL
+	}
+	if err := self.persist(evidence); err != nil {
+		return nil, err
+	}
+	prev.reported = true
+	return evidence, nil
+}
+
+func (self *evidencePool) persist(evidence *Evidence) error {
+	data, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(self.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// prune drops the votes before the block num
+func (self *evidencePool) prune(blkNum uint32) {
+	if self == nil {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for key := range self.votes {
+		if key.blkNum < blkNum {
+			delete(self.votes, key)
+		}
+	}
+}
+
+// onEquivocation records the evidence of the msg from peer
+func (self *Server) onEquivocation(peerIdx uint32, msg ConsensusMsg) {
+	evidence, err := self.evidence.check(peerIdx, msg, self.peerPool.GetPeerPubKey)
+	if err != nil {
+		log.Errorf("server %d failed to check equivocation of msg type %d from %d: %s", self.Index, msg.Type(), peerIdx, err)
+		return
+	}
+	if evidence == nil {
+		return
+	}
+	log.Warnf("server %d detected %s equivocation of peer %d for block %d, evidence saved",
+		self.Index, evidence.Type, evidence.PeerIndex, evidence.BlockNum)
+}
+
+// ReadEvidences loads the evidences recorded in the file, from the block num
+func ReadEvidences(path string, fromBlkNum uint32) ([]*Evidence, error) {
+	evidences := make([]*Evidence, 0)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return evidences, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxEvidenceRecordLen)
+	for scanner.Scan() {
+		evidence := &Evidence{}
+		if err := json.Unmarshal(scanner.Bytes(), evidence); err != nil {
+			// broken tail caused by a crash in writing
+			log.Warnf("vbft evidence: invalid record: %s", err)
+			continue
+		}
+		if evidence.BlockNum >= fromBlkNum {
+			evidences = append(evidences, evidence)
+		}
+	}
+	return evidences, scanner.Err()
+}
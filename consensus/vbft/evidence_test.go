@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvidencePool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vbft")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, VBFT_EVIDENCE_FILE)
+
+	acc := account.NewAccount("")
+	getPubKey := func(peerIdx uint32) keypair.PublicKey {
+		return acc.PublicKey
+	}
+	pool := newEvidencePool(path)
+
+	endorse, _ := newWalTestMsgs(10, 1)
+	ev, err := pool.check(1, endorse, getPubKey)
+	assert.Nil(t, err)
+	assert.Nil(t, ev)
+	// resent, or endorsement for empty block
+	ev, err = pool.check(1, endorse, getPubKey)
+	assert.Nil(t, err)
+	assert.Nil(t, ev)
+	emptyEndorse, _ := newWalTestMsgs(10, 2)
+	emptyEndorse.EndorseForEmpty = true
+	ev, err = pool.check(1, emptyEndorse, getPubKey)
+	assert.Nil(t, err)
+	assert.Nil(t, ev)
+
+	conflictEndorse, _ := newWalTestMsgs(10, 2)
+	// relayed by other peer
+	ev, err = pool.check(3, conflictEndorse, getPubKey)
+	assert.Nil(t, err)
+	assert.Nil(t, ev)
+
+	ev, err = pool.check(1, conflictEndorse, getPubKey)
+	assert.Nil(t, err)
+	assert.NotNil(t, ev)
+	assert.Equal(t, EVIDENCE_ENDORSEMENT, ev.Type)
+	assert.Equal(t, uint32(10), ev.BlockNum)
+	assert.Equal(t, uint32(1), ev.PeerIndex)
+	assert.Equal(t, vconfig.PubkeyID(acc.PublicKey), ev.PeerPubkey)
+	// reported only once
+	otherEndorse, _ := newWalTestMsgs(10, 3)
+	ev, err = pool.check(1, otherEndorse, getPubKey)
+	assert.Nil(t, err)
+	assert.Nil(t, ev)
+
+	evidences, err := ReadEvidences(path, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(evidences))
+	msg, err := SerializeVbftMsg(conflictEndorse)
+	assert.Nil(t, err)
+	assert.Equal(t, EVIDENCE_ENDORSEMENT, evidences[0].Type)
+	assert.Equal(t, hex.EncodeToString(msg), evidences[0].Msgs[1])
+	evidences, err = ReadEvidences(path, 11)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(evidences))
+
+	pool.prune(11)
+	assert.Equal(t, 0, len(pool.votes))
+}
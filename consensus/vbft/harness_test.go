@@ -0,0 +1,488 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology-eventbus/actor"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
+	msgpack "github.com/ontio/ontology/p2pserver/message/msg_pack"
+	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/p2pserver/mock"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	txpool "github.com/ontio/ontology/txnpool/common"
+)
+
+const (
+	simNodeNum = 7
+	// virtual time advanced in each step of simulation, and the real time
+	// waited for the servers to handle the fired events
+	simStep     = 50 * time.Millisecond
+	simStepWait = 2 * time.Millisecond
+)
+
+type simNode struct {
+	account *account.Account
+	p2pId   p2pcommon.PeerId
+	ledger  *ledger.Ledger
+	server  *Server
+}
+
+// simulation runs the vbft servers of a network in process, upon a message
+// level network with scripted faults and a simulated clock.
+type simulation struct {
+	t       *testing.T
+	dir     string
+	clock   *SimClock
+	network *mock.MsgNetwork
+	txpool  *actor.PID
+	nodes   []*simNode
+	genesis *config.GenesisConfig // replaced default genesis config
+
+	txs      bool   // the txpool serves a new transaction at every request
+	txNonce  uint32 // nonce of the last transaction served
+	pipeline bool   // servers are started with pipelined block execution
+}
+
+// simOption changes the simulation before the servers started.
+type simOption func(sim *simulation)
+
+func withTxs() simOption {
+	return func(sim *simulation) { sim.txs = true }
+}
+
+func withPipeline() simOption {
+	return func(sim *simulation) { sim.pipeline = true }
+}
+
+func newSimulation(t *testing.T, seed int64, opts ...simOption) *simulation {
+	log.InitLog(log.FatalLog, log.Stdout)
+	dir, err := ioutil.TempDir("", "vbft-sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := &simulation{
+		t:     t,
+		dir:   dir,
+		clock: NewSimClock(),
+	}
+	for _, opt := range opts {
+		opt(sim)
+	}
+	sim.network = mock.NewMsgNetwork(seed, func(d time.Duration, f func()) {
+		sim.clock.AfterFunc(d, f)
+	})
+	// without txs the servers get no transaction, and only empty blocks are proposed
+	payer := account.NewAccount("")
+	sim.txpool = actor.Spawn(simProps(nil, func(ctx actor.Context) {
+		switch ctx.Message().(type) {
+		case *txpool.GetTxnPoolReq:
+			rsp := &txpool.GetTxnPoolRsp{}
+			if sim.txs {
+				tx, err := sim.newTransaction(payer)
+				if err != nil {
+					t.Errorf("build transaction: %s", err)
+				} else {
+					rsp.TxnPool = append(rsp.TxnPool, &txpool.VerifiedTx{Tx: tx})
+				}
+			}
+			ctx.Respond(rsp)
+		case *txpool.VerifyBlockReq:
+			ctx.Respond(&txpool.VerifyBlockRsp{})
+		}
+	}))
+
+	var bookkeepers []keypair.PublicKey
+	vbftConfig := *config.PolarisConfig.VBFT
+	vbftConfig.Peers = nil
+	for i := 0; i < simNodeNum; i++ {
+		acc := account.NewAccount("")
+		sim.nodes = append(sim.nodes, &simNode{
+			account: acc,
+			p2pId:   p2pcommon.PseudoPeerIdFromUint64(uint64(i + 1)),
+		})
+		bookkeepers = append(bookkeepers, acc.PublicKey)
+		vbftConfig.Peers = append(vbftConfig.Peers, &config.VBFTPeerStakeInfo{
+			Index:      uint32(i + 1),
+			PeerPubkey: vconfig.PubkeyID(acc.PublicKey),
+			Address:    acc.Address.ToBase58(),
+			InitPos:    10000,
+		})
+	}
+	genesisConfig := &config.GenesisConfig{
+		ConsensusType: config.CONSENSUS_TYPE_VBFT,
+		VBFT:          &vbftConfig,
+		DBFT:          &config.DBFTConfig{},
+		SOLO:          &config.SOLOConfig{},
+	}
+	// the consensus payload of genesis block is built from the default config
+	sim.genesis, config.DefConfig.Genesis = config.DefConfig.Genesis, genesisConfig
+	block, err := genesis.BuildGenesisBlock(bookkeepers, genesisConfig)
+	if err != nil {
+		sim.close()
+		t.Fatalf("build genesis block: %s", err)
+	}
+
+	for i, node := range sim.nodes {
+		nodeDir := filepath.Join(dir, strconv.Itoa(i))
+		if node.ledger, err = ledger.InitLedger(filepath.Join(nodeDir, "ledger"), 0, bookkeepers, block); err != nil {
+			sim.close()
+			t.Fatalf("init ledger of node %d: %s", i, err)
+		}
+		node := node
+		p2p := sim.network.NewNode(node.p2pId, func(from p2pcommon.PeerId, msg p2pmsg.Message) {
+			if cons, ok := msg.(*p2pmsg.Consensus); ok {
+				payload := cons.Cons
+				payload.PeerId = from
+				node.server.pid.Tell(&payload)
+			}
+		})
+		node.server = newVbftServer(node.account, sim.txpool, p2p, node.ledger, sim.clock, nodeDir)
+		node.server.pipeline = sim.pipeline
+		node.server.pid = actor.Spawn(simProps(node.server.stop, node.server.Receive))
+		if err := node.server.initialize(); err != nil {
+			sim.close()
+			t.Fatalf("initialize server of node %d: %s", i, err)
+		}
+	}
+	for i, node := range sim.nodes {
+		if err := node.server.Start(); err != nil {
+			sim.close()
+			t.Fatalf("start server of node %d: %s", i, err)
+		}
+	}
+	// peers at the genesis block make no consensus on the committed block
+	// number, the servers are marked synced once they all start syncing
+	started := sim.run(time.Minute, func() bool {
+		for _, node := range sim.nodes {
+			if node.server.stateMgr.getState() < Syncing {
+				return false
+			}
+		}
+		return true
+	})
+	if !started {
+		sim.close()
+		t.Fatal("servers not start syncing")
+	}
+	for _, node := range sim.nodes {
+		node.server.stateMgr.StateEventC <- &StateEvent{Type: SyncDone}
+	}
+	return sim
+}
+
+// newTransaction builds a native transaction never served before, the
+// transactions are only accessed by the txpool actor.
+func (self *simulation) newTransaction(payer *account.Account) (*types.Transaction, error) {
+	self.txNonce++
+	mutable := utils.BuildNativeTransaction(nutils.OntContractAddress, "name", []byte{})
+	mutable.GasLimit = 20000
+	mutable.Nonce = self.txNonce
+	mutable.Payer = payer.Address
+	txHash := mutable.Hash()
+	sig, err := signature.Sign(payer, txHash.ToArray())
+	if err != nil {
+		return nil, err
+	}
+	mutable.Sigs = []types.Sig{{
+		PubKeys: []keypair.PublicKey{payer.PublicKey},
+		M:       1,
+		SigData: [][]byte{sig},
+	}}
+	return mutable.IntoImmutable()
+}
+
+// simStop stops the actor from inside, done is closed once the actor stopped.
+type simStop struct {
+	done chan struct{}
+}
+
+// simProps runs receive as the actor, on simStop it calls stop before the actor
+// stops, as Halt does for the server. The actor is stopped by itself, as stopping
+// it from another goroutine races with its mailbox.
+func simProps(stop func(), receive func(ctx actor.Context)) *actor.Props {
+	var done chan struct{}
+	return actor.FromFunc(func(ctx actor.Context) {
+		switch msg := ctx.Message().(type) {
+		case *simStop:
+			if stop != nil {
+				stop()
+			}
+			done = msg.done
+			ctx.Self().Stop()
+		case *actor.Stopped:
+			if done != nil {
+				close(done)
+			}
+		default:
+			receive(ctx)
+		}
+	})
+}
+
+// stopActor stops an actor spawned with simProps and waits for it
+func (self *simulation) stopActor(pid *actor.PID) bool {
+	stop := &simStop{done: make(chan struct{})}
+	pid.Tell(stop)
+	select {
+	case <-stop.done:
+		return true
+	case <-time.After(time.Minute):
+		return false
+	}
+}
+
+func (self *simulation) close() {
+	// no more msgs delivered once stopped advancing the clock
+	for _, node := range self.nodes {
+		self.network.SetDown(node.p2pId, true)
+	}
+	// the ledgers are closed after all the servers and their goroutines stopped
+	for i, node := range self.nodes {
+		if node.server == nil || node.server.pid == nil {
+			continue
+		}
+		if !self.stopActor(node.server.pid) {
+			self.t.Errorf("server of node %d not stopped", i)
+		}
+	}
+	for _, node := range self.nodes {
+		if node.ledger != nil {
+			_ = node.ledger.Close()
+		}
+	}
+	if !self.stopActor(self.txpool) {
+		self.t.Errorf("txpool not stopped")
+	}
+	if self.genesis != nil {
+		config.DefConfig.Genesis = self.genesis
+	}
+	os.RemoveAll(self.dir)
+}
+
+// run advances the clock until done returns true, or the virtual time elapsed
+func (self *simulation) run(timeout time.Duration, done func() bool) bool {
+	for elapsed := time.Duration(0); elapsed < timeout; elapsed += simStep {
+		if done() {
+			return true
+		}
+		self.clock.Advance(simStep)
+		time.Sleep(simStepWait)
+	}
+	return done()
+}
+
+func (self *simulation) height(i int) uint32 {
+	return self.nodes[i].ledger.GetCurrentBlockHeight()
+}
+
+// reached returns whether the ledgers of the nodes reach the height
+func (self *simulation) reached(height uint32, nodes ...int) bool {
+	for _, i := range nodes {
+		if self.height(i) < height {
+			return false
+		}
+	}
+	return true
+}
+
+// waitHeight runs until the ledgers of the nodes reach the height
+func (self *simulation) waitHeight(height uint32, timeout time.Duration, nodes ...int) {
+	ok := self.run(timeout, func() bool {
+		return self.reached(height, nodes...)
+	})
+	if !ok {
+		self.t.Fatalf("liveness: nodes %v not reach height %d in %s, heights %v", nodes, height, timeout, self.heights())
+	}
+}
+
+func (self *simulation) heights() []uint32 {
+	heights := make([]uint32, 0, len(self.nodes))
+	for i := range self.nodes {
+		heights = append(heights, self.height(i))
+	}
+	return heights
+}
+
+func (self *simulation) maxHeight() uint32 {
+	var max uint32
+	for _, height := range self.heights() {
+		if height > max {
+			max = height
+		}
+	}
+	return max
+}
+
+// checkSafety checks no two nodes saved different blocks at the same height
+func (self *simulation) checkSafety() {
+	if err := self.fork(); err != nil {
+		self.t.Fatalf("safety: %s", err)
+	}
+}
+
+func (self *simulation) fork() error {
+	for height := uint32(1); ; height++ {
+		var hash common.Uint256
+		found := -1
+		for i, node := range self.nodes {
+			if node.ledger.GetCurrentBlockHeight() < height {
+				continue
+			}
+			h := node.ledger.GetBlockHash(height)
+			if found < 0 {
+				hash, found = h, i
+			} else if h != hash {
+				return fmt.Errorf("block %d of node %d is %s, node %d is %s", height, found,
+					hash.ToHexString(), i, h.ToHexString())
+			}
+		}
+		if found < 0 {
+			return nil
+		}
+	}
+}
+
+func (self *simulation) peerIds(nodes ...int) []p2pcommon.PeerId {
+	ids := make([]p2pcommon.PeerId, 0, len(nodes))
+	for _, i := range nodes {
+		ids = append(ids, self.nodes[i].p2pId)
+	}
+	return ids
+}
+
+// proposer returns the node of the first proposer of the next block of node 0
+func (self *simulation) proposer() int {
+	server := self.nodes[0].server
+	blkNum := server.GetCurrentBlockNo()
+	block, _ := server.blockPool.getSealedBlock(blkNum - 1)
+	if block == nil {
+		self.t.Fatalf("sealed block %d not found", blkNum-1)
+	}
+	chainCfg := block.Info.NewChainConfig
+	if chainCfg == nil {
+		cfg := server.GetChainConfig()
+		chainCfg = &cfg
+	}
+	cfg, err := server.buildParticipantConfig(blkNum, block, chainCfg)
+	if err != nil {
+		self.t.Fatalf("build participant config of block %d: %s", blkNum, err)
+	}
+	for i, node := range self.nodes {
+		if node.server.Index == cfg.Proposers[0] {
+			return i
+		}
+	}
+	self.t.Fatalf("proposer %d of block %d not found", cfg.Proposers[0], blkNum)
+	return -1
+}
+
+// crash isolates the node from network, as if it stopped
+func (self *simulation) crash(i int) {
+	self.network.SetDown(self.nodes[i].p2pId, true)
+}
+
+func (self *simulation) recover(i int) {
+	self.network.SetDown(self.nodes[i].p2pId, false)
+}
+
+// equivocate makes node i send the proposals of a different block to the
+// victims besides the original ones, the block is signed by the node so it
+// passes the verification.
+func (self *simulation) equivocate(i int, victims ...int) {
+	byzantine := self.nodes[i]
+	isVictim := make(map[p2pcommon.PeerId]bool)
+	for _, id := range self.peerIds(victims...) {
+		isVictim[id] = true
+	}
+	conflicts := make(map[uint32]p2pmsg.Message)
+	self.network.SetFilter(func(from, to p2pcommon.PeerId, msg p2pmsg.Message) []p2pmsg.Message {
+		cons, ok := msg.(*p2pmsg.Consensus)
+		if from != byzantine.p2pId || !isVictim[to] || !ok {
+			return []p2pmsg.Message{msg}
+		}
+		m, err := DeserializeVbftMsg(cons.Cons.Data)
+		if err != nil || m.Type() != BlockProposalMessage {
+			return []p2pmsg.Message{msg}
+		}
+		blkNum := m.GetBlockNum()
+		conflict, present := conflicts[blkNum]
+		if !present {
+			if conflict, err = conflictProposal(byzantine.account, &cons.Cons, m.(*blockProposalMsg)); err != nil {
+				self.t.Errorf("build conflict proposal of block %d: %s", blkNum, err)
+				return []p2pmsg.Message{msg}
+			}
+			conflicts[blkNum] = conflict
+		}
+		return []p2pmsg.Message{conflict, msg}
+	})
+}
+
+func conflictProposal(acc *account.Account, orig *p2pmsg.ConsensusPayload, proposal *blockProposalMsg) (p2pmsg.Message, error) {
+	// decode the changed block again, since the block hash is cached
+	proposal.Block.Block.Header.ConsensusData++
+	blk, err := types.BlockFromRawBytes(common.SerializeToBytes(proposal.Block.Block))
+	if err != nil {
+		return nil, err
+	}
+	hash := blk.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	blk.Header.SigData = [][]byte{sig}
+	proposal.Block.Block = blk
+	data, err := SerializeVbftMsg(proposal)
+	if err != nil {
+		return nil, err
+	}
+	payload := &p2pmsg.ConsensusPayload{
+		Version:         orig.Version,
+		PrevHash:        orig.PrevHash,
+		Height:          orig.Height,
+		BookkeeperIndex: orig.BookkeeperIndex,
+		Timestamp:       orig.Timestamp,
+		Data:            data,
+		Owner:           acc.PublicKey,
+	}
+	sink := common.NewZeroCopySink(nil)
+	payload.SerializationUnsigned(sink)
+	if payload.Signature, err = signature.Sign(acc, sink.Bytes()); err != nil {
+		return nil, fmt.Errorf("sign payload: %s", err)
+	}
+	return msgpack.NewConsensus(payload), nil
+}
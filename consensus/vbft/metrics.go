@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// reasons of view changes, a round falls back from the proposal of the leader
+const (
+	viewChangeProposalTimeout = "proposal_timeout" // no proposal endorsed before proposal timeout
+	viewChangeEmptyEndorse    = "empty_endorse"    // endorsed for empty block on endorse timeout
+	viewChangeResync          = "resync"           // consensus stuck, restart syncing with peers
+)
+
+var (
+	roundMetric = prom.NewGauge(prom.GaugeOpts{
+		Name: "ontology_vbft_round",
+		Help: "ontology block number of current consensus round",
+	})
+
+	sealedBlocksMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_vbft_sealed_blocks_total",
+		Help: "ontology blocks sealed by consensus",
+	}, []string{"empty"})
+
+	viewChangesMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_vbft_view_changes_total",
+		Help: "ontology consensus rounds fallen back from the proposal of the leader",
+	}, []string{"reason"})
+
+	proposalLatencyMetric = prom.NewHistogram(prom.HistogramOpts{
+		Name: "ontology_vbft_proposal_latency_seconds",
+		Help: "ontology time from the start of a round to the first valid proposal",
+	})
+
+	roundDurationMetric = prom.NewHistogram(prom.HistogramOpts{
+		Name: "ontology_vbft_round_duration_seconds",
+		Help: "ontology time from the start of a round to the block sealed",
+	})
+
+	speculativeBlocksMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_vbft_speculative_blocks_total",
+		Help: "ontology blocks executed before sealed, reused or abandoned when a block is sealed at the height",
+	}, []string{"result"})
+
+	pipelinedProposalsMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_vbft_pipelined_proposals_total",
+		Help: "ontology proposals made upon the committed block before sealed, proposed or abandoned when the round starts",
+	}, []string{"result"})
+)
+
+func init() {
+	prom.MustRegister(roundMetric, sealedBlocksMetric, viewChangesMetric, proposalLatencyMetric, roundDurationMetric,
+		speculativeBlocksMetric, pipelinedProposalsMetric)
+}
+
+// roundTimer records the start time of current round to observe the proposal latency and round duration
+type roundTimer struct {
+	lock     sync.Mutex
+	blockNum uint32
+	start    time.Time
+	proposed bool
+}
+
+func (self *roundTimer) startRound(blockNum uint32) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.blockNum == blockNum && !self.start.IsZero() {
+		return
+	}
+	self.blockNum = blockNum
+	self.start = time.Now()
+	self.proposed = false
+	roundMetric.Set(float64(blockNum))
+}
+
+func (self *roundTimer) onProposal(blockNum uint32) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.blockNum != blockNum || self.start.IsZero() || self.proposed {
+		return
+	}
+	self.proposed = true
+	proposalLatencyMetric.Observe(time.Since(self.start).Seconds())
+}
+
+func (self *roundTimer) onSealed(blockNum uint32, empty bool) {
+	if empty {
+		sealedBlocksMetric.WithLabelValues("true").Inc()
+	} else {
+		sealedBlocksMetric.WithLabelValues("false").Inc()
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.blockNum != blockNum || self.start.IsZero() {
+		return
+	}
+	roundDurationMetric.Observe(time.Since(self.start).Seconds())
+	self.start = time.Time{}
+}
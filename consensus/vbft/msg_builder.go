@@ -21,13 +21,11 @@ package vbft
 import (
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
-	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/signature"
 	"github.com/ontio/ontology/core/types"
 )
@@ -184,19 +182,15 @@ func (self *Server) constructHeartbeatMsg() (*peerHeartbeatMsg, error) {
 	return msg, nil
 }
 
-func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, txs []*types.Transaction, consensusPayload []byte, blocktimestamp uint32) (*types.Block, error) {
+func (self *Server) constructBlock(lastBlock *Block, prevBlkHash common.Uint256, txs []*types.Transaction, consensusPayload []byte, blocktimestamp uint32) (*types.Block, error) {
+	blkNum := lastBlock.getBlockNum() + 1
 	txHash := []common.Uint256{}
 	for _, t := range txs {
 		txHash = append(txHash, t.Hash())
 	}
-	lastBlock, _ := self.blockPool.getSealedBlock(blkNum - 1)
-	if lastBlock == nil {
-		log.Errorf("constructBlock getlastblock failed blknum:%d", blkNum-1)
-		return nil, fmt.Errorf("constructBlock getlastblock failed blknum:%d", blkNum-1)
-	}
 
 	txRoot := common.ComputeMerkleRoot(txHash)
-	blockRoot := ledger.DefLedger.GetBlockRootWithNewTxRoots(lastBlock.Block.Header.Height, []common.Uint256{lastBlock.Block.Header.TransactionsRoot, txRoot})
+	blockRoot := self.ledger.GetBlockRootWithNewTxRoots(lastBlock.Block.Header.Height, []common.Uint256{lastBlock.Block.Header.TransactionsRoot, txRoot})
 
 	blkHeader := &types.Header{
 		PrevBlockHash:    prevBlkHash,
@@ -222,11 +216,7 @@ func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, tx
 	return blk, nil
 }
 
-func (self *Server) constructCrossChainMsg(blkNum uint32) (*types.CrossChainMsg, error) {
-	root, err := self.blockPool.getCrossStatesRoot(blkNum)
-	if err != nil {
-		return nil, err
-	}
+func (self *Server) constructCrossChainMsg(blkNum uint32, root common.Uint256) (*types.CrossChainMsg, error) {
 	log.Debugf("submitBlock height:%d statesroot:%+v", blkNum, root)
 	if root == common.UINT256_EMPTY {
 		return nil, nil
@@ -251,7 +241,24 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 	if prevBlk == nil {
 		return nil, fmt.Errorf("failed to get prevBlock (%d)", blkNum-1)
 	}
-	blocktimestamp := uint32(time.Now().Unix())
+	merkleRoot, err := self.blockPool.getExecMerkleRoot(blkNum - 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetExecMerkleRoot: %s,blkNum:%d", err, blkNum-1)
+	}
+	crossStatesRoot, err := self.blockPool.getCrossStatesRoot(blkNum - 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to crossChainMsgHash :%s,blkNum:%d", err, (blkNum - 1))
+	}
+	return self.constructProposalMsgUpon(prevBlk, prevBlkHash, merkleRoot, crossStatesRoot, sysTxs, userTxs, chainconfig)
+}
+
+// constructProposalMsgUpon constructs the proposal upon the previous block and its execution result,
+// the previous block is not necessarily sealed yet
+func (self *Server) constructProposalMsgUpon(prevBlk *Block, prevBlkHash, prevMerkleRoot, prevCrossStatesRoot common.Uint256,
+	sysTxs, userTxs []*types.Transaction, chainconfig *vconfig.ChainConfig) (*blockProposalMsg, error) {
+
+	blkNum := prevBlk.getBlockNum() + 1
+	blocktimestamp := uint32(self.clock.Now().Unix())
 	if prevBlk.Block.Header.Timestamp >= blocktimestamp {
 		blocktimestamp = prevBlk.Block.Header.Timestamp + 1
 	}
@@ -280,19 +287,15 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 		return nil, err
 	}
 
-	emptyBlk, err := self.constructBlock(blkNum, prevBlkHash, sysTxs, consensusPayload, blocktimestamp)
+	emptyBlk, err := self.constructBlock(prevBlk, prevBlkHash, sysTxs, consensusPayload, blocktimestamp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct empty block: %s", err)
 	}
-	blk, err := self.constructBlock(blkNum, prevBlkHash, append(sysTxs, userTxs...), consensusPayload, blocktimestamp)
+	blk, err := self.constructBlock(prevBlk, prevBlkHash, append(sysTxs, userTxs...), consensusPayload, blocktimestamp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to constuct blk: %s", err)
 	}
-	merkleRoot, err := self.blockPool.getExecMerkleRoot(blkNum - 1)
-	if err != nil {
-		return nil, fmt.Errorf("failed to GetExecMerkleRoot: %s,blkNum:%d", err, blkNum-1)
-	}
-	crossChainMsg, err := self.constructCrossChainMsg(blkNum - 1)
+	crossChainMsg, err := self.constructCrossChainMsg(blkNum-1, prevCrossStatesRoot)
 	if err != nil {
 		return nil, fmt.Errorf("failed to crossChainMsgHash :%s,blkNum:%d", err, (blkNum - 1))
 	}
@@ -301,7 +304,7 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 			Block:              blk,
 			EmptyBlock:         emptyBlk,
 			Info:               vbftBlkInfo,
-			PrevExecMerkleRoot: merkleRoot,
+			PrevExecMerkleRoot: prevMerkleRoot,
 			CrossChainMsg:      crossChainMsg,
 		},
 	}
@@ -26,7 +26,6 @@ import (
 
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
-	"github.com/ontio/ontology/core/ledger"
 )
 
 type SyncCheckReq struct {
@@ -109,9 +108,6 @@ func (self *Syncer) stop() {
 }
 
 func (self *Syncer) run() {
-	self.server.quitWg.Add(1)
-	defer self.server.quitWg.Done()
-
 	for {
 		select {
 		case <-self.syncCheckReqC:
@@ -176,7 +172,7 @@ func (self *Syncer) run() {
 			for self.nextReqBlkNum <= self.targetBlkNum {
 				// FIXME: compete with ledger syncing
 				var blk *Block
-				if self.nextReqBlkNum <= ledger.DefLedger.GetCurrentBlockHeight() {
+				if self.nextReqBlkNum <= self.server.ledger.GetCurrentBlockHeight() {
 					blk, _ = self.server.blockPool.getSealedBlock(self.nextReqBlkNum)
 				}
 				if blk == nil {
@@ -225,7 +221,7 @@ func (self *Syncer) run() {
 
 				// reset to default
 				self.nextReqBlkNum = 1
-				self.targetBlkNum = 0
+				atomic.StoreUint32(&self.targetBlkNum, 0)
 			}
 
 		case <-self.server.quitC:
@@ -257,7 +253,7 @@ func (self *Syncer) blockConsensusDone(blks BlockFromPeers) *Block {
 }
 
 func (self *Syncer) getCurrentTargetBlockNum() uint32 {
-	return self.targetBlkNum
+	return atomic.LoadUint32(&self.targetBlkNum)
 }
 
 func (self *Syncer) blockCheckMerkleRoot(blks BlockFromPeers) *Block {
@@ -311,7 +307,7 @@ func (self *Syncer) onNewBlockSyncReq(req *BlockSyncReq) {
 	if self.nextReqBlkNum == 1 {
 		self.nextReqBlkNum = req.startBlockNum
 	}
-	self.targetBlkNum = req.targetBlockNum
+	atomic.StoreUint32(&self.targetBlkNum, req.targetBlockNum)
 	// }
 
 	for _, peerIdx := range req.targetPeers {
@@ -417,7 +413,10 @@ func (self *PeerSyncer) requestBlock(blkNum uint32) (*Block, error) {
 		Msg:    msg,
 	}
 
-	t := time.NewTimer(time.Duration(atomic.LoadInt64(&makeProposalTimeout) * 2))
+	timeoutC := make(chan struct{})
+	t := self.server.clock.AfterFunc(time.Duration(atomic.LoadInt64(&makeProposalTimeout)*2), func() {
+		close(timeoutC)
+	})
 	defer t.Stop()
 
 	select {
@@ -433,7 +432,7 @@ func (self *PeerSyncer) requestBlock(blkNum uint32) (*Block, error) {
 			}
 			return pMsg.BlockData, nil
 		}
-	case <-t.C:
+	case <-timeoutC:
 		return nil, fmt.Errorf("timeout fetch block %d from peer %d", blkNum, self.peerIdx)
 	case <-self.server.quitC:
 		return nil, fmt.Errorf("peer syncing %d quit, failed fetching Block %d", self.peerIdx, blkNum)
@@ -448,7 +447,10 @@ func (self *PeerSyncer) requestBlockInfo(startBlkNum uint32) ([]*BlockInfo_, err
 		Msg:    msg,
 	}
 
-	t := time.NewTimer(time.Duration(atomic.LoadInt64(&makeProposalTimeout) * 2))
+	timeoutC := make(chan struct{})
+	t := self.server.clock.AfterFunc(time.Duration(atomic.LoadInt64(&makeProposalTimeout)*2), func() {
+		close(timeoutC)
+	})
 	defer t.Stop()
 
 	select {
@@ -464,7 +466,7 @@ func (self *PeerSyncer) requestBlockInfo(startBlkNum uint32) ([]*BlockInfo_, err
 			}
 			return pMsg.Blocks, nil
 		}
-	case <-t.C:
+	case <-timeoutC:
 		return nil, fmt.Errorf("timeout fetch blockInfo %d from peer %d", startBlkNum, self.peerIdx)
 	case <-self.server.quitC:
 		return nil, fmt.Errorf("peer syncer %d - %d quit, failed fetching BlockInfo %d",
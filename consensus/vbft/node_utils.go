@@ -367,12 +367,13 @@ func calcParticipant(vrf vconfig.VRFValue, dposTable []uint32, k uint32) uint32
 // check if commit msgs has reached consensus
 // return
 //		@ consensused proposer
+//		@ consensused block hash
 //		@ consensused for empty commit
 //
-func getCommitConsensus(commitMsgs []*blockCommitMsg, C int, N int) (uint32, bool) {
+func getCommitConsensus(commitMsgs []*blockCommitMsg, C int, N int) (uint32, common.Uint256, bool) {
 	emptyCommitCount := 0
 	emptyCommit := false
-	signCount := make(map[uint32]map[uint32]int)
+	signCount := make(map[common.Uint256]map[uint32]int)
 	for _, c := range commitMsgs {
 		if c.CommitForEmpty {
 			emptyCommitCount++
@@ -381,31 +382,42 @@ func getCommitConsensus(commitMsgs []*blockCommitMsg, C int, N int) (uint32, boo
 				emptyCommit = true
 			}
 		}
-		if _, present := signCount[c.BlockProposer]; !present {
-			signCount[c.BlockProposer] = make(map[uint32]int)
+		if _, present := signCount[c.CommitBlockHash]; !present {
+			signCount[c.CommitBlockHash] = make(map[uint32]int)
 		}
-		signCount[c.BlockProposer][c.Committer] += 1
+		signCount[c.CommitBlockHash][c.Committer] += 1
 		for endorser := range c.EndorsersSig {
-			signCount[c.BlockProposer][endorser] += 1
+			signCount[c.CommitBlockHash][endorser] += 1
 		}
-		if len(signCount[c.BlockProposer])+1 >= N-(N-1)/3 {
-			return c.BlockProposer, emptyCommit
+		if len(signCount[c.CommitBlockHash])+1 >= N-(N-1)/3 {
+			return c.BlockProposer, c.CommitBlockHash, emptyCommit
 		}
 	}
 
-	return math.MaxUint32, false
+	return math.MaxUint32, common.Uint256{}, false
 }
 
-func (self *Server) findBlockProposal(blkNum uint32, proposer uint32, forEmpty bool) *blockProposalMsg {
+// proposedBlockHash returns the hash of the block, or the empty block of proposal
+func proposedBlockHash(proposal *blockProposalMsg, forEmpty bool) common.Uint256 {
+	if !forEmpty {
+		return proposal.Block.Block.Hash()
+	}
+	if proposal.Block.EmptyBlock == nil {
+		return common.Uint256{}
+	}
+	return proposal.Block.EmptyBlock.Hash()
+}
+
+func (self *Server) findBlockProposal(blkNum uint32, proposer uint32, blkHash common.Uint256, forEmpty bool) *blockProposalMsg {
 	for _, p := range self.blockPool.getBlockProposals(blkNum) {
-		if p.Block.getProposer() == proposer {
+		if p.Block.getProposer() == proposer && proposedBlockHash(p, forEmpty) == blkHash {
 			return p
 		}
 	}
 
 	for _, p := range self.msgPool.GetProposalMsgs(blkNum) {
 		if pMsg := p.(*blockProposalMsg); pMsg != nil {
-			if pMsg.Block.getProposer() == proposer {
+			if pMsg.Block.getProposer() == proposer && proposedBlockHash(pMsg, forEmpty) == blkHash {
 				return pMsg
 			}
 		}
@@ -22,6 +22,7 @@ import (
 	"crypto/rand"
 	"crypto/sha512"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/ontio/ontology/common"
@@ -69,6 +70,7 @@ func constructServer() *Server {
 		config:                   chainconfig,
 		chainStore:               chainstore,
 		currentParticipantConfig: blockparticipantconfig,
+		clock:                    realClock{},
 	}
 	return server
 }
@@ -149,10 +151,42 @@ func TestGetCommitConsensus(t *testing.T) {
 	}
 	var commitMsgs []*blockCommitMsg
 	commitMsgs = append(commitMsgs, blockcommitmsg)
-	blockproposer, flag := getCommitConsensus(commitMsgs, 2, 7)
+	blockproposer, _, flag := getCommitConsensus(commitMsgs, 2, 7)
 	t.Logf("TestGetCommitConsensus %d ,%v", blockproposer, flag)
 }
 
+func TestGetCommitConsensusEquivocation(t *testing.T) {
+	// the commits of an equivocating proposer are split over its two blocks
+	hash1, hash2 := common.Uint256{1}, common.Uint256{2}
+	var commitMsgs []*blockCommitMsg
+	for i := uint32(2); i <= 7; i++ {
+		hash := hash1
+		if i > 4 {
+			hash = hash2
+		}
+		commitMsgs = append(commitMsgs, &blockCommitMsg{
+			Committer:       i,
+			BlockProposer:   1,
+			BlockNum:        1,
+			CommitBlockHash: hash,
+		})
+	}
+	if proposer, _, _ := getCommitConsensus(commitMsgs, 2, 7); proposer != math.MaxUint32 {
+		t.Fatalf("consensus on split commits of proposer %d", proposer)
+	}
+
+	commitMsgs = append(commitMsgs, &blockCommitMsg{
+		Committer:       1,
+		BlockProposer:   1,
+		BlockNum:        1,
+		CommitBlockHash: hash2,
+	})
+	proposer, hash, _ := getCommitConsensus(commitMsgs, 2, 7)
+	if proposer != 1 || hash != hash2 {
+		t.Fatalf("consensus of proposer %d, block %s", proposer, hash.ToHexString())
+	}
+}
+
 func newTestVrfValue() vconfig.VRFValue {
 	v := make([]byte, 1024)
 	rand.Read(v[:])
@@ -116,10 +116,11 @@ func (pool *PeerPool) getActivePeerCount() int {
 	return n
 }
 
-func (pool *PeerPool) waitPeerConnected(peerIdx uint32) {
+// waitPeerConnected returns false if the server quit before the peer connected
+func (pool *PeerPool) waitPeerConnected(peerIdx uint32) bool {
 	if !pool.isNewPeer(peerIdx) {
 		// peer already connected
-		return
+		return true
 	}
 
 	var C chan struct{}
@@ -132,7 +133,12 @@ func (pool *PeerPool) waitPeerConnected(peerIdx uint32) {
 	}
 	pool.lock.Unlock()
 
-	<-C
+	select {
+	case <-C:
+		return true
+	case <-pool.server.quitC:
+		return false
+	}
 }
 
 func (pool *PeerPool) peerConnected(peerIdx uint32) {
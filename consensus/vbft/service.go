@@ -22,6 +22,7 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"github.com/ontio/ontology-eventbus/actor"
 	"github.com/ontio/ontology/account"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	actorTypes "github.com/ontio/ontology/consensus/actor"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
@@ -58,6 +60,7 @@ const (
 	FastForward // for syncer catch up
 	ReBroadcast
 	SubmitBlock
+	PipelineProposal
 )
 
 const (
@@ -95,9 +98,13 @@ type Server struct {
 	ledger        *ledger.Ledger
 	incrValidator *increment.IncrementValidator
 	pid           *actor.PID
+	clock         Clock
+	dataDir       string // consensus wal and evidences
 
 	// some config
 	msgHistoryDuration uint32
+	pipeline           bool       // execute committed block and make next proposal before sealed
+	proposalLock       sync.Mutex // one proposal from the server for a block
 
 	//
 	// Note:
@@ -119,6 +126,9 @@ type Server struct {
 	syncer     *Syncer
 	stateMgr   *StateMgr
 	timer      *EventTimer
+	roundTimer roundTimer
+	wal        *consensusWal // signed endorsements and commitments, synced before sending
+	evidence   *evidencePool // conflicting proposals and endorsements of peers
 
 	msgRecvC   *sync.Map // map[uint32]chan *p2pMsgPayload
 	msgC       chan ConsensusMsg
@@ -126,26 +136,15 @@ type Server struct {
 	msgSendC   chan *SendMsgEvent
 	sub        *events.ActorSubscriber
 	quitC      chan struct{}
-	quit       bool
-	quitWg     sync.WaitGroup
+	quitWg     sync.WaitGroup // goroutines started by spawn
 }
 
 func NewVbftServer(account *account.Account, txpool *actor.PID, p2p p2p.P2P) (*Server, error) {
-	server := &Server{
-		msgHistoryDuration: 64,
-		account:            account,
-		poolActor:          &actorTypes.TxPoolActor{Pool: txpool},
-		p2p:                p2p,
-		ledger:             ledger.DefLedger,
-		incrValidator:      increment.NewIncrementValidator(20),
-	}
-	server.stateMgr = newStateMgr(server)
+	dataDir := filepath.Join(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	server := newVbftServer(account, txpool, p2p, ledger.DefLedger, SystemClock, dataDir)
+	server.pipeline = config.DefConfig.Consensus.EnablePipeline
 
-	props := actor.FromProducer(func() actor.Actor {
-		return server
-	})
-
-	pid, err := actor.SpawnNamed(props, "consensus_vbft")
+	pid, err := actor.SpawnNamed(server.props(), "consensus_vbft")
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +157,30 @@ func NewVbftServer(account *account.Account, txpool *actor.PID, p2p p2p.P2P) (*S
 	return server, nil
 }
 
+// newVbftServer creates the server on the ledger, the actor of server should
+// be spawned before initialized.
+func newVbftServer(account *account.Account, txpool *actor.PID, p2p p2p.P2P, ldg *ledger.Ledger,
+	clock Clock, dataDir string) *Server {
+	server := &Server{
+		msgHistoryDuration: 64,
+		account:            account,
+		poolActor:          &actorTypes.TxPoolActor{Pool: txpool},
+		p2p:                p2p,
+		ledger:             ldg,
+		incrValidator:      increment.NewIncrementValidator(20),
+		clock:              clock,
+		dataDir:            dataDir,
+	}
+	server.stateMgr = newStateMgr(server)
+	return server
+}
+
+func (self *Server) props() *actor.Props {
+	return actor.FromProducer(func() actor.Actor {
+		return self
+	})
+}
+
 func (self *Server) Receive(context actor.Context) {
 	switch msg := context.Message().(type) {
 	case *actor.Restarting:
@@ -391,12 +414,12 @@ func (self *Server) updateChainConfig() error {
 			}
 			peerIdx := p.Index
 			self.CreatePeerMsgChan(peerIdx)
-			go func() {
+			self.spawn(func() {
 				if err := self.run(publickey); err != nil {
 					log.Errorf("server %d, processor on peer %d failed: %s",
 						self.Index, peerIdx, err)
 				}
-			}()
+			})
 			log.Infof("updateChainConfig add peer index:%v,id:%v", p.ID, p.Index)
 		}
 	}
@@ -479,25 +502,35 @@ func (self *Server) initialize() error {
 	} else {
 		self.Index = math.MaxUint32
 	}
-	self.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
-	go self.syncer.run()
-	go self.stateMgr.run()
-	go self.msgSendLoop()
-	go self.timerLoop()
-	go self.actionLoop()
-	self.quitWg.Add(1)
-	go func() {
-		defer self.quitWg.Done()
 
+	if self.wal, err = openConsensusWal(filepath.Join(self.dataDir, VBFT_WAL_FILE)); err != nil {
+		return fmt.Errorf("failed to open consensus wal: %s", err)
+	}
+	self.replayWal()
+	self.evidence = newEvidencePool(filepath.Join(self.dataDir, VBFT_EVIDENCE_FILE))
+
+	// the blocks executed by chain store are notified to server directly, the
+	// subscription is for the blocks saved by ledger syncing
+	if self.sub != nil {
+		self.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	}
+	self.spawn(self.syncer.run)
+	self.spawn(self.stateMgr.run)
+	self.spawn(self.msgSendLoop)
+	self.spawn(self.timerLoop)
+	self.spawn(self.actionLoop)
+	self.spawn(func() {
 		for {
 			if err := self.processMsgEvent(); err != nil {
 				log.Errorf("server %d: %s", self.Index, err)
 			}
-			if self.quit {
-				break
+			select {
+			case <-self.quitC:
+				return
+			default:
 			}
 		}
-	}()
+	})
 
 	self.stateMgr.StateEventC <- &StateEvent{
 		Type: ConfigLoaded,
@@ -510,6 +543,31 @@ func (self *Server) initialize() error {
 	return nil
 }
 
+// restores the endorsements and commitments signed before restart, which are
+// rebroadcasted in the current round.
+func (self *Server) replayWal() {
+	blkNum := self.GetCurrentBlockNo()
+	if err := self.wal.truncate(blkNum); err != nil {
+		log.Errorf("server %d failed to truncate consensus wal: %s", self.Index, err)
+	}
+	for _, msg := range self.wal.msgs(blkNum) {
+		h, _ := HashMsg(msg)
+		if err := self.msgPool.AddMsg(msg, h); err != nil {
+			log.Errorf("server %d failed to replay msg of block %d: %s", self.Index, msg.GetBlockNum(), err)
+			continue
+		}
+		switch m := msg.(type) {
+		case *blockEndorseMsg:
+			self.blockPool.newBlockEndorsement(m)
+		case *blockCommitMsg:
+			if err := self.blockPool.newBlockCommitment(m); err != nil {
+				log.Errorf("server %d failed to replay commit of block %d: %s", self.Index, m.GetBlockNum(), err)
+			}
+		}
+		log.Infof("server %d replayed msg type %d of block %d from consensus wal", self.Index, msg.Type(), msg.GetBlockNum())
+	}
+}
+
 func (self *Server) start() error {
 	// check if server pubkey support VRF
 	if !vrf.ValidatePrivateKey(self.account.PrivateKey) || !vrf.ValidatePublicKey(self.account.PublicKey) {
@@ -525,23 +583,33 @@ func (self *Server) start() error {
 		pk := self.peerPool.GetPeerPubKey(peerIdx)
 		self.CreatePeerMsgChan(peerIdx)
 
-		go func() {
+		self.spawn(func() {
 			if err := self.run(pk); err != nil {
 				log.Errorf("server %d, processor on peer %d failed: %s",
 					self.Index, peerIdx, err)
 			}
-		}()
+		})
 	}
 
 	return nil
 }
 
+// spawn runs f in a goroutine, which stop waits for after quitC closed
+func (self *Server) spawn(f func()) {
+	self.quitWg.Add(1)
+	go func() {
+		defer self.quitWg.Done()
+		f()
+	}()
+}
+
 func (self *Server) stop() {
 
 	self.incrValidator.Clean()
-	self.sub.Unsubscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
-	// stop syncer, statemgr, msgSendLoop, timer, actionLoop, msgProcessingLoop
-	self.quit = true
+	if self.sub != nil {
+		self.sub.Unsubscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	}
+	// stop syncer, statemgr, msgSendLoop, timer, actionLoop, msgProcessingLoop and peer processors
 	close(self.quitC)
 	self.quitWg.Wait()
 
@@ -551,6 +619,9 @@ func (self *Server) stop() {
 	self.blockPool.clean()
 	self.chainStore.close()
 	self.peerPool.clean()
+	if err := self.wal.close(); err != nil {
+		log.Errorf("server %d failed to close consensus wal: %s", self.Index, err)
+	}
 }
 
 //
@@ -567,7 +638,9 @@ func (self *Server) run(peerPubKey keypair.PublicKey) error {
 	self.heartbeat()
 
 	// wait remote msgs
-	self.peerPool.waitPeerConnected(peerIdx)
+	if !self.peerPool.waitPeerConnected(peerIdx) {
+		return nil
+	}
 
 	defer func() {
 		// TODO: handle peer disconnection here
@@ -575,12 +648,15 @@ func (self *Server) run(peerPubKey keypair.PublicKey) error {
 		self.ClosePeerMsgChan(peerIdx)
 
 		self.peerPool.peerDisconnected(peerIdx)
-		self.stateMgr.StateEventC <- &StateEvent{
+		select {
+		case self.stateMgr.StateEventC <- &StateEvent{
 			Type: UpdatePeerState,
 			peerState: &PeerState{
 				peerIdx:   peerIdx,
 				connected: false,
 			},
+		}:
+		case <-self.quitC:
 		}
 	}()
 
@@ -659,6 +735,7 @@ func (self *Server) updateParticipantConfig() error {
 
 func (self *Server) startNewRound() error {
 	blkNum := self.GetCurrentBlockNo()
+	self.roundTimer.startRound(blkNum)
 
 	if err := self.updateParticipantConfig(); err != nil {
 		log.Errorf("startNewRound error:%s", err)
@@ -667,11 +744,23 @@ func (self *Server) startNewRound() error {
 	// check proposals in msgpool
 	var proposal *blockProposalMsg
 	if proposals := self.msgPool.GetProposalMsgs(blkNum); len(proposals) > 0 {
+		_, prevBlkHash := self.blockPool.getSealedBlock(blkNum - 1)
 		for _, p := range proposals {
 			msg := p.(*blockProposalMsg)
 			if msg == nil {
 				continue
 			}
+			// pipelined proposals upon the block not sealed
+			if msg.Block.getPrevBlockHash() != prevBlkHash {
+				if msg.Block.getProposer() == self.Index {
+					pipelinedProposalsMetric.WithLabelValues("abandoned").Inc()
+				}
+				self.msgPool.DropMsg(msg)
+				continue
+			}
+			if msg.Block.getProposer() == self.Index {
+				pipelinedProposalsMetric.WithLabelValues("proposed").Inc()
+			}
 			if self.isProposer(blkNum, msg.Block.getProposer()) {
 				// get proposal from proposer, process it
 				proposal = msg
@@ -711,14 +800,14 @@ func (self *Server) startNewRound() error {
 	}
 
 	chainCfg := self.GetChainConfig()
-	if _, _, done := self.blockPool.commitDone(blkNum, chainCfg.C, chainCfg.N); done && len(commits) > 0 {
+	if _, _, _, done := self.blockPool.commitDone(blkNum, chainCfg.C, chainCfg.N); done && len(commits) > 0 {
 		// resend commit msg to msg-processor to restart commit-done processing
 		// Note: commitDone will set Done flag in block-pool, so removed Done flag checking
 		// in commit msg processing.
 		self.blockPool.setCommitDone(blkNum)
 		self.processConsensusMsg(commits[0])
 		return nil
-	} else if _, _, done := self.blockPool.endorseDone(blkNum, chainCfg.C); done && len(endorses) > 0 {
+	} else if _, _, _, done := self.blockPool.endorseDone(blkNum, chainCfg.C); done && len(endorses) > 0 {
 		// resend endorse msg to msg-processor to restart endorse-done processing
 		self.processConsensusMsg(endorses[0])
 		return nil
@@ -769,6 +858,9 @@ func (self *Server) onConsensusMsg(peerIdx uint32, msg ConsensusMsg, msgHash com
 		log.Debugf("dup msg with msg type %d from %d", msg.Type(), peerIdx)
 		return
 	}
+	if msg.Type() == BlockProposalMessage || msg.Type() == BlockEndorseMessage {
+		self.onEquivocation(peerIdx, msg)
+	}
 
 	switch msg.Type() {
 	case BlockProposalMessage:
@@ -1144,7 +1236,7 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 
 	prevBlockTimestamp := blk.Block.Header.Timestamp
 	currentBlockTimestamp := msg.Block.Block.Header.Timestamp
-	if currentBlockTimestamp <= prevBlockTimestamp || currentBlockTimestamp > uint32(time.Now().Add(time.Minute*10).Unix()) {
+	if currentBlockTimestamp <= prevBlockTimestamp || currentBlockTimestamp > uint32(self.clock.Now().Add(time.Minute*10).Unix()) {
 		log.Errorf("BlockPrposalMessage check  blocknum:%d,prevBlockTimestamp:%d,currentBlockTimestamp:%d", msg.GetBlockNum(), prevBlockTimestamp, currentBlockTimestamp)
 		self.msgPool.DropMsg(msg)
 		return
@@ -1168,6 +1260,7 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 		log.Errorf("verify cross chain message error:%+v\n", msg.Block.CrossChainMsg)
 		return
 	}
+	self.roundTimer.onProposal(msgBlkNum)
 	txs := msg.Block.Block.Transactions
 	if len(txs) > 0 && self.nonSystxs(txs, msgBlkNum) {
 		height := msgBlkNum - 1
@@ -1186,6 +1279,7 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 		}
 		// start new routine to verify txs in proposal block
 		go func() {
+			start := time.Now()
 			if err := self.poolActor.VerifyBlock(txs, validHeight); err != nil && err != actor.ErrTimeout {
 				log.Errorf("server %d verify proposal blk from %d failed, blk %d, txs %d, err: %s",
 					self.Index, msg.Block.getProposer(), msgBlkNum, len(txs), err)
@@ -1202,6 +1296,7 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 					return
 				}
 			}
+			traceBlockTxs(msg.Block, "vbft.VerifyProposal", start)
 			self.processConsensusMsg(msg)
 		}()
 	} else {
@@ -1308,12 +1403,12 @@ func (self *Server) processMsgEvent() error {
 					//                      start WaitEndorsementTimer
 
 					// TODO: should only count endorsements from endorsers
-					if proposer, forEmpty, done := self.blockPool.endorseDone(msgBlkNum, self.GetChainConfig().C); done {
+					if proposer, blkHash, forEmpty, done := self.blockPool.endorseDone(msgBlkNum, self.GetChainConfig().C); done {
 						// stop endorse timer
 						self.timer.CancelEndorseMsgTimer(msgBlkNum)
 						// stop empty endorse timer
 						self.timer.CancelEndorseEmptyBlockTimer(msgBlkNum)
-						proposal := self.findBlockProposal(msgBlkNum, proposer, forEmpty)
+						proposal := self.findBlockProposal(msgBlkNum, proposer, blkHash, forEmpty)
 						if proposal == nil {
 							log.Infof("server %d endorse %d done, waiting proposal from %d", self.Index, msgBlkNum, proposer)
 						} else if self.isCommitter(msgBlkNum, self.Index) {
@@ -1361,9 +1456,9 @@ func (self *Server) processMsgEvent() error {
 					self.Index, pMsg.Committer, pMsg.BlockProposer, msgBlkNum, pMsg.CommitForEmpty)
 
 				chainCfg := self.GetChainConfig()
-				if proposer, forEmpty, done := self.blockPool.commitDone(msgBlkNum, chainCfg.C, chainCfg.N); done {
+				if proposer, blkHash, forEmpty, done := self.blockPool.commitDone(msgBlkNum, chainCfg.C, chainCfg.N); done {
 					self.blockPool.setCommitDone(msgBlkNum)
-					proposal := self.findBlockProposal(msgBlkNum, proposer, forEmpty)
+					proposal := self.findBlockProposal(msgBlkNum, proposer, blkHash, forEmpty)
 					if proposal == nil {
 						// TODO: commit done, but we not have the proposal, should request proposal from neighbours
 						//       commitTimeout handle this
@@ -1403,9 +1498,6 @@ func (self *Server) processMsgEvent() error {
 }
 
 func (self *Server) actionLoop() {
-	self.quitWg.Add(1)
-	defer self.quitWg.Done()
-
 	for {
 		select {
 		case action := <-self.bftActionC:
@@ -1502,7 +1594,7 @@ func (self *Server) actionLoop() {
 					}
 
 					// check if consensused
-					proposer, forEmpty := getCommitConsensus(commitMsgs, int(chainCfg.C), int(chainCfg.N))
+					proposer, blkHash, forEmpty := getCommitConsensus(commitMsgs, int(chainCfg.C), int(chainCfg.N))
 					if proposer == math.MaxUint32 {
 						if err := self.catchConsensus(blkNum); err != nil {
 							log.Infof("server %d fastforward done, catch consensus: %s", self.Index, err)
@@ -1518,7 +1610,7 @@ func (self *Server) actionLoop() {
 						if !ok {
 							continue
 						}
-						if p.Block.getProposer() == proposer {
+						if p.Block.getProposer() == proposer && proposedBlockHash(p, forEmpty) == blkHash {
 							proposal = p
 							break
 						}
@@ -1592,7 +1684,11 @@ func (self *Server) actionLoop() {
 				} else if proposal, forEmpty := self.blockPool.getEndorsedProposal(blkNum); proposal != nil {
 					// construct endorse msg
 					if endorseMsg, _ := self.constructEndorseMsg(proposal, forEmpty); endorseMsg != nil {
-						self.broadcast(endorseMsg)
+						if err := self.wal.append(endorseMsg); err != nil {
+							log.Errorf("server %d rebroadcasting endorse (%d): %s", self.Index, blkNum, err)
+						} else {
+							self.broadcast(endorseMsg)
+						}
 					}
 				}
 				if self.isCommitter(blkNum, self.Index) {
@@ -1608,8 +1704,8 @@ func (self *Server) actionLoop() {
 						}
 					}
 					if !committed {
-						if proposer, forEmpty, done := self.blockPool.endorseDone(blkNum, self.GetChainConfig().C); done {
-							proposal := self.findBlockProposal(blkNum, proposer, forEmpty)
+						if proposer, blkHash, forEmpty, done := self.blockPool.endorseDone(blkNum, self.GetChainConfig().C); done {
+							proposal := self.findBlockProposal(blkNum, proposer, blkHash, forEmpty)
 
 							// consensus ok, make endorsement
 							if proposal == nil {
@@ -1647,6 +1743,8 @@ func (self *Server) actionLoop() {
 						log.Errorf("SubmitBlock err:%s", err)
 					}
 				}
+			case PipelineProposal:
+				self.pipelineProposal(action.Proposal)
 			}
 		case <-self.quitC:
 			log.Infof("server %d actionLoop quit", self.Index)
@@ -1656,9 +1754,6 @@ func (self *Server) actionLoop() {
 }
 
 func (self *Server) timerLoop() {
-	self.quitWg.Add(1)
-	defer self.quitWg.Done()
-
 	for {
 		select {
 		case evt := <-self.timer.C:
@@ -1737,8 +1832,8 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 		if !isReady(self.getState()) {
 			return nil
 		}
-		if proposer, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.GetChainConfig().C); done {
-			proposal := self.findBlockProposal(evt.blockNum, proposer, forEmpty)
+		if proposer, blkHash, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.GetChainConfig().C); done {
+			proposal := self.findBlockProposal(evt.blockNum, proposer, blkHash, forEmpty)
 
 			// consensus ok, make endorsement
 			if proposal == nil {
@@ -1776,6 +1871,7 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 		}
 		proposal := self.getHighestRankProposal(evt.blockNum, proposals)
 		if proposal != nil {
+			viewChangesMetric.WithLabelValues(viewChangeEmptyEndorse).Inc()
 			if err := self.endorseBlock(proposal, true); err != nil {
 				return fmt.Errorf("failed to endorse block proposal (%d): %s", evt.blockNum, err)
 			}
@@ -1795,8 +1891,8 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 		if !isReady(self.getState()) {
 			return nil
 		}
-		if proposer, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.GetChainConfig().C); done {
-			proposal := self.findBlockProposal(evt.blockNum, proposer, forEmpty)
+		if proposer, blkHash, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.GetChainConfig().C); done {
+			proposal := self.findBlockProposal(evt.blockNum, proposer, blkHash, forEmpty)
 
 			// consensus ok, make endorsement
 			if proposal == nil {
@@ -1841,9 +1937,9 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 		}
 		if !self.blockPool.isCommitHadDone(evt.blockNum) {
 			chainCfg := self.GetChainConfig()
-			if proposer, forEmpty, done := self.blockPool.commitDone(evt.blockNum, chainCfg.C, chainCfg.N); done {
+			if proposer, blkHash, forEmpty, done := self.blockPool.commitDone(evt.blockNum, chainCfg.C, chainCfg.N); done {
 				self.blockPool.setCommitDone(evt.blockNum)
-				proposal := self.findBlockProposal(evt.blockNum, proposer, forEmpty)
+				proposal := self.findBlockProposal(evt.blockNum, proposer, blkHash, forEmpty)
 				if proposal == nil {
 					self.restartSyncing()
 					return fmt.Errorf("commit timeout, consensused proposal not available. need resync")
@@ -1948,6 +2044,10 @@ func (self *Server) endorseBlock(proposal *blockProposalMsg, forEmpty bool) erro
 	if err != nil {
 		return fmt.Errorf("failed to construct endorse msg: %s", err)
 	}
+	// persist the endorsement before sending, refuse to sign a conflicting one
+	if err := self.wal.append(endorseMsg); err != nil {
+		return fmt.Errorf("failed to write endorse msg to wal: %s", err)
+	}
 
 	// set the block as self-endorsed-block
 	if err := self.blockPool.setProposalEndorsed(proposal, forEmpty); err != nil {
@@ -2016,11 +2116,18 @@ func (self *Server) commitBlock(proposal *blockProposalMsg, forEmpty bool) error
 	if err != nil {
 		return fmt.Errorf("failed to construct commit msg: %s", err)
 	}
+	// persist the commitment before sending, refuse to sign a conflicting one
+	if err := self.wal.append(commitMsg); err != nil {
+		return fmt.Errorf("failed to write commit msg to wal: %s", err)
+	}
 
 	// set the block as committed-block
 	if err := self.blockPool.setProposalCommitted(proposal, forEmpty); err != nil {
 		return fmt.Errorf("failed to set proposal as committed: %s", err)
 	}
+	if self.pipeline && !forEmpty {
+		self.makePipelineProposal(proposal)
+	}
 
 	self.processConsensusMsg(commitMsg)
 	// if node is committer of current round
@@ -2084,6 +2191,7 @@ func (self *Server) fastForwardBlock(block *Block) error {
 }
 
 func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
+	start := time.Now()
 	sealedBlkNum := block.getBlockNum()
 	if sealedBlkNum < self.GetCurrentBlockNo() {
 		// we already in future round
@@ -2098,6 +2206,14 @@ func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
 	if err := self.blockPool.setBlockSealed(block, empty, sigdata); err != nil {
 		return fmt.Errorf("failed to seal proposal: %s", err)
 	}
+	self.roundTimer.onSealed(sealedBlkNum, empty)
+	if err := self.wal.truncate(sealedBlkNum + 1); err != nil {
+		log.Errorf("server %d failed to truncate consensus wal: %s", self.Index, err)
+	}
+	self.evidence.prune(sealedBlkNum + 1)
+	if !empty {
+		traceBlockTxs(block, "vbft.SealBlock", start)
+	}
 
 	// TODO: also persistent the block endorsers and committer msgs
 
@@ -2108,7 +2224,7 @@ func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
 
 	_, h := self.blockPool.getSealedBlock(sealedBlkNum)
 	prevBlkHash := block.getPrevBlockHash()
-	log.Infof("server %d, sealed block %d, proposer %d, prevhash: %s, hash: %s", self.Index,
+	log.WithFields(log.Fields{log.FIELD_HEIGHT: sealedBlkNum}).Infof("server %d, sealed block %d, proposer %d, prevhash: %s, hash: %s", self.Index,
 		sealedBlkNum, block.getProposer(), prevBlkHash.ToHexString(), h.ToHexString())
 
 	// broadcast to other modules
@@ -2122,9 +2238,6 @@ func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
 }
 
 func (self *Server) msgSendLoop() {
-	self.quitWg.Add(1)
-	defer self.quitWg.Done()
-
 	for {
 		select {
 		case evt := <-self.msgSendC:
@@ -2176,7 +2289,7 @@ func (self *Server) checkNeedUpdateChainConfig(blockNum uint32) bool {
 
 //checkUpdateChainConfig query leveldb check is force update
 func (self *Server) checkUpdateChainConfig(blkNum uint32) bool {
-	force, err := isUpdate(self.blockPool.getExecWriteSet(blkNum-1), self.GetChainConfig().View)
+	force, err := isUpdate(self.blockPool.getExecWriteSet(blkNum-1), self.ledger, self.GetChainConfig().View)
 	if err != nil {
 		log.Errorf("checkUpdateChainConfig err:%s", err)
 		return false
@@ -2217,6 +2330,14 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 			self.Index, blkNum, self.GetCurrentBlockNo())
 	}
 
+	self.proposalLock.Lock()
+	defer self.proposalLock.Unlock()
+	if msg := self.findPipelinedProposal(blkNum); msg != nil {
+		log.Infof("server %d use pipelined proposal for block %d", self.Index, blkNum)
+		self.processProposalMsg(msg)
+		return nil
+	}
+
 	validHeight := self.validHeight(blkNum)
 	sysTxs := make([]*types.Transaction, 0)
 	userTxs := make([]*types.Transaction, 0)
@@ -2224,7 +2345,7 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 	//check need upate chainconfig
 	var cfg *vconfig.ChainConfig
 	if self.checkNeedUpdateChainConfig(blkNum) || self.checkUpdateChainConfig(blkNum) {
-		chainconfig, err := getChainConfig(self.blockPool.getExecWriteSet(blkNum-1), blkNum)
+		chainconfig, err := getChainConfig(self.blockPool.getExecWriteSet(blkNum-1), self.ledger, blkNum)
 		if err != nil {
 			return fmt.Errorf("getChainConfig failed:%s", err)
 		}
@@ -2269,6 +2390,99 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 	return nil
 }
 
+// pipelineProposal executes the committed proposal before it is sealed, and makes the
+// proposal of next block upon it if the server is the proposer. The next proposal is
+// sent in advance, it is dropped when the round starts if another block is sealed.
+// It runs in actionLoop, where the blocks are sealed to the chain store.
+func (self *Server) pipelineProposal(proposal *blockProposalMsg) {
+	prevBlk := proposal.Block
+	blkNum := prevBlk.getBlockNum() + 1
+	execResult, err := self.blockPool.executeSpeculatively(prevBlk)
+	if err != nil {
+		log.Infof("server %d, speculative execution of block %d: %s", self.Index, blkNum-1, err)
+		return
+	}
+
+	// the proposals updating chain config are made upon the sealed block
+	chainCfg := self.GetChainConfig()
+	if prevBlk.getNewChainConfig() != nil || blkNum-prevBlk.getLastConfigBlockNum() >= chainCfg.MaxBlockChangeView {
+		return
+	}
+	if force, err := isUpdate(execResult.WriteSet, self.ledger, chainCfg.View); err != nil || force {
+		return
+	}
+	if !isActive(self.getState()) {
+		return
+	}
+	cfg, err := self.buildParticipantConfig(blkNum, prevBlk, &chainCfg)
+	if err != nil {
+		log.Errorf("server %d, pipelining block %d: %s", self.Index, blkNum, err)
+		return
+	}
+	for _, id := range cfg.Proposers {
+		if self.isPeerAlive(id, blkNum) {
+			if id != self.Index {
+				return
+			}
+			break
+		}
+	}
+
+	// skip the txs in previous block, which are still in txpool
+	included := make(map[common.Uint256]bool)
+	for _, tx := range prevBlk.Block.Transactions {
+		included[tx.Hash()] = true
+	}
+	validHeight := self.validHeight(blkNum - 1)
+	userTxs := make([]*types.Transaction, 0)
+	nonceCtx := make(map[common.Address]uint64)
+	for _, e := range self.poolActor.GetTxnPool(true, validHeight) {
+		if included[e.Tx.Hash()] {
+			continue
+		}
+		if err := self.incrValidator.Verify(e.Tx, validHeight, nonceCtx); err == nil {
+			userTxs = append(userTxs, e.Tx)
+		}
+	}
+	if len(userTxs) == 0 {
+		// wait for txs in the round as usual
+		return
+	}
+
+	msg, err := self.constructProposalMsgUpon(prevBlk, prevBlk.Block.Hash(), execResult.MerkleRoot,
+		execResult.CrossStatesRoot, nil, userTxs, nil)
+	if err != nil {
+		log.Errorf("server %d, failed to construct pipelined proposal for block %d: %s", self.Index, blkNum, err)
+		return
+	}
+	self.proposalLock.Lock()
+	defer self.proposalLock.Unlock()
+	// the proposal is made in the round once it started
+	if self.GetCurrentBlockNo() >= blkNum {
+		return
+	}
+	log.Infof("server %d make pipelined proposal for block %d with %d txs", self.Index, blkNum, len(userTxs))
+	h, _ := HashMsg(msg)
+	if err := self.msgPool.AddMsg(msg, h); err != nil {
+		log.Errorf("server %d, failed to add pipelined proposal for block %d: %s", self.Index, blkNum, err)
+		return
+	}
+	self.broadcast(msg)
+}
+
+// findPipelinedProposal returns the proposal of the server made before the round
+// started, if it is upon the sealed block
+func (self *Server) findPipelinedProposal(blkNum uint32) *blockProposalMsg {
+	_, prevBlkHash := self.blockPool.getSealedBlock(blkNum - 1)
+	for _, p := range self.msgPool.GetProposalMsgs(blkNum) {
+		if msg := p.(*blockProposalMsg); msg != nil && msg.Block.getProposer() == self.Index &&
+			msg.Block.getPrevBlockHash() == prevBlkHash {
+			return msg
+		}
+	}
+	return nil
+}
+
 func (self *Server) makeCommitment(proposal *blockProposalMsg, blkNum uint32, forEmpty bool) error {
 	if err := self.commitBlock(proposal, forEmpty); err != nil {
 		return fmt.Errorf("failed to commit block proposal (%d): %s", blkNum, err)
@@ -2325,6 +2539,16 @@ func (self *Server) makeBlockSubmit(blknum uint32) {
 	}()
 }
 
+func (self *Server) makePipelineProposal(proposal *blockProposalMsg) {
+	go func() {
+		self.bftActionC <- &BftAction{
+			Type:     PipelineProposal,
+			BlockNum: proposal.GetBlockNum(),
+			Proposal: proposal,
+		}
+	}()
+}
+
 func (self *Server) fetchProposal(blkNum uint32, proposer uint32) error {
 	msg := self.constructProposalFetchMsg(blkNum, proposer)
 	self.msgSendC <- &SendMsgEvent{
@@ -2342,6 +2566,9 @@ func (self *Server) handleProposalTimeout(evt *TimerEvent) error {
 		return nil
 	}
 	proposals := self.blockPool.getBlockProposals(evt.blockNum)
+	if evt.evtType == EventProposeBlockTimeout {
+		viewChangesMetric.WithLabelValues(viewChangeProposalTimeout).Inc()
+	}
 
 	log.Infof("server %d proposal timeout, known proposals %d, timeout: %d", self.Index, len(proposals), evt.evtType)
 
@@ -2406,14 +2633,17 @@ func (self *Server) catchConsensus(blkNum uint32) error {
 		return nil
 	}
 
-	proposals := make(map[uint32]*blockProposalMsg)
+	proposals := make(map[common.Uint256]*blockProposalMsg)
 	pMsgs := self.msgPool.GetProposalMsgs(blkNum)
 	for _, msg := range pMsgs {
 		p, ok := msg.(*blockProposalMsg)
 		if !ok {
 			continue
 		}
-		proposals[p.Block.getProposer()] = p
+		proposals[proposedBlockHash(p, false)] = p
+		if p.Block.EmptyBlock != nil {
+			proposals[proposedBlockHash(p, true)] = p
+		}
 	}
 
 	chainCfg := self.GetChainConfig()
@@ -2422,10 +2652,10 @@ func (self *Server) catchConsensus(blkNum uint32) error {
 	endorseDone := false
 	endorseEmpty := false
 	if len(eMsgs) > int(chainCfg.C) {
-		var maxProposer uint32
+		var maxHash common.Uint256
 		emptyCnt := 0
 		maxCnt := 0
-		proposers := make(map[uint32]int)
+		blocks := make(map[common.Uint256]int)
 		for _, msg := range eMsgs {
 			c, ok := msg.(*blockEndorseMsg)
 			if !ok {
@@ -2434,13 +2664,13 @@ func (self *Server) catchConsensus(blkNum uint32) error {
 			if c.EndorseForEmpty {
 				emptyCnt++
 			}
-			proposers[c.EndorsedProposer] += 1
-			if proposers[c.EndorsedProposer] > maxCnt {
-				maxProposer = c.EndorsedProposer
-				maxCnt = proposers[c.EndorsedProposer]
+			blocks[c.EndorsedBlockHash] += 1
+			if blocks[c.EndorsedBlockHash] > maxCnt {
+				maxHash = c.EndorsedBlockHash
+				maxCnt = blocks[c.EndorsedBlockHash]
 			}
 		}
-		proposal = proposals[maxProposer]
+		proposal = proposals[maxHash]
 		if maxCnt > int(chainCfg.C) {
 			endorseDone = true
 		}
@@ -2465,10 +2695,10 @@ func (self *Server) catchConsensus(blkNum uint32) error {
 		return nil
 	}
 
-	var maxProposer uint32
+	var maxHash common.Uint256
 	maxCnt := 0
 	emptyCnt := 0
-	proposers := make(map[uint32]int)
+	blocks := make(map[common.Uint256]int)
 	cMsgs := self.msgPool.GetCommitMsgs(blkNum)
 	for _, msg := range cMsgs {
 		c, ok := msg.(*blockCommitMsg)
@@ -2478,13 +2708,14 @@ func (self *Server) catchConsensus(blkNum uint32) error {
 		if c.CommitForEmpty {
 			emptyCnt++
 		}
-		proposers[c.BlockProposer] += 1
-		if proposers[c.BlockProposer] > maxCnt {
-			maxProposer = c.BlockProposer
+		blocks[c.CommitBlockHash] += 1
+		if blocks[c.CommitBlockHash] > maxCnt {
+			maxHash = c.CommitBlockHash
+			maxCnt = blocks[c.CommitBlockHash]
 		}
 	}
 
-	if p := proposals[maxProposer]; p != nil {
+	if p := proposals[maxHash]; p != nil {
 		return self.commitBlock(p, emptyCnt > 0)
 	}
 
@@ -2514,7 +2745,7 @@ func (self *Server) hasBlockConsensused() bool {
 	C := int(self.GetChainConfig().C)
 	cMsgs := self.msgPool.GetCommitMsgs(blkNum)
 	emptyCnt := 0
-	proposers := make(map[uint32]int)
+	blocks := make(map[common.Uint256]int)
 	for _, msg := range cMsgs {
 		c, ok := msg.(*blockCommitMsg)
 		if !ok {
@@ -2523,8 +2754,8 @@ func (self *Server) hasBlockConsensused() bool {
 		if c.CommitForEmpty {
 			emptyCnt++
 		}
-		proposers[c.BlockProposer] += 1
-		if proposers[c.BlockProposer] > C {
+		blocks[c.CommitBlockHash] += 1
+		if blocks[c.CommitBlockHash] > C {
 			return true
 		}
 	}
@@ -2533,6 +2764,7 @@ func (self *Server) hasBlockConsensused() bool {
 }
 
 func (self *Server) restartSyncing() {
+	viewChangesMetric.WithLabelValues(viewChangeResync).Inc()
 
 	// send sync request to self.sync, go syncing-state immediately
 	// stop all bft timers
@@ -0,0 +1,161 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package vbft
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// the simulations use 7 peers of C = 2, up to 2 faulty peers are tolerated.
+// The live peers are not guaranteed to make progress while some peers are faulty:
+// once the live endorsers split between two proposals of a block, endorseFailed
+// waits for the endorsements of the faulty peers. Only safety is checked then,
+// and liveness after the faulty peers are back.
+
+func TestSimulationNormal(t *testing.T) {
+	sim := newSimulation(t, 1)
+	defer sim.close()
+
+	sim.waitHeight(3, 10*time.Minute, 0, 1, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+}
+
+func TestSimulationPipeline(t *testing.T) {
+	sim := newSimulation(t, 6, withTxs(), withPipeline())
+	defer sim.close()
+
+	sim.waitHeight(6, 10*time.Minute, 0, 1, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+	txs := 0
+	for blkNum := uint32(1); blkNum <= 6; blkNum++ {
+		block, err := sim.nodes[0].ledger.GetBlockByHeight(blkNum)
+		if err != nil {
+			t.Fatalf("get block %d: %s", blkNum, err)
+		}
+		txs += len(block.Transactions)
+	}
+	if txs == 0 {
+		t.Fatalf("no transaction sealed")
+	}
+	if testutil.ToFloat64(pipelinedProposalsMetric.WithLabelValues("proposed")) == 0 {
+		t.Fatalf("no pipelined proposal")
+	}
+	if testutil.ToFloat64(speculativeBlocksMetric.WithLabelValues("reused")) == 0 {
+		t.Fatalf("no speculative execution result reused")
+	}
+}
+
+func TestSimulationPartition(t *testing.T) {
+	sim := newSimulation(t, 2)
+	defer sim.close()
+
+	sim.waitHeight(1, 10*time.Minute, 0, 1, 2, 3, 4, 5, 6)
+	sim.network.Partition(sim.peerIds(0, 1, 2, 3, 4), sim.peerIds(5, 6))
+	minority := sim.heights()
+	target := minority[0] + 3
+	sim.run(10*time.Minute, func() bool { return sim.reached(target, 0, 1, 2, 3, 4) })
+	heights := sim.heights()
+	for _, i := range []int{5, 6} {
+		// the minority can seal at most the block committed by majority before partitioned
+		if heights[i] > minority[i]+1 {
+			t.Fatalf("node %d of minority sealed block %d after partitioned at %d", i, heights[i], minority[i])
+		}
+	}
+	sim.checkSafety()
+
+	sim.network.Heal()
+	sim.waitHeight(sim.maxHeight()+1, 10*time.Minute, 0, 1, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+}
+
+func TestSimulationCrash(t *testing.T) {
+	sim := newSimulation(t, 3)
+	defer sim.close()
+
+	// the live tick of server detects no halt at the genesis height, so the
+	// nodes crash after the first block
+	sim.waitHeight(1, 10*time.Minute, 0, 1, 2, 3, 4, 5, 6)
+	sim.crash(0)
+	sim.crash(1)
+	target := sim.height(2) + 2
+	sim.run(10*time.Minute, func() bool { return sim.reached(target, 2, 3, 4, 5, 6) })
+	sim.checkSafety()
+
+	sim.recover(0)
+	sim.recover(1)
+	sim.waitHeight(sim.maxHeight()+1, 10*time.Minute, 0, 1, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+}
+
+func TestSimulationUnreliableNetwork(t *testing.T) {
+	sim := newSimulation(t, 4)
+	defer sim.close()
+
+	// messages are reordered by random delays
+	sim.network.SetDelay(10*time.Millisecond, 300*time.Millisecond)
+	sim.network.SetDropRate(5)
+	sim.waitHeight(3, 10*time.Minute, 0, 1, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+	if _, dropped := sim.network.Stats(); dropped == 0 {
+		t.Fatal("no message dropped")
+	}
+}
+
+func TestSimulationEquivocation(t *testing.T) {
+	sim := newSimulation(t, 5)
+	defer sim.close()
+
+	byzantine := sim.proposer()
+	var honest []int
+	for i := range sim.nodes {
+		if i != byzantine {
+			honest = append(honest, i)
+		}
+	}
+	sim.equivocate(byzantine, honest[:3]...)
+	// the honest peers may be stuck by the conflicting proposals
+	sim.run(2*time.Minute, func() bool { return false })
+
+	detected := false
+	for _, i := range honest {
+		evidences, err := ReadEvidences(filepath.Join(sim.dir, strconv.Itoa(i), VBFT_EVIDENCE_FILE), 0)
+		if err != nil {
+			t.Fatalf("read evidences of node %d: %s", i, err)
+		}
+		for _, evidence := range evidences {
+			if evidence.PeerIndex != sim.nodes[byzantine].server.Index {
+				t.Fatalf("node %d reported honest peer %d", i, evidence.PeerIndex)
+			}
+			detected = true
+		}
+	}
+	if !detected {
+		t.Fatal("equivocation not detected")
+	}
+
+	// endorsements and commits are counted by block hash, the conflicting
+	// blocks of the equivocating proposer must not both be sealed
+	if err := sim.fork(); err != nil {
+		t.Fatalf("safety: %s", err)
+	}
+}
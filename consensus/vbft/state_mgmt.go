@@ -90,7 +90,7 @@ type StateMgr struct {
 	StateEventC      chan *StateEvent
 	peers            map[uint32]*PeerState
 
-	liveTicker             *time.Timer
+	liveTicker             Timer
 	lastTickChainHeight    uint32
 	lastBlockSyncReqHeight uint32
 }
@@ -116,7 +116,7 @@ func (self *StateMgr) setState(newstate ServerState) {
 
 func (self *StateMgr) run() {
 	liveTimeout := time.Duration(atomic.LoadInt64(&peerHandshakeTimeout) * 5)
-	self.liveTicker = time.AfterFunc(liveTimeout, func() {
+	self.liveTicker = self.server.clock.AfterFunc(liveTimeout, func() {
 		self.StateEventC <- &StateEvent{
 			Type:     LiveTick,
 			blockNum: self.server.GetCommittedBlockNo(),
@@ -126,9 +126,6 @@ func (self *StateMgr) run() {
 	})
 
 	// wait config done
-	self.server.quitWg.Add(1)
-	defer self.server.quitWg.Done()
-
 	for {
 		select {
 		case evt := <-self.StateEventC:
@@ -367,7 +364,7 @@ func (self *StateMgr) setSyncedReady() error {
 	if prevState <= SyncReady {
 		log.Infof("server %d start sync ready", self.server.Index)
 		blkNum := self.server.GetCurrentBlockNo()
-		time.AfterFunc(self.syncReadyTimeout, func() {
+		self.server.clock.AfterFunc(self.syncReadyTimeout, func() {
 			self.StateEventC <- &StateEvent{
 				Type:     SyncReadyTimeout,
 				blockNum: blkNum,
@@ -478,7 +475,7 @@ func (self *StateMgr) canFastForward(targetBlkNum uint32) bool {
 				commitMsgs = append(commitMsgs, c)
 			}
 		}
-		proposer, _ := getCommitConsensus(commitMsgs, C, N)
+		proposer, blkHash, forEmpty := getCommitConsensus(commitMsgs, C, N)
 		if proposer == math.MaxUint32 {
 			log.Infof("server %d check fastforward false, no consensus in %d commit msg for block %d",
 				self.server.Index, len(commitMsgs), blkNum)
@@ -487,7 +484,8 @@ func (self *StateMgr) canFastForward(targetBlkNum uint32) bool {
 		// check if the proposal message is available
 		foundProposal := false
 		for _, msg := range self.server.msgPool.GetProposalMsgs(blkNum) {
-			if p := msg.(*blockProposalMsg); p != nil && p.Block.getProposer() == proposer {
+			if p := msg.(*blockProposalMsg); p != nil && p.Block.getProposer() == proposer &&
+				proposedBlockHash(p, forEmpty) == blkHash {
 				foundProposal = true
 				break
 			}
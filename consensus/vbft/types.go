@@ -71,6 +71,36 @@ func (blk *Block) getVrfProof() []byte {
 	return blk.Info.VrfProof
 }
 
+//
+// copyHeaders() returns a copy of the block which owns its headers and cross chain msg,
+// so that seal signatures can be added while the block is still being serialized
+// for sending.
+//
+func (blk *Block) copyHeaders() *Block {
+	copyBlock := func(b *types.Block) *types.Block {
+		if b == nil {
+			return nil
+		}
+		header := *b.Header
+		return &types.Block{
+			Header:       &header,
+			Transactions: b.Transactions,
+		}
+	}
+	cpy := &Block{
+		Block:              copyBlock(blk.Block),
+		EmptyBlock:         copyBlock(blk.EmptyBlock),
+		Info:               blk.Info,
+		PrevExecMerkleRoot: blk.PrevExecMerkleRoot,
+	}
+	if blk.CrossChainMsg != nil {
+		msg := *blk.CrossChainMsg
+		msg.SigData = append([][]byte(nil), blk.CrossChainMsg.SigData...)
+		cpy.CrossChainMsg = &msg
+	}
+	return cpy
+}
+
 func (blk *Block) Serialize() []byte {
 	payload := common.NewZeroCopySink(nil)
 	payload.WriteVarBytes(common.SerializeToBytes(blk.Block))
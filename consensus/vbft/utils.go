@@ -24,12 +24,14 @@ import (
 	"crypto/sha512"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology-crypto/vrf"
 	"github.com/ontio/ontology/account"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/tracing"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/signature"
@@ -126,16 +128,28 @@ func verifyVrf(pk keypair.PublicKey, blkNum uint32, prevVrf, newVrf, proof []byt
 	return nil
 }
 
-func GetVbftConfigInfo(memdb *overlaydb.MemDB) (*config.VBFTConfig, error) {
+// traceBlockTxs records a span started at start in the trace of each transaction of the block
+func traceBlockTxs(block *Block, name string, start time.Time) {
+	if !tracing.Enabled() {
+		return
+	}
+	height := tracing.ATTR_BLOCK_HEIGHT.Int64(int64(block.getBlockNum()))
+	proposer := tracing.ATTR_PROPOSER.Int64(int64(block.getProposer()))
+	for _, tx := range block.Block.Transactions {
+		tracing.RecordTxSpan(tx.Hash(), name, start, height, proposer)
+	}
+}
+
+func GetVbftConfigInfo(memdb *overlaydb.MemDB, backend *ledger.Ledger) (*config.VBFTConfig, error) {
 	//get governance view
-	goveranceview, err := GetGovernanceView(memdb)
+	goveranceview, err := GetGovernanceView(memdb, backend)
 	if err != nil {
 		return nil, err
 	}
 
 	//get preConfig
 	preCfg := new(gov.PreConfig)
-	data, err := GetStorageValue(memdb, ledger.DefLedger, nutils.GovernanceContractAddress, []byte(gov.PRE_CONFIG))
+	data, err := GetStorageValue(memdb, backend, nutils.GovernanceContractAddress, []byte(gov.PRE_CONFIG))
 	if err != nil && err != scommon.ErrNotFound {
 		return nil, err
 	}
@@ -159,7 +173,7 @@ func GetVbftConfigInfo(memdb *overlaydb.MemDB) (*config.VBFTConfig, error) {
 			MaxBlockChangeView:   uint32(preCfg.Configuration.MaxBlockChangeView),
 		}
 	} else {
-		data, err := GetStorageValue(memdb, ledger.DefLedger, nutils.GovernanceContractAddress, []byte(gov.VBFT_CONFIG))
+		data, err := GetStorageValue(memdb, backend, nutils.GovernanceContractAddress, []byte(gov.VBFT_CONFIG))
 		if err != nil {
 			return nil, err
 		}
@@ -182,14 +196,14 @@ func GetVbftConfigInfo(memdb *overlaydb.MemDB) (*config.VBFTConfig, error) {
 	return chainconfig, nil
 }
 
-func GetPeersConfig(memdb *overlaydb.MemDB) ([]*config.VBFTPeerStakeInfo, error) {
-	goveranceview, err := GetGovernanceView(memdb)
+func GetPeersConfig(memdb *overlaydb.MemDB, backend *ledger.Ledger) ([]*config.VBFTPeerStakeInfo, error) {
+	goveranceview, err := GetGovernanceView(memdb, backend)
 	if err != nil {
 		return nil, err
 	}
 	viewBytes := gov.GetUint32Bytes(goveranceview.View)
 	key := append([]byte(gov.PEER_POOL), viewBytes...)
-	data, err := GetStorageValue(memdb, ledger.DefLedger, nutils.GovernanceContractAddress, key)
+	data, err := GetStorageValue(memdb, backend, nutils.GovernanceContractAddress, key)
 	if err != nil {
 		return nil, err
 	}
@@ -214,8 +228,8 @@ func GetPeersConfig(memdb *overlaydb.MemDB) ([]*config.VBFTPeerStakeInfo, error)
 	return peerstakes, nil
 }
 
-func isUpdate(memdb *overlaydb.MemDB, view uint32) (bool, error) {
-	goveranceview, err := GetGovernanceView(memdb)
+func isUpdate(memdb *overlaydb.MemDB, backend *ledger.Ledger, view uint32) (bool, error) {
+	goveranceview, err := GetGovernanceView(memdb, backend)
 	if err != nil {
 		return false, err
 	}
@@ -249,8 +263,8 @@ func GetStorageValue(memdb *overlaydb.MemDB, backend *ledger.Ledger, addr common
 	return
 }
 
-func GetGovernanceView(memdb *overlaydb.MemDB) (*gov.GovernanceView, error) {
-	value, err := GetStorageValue(memdb, ledger.DefLedger, nutils.GovernanceContractAddress, []byte(gov.GOVERNANCE_VIEW))
+func GetGovernanceView(memdb *overlaydb.MemDB, backend *ledger.Ledger) (*gov.GovernanceView, error) {
+	value, err := GetStorageValue(memdb, backend, nutils.GovernanceContractAddress, []byte(gov.GOVERNANCE_VIEW))
 	if err != nil {
 		return nil, err
 	}
@@ -262,17 +276,17 @@ func GetGovernanceView(memdb *overlaydb.MemDB) (*gov.GovernanceView, error) {
 	return governanceView, nil
 }
 
-func getChainConfig(memdb *overlaydb.MemDB, blkNum uint32) (*vconfig.ChainConfig, error) {
-	config, err := GetVbftConfigInfo(memdb)
+func getChainConfig(memdb *overlaydb.MemDB, backend *ledger.Ledger, blkNum uint32) (*vconfig.ChainConfig, error) {
+	config, err := GetVbftConfigInfo(memdb, backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chainconfig from leveldb: %s", err)
 	}
 
-	peersinfo, err := GetPeersConfig(memdb)
+	peersinfo, err := GetPeersConfig(memdb, backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get peersinfo from leveldb: %s", err)
 	}
-	goverview, err := GetGovernanceView(memdb)
+	goverview, err := GetGovernanceView(memdb, backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get governanceview failed:%s", err)
 	}
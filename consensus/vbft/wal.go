@@ -0,0 +1,337 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+)
+
+const VBFT_WAL_FILE = "vbft.wal"
+
+var errDoubleSign = errors.New("conflicts with the signed msg in consensus wal")
+
+// walVote is the block signed by an endorsement or commitment
+type walVote struct {
+	forEmpty bool
+	blkHash  common.Uint256
+	msg      ConsensusMsg
+}
+
+// consensusWal is an append only log of the endorsements and commitments
+// signed by the server. A msg is synced to the log before being sent, so the
+// server never signs a conflicting one for the same block after restart.
+type consensusWal struct {
+	lock     sync.Mutex
+	path     string
+	writer   *os.File
+	closed   bool
+	endorses map[uint32][]*walVote // endorsements by block num, for block and empty block
+	commits  map[uint32]*walVote   // commitment by block num
+}
+
+// openConsensusWal loads the signed msgs from the log, a broken tail caused by
+// a crash in writing is truncated.
+func openConsensusWal(path string) (*consensusWal, error) {
+	wal := &consensusWal{
+		path:     path,
+		endorses: make(map[uint32][]*walVote),
+		commits:  make(map[uint32]*walVote),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	source := common.NewZeroCopySource(data)
+	valid := uint64(0)
+	for source.Len() > 0 {
+		payload, _, irregular, eof := source.NextVarBytes()
+		if irregular || eof {
+			log.Warnf("vbft wal: broken record at offset %d, truncated", valid)
+			break
+		}
+		msg, err := DeserializeVbftMsg(payload)
+		if err != nil {
+			log.Warnf("vbft wal: broken record at offset %d, truncated: %s", valid, err)
+			break
+		}
+		if err := wal.addVote(msg); err != nil {
+			return nil, fmt.Errorf("vbft wal: %s", err)
+		}
+		valid = source.Pos()
+	}
+
+	writer, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Truncate(int64(valid)); err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+	if _, err := writer.Seek(int64(valid), 0); err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+	wal.writer = writer
+	return wal, nil
+}
+
+func getVote(msg ConsensusMsg) (*walVote, error) {
+	switch m := msg.(type) {
+	case *blockEndorseMsg:
+		return &walVote{forEmpty: m.EndorseForEmpty, blkHash: m.EndorsedBlockHash, msg: msg}, nil
+	case *blockCommitMsg:
+		return &walVote{forEmpty: m.CommitForEmpty, blkHash: m.CommitBlockHash, msg: msg}, nil
+	}
+	return nil, fmt.Errorf("invalid msg type %d", msg.Type())
+}
+
+// returns the vote signed before for the same block of msg, or error if the
+// msg conflicts with it.
+func (self *consensusWal) getSignedLocked(msg ConsensusMsg, vote *walVote) (*walVote, error) {
+	blkNum := msg.GetBlockNum()
+	var signed *walVote
+	if msg.Type() == BlockEndorseMessage {
+		for _, v := range self.endorses[blkNum] {
+			if v.forEmpty == vote.forEmpty {
+				signed = v
+			}
+		}
+	} else {
+		signed = self.commits[blkNum]
+	}
+	if signed == nil {
+		return nil, nil
+	}
+	if signed.forEmpty != vote.forEmpty || signed.blkHash != vote.blkHash {
+		return nil, fmt.Errorf("msg type %d of block %d for %s %s", msg.Type(), blkNum,
+			vote.blkHash.ToHexString(), errDoubleSign)
+	}
+	return signed, nil
+}
+
+func (self *consensusWal) addVote(msg ConsensusMsg) error {
+	vote, err := getVote(msg)
+	if err != nil {
+		return err
+	}
+	signed, err := self.getSignedLocked(msg, vote)
+	if err != nil || signed != nil {
+		return err
+	}
+	blkNum := msg.GetBlockNum()
+	if msg.Type() == BlockEndorseMessage {
+		self.endorses[blkNum] = append(self.endorses[blkNum], vote)
+	} else {
+		self.commits[blkNum] = vote
+	}
+	return nil
+}
+
+// append records the endorsement or commitment and syncs it to disk, returns
+// errDoubleSign if a conflicting one of the same block had been signed.
+func (self *consensusWal) append(msg ConsensusMsg) error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	vote, err := getVote(msg)
+	if err != nil {
+		return err
+	}
+	signed, err := self.getSignedLocked(msg, vote)
+	if err != nil || signed != nil {
+		return err
+	}
+	if self.closed {
+		return errors.New("vbft wal closed")
+	}
+	// reopen if failed in truncating, the file is either the old or the new one
+	if self.writer == nil {
+		writer, err := os.OpenFile(self.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		self.writer = writer
+	}
+	payload, err := SerializeVbftMsg(msg)
+	if err != nil {
+		return err
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarBytes(payload)
+	if _, err := self.writer.Write(sink.Bytes()); err != nil {
+		return err
+	}
+	if err := self.writer.Sync(); err != nil {
+		return err
+	}
+	return self.addVote(msg)
+}
+
+// msgs returns the signed msgs from the block num
+func (self *consensusWal) msgs(fromBlkNum uint32) []ConsensusMsg {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var msgs []ConsensusMsg
+	for blkNum, votes := range self.endorses {
+		if blkNum >= fromBlkNum {
+			for _, v := range votes {
+				msgs = append(msgs, v.msg)
+			}
+		}
+	}
+	for blkNum, v := range self.commits {
+		if blkNum >= fromBlkNum {
+			msgs = append(msgs, v.msg)
+		}
+	}
+	return msgs
+}
+
+// truncate drops the msgs before the block num, which are sealed already
+func (self *consensusWal) truncate(blkNum uint32) error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	dropped := false
+	for num := range self.endorses {
+		if num < blkNum {
+			delete(self.endorses, num)
+			dropped = true
+		}
+	}
+	for num := range self.commits {
+		if num < blkNum {
+			delete(self.commits, num)
+			dropped = true
+		}
+	}
+	if !dropped || self.closed {
+		return nil
+	}
+
+	sink := common.NewZeroCopySink(nil)
+	for _, votes := range self.endorses {
+		for _, v := range votes {
+			payload, err := SerializeVbftMsg(v.msg)
+			if err != nil {
+				return err
+			}
+			sink.WriteVarBytes(payload)
+		}
+	}
+	for _, v := range self.commits {
+		payload, err := SerializeVbftMsg(v.msg)
+		if err != nil {
+			return err
+		}
+		sink.WriteVarBytes(payload)
+	}
+	if self.writer != nil {
+		if err := self.writer.Close(); err != nil {
+			return err
+		}
+		self.writer = nil
+	}
+
+	tmp := self.path + ".new"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(sink.Bytes()); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, self.path); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(self.path)); err != nil {
+		return err
+	}
+	writer, err := os.OpenFile(self.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	self.writer = writer
+	return nil
+}
+
+func (self *consensusWal) close() error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.closed = true
+	if self.writer == nil {
+		return nil
+	}
+	err := self.writer.Close()
+	self.writer = nil
+	return err
+}
+
+// syncDir persists the directory entries, so a created or renamed log survives a crash
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWalTestMsgs(blkNum uint32, hash byte) (*blockEndorseMsg, *blockCommitMsg) {
+	blkHash := common.Uint256{hash}
+	endorse := &blockEndorseMsg{
+		Endorser:          1,
+		EndorsedProposer:  2,
+		BlockNum:          blkNum,
+		EndorsedBlockHash: blkHash,
+	}
+	commit := &blockCommitMsg{
+		Committer:       1,
+		BlockProposer:   2,
+		BlockNum:        blkNum,
+		CommitBlockHash: blkHash,
+		EndorsersSig:    map[uint32][]byte{},
+	}
+	return endorse, commit
+}
+
+func TestConsensusWal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vbft")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, VBFT_WAL_FILE)
+
+	wal, err := openConsensusWal(path)
+	assert.Nil(t, err)
+	endorse, commit := newWalTestMsgs(10, 1)
+	assert.Nil(t, wal.append(endorse))
+	assert.Nil(t, wal.append(commit))
+	// resign the same block
+	assert.Nil(t, wal.append(endorse))
+
+	conflictEndorse, conflictCommit := newWalTestMsgs(10, 2)
+	assert.Contains(t, wal.append(conflictEndorse).Error(), errDoubleSign.Error())
+	assert.Contains(t, wal.append(conflictCommit).Error(), errDoubleSign.Error())
+	// endorse for empty block after the block
+	conflictEndorse.EndorseForEmpty = true
+	assert.Nil(t, wal.append(conflictEndorse))
+	assert.Nil(t, wal.close())
+
+	// crash in writing the next record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = f.Write([]byte{0xfd, 0x10})
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	wal, err = openConsensusWal(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(wal.msgs(10)))
+	assert.Equal(t, 0, len(wal.msgs(11)))
+	_, conflictCommit = newWalTestMsgs(10, 2)
+	assert.NotNil(t, wal.append(conflictCommit))
+
+	next, _ := newWalTestMsgs(11, 3)
+	assert.Nil(t, wal.append(next))
+	assert.Nil(t, wal.truncate(11))
+	assert.Equal(t, 1, len(wal.msgs(0)))
+	assert.Nil(t, wal.append(conflictCommit))
+	assert.Nil(t, wal.close())
+
+	wal, err = openConsensusWal(path)
+	assert.Nil(t, err)
+	msgs := wal.msgs(0)
+	assert.Equal(t, 2, len(msgs))
+	assert.Nil(t, wal.close())
+
+	// disabled
+	var nilWal *consensusWal
+	assert.Nil(t, nilWal.append(endorse))
+	assert.Nil(t, nilWal.truncate(11))
+}
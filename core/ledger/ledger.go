@@ -19,12 +19,17 @@
 package ledger
 
 import (
+	"errors"
 	"fmt"
 
+	types2 "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/store"
 	"github.com/ontio/ontology/core/store/ledgerstore"
 	"github.com/ontio/ontology/core/types"
+	types3 "github.com/ontio/ontology/smartcontract/service/evm/types"
+	"github.com/ontio/ontology/vm/evm"
 )
 
 var DefLedger *Ledger
@@ -37,6 +42,30 @@ func (self *Ledger) GetStore() store.LedgerStore {
 	return self.LedgerStore
 }
 
+// evmTracer is kept out of store.LedgerStore, since the evm package depends on core/store in its tests
+type evmTracer interface {
+	TraceEip155Call(msg types2.Message, height uint32, tracer evm.Tracer) (*types3.ExecutionResult, error)
+	TraceEip155Tx(txHash common.Uint256, tracer evm.Tracer) (*types3.ExecutionResult, error)
+}
+
+var errTraceNotSupported = errors.New("evm tracing is not supported by the ledger store")
+
+func (self *Ledger) TraceEip155Call(msg types2.Message, height uint32, tracer evm.Tracer) (*types3.ExecutionResult, error) {
+	ldgStore, ok := self.LedgerStore.(evmTracer)
+	if !ok {
+		return nil, errTraceNotSupported
+	}
+	return ldgStore.TraceEip155Call(msg, height, tracer)
+}
+
+func (self *Ledger) TraceEip155Tx(txHash common.Uint256, tracer evm.Tracer) (*types3.ExecutionResult, error) {
+	ldgStore, ok := self.LedgerStore.(evmTracer)
+	if !ok {
+		return nil, errTraceNotSupported
+	}
+	return ldgStore.TraceEip155Tx(txHash, tracer)
+}
+
 func InitLedger(dataDir string, stateHashHeight uint32, defaultBookkeeper []keypair.PublicKey,
 	genesisBlock *types.Block) (*Ledger, error) {
 	ldgStore, err := ledgerstore.NewLedgerStore(dataDir, stateHashHeight)
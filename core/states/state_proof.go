@@ -0,0 +1,218 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package states
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/merkle"
+)
+
+// WriteSetEntry is a single key value pair changed by a block, an empty value means the key is deleted
+type WriteSetEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// WriteSet is the sorted state changes of a block
+type WriteSet []WriteSetEntry
+
+// Hash returns the state change hash of block, which is the leaf of state merkle tree
+func (this WriteSet) Hash() common.Uint256 {
+	stateDiff := sha256.New()
+	for _, entry := range this {
+		stateDiff.Write(entry.Key)
+		stateDiff.Write(entry.Value)
+	}
+	var hash common.Uint256
+	stateDiff.Sum(hash[:0])
+	return hash
+}
+
+func (this WriteSet) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarUint(uint64(len(this)))
+	for _, entry := range this {
+		sink.WriteVarBytes(entry.Key)
+		sink.WriteVarBytes(entry.Value)
+	}
+}
+
+func (this *WriteSet) Deserialization(source *common.ZeroCopySource) error {
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	var entries WriteSet
+	for i := uint64(0); i < n; i++ {
+		key, err := nextVarBytes(source)
+		if err != nil {
+			return err
+		}
+		value, err := nextVarBytes(source)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, WriteSetEntry{Key: key, Value: value})
+	}
+	*this = entries
+	return nil
+}
+
+func nextVarBytes(source *common.ZeroCopySource) ([]byte, error) {
+	data, _, irregular, eof := source.NextVarBytes()
+	if irregular {
+		return nil, common.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+// StateProof proves that Key has Value at block Height. The value was last written at WriteHeight, the proof
+// carries the write sets of the blocks from WriteHeight to Height, which show the value written and that no later
+// block writes the key, and the compact hashes of the state merkle tree before WriteHeight. Appending the hashes of
+// the write sets to that tree gives the state merkle root of Height.
+//
+// A key never written since the state merkle tree started can not be proven absent, the tree only covers the
+// changes of blocks.
+type StateProof struct {
+	Key         []byte
+	Value       []byte
+	Height      uint32
+	WriteHeight uint32
+	TreeHashes  []common.Uint256
+	WriteSets   []WriteSet
+}
+
+func (this *StateProof) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarBytes(this.Key)
+	sink.WriteVarBytes(this.Value)
+	sink.WriteUint32(this.Height)
+	sink.WriteUint32(this.WriteHeight)
+	sink.WriteVarUint(uint64(len(this.TreeHashes)))
+	for _, hash := range this.TreeHashes {
+		sink.WriteHash(hash)
+	}
+	sink.WriteVarUint(uint64(len(this.WriteSets)))
+	for _, writeSet := range this.WriteSets {
+		writeSet.Serialization(sink)
+	}
+}
+
+func (this *StateProof) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	if this.Key, err = nextVarBytes(source); err != nil {
+		return err
+	}
+	if this.Value, err = nextVarBytes(source); err != nil {
+		return err
+	}
+	var eof bool
+	this.Height, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.WriteHeight, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof || n > source.Len()/common.UINT256_SIZE {
+		return io.ErrUnexpectedEOF
+	}
+	this.TreeHashes = make([]common.Uint256, 0, n)
+	for i := uint64(0); i < n; i++ {
+		hash, _ := source.NextHash()
+		this.TreeHashes = append(this.TreeHashes, hash)
+	}
+	n, _, irregular, eof = source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	// every write set takes one byte at least
+	if eof || n > source.Len() {
+		return io.ErrUnexpectedEOF
+	}
+	this.WriteSets = make([]WriteSet, n)
+	for i := range this.WriteSets {
+		if err = this.WriteSets[i].Deserialization(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks the proof against the state merkle root of block Height. stateHashCheckHeight is the height
+// the state merkle tree starts from, see config.GetStateHashCheckHeight.
+func (this *StateProof) Verify(root common.Uint256, stateHashCheckHeight uint32) error {
+	if this.WriteHeight <= stateHashCheckHeight || this.WriteHeight > this.Height {
+		return fmt.Errorf("invalid write height %d for proof at height %d", this.WriteHeight, this.Height)
+	}
+	if uint64(len(this.WriteSets)) != uint64(this.Height-this.WriteHeight)+1 {
+		return fmt.Errorf("proof should contain the write sets from height %d to %d", this.WriteHeight, this.Height)
+	}
+	treeSize := this.WriteHeight - stateHashCheckHeight
+	if len(this.TreeHashes) != bits.OnesCount32(treeSize) {
+		return fmt.Errorf("invalid state merkle tree hashes of size %d", treeSize)
+	}
+	for i, writeSet := range this.WriteSets {
+		value, found := writeSet.get(this.Key)
+		if i == 0 {
+			if !found {
+				return errors.New("key not found in write set")
+			}
+			if !bytes.Equal(value, this.Value) {
+				return errors.New("value mismatch with write set")
+			}
+		} else if found {
+			return fmt.Errorf("key is written again at height %d", this.WriteHeight+uint32(i))
+		}
+	}
+
+	tree := merkle.NewTree(treeSize, append([]common.Uint256{}, this.TreeHashes...), nil)
+	for _, writeSet := range this.WriteSets {
+		tree.AppendHash(writeSet.Hash())
+	}
+	if tree.Root() != root {
+		return errors.New("state merkle root mismatch")
+	}
+	return nil
+}
+
+func (this WriteSet) get(key []byte) ([]byte, bool) {
+	for _, entry := range this {
+		if bytes.Equal(entry.Key, key) {
+			return entry.Value, true
+		}
+	}
+	return nil, false
+}
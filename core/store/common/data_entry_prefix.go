@@ -23,10 +23,14 @@ type DataEntryPrefix byte
 
 const (
 	// DATA
-	DATA_BLOCK_HASH        DataEntryPrefix = 0x00 //Block height => block hash key prefix
-	DATA_HEADER                            = 0x01 //Block hash => block header+txhashes key prefix
-	DATA_TRANSACTION                       = 0x02 //Transction hash => transaction key prefix
-	DATA_STATE_MERKLE_ROOT                 = 0x21 // block height => write set hash + state merkle root
+	DATA_BLOCK_HASH          DataEntryPrefix = 0x00 //Block height => block hash key prefix
+	DATA_HEADER                              = 0x01 //Block hash => block header+txhashes key prefix
+	DATA_TRANSACTION                         = 0x02 //Transction hash => transaction key prefix
+	DATA_STATE_MERKLE_ROOT                   = 0x21 // block height => write set hash + state merkle root
+	DATA_WRITE_SET                           = 0x23 // block height => write set of block, saved if state proof enabled
+	DATA_STATE_HISTORY                       = 0x24 // key + inverted block height => value written at that height
+	DATA_STATE_UNDO_LOG                      = 0x26 // block height => state before the block is saved, used to roll back
+	DATA_STATE_HISTORY_INDEX                 = 0x27 // key => keys written in state history, saved in archive mode
 
 	// Transaction
 	ST_BOOKKEEPER DataEntryPrefix = 0x03 //BookKeeper state key prefix
@@ -39,6 +43,13 @@ const (
 	ST_ETH_ACCOUNT DataEntryPrefix = 0x31 // eth account: address -> [nonce, codeHash]
 
 	IX_HEADER_HASH_LIST DataEntryPrefix = 0x09 //Block height => block hash key prefix
+	IX_ADDRESS_TX       DataEntryPrefix = 0x15 // address + inverted block height + inverted tx index => tx hash
+	IX_ADDRESS_TX_BLOCK DataEntryPrefix = 0x16 // block height => address index entries of the block, used to prune
+
+	IX_CONTRACT_EVENT       DataEntryPrefix = 0x17 // contract + block height + tx index + notify index => tx hash
+	IX_CONTRACT_EVENT_NAME  DataEntryPrefix = 0x18 // contract + event name + block height + tx index + notify index => tx hash
+	IX_CONTRACT_EVENT_BLOCK DataEntryPrefix = 0x19 // block height => contract event index keys of the block, used to prune
+	IX_CONTRACT_EVENT_RANGE DataEntryPrefix = 0x1a // first and last block heights indexed continuously by contract event index
 
 	//SYSTEM
 	SYS_CURRENT_BLOCK        DataEntryPrefix = 0x10 //Current block key prefix
@@ -47,6 +58,7 @@ const (
 	SYS_BLOCK_MERKLE_TREE    DataEntryPrefix = 0x13 // Block merkle tree root key prefix
 	SYS_STATE_MERKLE_TREE    DataEntryPrefix = 0x20 // state merkle tree root key prefix
 	SYS_CROSS_CHAIN_MSG      DataEntryPrefix = 0x22 // state merkle tree root key prefix
+	SYS_STATE_HISTORY_HEIGHT DataEntryPrefix = 0x25 // first block height recorded in state history
 
 	EVENT_NOTIFY DataEntryPrefix = 0x14 //Event notify key prefix
 
@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//Package kvstore creates the key-value store backend of ledger by name
+package kvstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/pebblestore"
+)
+
+const (
+	BACKEND_LEVELDB = "leveldb"
+	BACKEND_PEBBLE  = "pebble"
+
+	DEFAULT_BACKEND = BACKEND_LEVELDB
+)
+
+//Backends return all supported backend names
+func Backends() []string {
+	return []string{BACKEND_LEVELDB, BACKEND_PEBBLE}
+}
+
+//IsValidBackend return whether the backend name is supported
+func IsValidBackend(backend string) bool {
+	for _, name := range Backends() {
+		if strings.ToLower(backend) == name {
+			return true
+		}
+	}
+	return false
+}
+
+//NewPersistStore open the store at path with the given backend. Empty backend means DEFAULT_BACKEND.
+//Opening a store created by another backend is refused, use migrate command to convert it
+func NewPersistStore(backend, path string) (common.PersistStore, error) {
+	backend = strings.ToLower(backend)
+	if backend == "" {
+		backend = DEFAULT_BACKEND
+	}
+	if !IsValidBackend(backend) {
+		return nil, fmt.Errorf("unsupported db backend %q, should be one of %s", backend, strings.Join(Backends(), ", "))
+	}
+	if err := RecoverMigration(path); err != nil {
+		return nil, err
+	}
+	exist, err := DetectBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	if exist != "" && exist != backend {
+		return nil, fmt.Errorf("db %s is created by %s backend, but %s is configured", path, exist, backend)
+	}
+	switch backend {
+	case BACKEND_PEBBLE:
+		return pebblestore.NewPebbleStore(path)
+	default:
+		return leveldbstore.NewLevelDBStore(path)
+	}
+}
+
+//DetectBackend return the backend which created the store at path, or empty string if there is no store.
+//Pebble always writes OPTIONS files while leveldb never does
+func DetectBackend(path string) (string, error) {
+	_, err := os.Stat(filepath.Join(path, "CURRENT"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	options, err := filepath.Glob(filepath.Join(path, "OPTIONS-*"))
+	if err != nil {
+		return "", err
+	}
+	if len(options) > 0 {
+		return BACKEND_PEBBLE, nil
+	}
+	return BACKEND_LEVELDB, nil
+}
+
+//MigratingPath return the path the store at path is copied to while migrating
+func MigratingPath(path string) string {
+	return path + ".migrating"
+}
+
+//BackupPath return the path the store at path created by backend is kept at after migrating
+func BackupPath(path, backend string) string {
+	return fmt.Sprintf("%s.%s.bak", path, backend)
+}
+
+//RecoverMigration finishes the store swap of a migration interrupted between moving the old store to
+//its backup and moving the migrated copy into place. The copy is complete once the old store is moved,
+//so it is moved to path if path is missing while both the copy and the backup exist
+func RecoverMigration(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return err
+	}
+	migrating := MigratingPath(path)
+	if _, err := os.Stat(migrating); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	backups, err := filepath.Glob(BackupPath(path, "*"))
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return nil
+	}
+	log.Warnf("recover interrupted migration of %s from %s", path, backups[0])
+	return os.Rename(migrating, path)
+}
+
+//Copy copies all key-value pairs of src into dst, committing every batchSize keys.
+//It returns the number of copied keys
+func Copy(dst, src common.PersistStore, batchSize int) (uint64, error) {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	iter := src.NewIterator(nil)
+	defer iter.Release()
+
+	count := uint64(0)
+	pending := 0
+	dst.NewBatch()
+	for iter.Next() {
+		// key and value are reused by iterator, batch copies them
+		dst.BatchPut(iter.Key(), iter.Value())
+		count++
+		pending++
+		if pending == batchSize {
+			if err := dst.BatchCommit(); err != nil {
+				return count, err
+			}
+			dst.NewBatch()
+			pending = 0
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return count, err
+	}
+	return count, dst.BatchCommit()
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package kvstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyBetweenBackends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvstore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	levelPath := filepath.Join(dir, "leveldb")
+	pebblePath := filepath.Join(dir, "pebble")
+
+	src, err := NewPersistStore(BACKEND_LEVELDB, levelPath)
+	assert.Nil(t, err)
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, src.Put([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%d", i))))
+	}
+
+	dst, err := NewPersistStore(BACKEND_PEBBLE, pebblePath)
+	assert.Nil(t, err)
+	count, err := Copy(dst, src, 7)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100), count)
+
+	for i := 0; i < 100; i++ {
+		val, err := dst.Get([]byte(fmt.Sprintf("key%03d", i)))
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("value%d", i), string(val))
+	}
+	assert.Nil(t, src.Close())
+	assert.Nil(t, dst.Close())
+
+	backend, err := DetectBackend(levelPath)
+	assert.Nil(t, err)
+	assert.Equal(t, BACKEND_LEVELDB, backend)
+	backend, err = DetectBackend(pebblePath)
+	assert.Nil(t, err)
+	assert.Equal(t, BACKEND_PEBBLE, backend)
+	backend, err = DetectBackend(filepath.Join(dir, "none"))
+	assert.Nil(t, err)
+	assert.Equal(t, "", backend)
+
+	_, err = NewPersistStore(BACKEND_LEVELDB, pebblePath)
+	assert.NotNil(t, err)
+	_, err = NewPersistStore("rocksdb", levelPath)
+	assert.NotNil(t, err)
+}
+
+func TestRecoverMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvstore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "store")
+	store, err := NewPersistStore(BACKEND_PEBBLE, MigratingPath(path))
+	assert.Nil(t, err)
+	assert.Nil(t, store.Put([]byte("key"), []byte("value")))
+	assert.Nil(t, store.Close())
+
+	// no backup, the migration did not reach the swap
+	assert.Nil(t, RecoverMigration(path))
+	backend, err := DetectBackend(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "", backend)
+
+	// crashed after moving the old store to its backup
+	assert.Nil(t, os.MkdirAll(BackupPath(path, BACKEND_LEVELDB), 0755))
+	store, err = NewPersistStore(BACKEND_PEBBLE, path)
+	assert.Nil(t, err)
+	val, err := store.Get([]byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(val))
+	assert.Nil(t, store.Close())
+	_, err = os.Stat(MigratingPath(path))
+	assert.True(t, os.IsNotExist(err))
+}
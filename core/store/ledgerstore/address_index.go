@@ -0,0 +1,188 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/ontio/ontology/smartcontract/service/native/ont"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+var ErrAddressIndexDisabled = errors.New("address index is not enabled, restart node with --enable-address-index")
+
+type addressIndexEntry struct {
+	addr    common.Address
+	txIndex uint32
+}
+
+// SaveAddressIndex indexes the transactions of the block by the addresses they touch
+func (this *EventStore) SaveAddressIndex(block *types.Block, notifies []*event.ExecuteNotify) {
+	height := block.Header.Height
+	entries := make([]addressIndexEntry, 0, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		var notify *event.ExecuteNotify
+		if i < len(notifies) {
+			notify = notifies[i]
+		}
+		txHash := tx.Hash()
+		for _, addr := range touchedAddresses(tx, notify) {
+			this.store.BatchPut(genAddressTxKey(addr, height, uint32(i)), txHash.ToArray())
+			entries = append(entries, addressIndexEntry{addr: addr, txIndex: uint32(i)})
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(uint32(len(entries)))
+	for _, entry := range entries {
+		sink.WriteAddress(entry.addr)
+		sink.WriteUint32(entry.txIndex)
+	}
+	this.store.BatchPut(genAddressTxBlockKey(height), sink.Bytes())
+}
+
+// GetAddressTxs return the indexed transactions of the address, the latest first
+func (this *EventStore) GetAddressTxs(addr common.Address, skip, limit uint32) ([]*scom.AddressTx, error) {
+	iter := this.store.NewIterator(genAddressTxPrefix(addr))
+	defer iter.Release()
+	txs := make([]*scom.AddressTx, 0)
+	for iter.Next() && uint32(len(txs)) < limit {
+		if skip > 0 {
+			skip--
+			continue
+		}
+		key := iter.Key()
+		if len(key) != 1+common.ADDR_LEN+8 {
+			return nil, fmt.Errorf("invalid address index key %x", key)
+		}
+		txHash, err := common.Uint256ParseFromBytes(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, &scom.AddressTx{
+			TxHash:  txHash,
+			Height:  ^binary.BigEndian.Uint32(key[1+common.ADDR_LEN:]),
+			TxIndex: ^binary.BigEndian.Uint32(key[1+common.ADDR_LEN+4:]),
+		})
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+func (this *EventStore) pruneAddressIndex(height uint32) error {
+	key := genAddressTxBlockKey(height)
+	data, err := this.store.Get(key)
+	if err == scom.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	source := common.NewZeroCopySource(data)
+	count, eof := source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	for i := uint32(0); i < count; i++ {
+		addr, eof := source.NextAddress()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		txIndex, eof := source.NextUint32()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		this.store.BatchDelete(genAddressTxKey(addr, height, txIndex))
+	}
+	this.store.BatchDelete(key)
+	return nil
+}
+
+// touchedAddresses return the payer, the signers, and the from/to of the ONT/ONG transfer notifications
+// of the transaction
+func touchedAddresses(tx *types.Transaction, notify *event.ExecuteNotify) []common.Address {
+	addrs := make([]common.Address, 0, 1+len(tx.Sigs))
+	seen := make(map[common.Address]bool)
+	add := func(addr common.Address) {
+		if addr != common.ADDRESS_EMPTY && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	add(tx.Payer)
+	for _, addr := range tx.GetSignatureAddresses() {
+		add(addr)
+	}
+	if notify == nil {
+		return addrs
+	}
+	for _, n := range notify.Notify {
+		if n.ContractAddress != utils.OntContractAddress && n.ContractAddress != utils.OngContractAddress {
+			continue
+		}
+		states, ok := n.States.([]interface{})
+		if !ok || len(states) < 3 {
+			continue
+		}
+		if name, _ := states[0].(string); name != ont.TRANSFER_NAME {
+			continue
+		}
+		for _, state := range states[1:3] {
+			str, _ := state.(string)
+			if addr, err := common.AddressFromBase58(str); err == nil {
+				add(addr)
+			}
+		}
+	}
+	return addrs
+}
+
+func genAddressTxPrefix(addr common.Address) []byte {
+	key := make([]byte, 1+common.ADDR_LEN)
+	key[0] = byte(scom.IX_ADDRESS_TX)
+	copy(key[1:], addr[:])
+	return key
+}
+
+// the height and tx index are inverted so that iterating the address prefix yields the latest transaction first
+func genAddressTxKey(addr common.Address, height, txIndex uint32) []byte {
+	key := make([]byte, 1+common.ADDR_LEN+8)
+	key[0] = byte(scom.IX_ADDRESS_TX)
+	copy(key[1:], addr[:])
+	binary.BigEndian.PutUint32(key[1+common.ADDR_LEN:], ^height)
+	binary.BigEndian.PutUint32(key[1+common.ADDR_LEN+4:], ^txIndex)
+	return key
+}
+
+func genAddressTxBlockKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.IX_ADDRESS_TX_BLOCK)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
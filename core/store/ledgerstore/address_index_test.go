@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPayerTransaction(t *testing.T, payer common.Address, nonce uint32) *types.Transaction {
+	tx := &types.MutableTransaction{
+		TxType:  types.InvokeNeo,
+		Nonce:   nonce,
+		Payer:   payer,
+		Payload: &payload.InvokeCode{Code: []byte{1}},
+		Sigs:    make([]types.Sig, 0),
+	}
+	res, err := tx.IntoImmutable()
+	assert.Nil(t, err)
+	return res
+}
+
+func TestAddressIndex(t *testing.T) {
+	store := &EventStore{store: leveldbstore.NewMemLevelDBStore()}
+	alice := common.AddressFromVmCode([]byte("alice"))
+	bob := common.AddressFromVmCode([]byte("bob"))
+	other := common.AddressFromVmCode([]byte("other"))
+
+	tx1 := newPayerTransaction(t, alice, 1)
+	tx2 := newPayerTransaction(t, bob, 2)
+	block1 := &types.Block{Header: &types.Header{Height: 1}, Transactions: []*types.Transaction{tx1, tx2}}
+	notifies1 := []*event.ExecuteNotify{
+		{Notify: []*event.NotifyEventInfo{
+			{ContractAddress: utils.OngContractAddress, States: []interface{}{"transfer", alice.ToBase58(), bob.ToBase58(), uint64(1)}},
+		}},
+		{Notify: []*event.NotifyEventInfo{
+			// transfer of other contracts is not indexed
+			{ContractAddress: other, States: []interface{}{"transfer", bob.ToBase58(), other.ToBase58(), uint64(1)}},
+		}},
+	}
+	tx3 := newPayerTransaction(t, alice, 3)
+	block2 := &types.Block{Header: &types.Header{Height: 2}, Transactions: []*types.Transaction{tx3}}
+
+	store.NewBatch()
+	store.SaveAddressIndex(block1, notifies1)
+	store.SaveAddressIndex(block2, []*event.ExecuteNotify{{}})
+	assert.Nil(t, store.CommitTo())
+
+	txs, err := store.GetAddressTxs(alice, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(txs))
+	assert.Equal(t, tx3.Hash(), txs[0].TxHash)
+	assert.Equal(t, uint32(2), txs[0].Height)
+	assert.Equal(t, tx1.Hash(), txs[1].TxHash)
+
+	txs, err = store.GetAddressTxs(bob, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(txs))
+	assert.Equal(t, tx2.Hash(), txs[0].TxHash)
+	assert.Equal(t, uint32(1), txs[0].TxIndex)
+	assert.Equal(t, tx1.Hash(), txs[1].TxHash)
+	assert.Equal(t, uint32(0), txs[1].TxIndex)
+
+	txs, err = store.GetAddressTxs(bob, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, tx1.Hash(), txs[0].TxHash)
+
+	txs, err = store.GetAddressTxs(other, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(txs))
+
+	store.NewBatch()
+	store.PruneBlock(1, []common.Uint256{tx1.Hash(), tx2.Hash()})
+	assert.Nil(t, store.CommitTo())
+
+	txs, err = store.GetAddressTxs(alice, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, tx3.Hash(), txs[0].TxHash)
+	txs, err = store.GetAddressTxs(bob, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(txs))
+}
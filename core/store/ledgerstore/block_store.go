@@ -27,16 +27,15 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/serialization"
 	scom "github.com/ontio/ontology/core/store/common"
-	"github.com/ontio/ontology/core/store/leveldbstore"
 	"github.com/ontio/ontology/core/types"
 )
 
 //Block store save the data of block & transaction
 type BlockStore struct {
-	enableCache bool                       //Is enable lru cache
-	dbDir       string                     //The path of store file
-	cache       *BlockCache                //The cache of block, if have.
-	store       *leveldbstore.LevelDBStore //block store handler
+	enableCache bool              //Is enable lru cache
+	dbDir       string            //The path of store file
+	cache       *BlockCache       //The cache of block, if have.
+	store       scom.PersistStore //block store handler
 }
 
 //NewBlockStore return the block store instance
@@ -50,7 +49,7 @@ func NewBlockStore(dbDir string, enableCache bool) (*BlockStore, error) {
 		}
 	}
 
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := newPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -140,9 +139,14 @@ func (this *BlockStore) loadHeaderWithTx(blockHash common.Uint256) (*types.Heade
 	if err != nil {
 		return nil, nil, err
 	}
+	return deserializeHeaderWithTx(value)
+}
+
+//deserializeHeaderWithTx parse the header record saved by SaveHeader
+func deserializeHeaderWithTx(value []byte) (*types.Header, []common.Uint256, error) {
 	source := common.NewZeroCopySource(value)
 	sysFee := new(common.Fixed64)
-	err = sysFee.Deserialization(source)
+	err := sysFee.Deserialization(source)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,312 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+)
+
+var ErrContractEventIndexDisabled = errors.New("contract event index is not enabled, restart node with --enable-contract-event-index")
+
+// event names longer than this are only indexed by contract
+const maxIndexedEventNameLen = 256
+
+// SaveContractEventIndex indexes the notifications of the block by contract address and by contract address
+// and event name. The index restarts from the block if the blocks before were not indexed.
+func (this *EventStore) SaveContractEventIndex(height uint32, notifies []*event.ExecuteNotify) error {
+	first, last, err := this.getBatchContractEventIndexRange()
+	if err == scom.ErrNotFound || (err == nil && (height < first || height > last+1)) {
+		first = height
+	} else if err != nil {
+		return err
+	}
+	this.putContractEventIndexRange(first, height)
+
+	keys := make([][]byte, 0)
+	for _, notify := range notifies {
+		txHash := notify.TxHash.ToArray()
+		for i, n := range notify.Notify {
+			key := genContractEventKey(n.ContractAddress, height, notify.TxIndex, uint32(i))
+			this.store.BatchPut(key, txHash)
+			keys = append(keys, key)
+			name := eventName(n)
+			if len(name) == 0 || len(name) > maxIndexedEventNameLen {
+				continue
+			}
+			key = genContractEventNameKey(n.ContractAddress, name, height, notify.TxIndex, uint32(i))
+			this.store.BatchPut(key, txHash)
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(uint32(len(keys)))
+	for _, key := range keys {
+		sink.WriteVarBytes(key)
+	}
+	this.store.BatchPut(genContractEventBlockKey(height), sink.Bytes())
+	return nil
+}
+
+// getContractEventIndexRange return the first and last block heights indexed continuously
+func (this *EventStore) getContractEventIndexRange() (uint32, uint32, error) {
+	data, err := this.store.Get(genContractEventRangeKey())
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(data) != 8 {
+		return 0, 0, fmt.Errorf("invalid contract event index range")
+	}
+	return binary.LittleEndian.Uint32(data), binary.LittleEndian.Uint32(data[4:]), nil
+}
+
+// contractEventIndexRange is the index range written in the current batch, which is not readable from the
+// store before committed
+type contractEventIndexRange struct {
+	first, last uint32
+	deleted     bool
+}
+
+// getBatchContractEventIndexRange return the index range including the changes of the current batch
+func (this *EventStore) getBatchContractEventIndexRange() (uint32, uint32, error) {
+	if r := this.eventIndexRange; r != nil {
+		if r.deleted {
+			return 0, 0, scom.ErrNotFound
+		}
+		return r.first, r.last, nil
+	}
+	return this.getContractEventIndexRange()
+}
+
+func (this *EventStore) putContractEventIndexRange(first, last uint32) {
+	this.store.BatchPut(genContractEventRangeKey(), encodeContractEventIndexRange(first, last))
+	this.eventIndexRange = &contractEventIndexRange{first: first, last: last}
+}
+
+func (this *EventStore) deleteContractEventIndexRange() {
+	this.store.BatchDelete(genContractEventRangeKey())
+	this.eventIndexRange = &contractEventIndexRange{deleted: true}
+}
+
+func encodeContractEventIndexRange(first, last uint32) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint32(buf[:], first)
+	binary.LittleEndian.PutUint32(buf[4:], last)
+	return buf[:]
+}
+
+// GetContractEvents return the notifications of the contract between the block heights, the earliest first.
+// An empty event name matches every event of the contract. A start height of 0 queries from the first indexed block,
+// scom.ErrContractEventNotIndexed is returned if the start height is before it.
+func (this *EventStore) GetContractEvents(contract common.Address, name string, startHeight, endHeight uint32,
+	skip, limit uint32) ([]*scom.ContractEvent, error) {
+	first, _, err := this.getContractEventIndexRange()
+	if err == scom.ErrNotFound {
+		return nil, scom.ErrContractEventNotIndexed
+	} else if err != nil {
+		return nil, err
+	}
+	if startHeight == 0 {
+		startHeight = first
+	} else if startHeight < first {
+		return nil, scom.ErrContractEventNotIndexed
+	}
+
+	var prefix []byte
+	if len(name) == 0 {
+		prefix = genContractEventPrefix(contract)
+	} else {
+		prefix = genContractEventNamePrefix(contract, name)
+	}
+	seekKey := appendEventPosition(prefix, startHeight, 0, 0)
+
+	iter := this.store.NewIterator(prefix)
+	defer iter.Release()
+
+	var found bool
+	if seeker, ok := iter.(interface{ Seek(key []byte) bool }); ok {
+		found = seeker.Seek(seekKey)
+	} else {
+		for found = iter.First(); found && bytes.Compare(iter.Key(), seekKey) < 0; found = iter.Next() {
+		}
+	}
+
+	var notify *event.ExecuteNotify
+	events := make([]*scom.ContractEvent, 0)
+	for ; found && uint32(len(events)) < limit; found = iter.Next() {
+		key := iter.Key()
+		if len(key) != len(prefix)+12 {
+			return nil, fmt.Errorf("invalid contract event index key %x", key)
+		}
+		height := binary.BigEndian.Uint32(key[len(prefix):])
+		if height > endHeight {
+			break
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		txHash, err := common.Uint256ParseFromBytes(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		if notify == nil || notify.TxHash != txHash {
+			notify, err = this.GetEventNotifyByTx(txHash)
+			if err != nil {
+				return nil, fmt.Errorf("get event notify of tx %s error: %s", txHash.ToHexString(), err)
+			}
+		}
+		index := binary.BigEndian.Uint32(key[len(prefix)+8:])
+		if index >= uint32(len(notify.Notify)) {
+			return nil, fmt.Errorf("notify index %d out of range in tx %s", index, txHash.ToHexString())
+		}
+		events = append(events, &scom.ContractEvent{
+			TxHash:  txHash,
+			Height:  height,
+			TxIndex: binary.BigEndian.Uint32(key[len(prefix)+4:]),
+			Notify:  notify.Notify[index],
+		})
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// pruneContractEventIndex removes the index of the block and shrinks the index range. The blocks are pruned from
+// the first one, and rolled back from the block above the rollback height, which drops all the blocks after it.
+func (this *EventStore) pruneContractEventIndex(height uint32) error {
+	first, last, err := this.getBatchContractEventIndexRange()
+	if err == nil && height >= first && height <= last {
+		if height == first && height < last {
+			this.putContractEventIndexRange(height+1, last)
+		} else if height > first {
+			this.putContractEventIndexRange(first, height-1)
+		} else {
+			this.deleteContractEventIndexRange()
+		}
+	} else if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+
+	key := genContractEventBlockKey(height)
+	data, err := this.store.Get(key)
+	if err == scom.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	source := common.NewZeroCopySource(data)
+	count, eof := source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	for i := uint32(0); i < count; i++ {
+		indexKey, err := source.ReadVarBytes()
+		if err != nil {
+			return err
+		}
+		this.store.BatchDelete(indexKey)
+	}
+	this.store.BatchDelete(key)
+	return nil
+}
+
+// eventName return the name of the notification: topic0 of evm logs, or the first state element of
+// NeoVM/Wasm/native notifications if it is a string
+func eventName(n *event.NotifyEventInfo) string {
+	if n.IsEvm {
+		var raw []byte
+		switch states := n.States.(type) {
+		case hexutil.Bytes:
+			raw = states
+		case string:
+			// decoded from the json persisted in event store
+			raw, _ = hexutil.Decode(states)
+		}
+		var storageLog types.StorageLog
+		if err := storageLog.Deserialization(common.NewZeroCopySource(raw)); err != nil || len(storageLog.Topics) == 0 {
+			return ""
+		}
+		return storageLog.Topics[0].Hex()
+	}
+	states, ok := n.States.([]interface{})
+	if !ok || len(states) == 0 {
+		return ""
+	}
+	name, _ := states[0].(string)
+	return name
+}
+
+func genContractEventPrefix(contract common.Address) []byte {
+	key := make([]byte, 1+common.ADDR_LEN)
+	key[0] = byte(scom.IX_CONTRACT_EVENT)
+	copy(key[1:], contract[:])
+	return key
+}
+
+// the name length is prefixed so that the events of one name are not interleaved with names it prefixes
+func genContractEventNamePrefix(contract common.Address, name string) []byte {
+	key := make([]byte, 1+common.ADDR_LEN+2+len(name))
+	key[0] = byte(scom.IX_CONTRACT_EVENT_NAME)
+	copy(key[1:], contract[:])
+	binary.BigEndian.PutUint16(key[1+common.ADDR_LEN:], uint16(len(name)))
+	copy(key[1+common.ADDR_LEN+2:], name)
+	return key
+}
+
+func appendEventPosition(prefix []byte, height, txIndex, notifyIndex uint32) []byte {
+	key := make([]byte, len(prefix)+12)
+	copy(key, prefix)
+	binary.BigEndian.PutUint32(key[len(prefix):], height)
+	binary.BigEndian.PutUint32(key[len(prefix)+4:], txIndex)
+	binary.BigEndian.PutUint32(key[len(prefix)+8:], notifyIndex)
+	return key
+}
+
+func genContractEventKey(contract common.Address, height, txIndex, notifyIndex uint32) []byte {
+	return appendEventPosition(genContractEventPrefix(contract), height, txIndex, notifyIndex)
+}
+
+func genContractEventNameKey(contract common.Address, name string, height, txIndex, notifyIndex uint32) []byte {
+	return appendEventPosition(genContractEventNamePrefix(contract, name), height, txIndex, notifyIndex)
+}
+
+func genContractEventRangeKey() []byte {
+	return []byte{byte(scom.IX_CONTRACT_EVENT_RANGE)}
+}
+
+func genContractEventBlockKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.IX_CONTRACT_EVENT_BLOCK)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
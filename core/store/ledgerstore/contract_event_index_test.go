@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"math"
+	"testing"
+
+	ethcomm "github.com/ethereum/go-ethereum/common"
+	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractEventIndex(t *testing.T) {
+	store := &EventStore{store: leveldbstore.NewMemLevelDBStore()}
+	token := common.AddressFromVmCode([]byte("token"))
+	other := common.AddressFromVmCode([]byte("other"))
+	topic := ethcomm.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+	blocks := map[uint32][]*event.ExecuteNotify{
+		1: {
+			{TxHash: common.Uint256{1}, TxIndex: 0, Notify: []*event.NotifyEventInfo{
+				{ContractAddress: token, States: []interface{}{"transfer", "a", "b"}},
+				{ContractAddress: other, States: []interface{}{"transfer", "a", "b"}},
+				{ContractAddress: token, States: []interface{}{"approve", "a", "b"}},
+			}},
+		},
+		2: {
+			{TxHash: common.Uint256{2}, TxIndex: 0},
+			{TxHash: common.Uint256{3}, TxIndex: 1, Notify: []*event.NotifyEventInfo{
+				event.NotifyEventInfoFromEvmLog(&types.StorageLog{Address: ethcomm.Address(token), Topics: []ethcomm.Hash{topic}}),
+			}},
+		},
+		3: {
+			{TxHash: common.Uint256{4}, TxIndex: 0, Notify: []*event.NotifyEventInfo{
+				{ContractAddress: token, States: []interface{}{"transfer", "b", "c"}},
+			}},
+		},
+	}
+	saveBlock := func(height uint32) {
+		store.NewBatch()
+		for _, notify := range blocks[height] {
+			assert.Nil(t, store.SaveEventNotifyByTx(notify.TxHash, notify))
+		}
+		assert.Nil(t, store.SaveContractEventIndex(height, blocks[height]))
+		assert.Nil(t, store.CommitTo())
+	}
+	_, err := store.GetContractEvents(token, "", 0, math.MaxUint32, 0, 10)
+	assert.Equal(t, scom.ErrContractEventNotIndexed, err)
+	for height := uint32(1); height <= 3; height++ {
+		saveBlock(height)
+	}
+
+	events, err := store.GetContractEvents(token, "", 0, math.MaxUint32, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(events))
+	assert.Equal(t, common.Uint256{1}, events[0].TxHash)
+	assert.Equal(t, common.Uint256{1}, events[1].TxHash)
+	assert.Equal(t, "approve", events[1].Notify.States.([]interface{})[0])
+	assert.Equal(t, common.Uint256{3}, events[2].TxHash)
+	assert.Equal(t, uint32(1), events[2].TxIndex)
+	assert.Equal(t, common.Uint256{4}, events[3].TxHash)
+
+	events, err = store.GetContractEvents(token, "transfer", 1, math.MaxUint32, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, uint32(1), events[0].Height)
+	assert.Equal(t, uint32(3), events[1].Height)
+
+	events, err = store.GetContractEvents(token, "transfer", 2, 3, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, common.Uint256{4}, events[0].TxHash)
+
+	events, err = store.GetContractEvents(token, "", 1, 2, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, common.Uint256{1}, events[0].TxHash)
+	assert.Equal(t, common.Uint256{3}, events[1].TxHash)
+
+	events, err = store.GetContractEvents(token, topic.Hex(), 1, math.MaxUint32, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, common.Uint256{3}, events[0].TxHash)
+
+	store.NewBatch()
+	store.PruneBlock(1, []common.Uint256{{1}})
+	assert.Nil(t, store.CommitTo())
+
+	_, err = store.GetContractEvents(token, "", 1, math.MaxUint32, 0, 10)
+	assert.Equal(t, scom.ErrContractEventNotIndexed, err)
+	events, err = store.GetContractEvents(token, "", 0, math.MaxUint32, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+	events, err = store.GetContractEvents(other, "transfer", 0, math.MaxUint32, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(events))
+
+	// the index restarts after the blocks not indexed
+	blocks[5] = blocks[3]
+	saveBlock(5)
+	_, err = store.GetContractEvents(token, "", 3, math.MaxUint32, 0, 10)
+	assert.Equal(t, scom.ErrContractEventNotIndexed, err)
+	events, err = store.GetContractEvents(token, "", 0, math.MaxUint32, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, uint32(5), events[0].Height)
+
+	// the range is removed with its only block
+	store.NewBatch()
+	store.PruneBlock(5, []common.Uint256{{4}})
+	assert.Nil(t, store.CommitTo())
+	_, err = store.GetContractEvents(token, "", 0, math.MaxUint32, 0, 10)
+	assert.Equal(t, scom.ErrContractEventNotIndexed, err)
+	for height := uint32(5); height <= 7; height++ {
+		blocks[height] = nil
+		saveBlock(height)
+	}
+	// pruned with the next block indexed in the same batch
+	store.NewBatch()
+	store.PruneBlock(5, nil)
+	store.PruneBlock(6, nil)
+	assert.Nil(t, store.SaveContractEventIndex(8, nil))
+	assert.Nil(t, store.CommitTo())
+	first, last, err := store.getContractEventIndexRange()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(7), first)
+	assert.Equal(t, uint32(8), last)
+
+	// rolled back to block 7
+	for height := uint32(9); height <= 10; height++ {
+		saveBlock(height)
+	}
+	store.NewBatch()
+	for height := uint32(8); height <= 10; height++ {
+		store.PruneBlock(height, nil)
+	}
+	assert.Nil(t, store.CommitTo())
+	first, last, err = store.getContractEventIndexRange()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(7), first)
+	assert.Equal(t, uint32(7), last)
+}
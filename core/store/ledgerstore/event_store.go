@@ -28,7 +28,6 @@ import (
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/common/serialization"
 	scom "github.com/ontio/ontology/core/store/common"
-	"github.com/ontio/ontology/core/store/leveldbstore"
 	"github.com/ontio/ontology/smartcontract/event"
 )
 
@@ -38,13 +37,15 @@ var UseNumber = false
 
 //Saving event notifies gen by smart contract execution
 type EventStore struct {
-	dbDir string                     //Store path
-	store *leveldbstore.LevelDBStore //Store handler
+	dbDir string            //Store path
+	store scom.PersistStore //Store handler
+
+	eventIndexRange *contractEventIndexRange //contract event index range written in current batch, nil if not written
 }
 
 //NewEventStore return event store instance
 func NewEventStore(dbDir string) (*EventStore, error) {
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := newPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +58,7 @@ func NewEventStore(dbDir string) (*EventStore, error) {
 //NewBatch start event commit batch
 func (this *EventStore) NewBatch() {
 	this.store.NewBatch()
+	this.eventIndexRange = nil
 }
 
 //SaveEventNotifyByTx persist event notify by transaction hash
@@ -103,6 +105,24 @@ func (this *EventStore) GetEventNotifyByTx(txHash common.Uint256) (*event.Execut
 
 //GetEventNotifyByBlock return all event notify of transaction in block
 func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error) {
+	txHashes, err := this.getTxHashesByBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	evtNotifies := make([]*event.ExecuteNotify, 0)
+	for _, txHash := range txHashes {
+		evtNotify, err := this.GetEventNotifyByTx(txHash)
+		if err != nil {
+			log.Errorf("getEventNotifyByTx Height:%d by txhash:%s error:%s", height, txHash.ToHexString(), err)
+			continue
+		}
+		evtNotifies = append(evtNotifies, evtNotify)
+	}
+	return evtNotifies, nil
+}
+
+//getTxHashesByBlock return the hashes of transactions in block saved by SaveEventNotifyByBlock
+func (this *EventStore) getTxHashesByBlock(height uint32) ([]common.Uint256, error) {
 	key := genEventNotifyByBlockKey(height)
 	data, err := this.store.Get(key)
 	if err != nil {
@@ -113,24 +133,25 @@ func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNo
 	if err != nil {
 		return nil, fmt.Errorf("ReadUint32 error %s", err)
 	}
-	evtNotifies := make([]*event.ExecuteNotify, 0)
+	txHashes := make([]common.Uint256, 0, size)
 	for i := uint32(0); i < size; i++ {
 		var txHash common.Uint256
 		err = txHash.Deserialize(reader)
 		if err != nil {
 			return nil, fmt.Errorf("txHash.Deserialize error %s", err)
 		}
-		evtNotify, err := this.GetEventNotifyByTx(txHash)
-		if err != nil {
-			log.Errorf("getEventNotifyByTx Height:%d by txhash:%s error:%s", height, txHash.ToHexString(), err)
-			continue
-		}
-		evtNotifies = append(evtNotifies, evtNotify)
+		txHashes = append(txHashes, txHash)
 	}
-	return evtNotifies, nil
+	return txHashes, nil
 }
 
 func (this *EventStore) PruneBlock(height uint32, hashes []common.Uint256) {
+	if err := this.pruneAddressIndex(height); err != nil {
+		log.Errorf("prune address index of block %d error: %s", height, err)
+	}
+	if err := this.pruneContractEventIndex(height); err != nil {
+		log.Errorf("prune contract event index of block %d error: %s", height, err)
+	}
 	key := genEventNotifyByBlockKey(height)
 	this.store.BatchDelete(key)
 	for _, hash := range hashes {
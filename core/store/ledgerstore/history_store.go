@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"errors"
+	"fmt"
+
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+)
+
+var errHistoryStoreReadOnly = errors.New("historical state is read only")
+
+// historyStore is a read only view of the state store at a block height, backed by the state history
+type historyStore struct {
+	state  *StateStore
+	height uint32
+}
+
+// NewOverlayDBAtHeight return an overlay db of the state at block height, only available in archive mode
+func (self *StateStore) NewOverlayDBAtHeight(height uint32) (*overlaydb.OverlayDB, error) {
+	if !self.stateArchiveEnabled {
+		return nil, ErrStateHistoryDisabled
+	}
+	if !self.historyHeightSaved || height < self.historyHeight {
+		return nil, fmt.Errorf("state history is not available at height %d", height)
+	}
+	return overlaydb.NewOverlayDB(&historyStore{state: self, height: height}), nil
+}
+
+func (self *historyStore) Get(key []byte) ([]byte, error) {
+	_, value, err := self.state.GetStateHistory(key, self.height)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return nil, scom.ErrNotFound
+	}
+	return value, nil
+}
+
+func (self *historyStore) Has(key []byte) (bool, error) {
+	_, err := self.Get(key)
+	if err == scom.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (self *historyStore) Put(key []byte, value []byte) error {
+	return errHistoryStoreReadOnly
+}
+
+func (self *historyStore) Delete(key []byte) error {
+	return errHistoryStoreReadOnly
+}
+
+func (self *historyStore) NewBatch() {}
+
+func (self *historyStore) BatchPut(key []byte, value []byte) {}
+
+func (self *historyStore) BatchDelete(key []byte) {}
+
+func (self *historyStore) BatchCommit() error {
+	return errHistoryStoreReadOnly
+}
+
+func (self *historyStore) Close() error {
+	return nil
+}
+
+// NewIterator iterates the state at the height, over the keys in current state and the keys written in state history
+func (self *historyStore) NewIterator(prefix []byte) scom.StoreIterator {
+	indexIter := &historyIndexIterator{self.state.store.NewIterator(genStateHistoryIndexKey(prefix))}
+	keys := overlaydb.NewJoinIter(indexIter, self.state.store.NewIterator(prefix))
+	return &historyIterator{history: self, keys: keys}
+}
+
+// historyIndexIterator iterates the keys in the state history index
+type historyIndexIterator struct {
+	scom.StoreIterator
+}
+
+func (self *historyIndexIterator) Key() []byte {
+	key := self.StoreIterator.Key()
+	if len(key) != 0 {
+		key = key[1:] // remove the index prefix
+	}
+	return key
+}
+
+type historyIterator struct {
+	history *historyStore
+	keys    scom.StoreIterator
+	key     []byte
+	value   []byte
+	err     error
+}
+
+func (self *historyIterator) First() bool {
+	return self.seek(self.keys.First())
+}
+
+func (self *historyIterator) Next() bool {
+	return self.seek(self.keys.Next())
+}
+
+// seek skips the keys not exist at the height
+func (self *historyIterator) seek(has bool) bool {
+	for ; has; has = self.keys.Next() {
+		value, err := self.history.Get(self.keys.Key())
+		if err == scom.ErrNotFound {
+			continue
+		} else if err != nil {
+			self.err = err
+			break
+		}
+		self.key, self.value = self.keys.Key(), value
+		return true
+	}
+	self.key, self.value = nil, nil
+	return false
+}
+
+func (self *historyIterator) Key() []byte {
+	return self.key
+}
+
+func (self *historyIterator) Value() []byte {
+	return self.value
+}
+
+func (self *historyIterator) Release() {
+	self.keys.Release()
+}
+
+func (self *historyIterator) Error() error {
+	if self.err != nil {
+		return self.err
+	}
+	return self.keys.Error()
+}
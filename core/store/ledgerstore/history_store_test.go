@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryStore(t *testing.T) {
+	db := NewMemStateStore(0)
+	_, err := db.NewOverlayDBAtHeight(0)
+	assert.Equal(t, ErrStateHistoryDisabled, err)
+
+	key := []byte("key")
+	untouched := []byte("untouched")
+	saveBlock := func(height uint32, kvs map[string]string) {
+		overlay := db.NewOverlayDB()
+		for k, v := range kvs {
+			if v == "" {
+				overlay.Delete([]byte(k))
+			} else {
+				overlay.Put([]byte(k), []byte(v))
+			}
+		}
+		db.NewBatch()
+		overlay.CommitTo()
+		assert.Nil(t, db.SaveStateHistory(height, overlay.GetWriteSet()))
+		assert.Nil(t, db.CommitTo())
+	}
+
+	// history is enabled after height 1
+	saveBlock(0, map[string]string{"key": "v0", "untouched": "u0"})
+	saveBlock(1, map[string]string{"key": "v1"})
+	assert.Nil(t, db.EnableStateArchive())
+	saveBlock(2, map[string]string{"other": "o2", "keyx": "x2"})
+	saveBlock(3, map[string]string{"key": "v3"})
+	saveBlock(4, map[string]string{"key": ""})
+	saveBlock(5, map[string]string{"key": "v5", "keyx": ""})
+
+	_, err = db.NewOverlayDBAtHeight(1)
+	assert.NotNil(t, err)
+	for height, expected := range map[uint32]string{2: "v1", 3: "v3", 4: "", 5: "v5"} {
+		overlay, err := db.NewOverlayDBAtHeight(height)
+		assert.Nil(t, err)
+		value, err := overlay.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(value), fmt.Sprintf("height %d", height))
+
+		value, err = overlay.Get(untouched)
+		assert.Nil(t, err)
+		assert.Equal(t, "u0", string(value))
+	}
+
+	iterate := func(height uint32, prefix string) map[string]string {
+		overlay, err := db.NewOverlayDBAtHeight(height)
+		assert.Nil(t, err)
+		kvs := make(map[string]string)
+		iter := overlay.NewIterator([]byte(prefix))
+		for has := iter.First(); has; has = iter.Next() {
+			kvs[string(iter.Key())] = string(iter.Value())
+		}
+		iter.Release()
+		assert.Nil(t, iter.Error())
+		return kvs
+	}
+	for height, expected := range map[uint32]map[string]string{
+		2: {"key": "v1", "keyx": "x2"},
+		3: {"key": "v3", "keyx": "x2"},
+		4: {"keyx": "x2"},
+		5: {"key": "v5"},
+	} {
+		assert.Equal(t, expected, iterate(height, "key"), fmt.Sprintf("height %d", height))
+		assert.Equal(t, map[string]string{"untouched": "u0"}, iterate(height, "u"))
+	}
+}
@@ -0,0 +1,725 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+)
+
+const (
+	maxLedgerCheckIssues   = 100    //stop checking after so many issues are found
+	ledgerCheckLogInterval = 100000 //number of blocks between progress logs
+	ledgerRepairBatchSize  = 1000   //number of blocks removed in one batch while repairing
+)
+
+//stores reported in LedgerCheckIssue
+const (
+	LEDGER_CHECK_STORE_BLOCK       = "block"
+	LEDGER_CHECK_STORE_STATE       = "state"
+	LEDGER_CHECK_STORE_EVENT       = "event"
+	LEDGER_CHECK_STORE_CROSS_CHAIN = "crosschain"
+)
+
+//LedgerCheckIssue is an inconsistency found in ledger stores
+type LedgerCheckIssue struct {
+	Height uint32 //block height the issue found at
+	Store  string //store the issue found in
+	Msg    string
+}
+
+func (this *LedgerCheckIssue) String() string {
+	return fmt.Sprintf("height:%d store:%s %s", this.Height, this.Store, this.Msg)
+}
+
+//LedgerCheckResult is the result of LedgerChecker.Check
+type LedgerCheckResult struct {
+	BlockHeight      uint32              //current block height of block store
+	StateHeight      uint32              //current block height of state store
+	EventHeight      uint32              //current block height of event store
+	PrunedHeight     uint32              //blocks below are pruned or not imported from snapshot, only headers are checked
+	ConsistentHeight uint32              //the last height that the stores are consistent up to
+	StateHash        common.Uint256      //hash of current world state, the same as calculateTotalStateHash. Empty if not calculated
+	Issues           []*LedgerCheckIssue //issues ordered by height, at most maxLedgerCheckIssues
+	Truncated        bool                //too many issues are found, the check is stopped
+
+	broken          bool   //some block is inconsistent, the ledger must be rolled back
+	brokenHeight    uint32 //the first inconsistent height
+	validIndexCount uint32 //the number of heights covered by valid header index list
+}
+
+//Consistent return whether no issue is found
+func (this *LedgerCheckResult) Consistent() bool {
+	return len(this.Issues) == 0
+}
+
+//LedgerChecker checks the consistency of ledger stores of a stopped node, and repairs the stores by rolling back
+//to the last consistent height
+type LedgerChecker struct {
+	blockStore      *BlockStore
+	stateStore      *StateStore
+	eventStore      *EventStore
+	crossChainStore *CrossChainStore
+	stateHashHeight uint32
+	calcStateHash   bool
+}
+
+//NewLedgerChecker open the ledger stores at dataDir without loading or recovering them
+func NewLedgerChecker(dataDir string, stateHashHeight uint32) (*LedgerChecker, error) {
+	checker := &LedgerChecker{stateHashHeight: stateHashHeight}
+	var err error
+	if checker.blockStore, err = NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), false); err != nil {
+		return nil, fmt.Errorf("NewBlockStore error %s", err)
+	}
+	stateDir := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirState)
+	store, err := newPersistStore(stateDir)
+	if err != nil {
+		checker.Close()
+		return nil, fmt.Errorf("NewStateStore error %s", err)
+	}
+	checker.stateStore = &StateStore{
+		dbDir:                stateDir,
+		store:                store,
+		merklePath:           fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), MerkleTreeStorePath),
+		stateHashCheckHeight: stateHashHeight,
+	}
+	if checker.eventStore, err = NewEventStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirEvent)); err != nil {
+		checker.Close()
+		return nil, fmt.Errorf("NewEventStore error %s", err)
+	}
+	if checker.crossChainStore, err = NewCrossChainStore(dataDir); err != nil {
+		checker.Close()
+		return nil, fmt.Errorf("NewCrossChainStore error %s", err)
+	}
+	return checker, nil
+}
+
+//Close all ledger stores
+func (this *LedgerChecker) Close() error {
+	var err error
+	if this.blockStore != nil {
+		err = this.blockStore.Close()
+	}
+	if this.stateStore != nil {
+		if this.stateStore.merkleHashStore != nil {
+			this.stateStore.merkleHashStore.Close()
+		}
+		if e := this.stateStore.store.Close(); err == nil {
+			err = e
+		}
+	}
+	if this.eventStore != nil {
+		if e := this.eventStore.Close(); err == nil {
+			err = e
+		}
+	}
+	if this.crossChainStore != nil {
+		if e := this.crossChainStore.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+//ledgerCheck is the state of a running check
+type ledgerCheck struct {
+	*LedgerChecker
+	result    *LedgerCheckResult
+	blockTree *merkle.CompactMerkleTree //block merkle tree rebuilt from headers
+	stateTree *merkle.CompactMerkleTree //state merkle tree rebuilt from state merkle roots
+	savedTree *merkle.CompactMerkleTree //block merkle tree saved in state store
+	index     map[uint32]common.Uint256 //heights in header index list
+}
+
+//addIssue records an issue which makes the ledger inconsistent from height
+func (this *ledgerCheck) addIssue(height uint32, store string, format string, a ...interface{}) {
+	if !this.result.broken || height < this.result.brokenHeight {
+		this.result.broken = true
+		this.result.brokenHeight = height
+	}
+	this.addIndexIssue(height, store, format, a...)
+}
+
+//addIndexIssue records an issue which can be repaired without rolling back, eg. header index list
+func (this *ledgerCheck) addIndexIssue(height uint32, store string, format string, a ...interface{}) {
+	if len(this.result.Issues) >= maxLedgerCheckIssues {
+		this.result.Truncated = true
+		return
+	}
+	this.result.Issues = append(this.result.Issues, &LedgerCheckIssue{
+		Height: height,
+		Store:  store,
+		Msg:    fmt.Sprintf(format, a...),
+	})
+}
+
+//EnableStateHash makes Check calculate the hash of current world state, which reads the whole state store.
+//Without it the hash is only calculated when the state store is at state hash height, where the state merkle
+//root covers the whole state and is compared with
+func (this *LedgerChecker) EnableStateHash() {
+	this.calcStateHash = true
+}
+
+//Check walks the header index, blocks, transactions, state merkle roots and events, and reports inconsistencies
+//between them. Transactions and events are only checked from the pruned height, and event notifies of transactions
+//are only checked if event log is enabled
+func (this *LedgerChecker) Check(genesisHash common.Uint256) (*LedgerCheckResult, error) {
+	version, err := this.blockStore.GetVersion()
+	if err != nil && err != scom.ErrNotFound {
+		return nil, fmt.Errorf("GetVersion error %s", err)
+	}
+	if err == scom.ErrNotFound || version != SYSTEM_VERSION {
+		return nil, fmt.Errorf("ledger is not initialized")
+	}
+	check := &ledgerCheck{LedgerChecker: this, result: &LedgerCheckResult{}}
+	result := check.result
+	currHash, blockHeight, err := this.blockStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("blockStore.GetCurrentBlock error %s", err)
+	}
+	stateHash, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	eventHash, eventHeight, err := this.eventStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("eventStore.GetCurrentBlock error %s", err)
+	}
+	prunedHeight, err := this.blockStore.GetBlockPrunedHeight()
+	if err != nil {
+		return nil, fmt.Errorf("GetBlockPrunedHeight error %s", err)
+	}
+	result.BlockHeight, result.StateHeight, result.EventHeight = blockHeight, stateHeight, eventHeight
+	result.PrunedHeight = prunedHeight
+
+	if err := check.loadHeaderIndex(); err != nil {
+		return nil, err
+	}
+	if err := check.loadBlockMerkleTree(); err != nil {
+		return nil, err
+	}
+	if stateHeight > blockHeight {
+		check.addIssue(blockHeight+1, LEDGER_CHECK_STORE_STATE, "state store is at height %d, higher than block store", stateHeight)
+	}
+	if eventHeight > blockHeight {
+		check.addIssue(blockHeight+1, LEDGER_CHECK_STORE_EVENT, "event store is at height %d, higher than block store", eventHeight)
+	} else if eventHeight < stateHeight {
+		check.addIssue(eventHeight+1, LEDGER_CHECK_STORE_EVENT, "event store is at height %d, lower than state store %d",
+			eventHeight, stateHeight)
+	}
+
+	for height := uint32(0); height <= blockHeight && !result.Truncated; height++ {
+		blockHash, err := this.blockStore.GetBlockHash(height)
+		if err != nil {
+			check.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "block hash not found: %s", err)
+			continue
+		}
+		if hash, ok := check.index[height]; ok && hash != blockHash {
+			check.addIndexIssue(height, LEDGER_CHECK_STORE_BLOCK, "header index %s is different from block hash %s",
+				hash.ToHexString(), blockHash.ToHexString())
+		}
+		if height == blockHeight && currHash != blockHash {
+			check.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "current block %s is different from block hash %s",
+				currHash.ToHexString(), blockHash.ToHexString())
+		}
+		if height == stateHeight && stateHash != blockHash {
+			check.addIssue(height, LEDGER_CHECK_STORE_STATE, "current block %s is different from block hash %s",
+				stateHash.ToHexString(), blockHash.ToHexString())
+		}
+		if height == eventHeight && eventHash != blockHash {
+			check.addIssue(height, LEDGER_CHECK_STORE_EVENT, "current block %s is different from block hash %s",
+				eventHash.ToHexString(), blockHash.ToHexString())
+		}
+		txHashes, ok := check.checkBlock(height, blockHash, genesisHash)
+		if ok && height <= eventHeight {
+			check.checkEvents(height, txHashes)
+		}
+		if height >= this.stateHashHeight && height <= stateHeight {
+			check.checkStateMerkleRoot(height)
+		}
+		if height > 0 && height%ledgerCheckLogInterval == 0 {
+			log.Infof("checked %d/%d blocks, %d issues found", height, blockHeight, len(result.Issues))
+		}
+	}
+	check.checkCrossChainMsgs()
+
+	if this.calcStateHash || stateHeight == this.stateHashHeight {
+		overlay := overlaydb.NewOverlayDB(this.stateStore.store)
+		result.StateHash, err = calculateTotalStateHash(overlay)
+		if err != nil {
+			return nil, fmt.Errorf("calculateTotalStateHash error %s", err)
+		}
+	}
+	if stateHeight == this.stateHashHeight {
+		writeSetHash, _, err := this.stateStore.getStateMerkleRootEntry(stateHeight)
+		if err == nil && writeSetHash != result.StateHash {
+			check.addIssue(stateHeight, LEDGER_CHECK_STORE_STATE, "state hash %s is different from state merkle tree %s",
+				result.StateHash.ToHexString(), writeSetHash.ToHexString())
+		}
+	}
+
+	result.ConsistentHeight = blockHeight
+	if result.broken && result.brokenHeight <= blockHeight {
+		result.ConsistentHeight = 0
+		if result.brokenHeight > 0 {
+			result.ConsistentHeight = result.brokenHeight - 1
+		}
+	}
+	return result, nil
+}
+
+//loadHeaderIndex loads the header index list, the heights must be contiguous from genesis block.
+//It's repairable since the list is rebuilt from block hashes
+func (this *ledgerCheck) loadHeaderIndex() error {
+	this.index = make(map[uint32]common.Uint256)
+	iter := this.blockStore.store.NewIterator([]byte{byte(scom.IX_HEADER_HASH_LIST)})
+	defer iter.Release()
+	for iter.Next() {
+		startHeight, err := genStartHeightByHeaderIndexKey(iter.Key())
+		if err != nil {
+			this.addIndexIssue(0, LEDGER_CHECK_STORE_BLOCK, "invalid header index key %x", iter.Key())
+			continue
+		}
+		source := common.NewZeroCopySource(iter.Value())
+		count, eof := source.NextUint32()
+		if eof || count != HEADER_INDEX_BATCH_SIZE || startHeight%HEADER_INDEX_BATCH_SIZE != 0 ||
+			startHeight+HEADER_INDEX_BATCH_SIZE > this.result.BlockHeight {
+			this.addIndexIssue(startHeight, LEDGER_CHECK_STORE_BLOCK, "invalid header index list")
+			continue
+		}
+		for i := uint32(0); i < count; i++ {
+			hash, eof := source.NextHash()
+			if eof {
+				this.addIndexIssue(startHeight, LEDGER_CHECK_STORE_BLOCK, "invalid header index list")
+				break
+			}
+			this.index[startHeight+i] = hash
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("header index list iterator error %s", err)
+	}
+	for {
+		if _, ok := this.index[this.result.validIndexCount]; !ok {
+			break
+		}
+		this.result.validIndexCount++
+	}
+	if this.result.validIndexCount != uint32(len(this.index)) {
+		this.addIndexIssue(this.result.validIndexCount, LEDGER_CHECK_STORE_BLOCK, "header index list is not contiguous")
+	}
+	return nil
+}
+
+//loadBlockMerkleTree loads the block merkle tree saved in state store with its hash store
+func (this *ledgerCheck) loadBlockMerkleTree() error {
+	treeSize, hashes, err := this.stateStore.GetBlockMerkleTree()
+	if err != nil && err != scom.ErrNotFound {
+		return fmt.Errorf("GetBlockMerkleTree error %s", err)
+	}
+	if treeSize != this.result.StateHeight+1 {
+		this.addIssue(this.result.StateHeight, LEDGER_CHECK_STORE_STATE, "block merkle tree size %d is inconsistent",
+			treeSize)
+	}
+	var hashStore merkle.HashStore
+	if store, err := merkle.NewFileHashStore(this.stateStore.merklePath, treeSize); err != nil {
+		this.addIssue(this.result.StateHeight, LEDGER_CHECK_STORE_STATE, "block merkle tree file %s", err)
+	} else {
+		hashStore = store
+		this.stateStore.merkleHashStore = store
+	}
+	this.savedTree = merkle.NewTree(treeSize, hashes, hashStore)
+	return nil
+}
+
+//checkBlock checks the header and transactions of block, return the transaction hashes and whether the block
+//is available, blocks below pruned height are not available
+func (this *ledgerCheck) checkBlock(height uint32, blockHash, genesisHash common.Uint256) ([]common.Uint256, bool) {
+	header, txHashes, err := this.blockStore.loadHeaderWithTx(blockHash)
+	if err == scom.ErrNotFound && height < this.result.PrunedHeight {
+		this.blockTree = nil
+		return nil, false
+	}
+	if err != nil {
+		this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "load header %s error %s", blockHash.ToHexString(), err)
+		this.blockTree = nil
+		return nil, false
+	}
+	if header.Height != height {
+		this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "header height %d is wrong", header.Height)
+	}
+	if header.Hash() != blockHash {
+		this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "header hash %s is different from block hash %s",
+			header.Hash().ToHexString(), blockHash.ToHexString())
+	}
+	if height == 0 && blockHash != genesisHash {
+		this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "genesis block hash %s is different from %s",
+			blockHash.ToHexString(), genesisHash.ToHexString())
+	}
+	if height > 0 {
+		prevHash, err := this.blockStore.GetBlockHash(height - 1)
+		if err == nil && header.PrevBlockHash != prevHash {
+			this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "header does not link to previous block %s",
+				prevHash.ToHexString())
+		}
+	}
+
+	//rebuild block merkle tree from headers, or from the saved tree if blocks below are pruned
+	if height == 0 {
+		this.blockTree = merkle.NewTree(0, nil, nil)
+	} else if this.blockTree == nil && height <= this.savedTree.TreeSize() {
+		if hashes, err := this.savedTree.HashesOfSize(height); err == nil {
+			this.blockTree = merkle.NewTree(height, hashes, nil)
+		}
+	}
+	if this.blockTree != nil {
+		this.blockTree.AppendHash(header.TransactionsRoot)
+		if height > 0 && this.blockTree.Root() != header.BlockRoot {
+			this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "block root %s is different from block merkle tree %s",
+				header.BlockRoot.ToHexString(), this.blockTree.Root().ToHexString())
+		}
+		if height == this.result.StateHeight && this.blockTree.Root() != this.savedTree.Root() {
+			this.addIssue(height, LEDGER_CHECK_STORE_STATE, "block merkle tree root %s is different from headers %s",
+				this.savedTree.Root().ToHexString(), this.blockTree.Root().ToHexString())
+		}
+	}
+
+	if height < this.result.PrunedHeight {
+		return txHashes, false
+	}
+	for _, txHash := range txHashes {
+		tx, txHeight, err := this.blockStore.loadTransaction(txHash)
+		if err != nil {
+			this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "load transaction %s error %s", txHash.ToHexString(), err)
+			continue
+		}
+		if tx.Hash() != txHash {
+			this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "transaction %s has wrong hash %s", txHash.ToHexString(),
+				tx.Hash().ToHexString())
+		}
+		if txHeight != height {
+			this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "transaction %s is saved at height %d",
+				txHash.ToHexString(), txHeight)
+		}
+	}
+	//ComputeMerkleRoot uses the hashes as workspace
+	if txRoot := common.ComputeMerkleRoot(append([]common.Uint256{}, txHashes...)); txRoot != header.TransactionsRoot {
+		this.addIssue(height, LEDGER_CHECK_STORE_BLOCK, "transactions root %s is different from header %s",
+			txRoot.ToHexString(), header.TransactionsRoot.ToHexString())
+	}
+	return txHashes, true
+}
+
+//checkEvents checks the transactions and event notifies saved in event store of block
+func (this *ledgerCheck) checkEvents(height uint32, txHashes []common.Uint256) {
+	if len(txHashes) == 0 {
+		return
+	}
+	hashes, err := this.eventStore.getTxHashesByBlock(height)
+	if err != nil {
+		this.addIssue(height, LEDGER_CHECK_STORE_EVENT, "load transactions of block error %s", err)
+		return
+	}
+	if len(hashes) != len(txHashes) {
+		this.addIssue(height, LEDGER_CHECK_STORE_EVENT, "transactions of block are different from block store")
+		return
+	}
+	for i := range hashes {
+		if hashes[i] != txHashes[i] {
+			this.addIssue(height, LEDGER_CHECK_STORE_EVENT, "transactions of block are different from block store")
+			return
+		}
+	}
+	if !config.DefConfig.Common.EnableEventLog {
+		return
+	}
+	for _, txHash := range txHashes {
+		if _, err := this.eventStore.store.Get(genEventNotifyByTxKey(txHash)); err != nil {
+			this.addIssue(height, LEDGER_CHECK_STORE_EVENT, "load event notify of transaction %s error %s",
+				txHash.ToHexString(), err)
+		}
+	}
+}
+
+//checkStateMerkleRoot rebuilds the state merkle tree from the write set hashes and checks the saved roots.
+//A ledger imported from snapshot has no state merkle roots below snapshot height, the tree can not be rebuilt
+func (this *ledgerCheck) checkStateMerkleRoot(height uint32) {
+	writeSetHash, root, err := this.stateStore.getStateMerkleRootEntry(height)
+	if err == scom.ErrNotFound && height < this.result.PrunedHeight {
+		this.stateTree = nil
+		return
+	}
+	if err != nil {
+		this.addIssue(height, LEDGER_CHECK_STORE_STATE, "load state merkle root error %s", err)
+		this.stateTree = nil
+		return
+	}
+	if height == this.stateHashHeight {
+		this.stateTree = merkle.NewTree(0, nil, nil)
+	}
+	if this.stateTree != nil {
+		this.stateTree.AppendHash(writeSetHash)
+		if this.stateTree.Root() != root {
+			this.addIssue(height, LEDGER_CHECK_STORE_STATE, "state merkle root %s is different from state merkle tree %s",
+				root.ToHexString(), this.stateTree.Root().ToHexString())
+		}
+	}
+	if height != this.result.StateHeight {
+		return
+	}
+	treeSize, hashes, err := this.stateStore.GetStateMerkleTree()
+	if err != nil {
+		this.addIssue(height, LEDGER_CHECK_STORE_STATE, "load state merkle tree error %s", err)
+		return
+	}
+	if treeSize != height-this.stateHashHeight+1 {
+		this.addIssue(height, LEDGER_CHECK_STORE_STATE, "state merkle tree size %d is inconsistent", treeSize)
+		return
+	}
+	if savedRoot := merkle.NewTree(treeSize, hashes, nil).Root(); savedRoot != root {
+		this.addIssue(height, LEDGER_CHECK_STORE_STATE, "state merkle tree root %s is different from state merkle root %s",
+			savedRoot.ToHexString(), root.ToHexString())
+	}
+}
+
+//checkCrossChainMsgs checks the cross chain messages are decodable and not above current block
+func (this *ledgerCheck) checkCrossChainMsgs() {
+	iter := this.crossChainStore.store.NewIterator([]byte{byte(scom.SYS_CROSS_CHAIN_MSG)})
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != 5 {
+			continue
+		}
+		height := binary.LittleEndian.Uint32(key[1:])
+		msg := new(types.CrossChainMsg)
+		if err := msg.Deserialization(common.NewZeroCopySource(iter.Value())); err != nil {
+			this.addIssue(height, LEDGER_CHECK_STORE_CROSS_CHAIN, "cross chain msg deserialize error %s", err)
+		} else if msg.Height != height {
+			this.addIssue(height, LEDGER_CHECK_STORE_CROSS_CHAIN, "cross chain msg height %d is wrong", msg.Height)
+		} else if height > this.result.BlockHeight {
+			this.addIssue(height, LEDGER_CHECK_STORE_CROSS_CHAIN, "cross chain msg is above current block")
+		}
+	}
+	if err := iter.Error(); err != nil {
+		this.addIssue(this.result.BlockHeight, LEDGER_CHECK_STORE_CROSS_CHAIN, "iterator error %s", err)
+	}
+}
+
+//Repair rolls the ledger stores back to the consistent height of result, and rebuilds the header index list. The
+//state store is rolled back with its undo logs, which are only kept for the latest blocks
+func (this *LedgerChecker) Repair(result *LedgerCheckResult) error {
+	if result.Consistent() {
+		return nil
+	}
+	height := result.ConsistentHeight
+	if result.broken && result.brokenHeight == 0 {
+		return fmt.Errorf("genesis block is inconsistent, the ledger must be resynced")
+	}
+	if height < result.StateHeight {
+		if err := this.stateStore.checkRollback(result.StateHeight, height); err != nil {
+			return fmt.Errorf("can not roll back state store from height %d to %d: %s, resync the node or import "+
+				"a snapshot", result.StateHeight, height, err)
+		}
+	}
+	return this.rollback(result.BlockHeight, result.StateHeight, result.EventHeight, height, result.validIndexCount)
+}
+
+//Rollback removes the blocks above height from the ledger stores, and rolls the state store back with its undo
+//logs, which are only kept for the latest blocks
+func (this *LedgerChecker) Rollback(height uint32) error {
+	_, blockHeight, err := this.blockStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("blockStore.GetCurrentBlock error %s", err)
+	}
+	_, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	_, eventHeight, err := this.eventStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("eventStore.GetCurrentBlock error %s", err)
+	}
+	prunedHeight, err := this.blockStore.GetBlockPrunedHeight()
+	if err != nil {
+		return fmt.Errorf("GetBlockPrunedHeight error %s", err)
+	}
+	if height >= blockHeight && height >= stateHeight && height >= eventHeight {
+		return fmt.Errorf("height %d is not lower than current block height %d", height, blockHeight)
+	}
+	if height < prunedHeight {
+		return fmt.Errorf("can not roll back to height %d, blocks below %d are pruned", height, prunedHeight)
+	}
+	if height < stateHeight {
+		if err := this.stateStore.checkRollback(stateHeight, height); err != nil {
+			return fmt.Errorf("can not roll back state store from height %d to %d: %s", stateHeight, height, err)
+		}
+	}
+	//the header index list below height is kept
+	return this.rollback(blockHeight, stateHeight, eventHeight, height, blockHeight+1)
+}
+
+//rollback rolls all stores back to height. Every store is rolled back in batches and its current block saved, so
+//an interrupted rollback can be started again
+func (this *LedgerChecker) rollback(blockHeight, stateHeight, eventHeight, height, validIndexCount uint32) error {
+	blockHash, err := this.blockStore.GetBlockHash(height)
+	if err != nil {
+		return fmt.Errorf("GetBlockHash height:%d error %s", height, err)
+	}
+	if err := this.rollbackStateStore(stateHeight, height); err != nil {
+		return fmt.Errorf("roll back state store error %s", err)
+	}
+	if err := this.repairEventStore(blockHeight, eventHeight, height, blockHash); err != nil {
+		return fmt.Errorf("repair event store error %s", err)
+	}
+	if err := this.repairCrossChainStore(height); err != nil {
+		return fmt.Errorf("repair cross chain store error %s", err)
+	}
+	if err := this.repairBlockStore(blockHeight, height, blockHash, validIndexCount); err != nil {
+		return fmt.Errorf("repair block store error %s", err)
+	}
+	return nil
+}
+
+func (this *LedgerChecker) rollbackStateStore(stateHeight, height uint32) error {
+	for h := stateHeight; h > height; h-- {
+		prevHash, err := this.blockStore.GetBlockHash(h - 1)
+		if err != nil {
+			return fmt.Errorf("GetBlockHash height:%d error %s", h-1, err)
+		}
+		if err := this.stateStore.rollbackBlock(h, prevHash); err != nil {
+			return err
+		}
+		if (stateHeight-h+1)%ledgerCheckLogInterval == 0 {
+			log.Infof("state store rolled back to height %d", h-1)
+		}
+	}
+	return nil
+}
+
+func (this *LedgerChecker) repairBlockStore(blockHeight, height uint32, blockHash common.Uint256,
+	validIndexCount uint32) error {
+	store := this.blockStore.store
+	store.NewBatch()
+	for h := height + 1; h <= blockHeight; h++ {
+		hash, err := this.blockStore.GetBlockHash(h)
+		if err == nil {
+			_, txHashes, err := this.blockStore.loadHeaderWithTx(hash)
+			if err == nil {
+				for _, txHash := range txHashes {
+					if _, txHeight, err := this.blockStore.loadTransaction(txHash); err == nil && txHeight != h {
+						continue
+					}
+					store.BatchDelete(genTransactionKey(txHash))
+				}
+			}
+			store.BatchDelete(genHeaderKey(hash))
+		}
+		store.BatchDelete(genBlockHashKey(h))
+		if (h-height)%ledgerRepairBatchSize == 0 {
+			if err := store.BatchCommit(); err != nil {
+				return err
+			}
+			store.NewBatch()
+		}
+	}
+
+	//the same as saveHeaderIndexList, only full batches below current height are saved
+	keep := validIndexCount - validIndexCount%HEADER_INDEX_BATCH_SIZE
+	iter := store.NewIterator([]byte{byte(scom.IX_HEADER_HASH_LIST)})
+	for iter.Next() {
+		start, err := genStartHeightByHeaderIndexKey(iter.Key())
+		if err != nil || start >= keep || start+HEADER_INDEX_BATCH_SIZE > height {
+			store.BatchDelete(iter.Key())
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	for start := keep; start+HEADER_INDEX_BATCH_SIZE <= height; start += HEADER_INDEX_BATCH_SIZE {
+		hashes := make([]common.Uint256, 0, HEADER_INDEX_BATCH_SIZE)
+		for h := start; h < start+HEADER_INDEX_BATCH_SIZE; h++ {
+			hash, err := this.blockStore.GetBlockHash(h)
+			if err != nil {
+				return fmt.Errorf("GetBlockHash height:%d error %s", h, err)
+			}
+			hashes = append(hashes, hash)
+		}
+		this.blockStore.SaveHeaderIndexList(start, hashes)
+	}
+	this.blockStore.SaveCurrentBlock(height, blockHash)
+	return store.BatchCommit()
+}
+
+func (this *LedgerChecker) repairEventStore(blockHeight, eventHeight, height uint32, blockHash common.Uint256) error {
+	this.eventStore.NewBatch()
+	top := eventHeight
+	if blockHeight > top {
+		top = blockHeight
+	}
+	for h := height + 1; h <= top; h++ {
+		txHashes, _ := this.eventStore.getTxHashesByBlock(h)
+		if hash, err := this.blockStore.GetBlockHash(h); err == nil {
+			if _, hashes, err := this.blockStore.loadHeaderWithTx(hash); err == nil {
+				txHashes = append(txHashes, hashes...)
+			}
+		}
+		this.eventStore.PruneBlock(h, txHashes)
+		if (h-height)%ledgerRepairBatchSize == 0 {
+			if err := this.eventStore.CommitTo(); err != nil {
+				return err
+			}
+			this.eventStore.NewBatch()
+		}
+	}
+	if eventHeight > height {
+		this.eventStore.SaveCurrentBlock(height, blockHash)
+	}
+	return this.eventStore.CommitTo()
+}
+
+func (this *LedgerChecker) repairCrossChainStore(height uint32) error {
+	store := this.crossChainStore.store
+	store.NewBatch()
+	iter := store.NewIterator([]byte{byte(scom.SYS_CROSS_CHAIN_MSG)})
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != 5 {
+			continue
+		}
+		if binary.LittleEndian.Uint32(key[1:]) > height {
+			store.BatchDelete(key)
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return store.BatchCommit()
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/genesis"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func checkTestLedger(t *testing.T, dir string, genesisHash common.Uint256, repair bool) (*LedgerCheckResult, error) {
+	checker, err := NewLedgerChecker(dir, 0)
+	assert.Nil(t, err)
+	defer checker.Close()
+	checker.EnableStateHash()
+	result, err := checker.Check(genesisHash)
+	assert.Nil(t, err)
+	if repair {
+		return result, checker.Repair(result)
+	}
+	return result, nil
+}
+
+//saveBlockHeaderOnly saves an empty block to block store only, as if the node crashed before committing state store
+func saveBlockHeaderOnly(t *testing.T, store *LedgerStoreImp, prev *types.Header, txRoots []common.Uint256) *types.Header {
+	payload, err := json.Marshal(&vconfig.VbftBlockInfo{})
+	assert.Nil(t, err)
+	txRoot := common.ComputeMerkleRoot(nil)
+	header := &types.Header{
+		PrevBlockHash:    prev.Hash(),
+		TransactionsRoot: txRoot,
+		BlockRoot:        store.GetBlockRootWithNewTxRoots(store.GetCurrentBlockHeight()+1, append(txRoots, txRoot)),
+		Timestamp:        prev.Timestamp + 1,
+		Height:           prev.Height + 1,
+		ConsensusPayload: payload,
+	}
+	store.blockStore.NewBatch()
+	assert.Nil(t, store.saveBlockToBlockStore(&types.Block{Header: header}))
+	assert.Nil(t, store.blockStore.CommitTo())
+	return header
+}
+
+func TestLedgerCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledgercheck")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	undoDepth := config.DefConfig.Common.StateUndoDepth
+	config.DefConfig.Common.StateUndoDepth = 10
+	defer func() { config.DefConfig.Common.StateUndoDepth = undoDepth }()
+
+	var bookkeepers []keypair.PublicKey
+	for i := 0; i < 7; i++ {
+		bookkeepers = append(bookkeepers, account.NewAccount("").PublicKey)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	genesisHash := genesisBlock.Hash()
+
+	store := newTestLedger(t, dir, genesisBlock, bookkeepers)
+	for i := 0; i < 5; i++ {
+		addEmptyBlock(t, store)
+	}
+	prev, err := store.GetHeaderByHeight(5)
+	assert.Nil(t, err)
+	var txRoots []common.Uint256
+	for i := 0; i < 2; i++ {
+		prev = saveBlockHeaderOnly(t, store, prev, txRoots)
+		txRoots = append(txRoots, prev.TransactionsRoot)
+	}
+	assert.Nil(t, store.Close())
+
+	result, err := checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+	assert.True(t, result.Consistent())
+	assert.Equal(t, uint32(7), result.BlockHeight)
+	assert.Equal(t, uint32(5), result.StateHeight)
+	assert.Equal(t, uint32(7), result.ConsistentHeight)
+	assert.NotEqual(t, common.UINT256_EMPTY, result.StateHash)
+
+	// the state hash is only calculated on request above state hash height
+	checker, err := NewLedgerChecker(dir, 0)
+	assert.Nil(t, err)
+	result, err = checker.Check(genesisHash)
+	assert.Nil(t, err)
+	assert.Equal(t, common.UINT256_EMPTY, result.StateHash)
+	assert.Nil(t, checker.Close())
+
+	// break the last block and add a cross chain msg above current block
+	checker, err = NewLedgerChecker(dir, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, checker.blockStore.store.Delete(genHeaderKey(prev.Hash())))
+	assert.Nil(t, checker.crossChainStore.SaveMsgToCrossChainStore(&types.CrossChainMsg{Height: 9}))
+	assert.Nil(t, checker.Close())
+
+	result, err = checkTestLedger(t, dir, genesisHash, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.Issues))
+	assert.Equal(t, uint32(6), result.ConsistentHeight)
+	result, err = checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+	assert.True(t, result.Consistent())
+	assert.Equal(t, uint32(6), result.BlockHeight)
+
+	store = newTestLedger(t, dir, genesisBlock, bookkeepers)
+	assert.Equal(t, uint32(6), store.GetCurrentBlockHeight())
+	addEmptyBlock(t, store)
+	assert.Nil(t, store.Close())
+	result, err = checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+	assert.True(t, result.Consistent())
+	assert.Equal(t, uint32(7), result.StateHeight)
+
+	// state store is rolled back with undo logs
+	checker, err = NewLedgerChecker(dir, 0)
+	assert.Nil(t, err)
+	checker.stateStore.NewBatch()
+	checker.stateStore.batchPutStateMerkleRoot(3, common.UINT256_EMPTY, common.UINT256_EMPTY)
+	assert.Nil(t, checker.stateStore.CommitTo())
+	assert.Nil(t, checker.Close())
+	result, err = checkTestLedger(t, dir, genesisHash, true)
+	assert.Nil(t, err)
+	assert.False(t, result.Consistent())
+	assert.Equal(t, uint32(2), result.ConsistentHeight)
+	result, err = checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+	assert.True(t, result.Consistent())
+	assert.Equal(t, uint32(2), result.BlockHeight)
+	assert.Equal(t, uint32(2), result.StateHeight)
+}
+
+//addBlockWithWrites adds an empty block whose write set is replaced by writes, an empty value deletes the key
+func addBlockWithWrites(t *testing.T, store *LedgerStoreImp, writes map[string]string) {
+	prev, err := store.GetHeaderByHeight(store.GetCurrentBlockHeight())
+	assert.Nil(t, err)
+	payload, err := json.Marshal(&vconfig.VbftBlockInfo{})
+	assert.Nil(t, err)
+	txRoot := common.ComputeMerkleRoot(nil)
+	header := &types.Header{
+		PrevBlockHash:    prev.Hash(),
+		TransactionsRoot: txRoot,
+		BlockRoot:        store.GetBlockRootWithNewTxRoots(prev.Height+1, []common.Uint256{txRoot}),
+		Timestamp:        prev.Timestamp + 1,
+		Height:           prev.Height + 1,
+		ConsensusPayload: payload,
+	}
+	block := &types.Block{Header: header}
+	result, err := store.executeBlock(block)
+	assert.Nil(t, err)
+	result.WriteSet = overlaydb.NewMemDB(0, 0)
+	for key, value := range writes {
+		result.WriteSet.Put([]byte(key), []byte(value))
+	}
+	assert.Nil(t, store.submitBlock(block, nil, result))
+}
+
+func TestLedgerRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledgerrollback")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	undoDepth := config.DefConfig.Common.StateUndoDepth
+	config.DefConfig.Common.StateUndoDepth = 10
+	defer func() { config.DefConfig.Common.StateUndoDepth = undoDepth }()
+
+	var bookkeepers []keypair.PublicKey
+	for i := 0; i < 7; i++ {
+		bookkeepers = append(bookkeepers, account.NewAccount("").PublicKey)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	genesisHash := genesisBlock.Hash()
+
+	keyA := string([]byte{byte(scom.ST_STORAGE)}) + "rollback-a"
+	keyB := string([]byte{byte(scom.ST_STORAGE)}) + "rollback-b"
+	store := newTestLedger(t, dir, genesisBlock, bookkeepers)
+	addBlockWithWrites(t, store, map[string]string{keyA: "a1"})
+	addEmptyBlock(t, store)
+	assert.Nil(t, store.Close())
+	before, err := checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+
+	store = newTestLedger(t, dir, genesisBlock, bookkeepers)
+	addBlockWithWrites(t, store, map[string]string{keyA: "a2", keyB: "b1"})
+	addBlockWithWrites(t, store, map[string]string{keyA: ""})
+	addEmptyBlock(t, store)
+	assert.Nil(t, store.Close())
+	after, err := checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+	assert.True(t, after.Consistent())
+	assert.Equal(t, uint32(5), after.StateHeight)
+	assert.NotEqual(t, before.StateHash, after.StateHash)
+
+	checker, err := NewLedgerChecker(dir, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, checker.Rollback(2))
+	assert.NotNil(t, checker.Rollback(2))
+	assert.Nil(t, checker.Close())
+	result, err := checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+	assert.True(t, result.Consistent())
+	assert.Equal(t, uint32(2), result.BlockHeight)
+	assert.Equal(t, uint32(2), result.StateHeight)
+	assert.Equal(t, uint32(2), result.EventHeight)
+	assert.Equal(t, before.StateHash, result.StateHash)
+
+	store = newTestLedger(t, dir, genesisBlock, bookkeepers)
+	value, err := store.stateStore.store.Get([]byte(keyA))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("a1"), value)
+	_, err = store.stateStore.store.Get([]byte(keyB))
+	assert.Equal(t, scom.ErrNotFound, err)
+	addEmptyBlock(t, store)
+	assert.Equal(t, uint32(3), store.GetCurrentBlockHeight())
+	assert.Nil(t, store.Close())
+
+	// undo log of height 2 is missing, nothing is rolled back
+	checker, err = NewLedgerChecker(dir, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, checker.stateStore.store.Delete(genStateUndoLogKey(2)))
+	assert.NotNil(t, checker.Rollback(1))
+	assert.Nil(t, checker.Close())
+	result, err = checkTestLedger(t, dir, genesisHash, false)
+	assert.Nil(t, err)
+	assert.True(t, result.Consistent())
+	assert.Equal(t, uint32(3), result.BlockHeight)
+	assert.Equal(t, uint32(3), result.StateHeight)
+}
@@ -38,12 +38,14 @@ import (
 	"github.com/ontio/ontology/common/config"
 	sysconfig "github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/tracing"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/signature"
 	"github.com/ontio/ontology/core/states"
 	"github.com/ontio/ontology/core/store"
 	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/kvstore"
 	"github.com/ontio/ontology/core/store/overlaydb"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
@@ -72,10 +74,11 @@ const (
 
 var (
 	//Storage save path.
-	DBDirEvent          = "ledgerevent"
-	DBDirBlock          = "block"
-	DBDirState          = "states"
-	MerkleTreeStorePath = "merkle_tree.db"
+	DBDirEvent               = "ledgerevent"
+	DBDirBlock               = "block"
+	DBDirState               = "states"
+	MerkleTreeStorePath      = "merkle_tree.db"
+	StateMerkleTreeStorePath = "state_merkle_tree.db"
 )
 
 type PrexecuteParam struct {
@@ -102,16 +105,34 @@ type LedgerStoreImp struct {
 	savingBlockSemaphore       chan bool
 	closing                    bool
 	preserveBlockHistoryLength uint32 // block could be pruned if blockHeight + preserveBlockHistoryLength < currHeight , disable prune if equals 0
+	addressIndexEnabled        bool   // index transactions by the addresses they touch
+	contractEventIndexEnabled  bool   // index notifications by contract address and event name
+}
+
+//newPersistStore open the key-value store at dbDir with the configured db backend
+func newPersistStore(dbDir string) (scom.PersistStore, error) {
+	return kvstore.NewPersistStore(config.DefConfig.Common.DBBackend, dbDir)
+}
+
+//PersistStoreDirs return the directories of all key-value stores of ledger under dataDir
+func PersistStoreDirs(dataDir string) []string {
+	dirs := []string{DBDirBlock, DBDirState, DBDirEvent, DBDirCrossChain}
+	for i, dir := range dirs {
+		dirs[i] = fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), dir)
+	}
+	return dirs
 }
 
 //NewLedgerStore return LedgerStoreImp instance
 func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, error) {
 	ledgerStore := &LedgerStoreImp{
-		headerIndex:          make(map[uint32]common.Uint256),
-		headerCache:          make(map[common.Uint256]*types.Header, 0),
-		vbftPeerInfoMap:      make(map[uint32]map[string]uint32),
-		savingBlockSemaphore: make(chan bool, 1),
-		stateHashCheckHeight: stateHashHeight,
+		headerIndex:               make(map[uint32]common.Uint256),
+		headerCache:               make(map[common.Uint256]*types.Header, 0),
+		vbftPeerInfoMap:           make(map[uint32]map[string]uint32),
+		savingBlockSemaphore:      make(chan bool, 1),
+		stateHashCheckHeight:      stateHashHeight,
+		addressIndexEnabled:       config.DefConfig.Common.EnableAddressIndex,
+		contractEventIndexEnabled: config.DefConfig.Common.EnableContractEventIndex,
 	}
 
 	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), true)
@@ -133,6 +154,19 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, er
 		return nil, fmt.Errorf("NewStateStore error %s", err)
 	}
 	ledgerStore.stateStore = stateStore
+	stateStore.EnableStateUndoLog(uint32(config.DefConfig.Common.StateUndoDepth))
+	if config.DefConfig.Common.EnableStateProof {
+		err = stateStore.EnableStateProof(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), StateMerkleTreeStorePath))
+		if err != nil {
+			return nil, fmt.Errorf("EnableStateProof error %s", err)
+		}
+	}
+	if config.DefConfig.Common.EnableArchive {
+		err = stateStore.EnableStateArchive()
+		if err != nil {
+			return nil, fmt.Errorf("EnableStateArchive error %s", err)
+		}
+	}
 
 	eventState, err := NewEventStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirEvent))
 	if err != nil {
@@ -200,9 +234,9 @@ func (this *LedgerStoreImp) InitLedgerStoreWithGenesisBlock(genesisBlock *types.
 			return fmt.Errorf("init error %s", err)
 		}
 	}
-	//load vbft peerInfo
+	//load vbft peerInfo, sbft headers keep the chain config the same way
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
-	if consensusType == "vbft" {
+	if consensusType == "vbft" || consensusType == "sbft" {
 		header, err := this.GetHeaderByHash(this.currBlockHash)
 		if err != nil {
 			return err
@@ -317,7 +351,8 @@ func (this *LedgerStoreImp) recoverStore() error {
 	if err != nil {
 		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
 	}
-	for i := stateHeight; i < blockHeight; i++ {
+	// block store is committed before state store, the blocks above state height are not executed
+	for i := stateHeight + 1; i <= blockHeight; i++ {
 		blockHash, err := this.blockStore.GetBlockHash(i)
 		if err != nil {
 			return fmt.Errorf("blockStore.GetBlockHash height:%d error:%s", i, err)
@@ -461,7 +496,7 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header) error {
 		return fmt.Errorf("block timestamp is incorrect")
 	}
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
-	if consensusType == "vbft" {
+	if consensusType == "vbft" || consensusType == "sbft" {
 		blkInfo, err := vconfig.VbftBlock(header)
 		if err != nil {
 			return err
@@ -488,6 +523,10 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header) error {
 		}
 		this.lock.RUnlock()
 		m := len(vbftPeerInfo) - (len(vbftPeerInfo)*6)/7
+		if consensusType == "sbft" {
+			// quorum certificate of hotstuff
+			m = len(vbftPeerInfo) - (len(vbftPeerInfo)-1)/3
+		}
 		if len(header.Bookkeepers) < m {
 			return fmt.Errorf("header Bookkeepers %d more than 6/7 len vbftPeerInfo%d", len(header.Bookkeepers), len(vbftPeerInfo))
 		}
@@ -535,7 +574,7 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header) error {
 	return nil
 }
 
-func (this *LedgerStoreImp) verifyCrossChainMsg(crossChainMsg *types.CrossChainMsg, bookkeepers []keypair.PublicKey) error {
+func verifyCrossChainMsg(crossChainMsg *types.CrossChainMsg, bookkeepers []keypair.PublicKey) error {
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
 	hash := crossChainMsg.Hash()
 	if consensusType == "vbft" {
@@ -643,7 +682,7 @@ func (this *LedgerStoreImp) SubmitBlock(block *types.Block, ccMsg *types.CrossCh
 		if root != ccMsg.StatesRoot {
 			return fmt.Errorf("cross state root compare fail, expected:%x actual:%x", ccMsg.StatesRoot, root)
 		}
-		if err := this.verifyCrossChainMsg(ccMsg, block.Header.Bookkeepers); err != nil {
+		if err := verifyCrossChainMsg(ccMsg, block.Header.Bookkeepers); err != nil {
 			return fmt.Errorf("verifyCrossChainMsg error: %s", err)
 		}
 	}
@@ -686,7 +725,7 @@ func (this *LedgerStoreImp) AddBlock(block *types.Block, ccMsg *types.CrossChain
 		if root != ccMsg.StatesRoot {
 			return fmt.Errorf("cross state root compare fail, expected:%x actual:%x", ccMsg.StatesRoot, root)
 		}
-		if err := this.verifyCrossChainMsg(ccMsg, block.Header.Bookkeepers); err != nil {
+		if err := verifyCrossChainMsg(ccMsg, block.Header.Bookkeepers); err != nil {
 			return fmt.Errorf("verifyCrossChainMsg error: %s", err)
 		}
 	}
@@ -720,6 +759,7 @@ func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 }
 
 func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.ExecuteResult, err error) {
+	defer observeDuration(blockExecuteMetric, time.Now())
 	overlay := this.stateStore.NewOverlayDB()
 	if block.Header.Height != 0 {
 		config := &smartcontract.Config{
@@ -782,13 +822,14 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 	return
 }
 
+//world state hashed by calculateTotalStateHash, in the order of hashing
+var totalStatePrefixes = []scom.DataEntryPrefix{scom.ST_CONTRACT, scom.ST_STORAGE, scom.ST_DESTROYED,
+	scom.ST_ETH_CODE, scom.ST_ETH_ACCOUNT}
+
 func calculateTotalStateHash(overlay *overlaydb.OverlayDB) (result common.Uint256, err error) {
 	stateDiff := sha256.New()
 
-	prefix := []scom.DataEntryPrefix{scom.ST_CONTRACT, scom.ST_STORAGE, scom.ST_DESTROYED, scom.ST_ETH_CODE,
-		scom.ST_ETH_ACCOUNT}
-
-	for _, v := range prefix {
+	for _, v := range totalStatePrefixes {
 		iter := overlay.NewIterator([]byte{byte(v)})
 		err = accumulateHash(stateDiff, iter)
 		iter.Release()
@@ -821,7 +862,12 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 		}
 	}
 
-	err := this.stateStore.AddStateMerkleTreeRoot(blockHeight, result.Hash)
+	err := this.stateStore.SaveStateUndoLog(blockHeight, result.WriteSet)
+	if err != nil {
+		return fmt.Errorf("SaveStateUndoLog error %s", err)
+	}
+
+	err = this.stateStore.AddStateMerkleTreeRoot(blockHeight, result.Hash)
 	if err != nil {
 		return fmt.Errorf("AddBlockMerkleTreeRoot error %s", err)
 	}
@@ -850,6 +896,10 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 			this.stateStore.BatchPutRawKeyVal(key, val)
 		}
 	})
+	err = this.stateStore.SaveStateHistory(blockHeight, result.WriteSet)
+	if err != nil {
+		return fmt.Errorf("SaveStateHistory error %s", err)
+	}
 
 	return nil
 }
@@ -896,10 +946,7 @@ func (this *LedgerStoreImp) tryPruneBlock(header *types.Header) bool {
 	if this.preserveBlockHistoryLength == 0 {
 		return false
 	}
-	height := this.maxAllowedPruneHeight(header)
-	if height+this.preserveBlockHistoryLength >= header.Height {
-		height = header.Height - this.preserveBlockHistoryLength
-	}
+	height := this.pruneTargetHeight(header, this.preserveBlockHistoryLength)
 	pruned, err := this.blockStore.GetBlockPrunedHeight()
 	if err != nil {
 		return false
@@ -908,18 +955,38 @@ func (this *LedgerStoreImp) tryPruneBlock(header *types.Header) bool {
 		return false
 	}
 
+	this.pruneBlockRange(pruned, height, pruneBatchSize)
+	return true
+}
+
+// pruneTargetHeight returns the height before which blocks can be pruned while
+// keeping numBeforeCurr blocks before header and the blocks vbft still refers to
+func (this *LedgerStoreImp) pruneTargetHeight(header *types.Header, numBeforeCurr uint32) uint32 {
+	height := this.maxAllowedPruneHeight(header)
+	if height+numBeforeCurr >= header.Height {
+		height = header.Height - numBeforeCurr
+	}
+	return height
+}
+
+// pruneBlockRange prunes the blocks after pruned and before height into the
+// current block and event store batches, at most batchSize-1 of them. Returns
+// the new pruned height.
+func (this *LedgerStoreImp) pruneBlockRange(pruned, height, batchSize uint32) uint32 {
 	pruneHeight := pruned + 1
-	for ; pruneHeight-pruned < pruneBatchSize && pruneHeight < height; pruneHeight++ {
+	for ; pruneHeight-pruned < batchSize && pruneHeight < height; pruneHeight++ {
 		hash := this.GetBlockHash(pruneHeight)
 		txHashes := this.blockStore.PruneBlock(hash)
 		this.eventStore.PruneBlock(pruneHeight, txHashes)
 	}
 	this.blockStore.SaveBlockPrunedHeight(pruneHeight)
-	return true
+	return pruneHeight
 }
 
 //saveBlock do the job of execution samrt contract and commit block to store.
 func (this *LedgerStoreImp) submitBlock(block *types.Block, crossChainMsg *types.CrossChainMsg, result store.ExecuteResult) error {
+	start := time.Now()
+	defer observeDuration(blockCommitMetric, start)
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
 	blockRoot := this.GetBlockRootWithNewTxRoots(block.Header.Height, []common.Uint256{block.Header.TransactionsRoot})
@@ -945,6 +1012,15 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, crossChainMsg *types
 		return fmt.Errorf("save to state store height:%d error:%s", blockHeight, err)
 	}
 	this.saveBlockToEventStore(block)
+	if this.addressIndexEnabled {
+		this.eventStore.SaveAddressIndex(block, result.Notify)
+	}
+	if this.contractEventIndexEnabled {
+		err = this.eventStore.SaveContractEventIndex(blockHeight, result.Notify)
+		if err != nil {
+			return fmt.Errorf("save contract event index height:%d error:%s", blockHeight, err)
+		}
+	}
 	err = this.blockStore.CommitTo()
 	if err != nil {
 		return fmt.Errorf("blockStore.CommitTo height:%d error %s", blockHeight, err)
@@ -959,6 +1035,9 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, crossChainMsg *types
 		return fmt.Errorf("stateStore.CommitTo height:%d error %s", blockHeight, err)
 	}
 	this.setCurrentBlock(blockHeight, blockHash)
+	for _, tx := range block.Transactions {
+		tracing.RecordTxSpan(tx.Hash(), "ledger.SubmitBlock", start, tracing.ATTR_BLOCK_HEIGHT.Int64(int64(blockHeight)))
+	}
 
 	if events.DefActorPublisher != nil {
 		events.DefActorPublisher.Publish(
@@ -1000,6 +1079,10 @@ func (this *LedgerStoreImp) saveBlock(block *types.Block, ccMsg *types.CrossChai
 	return this.submitBlock(block, ccMsg, result)
 }
 
+func txLogFields(block *types.Block, txHash common.Uint256) log.Fields {
+	return log.Fields{log.FIELD_HEIGHT: block.Header.Height, log.FIELD_TXHASH: txHash.ToHexString()}
+}
+
 func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cache *storage.CacheDB, gasTable map[string]uint64,
 	block *types.Block, tx *types.Transaction, txIndex uint32) (*event.ExecuteNotify, []common.Uint256, error) {
 	txHash := tx.Hash()
@@ -1013,7 +1096,7 @@ func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cach
 			return nil, nil, fmt.Errorf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
 		if err != nil {
-			log.Debugf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), err)
+			log.WithFields(txLogFields(block, txHash)).Debugf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), err)
 		}
 	case types.InvokeNeo, types.InvokeWasm:
 		crossStateHashes, err = this.stateStore.HandleInvokeTransaction(this, overlay, gasTable, cache, tx, block, notify)
@@ -1021,7 +1104,7 @@ func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cach
 			return nil, nil, fmt.Errorf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
 		if err != nil {
-			log.Debugf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), err)
+			log.WithFields(txLogFields(block, txHash)).Debugf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), err)
 		}
 	case types.EIP155:
 		eiptx, err := tx.GetEIP155Tx()
@@ -1040,7 +1123,7 @@ func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cach
 			return nil, nil, fmt.Errorf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
 		if err != nil {
-			log.Debugf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), err)
+			log.WithFields(txLogFields(block, txHash)).Debugf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), err)
 		}
 	}
 	return notify, crossStateHashes, nil
@@ -1108,6 +1191,14 @@ func (this *LedgerStoreImp) GetCrossChainMsg(height uint32) (*types.CrossChainMs
 	return this.crossChainStore.GetCrossChainMsg(height)
 }
 
+//GetStateProof return the proof of raw state key at block height against the state merkle root
+func (this *LedgerStoreImp) GetStateProof(key []byte, height uint32) (*states.StateProof, error) {
+	if height > this.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("block height %d not found", height)
+	}
+	return this.stateStore.GetStateProof(key, height)
+}
+
 func (this *LedgerStoreImp) GetCrossStatesProof(height uint32, key []byte) ([]byte, error) {
 	hashes, err := this.stateStore.GetCrossStates(height)
 	if err != nil {
@@ -1218,6 +1309,25 @@ func (this *LedgerStoreImp) GetEventNotifyByBlock(height uint32) ([]*event.Execu
 	return this.eventStore.GetEventNotifyByBlock(height)
 }
 
+//GetAddressTxs return the transactions touching the address, the latest first. Transactions of pruned blocks
+//are removed from the index
+func (this *LedgerStoreImp) GetAddressTxs(addr common.Address, skip, limit uint32) ([]*scom.AddressTx, error) {
+	if !this.addressIndexEnabled {
+		return nil, ErrAddressIndexDisabled
+	}
+	return this.eventStore.GetAddressTxs(addr, skip, limit)
+}
+
+//GetContractEvents return the notifications of the contract between the block heights, the earliest first.
+//An empty event name matches every event of the contract
+func (this *LedgerStoreImp) GetContractEvents(contract common.Address, name string, startHeight, endHeight uint32,
+	skip, limit uint32) ([]*scom.ContractEvent, error) {
+	if !this.contractEventIndexEnabled {
+		return nil, ErrContractEventIndexDisabled
+	}
+	return this.eventStore.GetContractEvents(contract, name, startHeight, endHeight, skip, limit)
+}
+
 //PreExecuteContract return the result of smart contract execution without commit to store
 func (this *LedgerStoreImp) PreExecuteContractBatch(txes []*types.Transaction, atomic bool) ([]*sstate.PreExecResult, uint32, error) {
 	if atomic {
@@ -1393,7 +1503,87 @@ func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.P
 }
 
 func (this *LedgerStoreImp) PreExecuteEip155Tx(msg types3.Message) (*types4.ExecutionResult, error) {
-	height := this.GetCurrentBlockHeight()
+	return this.preExecuteEip155Tx(msg, this.GetCurrentBlockHeight(), this.GetCacheDB(), evm2.Config{})
+}
+
+//PreExecuteEip155TxAtHeight executes msg against the state at block height, only available in archive mode
+func (this *LedgerStoreImp) PreExecuteEip155TxAtHeight(msg types3.Message, height uint32) (*types4.ExecutionResult, error) {
+	cache, err := this.GetCacheDBAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return this.preExecuteEip155Tx(msg, height, cache, evm2.Config{})
+}
+
+//TraceEip155Call executes msg with the tracer against the state at block height, the state of past blocks is only
+//available in archive mode
+func (this *LedgerStoreImp) TraceEip155Call(msg types3.Message, height uint32, tracer evm2.Tracer) (*types4.ExecutionResult, error) {
+	vmConfig := evm2.Config{Debug: true, Tracer: tracer}
+	if height == this.GetCurrentBlockHeight() {
+		return this.preExecuteEip155Tx(msg, height, this.GetCacheDB(), vmConfig)
+	}
+	cache, err := this.GetCacheDBAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return this.preExecuteEip155Tx(msg, height, cache, vmConfig)
+}
+
+//TraceEip155Tx re-executes the committed eip155 transaction with the tracer against the state of its parent block,
+//after replaying the transactions before it in the same block. It needs the state history of archive mode.
+func (this *LedgerStoreImp) TraceEip155Tx(txHash common.Uint256, tracer evm2.Tracer) (*types4.ExecutionResult, error) {
+	tx, height, err := this.GetTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if !tx.IsEipTx() {
+		return nil, fmt.Errorf("transaction %s is not an eip155 transaction", txHash.ToHexString())
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("transaction in genesis block can not be traced")
+	}
+	block, err := this.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := this.stateStore.NewOverlayDBAtHeight(height - 1)
+	if err != nil {
+		return nil, err
+	}
+	// the global params of the block are not refreshed, since that changes the gas table of running node
+	gasTable := make(map[string]uint64)
+	neovm.GAS_TABLE.Range(func(k, value interface{}) bool {
+		gasTable[k.(string)] = value.(uint64)
+		return true
+	})
+	cache := storage.NewCacheDB(overlay)
+	for i, prev := range block.Transactions {
+		cache.Reset()
+		if prev.Hash() != txHash {
+			if _, _, err := this.handleTransaction(overlay, cache, gasTable, block, prev, uint32(i)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		eiptx, err := prev.GetEIP155Tx()
+		if err != nil {
+			return nil, err
+		}
+		ctx := Eip155Context{
+			BlockHash: block.Hash(),
+			TxIndex:   uint32(i),
+			Height:    height,
+			Timestamp: block.Header.Timestamp,
+		}
+		notify := &event.ExecuteNotify{TxHash: txHash, State: event.CONTRACT_STATE_FAIL, TxIndex: uint32(i)}
+		return this.stateStore.handleEIP155Transaction(this, cache, eiptx, ctx, notify, true,
+			evm2.Config{Debug: true, Tracer: tracer})
+	}
+	return nil, fmt.Errorf("transaction %s not found in block %d", txHash.ToHexString(), height)
+}
+
+func (this *LedgerStoreImp) preExecuteEip155Tx(msg types3.Message, height uint32, cache *storage.CacheDB,
+	vmConfig evm2.Config) (*types4.ExecutionResult, error) {
 	// use previous block time to make it predictable for easy test
 	blockTime := uint32(time.Now().Unix())
 	if header, err := this.GetHeaderByHeight(height); err == nil {
@@ -1409,9 +1599,8 @@ func (this *LedgerStoreImp) PreExecuteEip155Tx(msg types3.Message) (*types4.Exec
 	config := params.GetChainConfig(sysconfig.DefConfig.P2PNode.EVMChainId)
 	txContext := evm.NewEVMTxContext(msg)
 	blockContext := evm.NewEVMBlockContext(height, blockTime, this)
-	cache := this.GetCacheDB()
 	statedb := storage.NewStateDB(cache, common2.Hash{}, common2.Hash(ctx.BlockHash), ong.OngBalanceHandle{})
-	vmenv := evm2.NewEVM(blockContext, txContext, statedb, config, evm2.Config{})
+	vmenv := evm2.NewEVM(blockContext, txContext, statedb, config, vmConfig)
 	res, err := evm.ApplyMessage(vmenv, msg, common2.Address(utils.GovernanceContractAddress))
 	return res, err
 }
@@ -1436,6 +1625,10 @@ func (this *LedgerStoreImp) Close() error {
 	if err != nil {
 		return fmt.Errorf("stateStore close error %s", err)
 	}
+	err = this.crossChainStore.Close()
+	if err != nil {
+		return fmt.Errorf("crossChainStore close error %s", err)
+	}
 	return nil
 }
 
@@ -1451,6 +1644,61 @@ func (this *LedgerStoreImp) EnableBlockPrune(numBeforeCurr uint32) {
 	this.preserveBlockHistoryLength = numBeforeCurr
 }
 
+// the number of blocks pruned in a batch when pruning manually, the block
+// saving is blocked during each batch
+const manualPruneBatchSize = 1000
+
+// PruneBlocks prunes the blocks earlier than numBeforeCurr blocks before the
+// current block at once, no matter whether block pruning is enabled. Returns
+// the pruned height.
+func (this *LedgerStoreImp) PruneBlocks(numBeforeCurr uint32) (uint32, error) {
+	if numBeforeCurr < minPruneBlocksBeforeCurr {
+		numBeforeCurr = minPruneBlocksBeforeCurr
+	}
+	for {
+		pruned, done, err := this.pruneBlockBatch(numBeforeCurr)
+		if err != nil || done {
+			return pruned, err
+		}
+	}
+}
+
+func (this *LedgerStoreImp) pruneBlockBatch(numBeforeCurr uint32) (pruned uint32, done bool, err error) {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	if this.closing {
+		return 0, true, fmt.Errorf("prune block error: ledger is closing")
+	}
+
+	pruned, err = this.blockStore.GetBlockPrunedHeight()
+	if err != nil {
+		return 0, true, err
+	}
+	header, err := this.GetHeaderByHeight(this.GetCurrentBlockHeight())
+	if err != nil {
+		return pruned, true, err
+	}
+	if header.Height <= numBeforeCurr {
+		return pruned, true, nil
+	}
+	height := this.pruneTargetHeight(header, numBeforeCurr)
+	if pruned+1 >= height {
+		return pruned, true, nil
+	}
+
+	this.blockStore.NewBatch()
+	this.eventStore.NewBatch()
+	pruneHeight := this.pruneBlockRange(pruned, height, manualPruneBatchSize)
+	if err = this.blockStore.CommitTo(); err != nil {
+		return pruned, true, fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	if err = this.eventStore.CommitTo(); err != nil {
+		return pruned, true, fmt.Errorf("eventStore.CommitTo error %s", err)
+	}
+	log.Infof("blocks pruned from %d to %d", pruned+1, pruneHeight)
+	return pruneHeight, false, nil
+}
+
 func (this *LedgerStoreImp) maxAllowedPruneHeight(currHeader *types.Header) uint32 {
 	if currHeader.Height <= config.GetContractApiDeprecateHeight() {
 		return 0
@@ -1475,3 +1723,15 @@ func (this *LedgerStoreImp) GetCacheDB() *storage.CacheDB {
 	return storage.NewCacheDB(overlay)
 
 }
+
+//GetCacheDBAtHeight return the cache db of the state at block height, only available in archive mode
+func (this *LedgerStoreImp) GetCacheDBAtHeight(height uint32) (*storage.CacheDB, error) {
+	if height > this.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("block height %d not found", height)
+	}
+	overlay, err := this.stateStore.NewOverlayDBAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewCacheDB(overlay), nil
+}
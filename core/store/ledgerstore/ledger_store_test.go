@@ -20,14 +20,17 @@ package ledgerstore
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/genesis"
+	"github.com/stretchr/testify/assert"
 )
 
 var testBlockStore *BlockStore
@@ -138,3 +141,40 @@ func TestInitLedgerStoreWithGenesisBlock(t *testing.T) {
 		return
 	}
 }
+
+func TestRecoverStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recover")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var bookkeepers []keypair.PublicKey
+	for i := 0; i < 7; i++ {
+		bookkeepers = append(bookkeepers, account.NewAccount("").PublicKey)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+
+	store := newTestLedger(t, dir, genesisBlock, bookkeepers)
+	for i := 0; i < 5; i++ {
+		addEmptyBlock(t, store)
+	}
+	treeSize := store.stateStore.merkleTree.TreeSize()
+	prev, err := store.GetHeaderByHeight(5)
+	assert.Nil(t, err)
+	// crash after committing block 6 and 7 to block store, before state store
+	var txRoots []common.Uint256
+	for i := 0; i < 2; i++ {
+		prev = saveBlockHeaderOnly(t, store, prev, txRoots)
+		txRoots = append(txRoots, prev.TransactionsRoot)
+	}
+	assert.Nil(t, store.Close())
+
+	store = newTestLedger(t, dir, genesisBlock, bookkeepers)
+	defer store.Close()
+	assert.Equal(t, uint32(7), store.GetCurrentBlockHeight())
+	_, stateHeight, err := store.stateStore.GetCurrentBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(7), stateHeight)
+	// each executed block appends its tx root, only block 6 and 7 are executed again
+	assert.Equal(t, treeSize+2, store.stateStore.merkleTree.TreeSize())
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	blockExecuteMetric = prom.NewHistogram(prom.HistogramOpts{
+		Name: "ontology_ledger_block_execute_seconds",
+		Help: "ontology time to execute the transactions of a block",
+	})
+
+	blockCommitMetric = prom.NewHistogram(prom.HistogramOpts{
+		Name: "ontology_ledger_block_commit_seconds",
+		Help: "ontology time to save an executed block to ledger stores",
+	})
+)
+
+func init() {
+	prom.MustRegister(blockExecuteMetric, blockCommitMetric)
+}
+
+func observeDuration(metric prom.Histogram, start time.Time) {
+	metric.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,710 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+)
+
+const (
+	SNAPSHOT_MAGIC    = "ONTSNAP"
+	SNAPSHOT_VERSION  = byte(1)
+	snapshotBatchSize = 10000 //number of records committed in one batch while importing snapshot
+)
+
+//SnapshotInfo describes the block height a snapshot is taken at
+type SnapshotInfo struct {
+	Height          uint32
+	BlockHash       common.Uint256
+	StateHashHeight uint32         //state hash check height of the network
+	WriteSetHash    common.Uint256 //state merkle tree leaf of the block
+	StateMerkleRoot common.Uint256 //state merkle root of the block, empty below StateHashHeight
+	StateHash       common.Uint256 //hash of the world state, the same as the StateHash of db check at the height
+}
+
+func (this *SnapshotInfo) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint32(this.Height)
+	sink.WriteHash(this.BlockHash)
+	sink.WriteUint32(this.StateHashHeight)
+	sink.WriteHash(this.WriteSetHash)
+	sink.WriteHash(this.StateMerkleRoot)
+	sink.WriteHash(this.StateHash)
+}
+
+func (this *SnapshotInfo) Deserialize(r io.Reader) error {
+	var err error
+	if this.Height, err = serialization.ReadUint32(r); err != nil {
+		return err
+	}
+	if err = this.BlockHash.Deserialize(r); err != nil {
+		return err
+	}
+	if this.StateHashHeight, err = serialization.ReadUint32(r); err != nil {
+		return err
+	}
+	if err = this.WriteSetHash.Deserialize(r); err != nil {
+		return err
+	}
+	if err = this.StateMerkleRoot.Deserialize(r); err != nil {
+		return err
+	}
+	return this.StateHash.Deserialize(r)
+}
+
+//ExportSnapshot writes a snapshot of the ledger at height to w. The snapshot contains the headers up to height,
+//the block at height, the block and state merkle trees, the cross chain msgs and the world state, followed by a
+//sha256 checksum. State store only keeps the latest state, the state below current block height is reverted with
+//the state undo logs, so height must be within the latest blocks saved with --state-undo-depth
+func (this *LedgerStoreImp) ExportSnapshot(w io.Writer, height uint32) (*SnapshotInfo, error) {
+	currHeight := this.GetCurrentBlockHeight()
+	if height > currHeight {
+		return nil, fmt.Errorf("height %d is above current block height %d", height, currHeight)
+	}
+	_, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if stateHeight != currHeight {
+		return nil, fmt.Errorf("state store height %d is inconsistent with block height %d", stateHeight, currHeight)
+	}
+	state, blockTree, stateTree, err := this.stateStore.stateAt(currHeight, height)
+	if err != nil {
+		return nil, err
+	}
+	info := &SnapshotInfo{
+		Height:          height,
+		BlockHash:       this.GetBlockHash(height),
+		StateHashHeight: this.stateHashCheckHeight,
+	}
+	if height >= this.stateHashCheckHeight {
+		info.WriteSetHash, info.StateMerkleRoot, err = this.stateStore.getStateMerkleRootEntry(height)
+		if err != nil {
+			return nil, fmt.Errorf("get state merkle root of height %d error %s", height, err)
+		}
+	} else {
+		stateTree = merkle.NewTree(0, nil, nil)
+	}
+	if info.StateHash, err = calculateTotalStateHash(state); err != nil {
+		return nil, fmt.Errorf("calculateTotalStateHash error %s", err)
+	}
+
+	hasher := sha256.New()
+	writer := bufio.NewWriter(w)
+	out := io.MultiWriter(writer, hasher)
+	sink := common.NewZeroCopySink(nil)
+	write := func() error {
+		_, err := out.Write(sink.Bytes())
+		sink.Reset()
+		return err
+	}
+
+	sink.WriteBytes([]byte(SNAPSHOT_MAGIC))
+	sink.WriteByte(SNAPSHOT_VERSION)
+	info.Serialization(sink)
+	if err := write(); err != nil {
+		return nil, err
+	}
+
+	for h := uint32(0); h <= height; h++ {
+		value, err := this.blockStore.store.Get(genHeaderKey(this.GetBlockHash(h)))
+		if err != nil {
+			return nil, fmt.Errorf("get header of height %d error %s", h, err)
+		}
+		sink.WriteVarBytes(value)
+		if err := write(); err != nil {
+			return nil, err
+		}
+	}
+
+	block, err := this.blockStore.GetBlock(info.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("get block of height %d error %s", height, err)
+	}
+	sink.WriteUint32(uint32(len(block.Transactions)))
+	for _, tx := range block.Transactions {
+		sink.WriteVarBytes(tx.ToArray())
+	}
+
+	writeMerkleTree(sink, blockTree.TreeSize(), blockTree.Hashes())
+	writeMerkleTree(sink, stateTree.TreeSize(), stateTree.Hashes())
+
+	crossStates, err := this.stateStore.GetCrossStates(height)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, fmt.Errorf("GetCrossStates error %s", err)
+	}
+	sink.WriteUint32(uint32(len(crossStates)))
+	for _, hash := range crossStates {
+		sink.WriteHash(hash)
+	}
+	if err := write(); err != nil {
+		return nil, err
+	}
+
+	if err := this.exportCrossChainMsgs(sink, write, height); err != nil {
+		return nil, err
+	}
+
+	for _, prefix := range totalStatePrefixes {
+		var err error
+		iter := state.NewIterator([]byte{byte(prefix)})
+		for has := iter.First(); has; has = iter.Next() {
+			sink.WriteVarBytes(iter.Key())
+			sink.WriteVarBytes(iter.Value())
+			if err = write(); err != nil {
+				break
+			}
+		}
+		iter.Release()
+		if err == nil {
+			err = iter.Error()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// keys are never empty, an empty key ends the state
+	sink.WriteVarBytes(nil)
+	if err := write(); err != nil {
+		return nil, err
+	}
+
+	if _, err := writer.Write(hasher.Sum(nil)); err != nil {
+		return nil, err
+	}
+	return info, writer.Flush()
+}
+
+//exportCrossChainMsgs writes the cross chain msgs saved up to height, the msg of a height is saved with the next block
+func (this *LedgerStoreImp) exportCrossChainMsgs(sink *common.ZeroCopySink, write func() error, height uint32) error {
+	var err error
+	iter := this.crossChainStore.store.NewIterator([]byte{byte(scom.SYS_CROSS_CHAIN_MSG)})
+	for iter.Next() {
+		if key := iter.Key(); len(key) == 5 && binary.LittleEndian.Uint32(key[1:]) >= height {
+			continue
+		}
+		sink.WriteVarBytes(iter.Value())
+		if err = write(); err != nil {
+			break
+		}
+	}
+	iter.Release()
+	if err == nil {
+		err = iter.Error()
+	}
+	if err != nil {
+		return err
+	}
+	// msgs are never empty, an empty msg ends the cross chain msgs
+	sink.WriteVarBytes(nil)
+	return write()
+}
+
+func writeMerkleTree(sink *common.ZeroCopySink, treeSize uint32, hashes []common.Uint256) {
+	sink.WriteUint32(treeSize)
+	sink.WriteUint32(uint32(len(hashes)))
+	for _, hash := range hashes {
+		sink.WriteHash(hash)
+	}
+}
+
+func readMerkleTree(r io.Reader) (*merkle.CompactMerkleTree, error) {
+	treeSize, err := serialization.ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	count, err := serialization.ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	// a compact merkle tree keeps one hash for each bit of tree size
+	if count > 32 {
+		return nil, fmt.Errorf("invalid merkle tree hash count %d", count)
+	}
+	hashes := make([]common.Uint256, count)
+	for i := range hashes {
+		if err := hashes[i].Deserialize(r); err != nil {
+			return nil, err
+		}
+	}
+	return merkle.NewTree(treeSize, hashes, nil), nil
+}
+
+//snapshotImporter writes a verified snapshot into the stores of an empty ledger
+type snapshotImporter struct {
+	blockStore *BlockStore
+	stateStore *StateStore
+	eventStore *EventStore
+	crossChain *CrossChainStore
+	merklePath string
+	// hash store of block merkle tree, flushed once when import finished
+	hashFile   *os.File
+	hashWriter *bufio.Writer
+}
+
+//ImportSnapshot verifies the snapshot read from r and writes it into an empty ledger at dataDir, so that
+//the node starts syncing from the block after the snapshot. The headers in snapshot must link the genesis block
+//to the block of trustedHash, the block merkle tree and the block at snapshot height must match the header, the
+//state merkle tree must have the root of the snapshot, and the cross chain msgs must be signed by the bookkeepers
+//of the headers. The state merkle tree only proves the write sets of blocks, so the world state is hashed while
+//importing and must be the same as stateHash, the trusted state hash of the snapshot height, eg. the StateHash of
+//db check or snapshot export on a trusted node. The bookkeeper state is only written by genesis block, it is
+//rebuilt from bookkeepers
+func ImportSnapshot(dataDir string, r io.Reader, stateHashHeight uint32, bookkeepers []keypair.PublicKey,
+	genesisHash, trustedHash, stateHash common.Uint256) (*SnapshotInfo, error) {
+	importer, err := newSnapshotImporter(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := importer.importSnapshot(r, stateHashHeight, bookkeepers, genesisHash, trustedHash, stateHash)
+	if closeErr := importer.close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func newSnapshotImporter(dataDir string) (*snapshotImporter, error) {
+	importer := &snapshotImporter{
+		merklePath: fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), MerkleTreeStorePath),
+	}
+	var err error
+	if importer.blockStore, err = NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), false); err != nil {
+		return nil, fmt.Errorf("NewBlockStore error %s", err)
+	}
+	stateDir := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirState)
+	store, err := newPersistStore(stateDir)
+	if err != nil {
+		importer.close()
+		return nil, fmt.Errorf("NewStateStore error %s", err)
+	}
+	importer.stateStore = &StateStore{dbDir: stateDir, store: store, merklePath: importer.merklePath}
+	if importer.eventStore, err = NewEventStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirEvent)); err != nil {
+		importer.close()
+		return nil, fmt.Errorf("NewEventStore error %s", err)
+	}
+	if importer.crossChain, err = NewCrossChainStore(dataDir); err != nil {
+		importer.close()
+		return nil, err
+	}
+	version, err := importer.blockStore.GetVersion()
+	if err != nil && err != scom.ErrNotFound {
+		importer.close()
+		return nil, fmt.Errorf("GetVersion error %s", err)
+	}
+	if err == nil && version == SYSTEM_VERSION {
+		importer.close()
+		return nil, fmt.Errorf("ledger already exists in %s", dataDir)
+	}
+	if importer.hashFile, err = os.OpenFile(importer.merklePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755); err != nil {
+		importer.close()
+		return nil, err
+	}
+	importer.hashWriter = bufio.NewWriter(importer.hashFile)
+	return importer, nil
+}
+
+func (this *snapshotImporter) close() error {
+	var err error
+	if this.hashFile != nil {
+		err = this.hashWriter.Flush()
+		if syncErr := this.hashFile.Sync(); err == nil {
+			err = syncErr
+		}
+		this.hashFile.Close()
+	}
+	if this.blockStore != nil {
+		this.blockStore.Close()
+	}
+	if this.stateStore != nil {
+		this.stateStore.store.Close()
+	}
+	if this.eventStore != nil {
+		this.eventStore.Close()
+	}
+	if this.crossChain != nil {
+		this.crossChain.Close()
+	}
+	return err
+}
+
+//Append implements merkle.HashStore to build the block merkle tree file, hashes are buffered until close
+func (this *snapshotImporter) Append(hash []common.Uint256) error {
+	for _, h := range hash {
+		if _, err := this.hashWriter.Write(h[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *snapshotImporter) Flush() error {
+	return nil
+}
+
+func (this *snapshotImporter) Close() {}
+
+func (this *snapshotImporter) GetHash(pos uint32) (common.Uint256, error) {
+	return merkle.EMPTY_HASH, errors.New("not supported while importing snapshot")
+}
+
+func (this *snapshotImporter) importSnapshot(r io.Reader, stateHashHeight uint32, bookkeepers []keypair.PublicKey,
+	genesisHash, trustedHash, stateHash common.Uint256) (*SnapshotInfo, error) {
+	hasher := sha256.New()
+	reader := bufio.NewReader(r)
+	source := io.TeeReader(reader, hasher)
+
+	magic := make([]byte, len(SNAPSHOT_MAGIC))
+	if _, err := io.ReadFull(source, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != SNAPSHOT_MAGIC {
+		return nil, errors.New("not a snapshot file")
+	}
+	version, err := serialization.ReadByte(source)
+	if err != nil {
+		return nil, err
+	}
+	if version != SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	info := &SnapshotInfo{}
+	if err := info.Deserialize(source); err != nil {
+		return nil, err
+	}
+	if info.StateHashHeight != stateHashHeight {
+		return nil, fmt.Errorf("snapshot state hash height %d is different from the network %d, wrong network?",
+			info.StateHashHeight, stateHashHeight)
+	}
+	if info.BlockHash != trustedHash {
+		return nil, fmt.Errorf("snapshot block hash %s is different from the trusted hash %s",
+			info.BlockHash.ToHexString(), trustedHash.ToHexString())
+	}
+	if info.StateHash != stateHash {
+		return nil, fmt.Errorf("snapshot state hash %s is different from the trusted hash %s",
+			info.StateHash.ToHexString(), stateHash.ToHexString())
+	}
+
+	header, txHashes, blockTree, err := this.importHeaders(source, info.Height, genesisHash)
+	if err != nil {
+		return nil, err
+	}
+	if header.Hash() != info.BlockHash {
+		return nil, fmt.Errorf("header hash of height %d is %s, expected %s", info.Height,
+			header.Hash().ToHexString(), info.BlockHash.ToHexString())
+	}
+	if err := this.importTransactions(source, header, txHashes); err != nil {
+		return nil, err
+	}
+
+	tree, err := readMerkleTree(source)
+	if err != nil {
+		return nil, err
+	}
+	if tree.TreeSize() != blockTree.TreeSize() || tree.Root() != blockTree.Root() {
+		return nil, errors.New("block merkle tree is inconsistent with headers")
+	}
+	if info.Height != 0 && blockTree.Root() != header.BlockRoot {
+		return nil, fmt.Errorf("block merkle root %s is different from the header %s",
+			blockTree.Root().ToHexString(), header.BlockRoot.ToHexString())
+	}
+	tree, err = readMerkleTree(source)
+	if err != nil {
+		return nil, err
+	}
+	if info.Height >= stateHashHeight {
+		if tree.TreeSize() != info.Height-stateHashHeight+1 {
+			return nil, fmt.Errorf("state merkle tree size %d is inconsistent with height %d", tree.TreeSize(), info.Height)
+		}
+		if tree.Root() != info.StateMerkleRoot {
+			return nil, fmt.Errorf("state merkle tree root %s is different from the snapshot %s",
+				tree.Root().ToHexString(), info.StateMerkleRoot.ToHexString())
+		}
+	} else if tree.TreeSize() != 0 {
+		return nil, errors.New("unexpected state merkle tree below state hash height")
+	}
+
+	this.stateStore.NewBatch()
+	this.stateStore.batchPutMerkleTree(this.stateStore.genBlockMerkleTreeKey(), blockTree)
+	if info.Height >= stateHashHeight {
+		this.stateStore.batchPutMerkleTree(this.stateStore.genStateMerkleTreeKey(), tree)
+		this.stateStore.batchPutStateMerkleRoot(info.Height, info.WriteSetHash, info.StateMerkleRoot)
+	}
+	count, err := serialization.ReadUint32(source)
+	if err != nil {
+		return nil, err
+	}
+	crossStates := make([]common.Uint256, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var hash common.Uint256
+		if err := hash.Deserialize(source); err != nil {
+			return nil, err
+		}
+		crossStates = append(crossStates, hash)
+	}
+	this.stateStore.SaveCrossStates(info.Height, crossStates)
+	if err := this.stateStore.CommitTo(); err != nil {
+		return nil, err
+	}
+
+	if err := this.importCrossChainMsgs(source, info.Height); err != nil {
+		return nil, err
+	}
+	hash, err := this.importState(source)
+	if err != nil {
+		return nil, err
+	}
+	if hash != stateHash {
+		return nil, fmt.Errorf("state hash %s of snapshot is different from the trusted hash %s",
+			hash.ToHexString(), stateHash.ToHexString())
+	}
+
+	sum := hasher.Sum(nil)
+	checksum := make([]byte, len(sum))
+	if _, err := io.ReadFull(reader, checksum); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sum, checksum) {
+		return nil, errors.New("snapshot checksum mismatch")
+	}
+
+	// the ledger becomes valid only after the current block and version are saved
+	bookkeepers = keypair.SortPublicKeys(bookkeepers)
+	if err := this.stateStore.SaveBookkeeperState(&states.BookkeeperState{
+		CurrBookkeeper: bookkeepers,
+		NextBookkeeper: bookkeepers,
+	}); err != nil {
+		return nil, err
+	}
+	this.stateStore.NewBatch()
+	this.stateStore.SaveCurrentBlock(info.Height, info.BlockHash)
+	if err := this.stateStore.CommitTo(); err != nil {
+		return nil, err
+	}
+	this.eventStore.NewBatch()
+	this.eventStore.SaveCurrentBlock(info.Height, info.BlockHash)
+	if err := this.eventStore.CommitTo(); err != nil {
+		return nil, err
+	}
+	this.blockStore.NewBatch()
+	this.blockStore.SaveCurrentBlock(info.Height, info.BlockHash)
+	// blocks below snapshot height have no transactions, the same as pruned blocks
+	this.blockStore.SaveBlockPrunedHeight(info.Height)
+	if err := this.blockStore.CommitTo(); err != nil {
+		return nil, err
+	}
+	if err := this.blockStore.SaveVersion(SYSTEM_VERSION); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+//importHeaders verifies the header chain and saves the headers, header index and block merkle tree.
+//It returns the last header with its transaction hashes
+func (this *snapshotImporter) importHeaders(source io.Reader, height uint32, genesisHash common.Uint256) (
+	*types.Header, []common.Uint256, *merkle.CompactMerkleTree, error) {
+	blockTree := merkle.NewTree(0, nil, this)
+	var prev *types.Header
+	var txHashes []common.Uint256
+	indexList := make([]common.Uint256, 0, HEADER_INDEX_BATCH_SIZE)
+	this.blockStore.NewBatch()
+	for h := uint32(0); h <= height; h++ {
+		value, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("read header of height %d error %s", h, err)
+		}
+		header, hashes, err := deserializeHeaderWithTx(value)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("deserialize header of height %d error %s", h, err)
+		}
+		blockHash := header.Hash()
+		if header.Height != h {
+			return nil, nil, nil, fmt.Errorf("header height %d is not %d", header.Height, h)
+		}
+		if h == 0 && blockHash != genesisHash {
+			return nil, nil, nil, fmt.Errorf("genesis block hash %s is different from %s", blockHash.ToHexString(),
+				genesisHash.ToHexString())
+		}
+		if prev != nil {
+			if header.PrevBlockHash != prev.Hash() {
+				return nil, nil, nil, fmt.Errorf("header of height %d does not link to previous header", h)
+			}
+			if header.Timestamp <= prev.Timestamp {
+				return nil, nil, nil, fmt.Errorf("header timestamp of height %d is incorrect", h)
+			}
+		}
+		this.blockStore.store.BatchPut(genHeaderKey(blockHash), value)
+		this.blockStore.SaveBlockHash(h, blockHash)
+		blockTree.AppendHash(header.TransactionsRoot)
+
+		// the same as saveHeaderIndexList, only full batches below current height are saved
+		indexList = append(indexList, blockHash)
+		if uint32(len(indexList)) == HEADER_INDEX_BATCH_SIZE {
+			start := h + 1 - HEADER_INDEX_BATCH_SIZE
+			if height-start >= HEADER_INDEX_BATCH_SIZE {
+				this.blockStore.SaveHeaderIndexList(start, indexList)
+			}
+			indexList = indexList[:0]
+		}
+		if (h+1)%snapshotBatchSize == 0 {
+			if err := this.blockStore.CommitTo(); err != nil {
+				return nil, nil, nil, err
+			}
+			this.blockStore.NewBatch()
+		}
+		prev, txHashes = header, hashes
+	}
+	if err := this.blockStore.CommitTo(); err != nil {
+		return nil, nil, nil, err
+	}
+	return prev, txHashes, blockTree, nil
+}
+
+func (this *snapshotImporter) importTransactions(source io.Reader, header *types.Header, txHashes []common.Uint256) error {
+	count, err := serialization.ReadUint32(source)
+	if err != nil {
+		return err
+	}
+	if int(count) != len(txHashes) {
+		return fmt.Errorf("transaction count %d of block %d is different from header %d", count, header.Height,
+			len(txHashes))
+	}
+	this.blockStore.NewBatch()
+	hashes := make([]common.Uint256, 0, count)
+	for i := uint32(0); i < count; i++ {
+		raw, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return err
+		}
+		tx, err := types.TransactionFromRawBytes(raw)
+		if err != nil {
+			return err
+		}
+		if tx.Hash() != txHashes[i] {
+			return fmt.Errorf("transaction %s of block %d is different from header", tx.Hash().ToHexString(),
+				header.Height)
+		}
+		hashes = append(hashes, tx.Hash())
+		this.blockStore.SaveTransaction(tx, header.Height)
+	}
+	if common.ComputeMerkleRoot(hashes) != header.TransactionsRoot {
+		return fmt.Errorf("transaction root of block %d mismatch", header.Height)
+	}
+	return this.blockStore.CommitTo()
+}
+
+//importCrossChainMsgs verifies the cross chain msgs with the bookkeepers of the headers they are saved with
+func (this *snapshotImporter) importCrossChainMsgs(source io.Reader, height uint32) error {
+	this.crossChain.store.NewBatch()
+	for count := 1; ; count++ {
+		value, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return err
+		}
+		if len(value) == 0 {
+			break
+		}
+		msg := new(types.CrossChainMsg)
+		if err := msg.Deserialization(common.NewZeroCopySource(value)); err != nil {
+			return fmt.Errorf("deserialize cross chain msg error %s", err)
+		}
+		if msg.Height >= height {
+			return fmt.Errorf("unexpected cross chain msg of height %d", msg.Height)
+		}
+		blockHash, err := this.blockStore.GetBlockHash(msg.Height + 1)
+		if err != nil {
+			return err
+		}
+		header, err := this.blockStore.GetHeader(blockHash)
+		if err != nil {
+			return err
+		}
+		if err := verifyCrossChainMsg(msg, header.Bookkeepers); err != nil {
+			return fmt.Errorf("verify cross chain msg of height %d error %s", msg.Height, err)
+		}
+		this.crossChain.store.BatchPut(this.crossChain.genCrossChainMsgKey(msg.Height), value)
+		if count%snapshotBatchSize == 0 {
+			if err := this.crossChain.store.BatchCommit(); err != nil {
+				return err
+			}
+			this.crossChain.store.NewBatch()
+		}
+	}
+	return this.crossChain.store.BatchCommit()
+}
+
+//importState saves the world state and returns its hash. Keys must be in the order of calculateTotalStateHash
+//without duplication, so that the hash is the same as the state saved
+func (this *snapshotImporter) importState(source io.Reader) (common.Uint256, error) {
+	hasher := sha256.New()
+	prefix := 0
+	var lastKey []byte
+	this.stateStore.NewBatch()
+	for count := 1; ; count++ {
+		key, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return common.UINT256_EMPTY, err
+		}
+		if len(key) == 0 {
+			break
+		}
+		for prefix < len(totalStatePrefixes) && key[0] != byte(totalStatePrefixes[prefix]) {
+			prefix, lastKey = prefix+1, nil
+		}
+		if prefix == len(totalStatePrefixes) {
+			return common.UINT256_EMPTY, fmt.Errorf("unexpected state key %x", key)
+		}
+		if lastKey != nil && bytes.Compare(key, lastKey) <= 0 {
+			return common.UINT256_EMPTY, fmt.Errorf("state key %x is out of order", key)
+		}
+		value, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return common.UINT256_EMPTY, err
+		}
+		hasher.Write(key)
+		hasher.Write(value)
+		this.stateStore.BatchPutRawKeyVal(key, value)
+		if count%snapshotBatchSize == 0 {
+			if err := this.stateStore.CommitTo(); err != nil {
+				return common.UINT256_EMPTY, err
+			}
+			this.stateStore.NewBatch()
+		}
+		lastKey = key
+	}
+	var hash common.Uint256
+	hasher.Sum(hash[:0])
+	return hash, this.stateStore.CommitTo()
+}
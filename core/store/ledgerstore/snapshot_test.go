@@ -0,0 +1,182 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLedger(t *testing.T, dir string, genesisBlock *types.Block, bookkeepers []keypair.PublicKey) *LedgerStoreImp {
+	store, err := NewLedgerStore(dir, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, store.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	return store
+}
+
+func addEmptyBlock(t *testing.T, store *LedgerStoreImp) *types.Block {
+	prev, err := store.GetHeaderByHeight(store.GetCurrentBlockHeight())
+	assert.Nil(t, err)
+	payload, err := json.Marshal(&vconfig.VbftBlockInfo{})
+	assert.Nil(t, err)
+	txRoot := common.ComputeMerkleRoot(nil)
+	header := &types.Header{
+		PrevBlockHash:    prev.Hash(),
+		TransactionsRoot: txRoot,
+		BlockRoot:        store.GetBlockRootWithNewTxRoots(prev.Height+1, []common.Uint256{txRoot}),
+		Timestamp:        prev.Timestamp + 1,
+		Height:           prev.Height + 1,
+		ConsensusPayload: payload,
+	}
+	block := &types.Block{Header: header}
+	result, err := store.executeBlock(block)
+	assert.Nil(t, err)
+	assert.Nil(t, store.submitBlock(block, nil, result))
+	return block
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var bookkeepers []keypair.PublicKey
+	for i := 0; i < 7; i++ {
+		bookkeepers = append(bookkeepers, account.NewAccount("").PublicKey)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+
+	source := newTestLedger(t, filepath.Join(dir, "source"), genesisBlock, bookkeepers)
+	defer source.Close()
+	source.stateStore.EnableStateUndoLog(3)
+	for i := 0; i < 5; i++ {
+		addEmptyBlock(t, source)
+	}
+	height, blockHash := source.GetCurrentBlock()
+	stateHash, err := calculateTotalStateHash(overlaydb.NewOverlayDB(source.stateStore.store))
+	assert.Nil(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	_, err = source.ExportSnapshot(buf, height+1)
+	assert.NotNil(t, err)
+	_, err = source.ExportSnapshot(buf, height-4)
+	assert.NotNil(t, err)
+	info, err := source.ExportSnapshot(buf, height)
+	assert.Nil(t, err)
+	root, err := source.GetStateMerkleRoot(height)
+	assert.Nil(t, err)
+	assert.Equal(t, root, info.StateMerkleRoot)
+	assert.Equal(t, stateHash, info.StateHash)
+	snapshot := buf.Bytes()
+
+	genesisHash := genesisBlock.Hash()
+	_, err = ImportSnapshot(filepath.Join(dir, "untrusted"), bytes.NewReader(snapshot), 0, bookkeepers, genesisHash,
+		genesisHash, stateHash)
+	assert.NotNil(t, err)
+	_, err = ImportSnapshot(filepath.Join(dir, "wronghash"), bytes.NewReader(snapshot), 0, bookkeepers, genesisHash,
+		blockHash, genesisHash)
+	assert.NotNil(t, err)
+	corrupted := append([]byte{}, snapshot...)
+	corrupted[len(corrupted)-40] ^= 1
+	_, err = ImportSnapshot(filepath.Join(dir, "corrupted"), bytes.NewReader(corrupted), 0, bookkeepers, genesisHash,
+		blockHash, stateHash)
+	assert.NotNil(t, err)
+	// the state is verified by the trusted state hash even if the checksum is forged
+	forged := append([]byte{}, corrupted[:len(corrupted)-sha256.Size]...)
+	sum := sha256.Sum256(forged)
+	forged = append(forged, sum[:]...)
+	_, err = ImportSnapshot(filepath.Join(dir, "forged"), bytes.NewReader(forged), 0, bookkeepers, genesisHash,
+		blockHash, stateHash)
+	assert.NotNil(t, err)
+
+	targetDir := filepath.Join(dir, "target")
+	imported, err := ImportSnapshot(targetDir, bytes.NewReader(snapshot), 0, bookkeepers, genesisHash, blockHash,
+		stateHash)
+	assert.Nil(t, err)
+	assert.Equal(t, info, imported)
+	_, err = ImportSnapshot(targetDir, bytes.NewReader(snapshot), 0, bookkeepers, genesisHash, blockHash, stateHash)
+	assert.NotNil(t, err)
+
+	target := newTestLedger(t, targetDir, genesisBlock, bookkeepers)
+	defer target.Close()
+	targetHeight, targetHash := target.GetCurrentBlock()
+	assert.Equal(t, height, targetHeight)
+	assert.Equal(t, blockHash, targetHash)
+	targetRoot, err := target.GetStateMerkleRoot(height)
+	assert.Nil(t, err)
+	assert.Equal(t, root, targetRoot)
+	bookkeeperState, err := target.GetBookkeeperState()
+	assert.Nil(t, err)
+	expectedState, err := source.GetBookkeeperState()
+	assert.Nil(t, err)
+	assert.Equal(t, expectedState, bookkeeperState)
+	supply, err := target.GetStorageItem(utils.OntContractAddress, []byte("totalSupply"))
+	assert.Nil(t, err)
+	expectedSupply, err := source.GetStorageItem(utils.OntContractAddress, []byte("totalSupply"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, supply)
+	assert.Equal(t, expectedSupply, supply)
+	proof, err := target.GetMerkleProof(1, height)
+	assert.Nil(t, err)
+	expected, err := source.GetMerkleProof(1, height)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, proof)
+
+	// the imported ledger continues from the next block with the same result
+	block := addEmptyBlock(t, source)
+	result, err := target.executeBlock(block)
+	assert.Nil(t, err)
+	assert.Nil(t, target.submitBlock(block, nil, result))
+	root, err = source.GetStateMerkleRoot(height + 1)
+	assert.Nil(t, err)
+	targetRoot, err = target.GetStateMerkleRoot(height + 1)
+	assert.Nil(t, err)
+	assert.Equal(t, root, targetRoot)
+
+	// the snapshot below current height is reverted with the undo logs
+	buf.Reset()
+	info, err = source.ExportSnapshot(buf, height-1)
+	assert.Nil(t, err)
+	assert.Equal(t, height-1, info.Height)
+	assert.Equal(t, source.GetBlockHash(height-1), info.BlockHash)
+	root, err = source.GetStateMerkleRoot(height - 1)
+	assert.Nil(t, err)
+	assert.Equal(t, root, info.StateMerkleRoot)
+	imported, err = ImportSnapshot(filepath.Join(dir, "previous"), bytes.NewReader(buf.Bytes()), 0, bookkeepers,
+		genesisHash, info.BlockHash, stateHash)
+	assert.Nil(t, err)
+	assert.Equal(t, info, imported)
+}
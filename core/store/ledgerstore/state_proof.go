@@ -0,0 +1,375 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/merkle"
+)
+
+// MAX_STATE_PROOF_BLOCKS is the max number of blocks from the last write of a key to the height of its proof, the
+// proof carries the write sets of all of them
+const MAX_STATE_PROOF_BLOCKS = 4096
+
+// MAX_STATE_PROOF_SIZE is the max total size in bytes of the write sets carried by a proof
+const MAX_STATE_PROOF_SIZE = 1024 * 1024
+
+var (
+	ErrStateProofDisabled   = errors.New("state proof is not enabled, restart node with --enable-state-proof")
+	ErrStateHistoryDisabled = errors.New("historical state is not available, the node is not an archive node (--enable-archive)")
+)
+
+// EnableStateHistory records the value of every written key at each block height, so the state of any block
+// height since then can be read
+func (self *StateStore) EnableStateHistory() error {
+	data, err := self.store.Get(genStateHistoryHeightKey())
+	switch err {
+	case nil:
+		if len(data) != 4 {
+			return fmt.Errorf("invalid state history height")
+		}
+		self.historyHeight = binary.LittleEndian.Uint32(data)
+		self.historyHeightSaved = true
+	case scom.ErrNotFound:
+	default:
+		return err
+	}
+	self.stateHistoryEnabled = true
+	return nil
+}
+
+// EnableStateArchive enables state history, and indexes the written keys so the state of past blocks can be read
+// and iterated
+func (self *StateStore) EnableStateArchive() error {
+	if err := self.EnableStateHistory(); err != nil {
+		return err
+	}
+	self.stateArchiveEnabled = true
+	return nil
+}
+
+// EnableStateProof enables state history, records the write set of every block, and persists the state merkle tree
+// hashes to hashStorePath so that inclusion proofs can be generated
+func (self *StateStore) EnableStateProof(hashStorePath string) error {
+	var treeSize uint32
+	var hashes []common.Uint256
+	if self.deltaMerkleTree != nil {
+		treeSize = self.deltaMerkleTree.TreeSize()
+		hashes = self.deltaMerkleTree.Hashes()
+	}
+	hashStore, err := merkle.NewFileHashStore(hashStorePath, treeSize)
+	if err != nil {
+		log.Warnf("state merkle hash store is inconsistent with state store, rebuilding: %s", err)
+		hashStore, err = self.rebuildStateHashStore(hashStorePath, treeSize)
+		if err != nil {
+			return fmt.Errorf("rebuild state merkle hash store error %s", err)
+		}
+	}
+	if self.deltaMerkleTree != nil {
+		self.deltaMerkleTree = merkle.NewTree(treeSize, hashes, hashStore)
+	}
+	if self.deltaHashStore != nil {
+		self.deltaHashStore.Close()
+	}
+	self.deltaHashStore = hashStore
+
+	if err := self.EnableStateHistory(); err != nil {
+		return err
+	}
+	self.stateProofEnabled = true
+	return nil
+}
+
+func (self *StateStore) rebuildStateHashStore(hashStorePath string, treeSize uint32) (merkle.HashStore, error) {
+	if err := os.Remove(hashStorePath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(hashStorePath, os.O_RDWR|os.O_CREATE, 0755)
+	if err != nil {
+		return nil, err
+	}
+	buffered := &bufferedHashStore{writer: bufio.NewWriter(file)}
+	tree := merkle.NewTree(0, nil, buffered)
+	for i := uint32(0); i < treeSize; i++ {
+		leaf, err := self.getWriteSetHash(self.stateHashCheckHeight + i)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		tree.AppendHash(leaf)
+	}
+	err = buffered.writer.Flush()
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	if buffered.err != nil {
+		return nil, buffered.err
+	}
+	if treeSize != 0 && tree.Root() != self.deltaMerkleTree.Root() {
+		return nil, fmt.Errorf("rebuilt state merkle root mismatch")
+	}
+	return merkle.NewFileHashStore(hashStorePath, treeSize)
+}
+
+// bufferedHashStore only supports appending, it avoids syncing the file on every leaf while rebuilding
+type bufferedHashStore struct {
+	writer *bufio.Writer
+	err    error
+}
+
+func (self *bufferedHashStore) Append(hashes []common.Uint256) error {
+	for _, hash := range hashes {
+		if _, err := self.writer.Write(hash[:]); err != nil && self.err == nil {
+			self.err = err
+		}
+	}
+	return self.err
+}
+
+func (self *bufferedHashStore) Flush() error {
+	return nil
+}
+
+func (self *bufferedHashStore) Close() {}
+
+func (self *bufferedHashStore) GetHash(pos uint32) (common.Uint256, error) {
+	return common.Uint256{}, errors.New("not supported")
+}
+
+// SaveStateHistory saves the state changes of block to batch, does nothing if state history is disabled
+func (self *StateStore) SaveStateHistory(height uint32, writeSet *overlaydb.MemDB) error {
+	if !self.stateHistoryEnabled {
+		return nil
+	}
+	if !self.historyHeightSaved {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], height)
+		self.store.BatchPut(genStateHistoryHeightKey(), buf[:])
+		self.historyHeight = height
+		self.historyHeightSaved = true
+	}
+
+	var ws states.WriteSet
+	var err error
+	writeSet.ForEach(func(key, val []byte) {
+		if err != nil {
+			return
+		}
+		if self.stateArchiveEnabled {
+			err = self.indexStateHistory(key)
+		}
+		self.store.BatchPut(genStateHistoryKey(key, height), val)
+		ws = append(ws, states.WriteSetEntry{Key: key, Value: val})
+	})
+	if err != nil {
+		return err
+	}
+	if self.stateProofEnabled && height > self.stateHashCheckHeight {
+		sink := common.NewZeroCopySink(nil)
+		ws.Serialization(sink)
+		self.store.BatchPut(genWriteSetKey(height), sink.Bytes())
+	}
+	return nil
+}
+
+// indexStateHistory records the key written in state history. If state history is enabled after genesis, the value
+// before the first recorded write is kept so earlier heights can still be read.
+func (self *StateStore) indexStateHistory(key []byte) error {
+	indexKey := genStateHistoryIndexKey(key)
+	_, err := self.store.Get(indexKey)
+	if err == nil {
+		return nil
+	} else if err != scom.ErrNotFound {
+		return err
+	}
+	self.store.BatchPut(indexKey, []byte{1})
+	if self.historyHeight == 0 {
+		return nil
+	}
+	// the history recorded before archive mode was enabled is not indexed
+	historyKey := genStateHistoryKey(key, 0)
+	iter := self.store.NewIterator(historyKey[:len(historyKey)-4])
+	has := iter.First()
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if has {
+		return nil
+	}
+	value, err := self.store.Get(key)
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	self.store.BatchPut(genStateHistoryKey(key, self.historyHeight-1), value)
+	return nil
+}
+
+// GetStateHistory return the value of raw key at block height and the height it was last written, or the height
+// before state history started if it was not written since then. An empty value means the key was deleted,
+// scom.ErrNotFound is returned if the key does not exist.
+func (self *StateStore) GetStateHistory(key []byte, height uint32) (uint32, []byte, error) {
+	if !self.stateHistoryEnabled {
+		return 0, nil, ErrStateHistoryDisabled
+	}
+	if !self.historyHeightSaved || height < self.historyHeight {
+		return 0, nil, fmt.Errorf("state history is not available at height %d", height)
+	}
+	seekKey := genStateHistoryKey(key, height)
+	iter := self.store.NewIterator(seekKey[:len(seekKey)-4])
+	defer iter.Release()
+
+	found := false
+	if seeker, ok := iter.(interface{ Seek(key []byte) bool }); ok {
+		found = seeker.Seek(seekKey)
+	} else {
+		for has := iter.First(); has; has = iter.Next() {
+			if bytes.Compare(iter.Key(), seekKey) >= 0 {
+				found = true
+				break
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, nil, err
+	}
+	if !found {
+		if self.historyHeight == 0 {
+			return 0, nil, scom.ErrNotFound
+		}
+		// not written since state history started
+		value, err := self.store.Get(key)
+		if err != nil {
+			return 0, nil, err
+		}
+		return self.historyHeight - 1, value, nil
+	}
+	histKey := iter.Key()
+	writeHeight := ^binary.BigEndian.Uint32(histKey[len(histKey)-4:])
+	value := make([]byte, len(iter.Value()))
+	copy(value, iter.Value())
+	return writeHeight, value, nil
+}
+
+// GetStateProof return the proof of raw key at block height against state merkle root of that height
+func (self *StateStore) GetStateProof(key []byte, height uint32) (*states.StateProof, error) {
+	if !self.stateProofEnabled {
+		return nil, ErrStateProofDisabled
+	}
+	if height <= self.stateHashCheckHeight {
+		return nil, fmt.Errorf("state proof is not available before state hash check height %d", self.stateHashCheckHeight)
+	}
+	writeHeight, value, err := self.GetStateHistory(key, height)
+	if err != nil {
+		return nil, err
+	}
+	if writeHeight <= self.stateHashCheckHeight {
+		return nil, fmt.Errorf("key was last written at height %d, before state hash check height %d",
+			writeHeight, self.stateHashCheckHeight)
+	}
+	if writeHeight < self.historyHeight {
+		return nil, fmt.Errorf("key was last written before state history started at height %d, it can not be proven until written again",
+			self.historyHeight)
+	}
+	if height-writeHeight >= MAX_STATE_PROOF_BLOCKS {
+		return nil, fmt.Errorf("key was last written at height %d, a proof can span %d blocks at most, it can be proven at heights %d to %d",
+			writeHeight, MAX_STATE_PROOF_BLOCKS, writeHeight, writeHeight+MAX_STATE_PROOF_BLOCKS-1)
+	}
+	writeSets := make([]states.WriteSet, 0, height-writeHeight+1)
+	size := 0
+	for h := writeHeight; h <= height; h++ {
+		data, err := self.store.Get(genWriteSetKey(h))
+		if err != nil {
+			return nil, fmt.Errorf("get write set of height %d error %s", h, err)
+		}
+		size += len(data)
+		if size > MAX_STATE_PROOF_SIZE {
+			return nil, fmt.Errorf("write sets from height %d to %d exceed the max state proof size %d",
+				writeHeight, h, MAX_STATE_PROOF_SIZE)
+		}
+		var writeSet states.WriteSet
+		if err := writeSet.Deserialization(common.NewZeroCopySource(data)); err != nil {
+			return nil, err
+		}
+		writeSets = append(writeSets, writeSet)
+	}
+	hashes, err := self.deltaMerkleTree.HashesOfSize(writeHeight - self.stateHashCheckHeight)
+	if err != nil {
+		return nil, err
+	}
+	return &states.StateProof{
+		Key:         key,
+		Value:       value,
+		Height:      height,
+		WriteHeight: writeHeight,
+		TreeHashes:  hashes,
+		WriteSets:   writeSets,
+	}, nil
+}
+
+func (self *StateStore) getWriteSetHash(height uint32) (common.Uint256, error) {
+	value, err := self.store.Get(self.genStateMerkleRootKey(height))
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	hash, eof := common.NewZeroCopySource(value).NextHash()
+	if eof {
+		return common.Uint256{}, io.ErrUnexpectedEOF
+	}
+	return hash, nil
+}
+
+func genWriteSetKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.DATA_WRITE_SET)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+// the key length is prefixed so that the history of one key is not interleaved with keys it prefixes, and the
+// height is inverted so that seeking finds the last write at or before the height
+func genStateHistoryKey(key []byte, height uint32) []byte {
+	result := make([]byte, 1+2+len(key)+4)
+	result[0] = byte(scom.DATA_STATE_HISTORY)
+	binary.BigEndian.PutUint16(result[1:], uint16(len(key)))
+	copy(result[3:], key)
+	binary.BigEndian.PutUint32(result[3+len(key):], ^height)
+	return result
+}
+
+func genStateHistoryIndexKey(key []byte) []byte {
+	return append([]byte{byte(scom.DATA_STATE_HISTORY_INDEX)}, key...)
+}
+
+func genStateHistoryHeightKey() []byte {
+	return []byte{byte(scom.SYS_STATE_HISTORY_HEIGHT)}
+}
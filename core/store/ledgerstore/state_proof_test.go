@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateProof(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stateproof")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	const checkHeight = 2
+	db := NewMemStateStore(checkHeight)
+	assert.Nil(t, db.EnableStateProof(filepath.Join(dir, StateMerkleTreeStorePath)))
+
+	key := []byte("key")
+	other := []byte("key-other")
+	for height := uint32(0); height <= 10; height++ {
+		overlay := db.NewOverlayDB()
+		overlay.Put(other, []byte(fmt.Sprintf("other-%d", height)))
+		switch height {
+		case 4:
+			overlay.Put(key, []byte("v4"))
+		case 7:
+			overlay.Put(key, []byte("v7"))
+		case 9:
+			overlay.Delete(key)
+		}
+		writeSetHash := overlay.ChangeHash()
+
+		db.NewBatch()
+		assert.Nil(t, db.AddStateMerkleTreeRoot(height, writeSetHash))
+		overlay.GetWriteSet().ForEach(func(k, v []byte) {
+			if len(v) == 0 {
+				db.BatchDeleteRawKey(k)
+			} else {
+				db.BatchPutRawKeyVal(k, v)
+			}
+		})
+		db.SaveStateHistory(height, overlay.GetWriteSet())
+		assert.Nil(t, db.CommitTo())
+	}
+
+	// a missing hash store is rebuilt from the saved write set hashes
+	assert.Nil(t, db.EnableStateProof(filepath.Join(dir, "rebuild.db")))
+
+	_, _, err = db.GetStateHistory(key, 3)
+	assert.Equal(t, scom.ErrNotFound, err)
+	for height, expected := range map[uint32]string{4: "v4", 6: "v4", 7: "v7", 8: "v7", 9: "", 10: ""} {
+		_, value, err := db.GetStateHistory(key, height)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(value))
+
+		proof, err := db.GetStateProof(key, height)
+		assert.Nil(t, err)
+		root, err := db.GetStateMerkleRoot(height)
+		assert.Nil(t, err)
+		assert.Nil(t, proof.Verify(root, checkHeight))
+
+		raw := common.SerializeToBytes(proof)
+		decoded := new(states.StateProof)
+		assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(raw)))
+		assert.Equal(t, raw, common.SerializeToBytes(decoded))
+
+		decoded.Value = []byte("forged")
+		assert.NotNil(t, decoded.Verify(root, checkHeight))
+	}
+
+	// a proof skipping the later write of key is rejected
+	proof, err := db.GetStateProof(key, 6)
+	assert.Nil(t, err)
+	later, err := db.GetStateProof(key, 8)
+	assert.Nil(t, err)
+	proof.Height = 8
+	proof.WriteSets = append(proof.WriteSets, later.WriteSets...)
+	root, err := db.GetStateMerkleRoot(8)
+	assert.Nil(t, err)
+	assert.NotNil(t, proof.Verify(root, checkHeight))
+	proof.WriteSets[3] = proof.WriteSets[2]
+	assert.NotNil(t, proof.Verify(root, checkHeight))
+
+	_, err = db.GetStateProof(other, checkHeight)
+	assert.NotNil(t, err)
+}
+
+func TestStateProofOfIdleKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stateproof")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	const checkHeight = 1
+	const writeHeight = 3
+	db := NewMemStateStore(checkHeight)
+	assert.Nil(t, db.EnableStateProof(filepath.Join(dir, StateMerkleTreeStorePath)))
+
+	// the key of an idle account is not written again, while the other keys are written in every block
+	key := []byte("idle")
+	other := []byte("key-other")
+	last := uint32(writeHeight + MAX_STATE_PROOF_BLOCKS)
+	for height := uint32(0); height <= last; height++ {
+		overlay := db.NewOverlayDB()
+		overlay.Put(other, []byte(fmt.Sprintf("other-%d", height)))
+		if height == writeHeight {
+			overlay.Put(key, []byte("balance"))
+		}
+		db.NewBatch()
+		assert.Nil(t, db.AddStateMerkleTreeRoot(height, overlay.ChangeHash()))
+		overlay.GetWriteSet().ForEach(func(k, v []byte) {
+			db.BatchPutRawKeyVal(k, v)
+		})
+		db.SaveStateHistory(height, overlay.GetWriteSet())
+		assert.Nil(t, db.CommitTo())
+	}
+
+	proof, err := db.GetStateProof(key, last-1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(writeHeight), proof.WriteHeight)
+	assert.Equal(t, MAX_STATE_PROOF_BLOCKS, len(proof.WriteSets))
+	root, err := db.GetStateMerkleRoot(last - 1)
+	assert.Nil(t, err)
+	assert.Nil(t, proof.Verify(root, checkHeight))
+
+	// the error tells the heights the key can still be proven at
+	_, err = db.GetStateProof(key, last)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("heights %d to %d", writeHeight, last-1))
+}
@@ -53,12 +53,19 @@ type StateStore struct {
 	deltaMerkleTree      *merkle.CompactMerkleTree //Merkle tree of delta state root
 	merkleHashStore      merkle.HashStore
 	stateHashCheckHeight uint32
+	stateHistoryEnabled  bool             //Whether state history is recorded, for archive mode and state proof
+	stateArchiveEnabled  bool             //Whether the state of past blocks can be read and iterated, in archive mode
+	stateProofEnabled    bool             //Whether write sets are recorded for state proof
+	deltaHashStore       merkle.HashStore //Hash store of delta merkle tree, only used if state proof enabled
+	historyHeight        uint32           //First block height recorded in state history
+	historyHeightSaved   bool
+	undoLogDepth         uint32 //Number of latest blocks whose undo logs are kept, 0 means no undo log
 }
 
 //NewStateStore return state store instance
 func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*StateStore, error) {
 	var err error
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := newPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -170,6 +177,12 @@ func (self *StateStore) GetStateMerkleRoot(height uint32) (result common.Uint256
 	if height < self.stateHashCheckHeight {
 		return
 	}
+	_, result, err = self.getStateMerkleRootEntry(height)
+	return
+}
+
+//getStateMerkleRootEntry return the write set hash and state merkle root saved at block height
+func (self *StateStore) getStateMerkleRootEntry(height uint32) (writeSetHash, root common.Uint256, err error) {
 	key := self.genStateMerkleRootKey(height)
 	var value []byte
 	value, err = self.store.Get(key)
@@ -177,11 +190,11 @@ func (self *StateStore) GetStateMerkleRoot(height uint32) (result common.Uint256
 		return
 	}
 	source := common.NewZeroCopySource(value)
-	_, eof := source.NextHash()
+	writeSetHash, eof := source.NextHash()
 	if eof {
 		err = io.ErrUnexpectedEOF
 	}
-	result, eof = source.NextHash()
+	root, eof = source.NextHash()
 	if eof {
 		err = io.ErrUnexpectedEOF
 	}
@@ -192,43 +205,38 @@ func (self *StateStore) AddStateMerkleTreeRoot(blockHeight uint32, writeSetHash
 	if blockHeight < self.stateHashCheckHeight {
 		return nil
 	} else if blockHeight == self.stateHashCheckHeight {
-		self.deltaMerkleTree = merkle.NewTree(0, nil, nil)
+		self.deltaMerkleTree = merkle.NewTree(0, nil, self.deltaHashStore)
 	}
-	key := self.genStateMerkleTreeKey()
-
 	self.deltaMerkleTree.AppendHash(writeSetHash)
-	treeSize := self.deltaMerkleTree.TreeSize()
-	hashes := self.deltaMerkleTree.Hashes()
-	value := common.NewZeroCopySink(make([]byte, 0, 4+len(hashes)*common.UINT256_SIZE))
-	value.WriteUint32(treeSize)
-	for _, hash := range hashes {
-		value.WriteHash(hash)
-	}
-	self.store.BatchPut(key, value.Bytes())
-
-	key = self.genStateMerkleRootKey(blockHeight)
-	value.Reset()
-	value.WriteHash(writeSetHash)
-	value.WriteHash(self.deltaMerkleTree.Root())
-	self.store.BatchPut(key, value.Bytes())
+	self.batchPutMerkleTree(self.genStateMerkleTreeKey(), self.deltaMerkleTree)
+	self.batchPutStateMerkleRoot(blockHeight, writeSetHash, self.deltaMerkleTree.Root())
 
 	return nil
 }
 
 //AddBlockMerkleTreeRoot add a new tree root
 func (self *StateStore) AddBlockMerkleTreeRoot(txRoot common.Uint256) error {
-	key := self.genBlockMerkleTreeKey()
-
 	self.merkleTree.AppendHash(txRoot)
-	treeSize := self.merkleTree.TreeSize()
-	hashes := self.merkleTree.Hashes()
+	self.batchPutMerkleTree(self.genBlockMerkleTreeKey(), self.merkleTree)
+	return nil
+}
+
+func (self *StateStore) batchPutMerkleTree(key []byte, tree *merkle.CompactMerkleTree) {
+	treeSize := tree.TreeSize()
+	hashes := tree.Hashes()
 	value := common.NewZeroCopySink(make([]byte, 0, 4+len(hashes)*common.UINT256_SIZE))
 	value.WriteUint32(treeSize)
 	for _, hash := range hashes {
 		value.WriteHash(hash)
 	}
 	self.store.BatchPut(key, value.Bytes())
-	return nil
+}
+
+func (self *StateStore) batchPutStateMerkleRoot(height uint32, writeSetHash, root common.Uint256) {
+	value := common.NewZeroCopySink(make([]byte, 0, 2*common.UINT256_SIZE))
+	value.WriteHash(writeSetHash)
+	value.WriteHash(root)
+	self.store.BatchPut(self.genStateMerkleRootKey(height), value.Bytes())
 }
 
 //GetMerkleProof return merkle proof of block
@@ -527,6 +535,9 @@ func (self *StateStore) ClearAll() error {
 //Close state store
 func (self *StateStore) Close() error {
 	self.merkleHashStore.Close()
+	if self.deltaHashStore != nil {
+		self.deltaHashStore.Close()
+	}
 	return self.store.Close()
 }
 
@@ -23,6 +23,8 @@ import (
 	"testing"
 
 	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
 	"github.com/ontio/ontology/merkle"
 	"github.com/stretchr/testify/assert"
 )
@@ -71,3 +73,26 @@ func TestStateMerkleRoot(t *testing.T) {
 	}
 
 }
+
+func TestStateUndoLogEmptyValue(t *testing.T) {
+	store := NewMemStateStore(0)
+	store.EnableStateUndoLog(10)
+	empty, absent := []byte("empty"), []byte("absent")
+	assert.Nil(t, store.store.Put(empty, []byte{}))
+
+	writeSet := overlaydb.NewMemDB(0, 0)
+	writeSet.Put(empty, []byte("v1"))
+	writeSet.Put(absent, []byte("v1"))
+	store.NewBatch()
+	assert.Nil(t, store.SaveStateUndoLog(1, writeSet))
+	assert.Nil(t, store.CommitTo())
+	assert.Nil(t, store.store.Put(empty, []byte("v1")))
+	assert.Nil(t, store.store.Put(absent, []byte("v1")))
+
+	assert.Nil(t, store.rollbackBlock(1, common.UINT256_EMPTY))
+	value, err := store.store.Get(empty)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(value))
+	_, err = store.store.Get(absent)
+	assert.Equal(t, scom.ErrNotFound, err)
+}
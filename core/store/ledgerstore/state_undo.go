@@ -0,0 +1,288 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/merkle"
+)
+
+// stateUndoLog is the state before a block is saved to state store, it is used to roll the block back
+type stateUndoLog struct {
+	blockTreeSize   uint32
+	blockTreeHashes []common.Uint256
+	stateTreeSize   uint32 //0 if the block is below state hash height
+	stateTreeHashes []common.Uint256
+	prevValues      []undoEntry //previous values of the keys written by the block
+}
+
+// undoEntry is the value of key before the block is saved, a key may exist with empty value
+type undoEntry struct {
+	key   []byte
+	value []byte
+	exist bool
+}
+
+func (this *stateUndoLog) Serialization(sink *common.ZeroCopySink) {
+	writeUndoMerkleTree(sink, this.blockTreeSize, this.blockTreeHashes)
+	writeUndoMerkleTree(sink, this.stateTreeSize, this.stateTreeHashes)
+	sink.WriteVarUint(uint64(len(this.prevValues)))
+	for _, entry := range this.prevValues {
+		sink.WriteVarBytes(entry.key)
+		sink.WriteBool(entry.exist)
+		sink.WriteVarBytes(entry.value)
+	}
+}
+
+func (this *stateUndoLog) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	if this.blockTreeSize, this.blockTreeHashes, err = readUndoMerkleTree(source); err != nil {
+		return err
+	}
+	if this.stateTreeSize, this.stateTreeHashes, err = readUndoMerkleTree(source); err != nil {
+		return err
+	}
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.prevValues = nil
+	for i := uint64(0); i < n; i++ {
+		var entry undoEntry
+		if entry.key, err = nextUndoBytes(source); err != nil {
+			return err
+		}
+		entry.exist, irregular, eof = source.NextBool()
+		if irregular {
+			return common.ErrIrregularData
+		}
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		if entry.value, err = nextUndoBytes(source); err != nil {
+			return err
+		}
+		this.prevValues = append(this.prevValues, entry)
+	}
+	return nil
+}
+
+func nextUndoBytes(source *common.ZeroCopySource) ([]byte, error) {
+	data, _, irregular, eof := source.NextVarBytes()
+	if irregular {
+		return nil, common.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+func writeUndoMerkleTree(sink *common.ZeroCopySink, treeSize uint32, hashes []common.Uint256) {
+	sink.WriteUint32(treeSize)
+	sink.WriteVarUint(uint64(len(hashes)))
+	for _, hash := range hashes {
+		sink.WriteHash(hash)
+	}
+}
+
+func readUndoMerkleTree(source *common.ZeroCopySource) (uint32, []common.Uint256, error) {
+	treeSize, eof := source.NextUint32()
+	if eof {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return 0, nil, common.ErrIrregularData
+	}
+	if eof || n > source.Len()/common.UINT256_SIZE {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	hashes := make([]common.Uint256, 0, n)
+	for i := uint64(0); i < n; i++ {
+		hash, _ := source.NextHash()
+		hashes = append(hashes, hash)
+	}
+	return treeSize, hashes, nil
+}
+
+// EnableStateUndoLog records the state before each block is saved for the latest depth blocks, so that the state
+// store can be rolled back by the db rollback command
+func (self *StateStore) EnableStateUndoLog(depth uint32) {
+	self.undoLogDepth = depth
+}
+
+// SaveStateUndoLog saves the undo log of block to batch, and removes the undo log out of depth. It must be called
+// before the merkle trees and the write set of block are saved
+func (self *StateStore) SaveStateUndoLog(height uint32, writeSet *overlaydb.MemDB) error {
+	if self.undoLogDepth == 0 || height == 0 {
+		return nil
+	}
+	undo := &stateUndoLog{
+		blockTreeSize:   self.merkleTree.TreeSize(),
+		blockTreeHashes: self.merkleTree.Hashes(),
+	}
+	if self.deltaMerkleTree != nil && height > self.stateHashCheckHeight {
+		undo.stateTreeSize = self.deltaMerkleTree.TreeSize()
+		undo.stateTreeHashes = self.deltaMerkleTree.Hashes()
+	}
+	var err error
+	writeSet.ForEach(func(key, _ []byte) {
+		if err != nil {
+			return
+		}
+		value, e := self.store.Get(key)
+		if e != nil && e != scom.ErrNotFound {
+			err = e
+			return
+		}
+		undo.prevValues = append(undo.prevValues, undoEntry{key: key, value: value, exist: e == nil})
+	})
+	if err != nil {
+		return err
+	}
+	sink := common.NewZeroCopySink(nil)
+	undo.Serialization(sink)
+	self.store.BatchPut(genStateUndoLogKey(height), sink.Bytes())
+	if height > self.undoLogDepth {
+		self.store.BatchDelete(genStateUndoLogKey(height - self.undoLogDepth))
+	}
+	return nil
+}
+
+func (self *StateStore) getStateUndoLog(height uint32) (*stateUndoLog, error) {
+	data, err := self.store.Get(genStateUndoLogKey(height))
+	if err != nil {
+		return nil, err
+	}
+	undo := &stateUndoLog{}
+	if err := undo.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, err
+	}
+	return undo, nil
+}
+
+// checkRollback checks that the state store can be rolled back from current height to height
+func (self *StateStore) checkRollback(currHeight, height uint32) error {
+	data, err := self.store.Get(genStateHistoryHeightKey())
+	if err == nil && len(data) == 4 {
+		// the previous states of history are recorded at the height before it started
+		if historyHeight := binary.LittleEndian.Uint32(data); height+1 < historyHeight {
+			return fmt.Errorf("can not roll back below height %d where state history started", historyHeight-1)
+		}
+	} else if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	for h := currHeight; h > height; h-- {
+		_, err := self.store.Get(genStateUndoLogKey(h))
+		if err == scom.ErrNotFound {
+			return fmt.Errorf("state undo log of height %d not found, undo logs are only kept for the latest "+
+				"blocks saved with --state-undo-depth", h)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stateAt reverts the state of current height to height in an overlay with the undo logs, it returns the overlay
+// and the block and state merkle trees of height
+func (self *StateStore) stateAt(currHeight, height uint32) (*overlaydb.OverlayDB, *merkle.CompactMerkleTree,
+	*merkle.CompactMerkleTree, error) {
+	overlay := overlaydb.NewOverlayDB(self.store)
+	if height == currHeight {
+		treeSize, hashes, err := self.GetBlockMerkleTree()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("GetBlockMerkleTree error %s", err)
+		}
+		blockTree := merkle.NewTree(treeSize, hashes, nil)
+		treeSize, hashes, err = self.GetStateMerkleTree()
+		if err != nil && err != scom.ErrNotFound {
+			return nil, nil, nil, fmt.Errorf("GetStateMerkleTree error %s", err)
+		}
+		return overlay, blockTree, merkle.NewTree(treeSize, hashes, nil), nil
+	}
+	var undo *stateUndoLog
+	for h := currHeight; h > height; h-- {
+		var err error
+		undo, err = self.getStateUndoLog(h)
+		if err == scom.ErrNotFound {
+			return nil, nil, nil, fmt.Errorf("state undo log of height %d not found, undo logs are only kept "+
+				"for the latest blocks saved with --state-undo-depth", h)
+		} else if err != nil {
+			return nil, nil, nil, fmt.Errorf("get state undo log of height %d error %s", h, err)
+		}
+		for _, entry := range undo.prevValues {
+			if entry.exist {
+				overlay.Put(entry.key, entry.value)
+			} else {
+				overlay.Delete(entry.key)
+			}
+		}
+	}
+	// the undo log of the block after height records the merkle trees of height
+	return overlay, merkle.NewTree(undo.blockTreeSize, undo.blockTreeHashes, nil),
+		merkle.NewTree(undo.stateTreeSize, undo.stateTreeHashes, nil), nil
+}
+
+// rollbackBlock reverts the block at height with its undo log and sets the previous block as current block
+func (self *StateStore) rollbackBlock(height uint32, prevHash common.Uint256) error {
+	undo, err := self.getStateUndoLog(height)
+	if err != nil {
+		return fmt.Errorf("get state undo log of height %d error %s", height, err)
+	}
+	self.NewBatch()
+	for _, entry := range undo.prevValues {
+		if entry.exist {
+			self.store.BatchPut(entry.key, entry.value)
+		} else {
+			self.store.BatchDelete(entry.key)
+		}
+		self.store.BatchDelete(genStateHistoryKey(entry.key, height))
+	}
+	self.batchPutMerkleTree(self.genBlockMerkleTreeKey(), merkle.NewTree(undo.blockTreeSize, undo.blockTreeHashes, nil))
+	if undo.stateTreeSize == 0 {
+		self.store.BatchDelete(self.genStateMerkleTreeKey())
+	} else {
+		self.batchPutMerkleTree(self.genStateMerkleTreeKey(),
+			merkle.NewTree(undo.stateTreeSize, undo.stateTreeHashes, nil))
+	}
+	self.store.BatchDelete(self.genStateMerkleRootKey(height))
+	self.store.BatchDelete(self.genCrossStatesKey(height))
+	self.store.BatchDelete(genWriteSetKey(height))
+	self.store.BatchDelete(genStateUndoLogKey(height))
+	self.SaveCurrentBlock(height-1, prevHash)
+	return self.store.BatchCommit()
+}
+
+func genStateUndoLogKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.DATA_STATE_UNDO_LOG)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
@@ -68,10 +68,12 @@ func NewLevelDBStore(file string) (*LevelDBStore, error) {
 		return nil, err
 	}
 
-	return &LevelDBStore{
+	store := &LevelDBStore{
 		db:    db,
 		batch: nil,
-	}, nil
+	}
+	trackStore(store, file)
+	return store, nil
 }
 
 func NewMemLevelDBStore() *LevelDBStore {
@@ -146,6 +148,7 @@ func (self *LevelDBStore) BatchCommit() error {
 
 //Close leveldb
 func (self *LevelDBStore) Close() error {
+	untrackStore(self)
 	err := self.db.Close()
 	return err
 }
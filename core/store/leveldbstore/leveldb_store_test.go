@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
 )
 
 var testLevelDB *LevelDBStore
@@ -140,3 +142,35 @@ func TestIterator(t *testing.T) {
 	}
 
 }
+
+func TestStatsCollector(t *testing.T) {
+	count, err := countMetrics("ontology_leveldb_open_tables")
+	if err != nil {
+		t.Errorf("countMetrics error:%s", err)
+		return
+	}
+	if count != 1 {
+		t.Errorf("open tables metric count %d != 1", count)
+		return
+	}
+	store := NewMemLevelDBStore()
+	defer store.Close()
+	count, _ = countMetrics("ontology_leveldb_open_tables")
+	if count != 1 {
+		t.Errorf("memory store should not be reported")
+	}
+}
+
+// countMetrics returns the number of metrics of the registered metric family name
+func countMetrics(name string) (int, error) {
+	families, err := prom.DefaultGatherer.Gather()
+	if err != nil {
+		return 0, err
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return len(family.GetMetric()), nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package leveldbstore
+
+import (
+	"strconv"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var (
+	ioReadDesc = prom.NewDesc("ontology_leveldb_io_read_bytes_total",
+		"ontology leveldb bytes read from disk", []string{"db"}, nil)
+	ioWriteDesc = prom.NewDesc("ontology_leveldb_io_write_bytes_total",
+		"ontology leveldb bytes written to disk", []string{"db"}, nil)
+	writeDelaysDesc = prom.NewDesc("ontology_leveldb_write_delays_total",
+		"ontology leveldb writes delayed by compaction", []string{"db"}, nil)
+	writeDelayDesc = prom.NewDesc("ontology_leveldb_write_delay_seconds_total",
+		"ontology leveldb time writes delayed by compaction", []string{"db"}, nil)
+	compactionsDesc = prom.NewDesc("ontology_leveldb_compactions_total",
+		"ontology leveldb compactions by type", []string{"db", "type"}, nil)
+	compactionTimeDesc = prom.NewDesc("ontology_leveldb_compaction_seconds_total",
+		"ontology leveldb compaction time by level", []string{"db", "level"}, nil)
+	levelSizeDesc = prom.NewDesc("ontology_leveldb_level_size_bytes",
+		"ontology leveldb size of tables by level", []string{"db", "level"}, nil)
+	levelTablesDesc = prom.NewDesc("ontology_leveldb_level_tables",
+		"ontology leveldb number of tables by level", []string{"db", "level"}, nil)
+	openTablesDesc = prom.NewDesc("ontology_leveldb_open_tables",
+		"ontology leveldb number of opened tables", []string{"db"}, nil)
+	blockCacheDesc = prom.NewDesc("ontology_leveldb_block_cache_bytes",
+		"ontology leveldb size of block cache", []string{"db"}, nil)
+)
+
+// openStores are the file stores reported by statsCollector, the value is the file path
+var (
+	openStoresLock sync.Mutex
+	openStores     = make(map[*LevelDBStore]string)
+)
+
+func init() {
+	prom.MustRegister(statsCollector{})
+}
+
+func trackStore(store *LevelDBStore, file string) {
+	openStoresLock.Lock()
+	openStores[store] = file
+	openStoresLock.Unlock()
+}
+
+func untrackStore(store *LevelDBStore) {
+	openStoresLock.Lock()
+	delete(openStores, store)
+	openStoresLock.Unlock()
+}
+
+// statsCollector reports the stats of open leveldb stores when scraped
+type statsCollector struct{}
+
+func (statsCollector) Describe(ch chan<- *prom.Desc) {
+	for _, desc := range []*prom.Desc{ioReadDesc, ioWriteDesc, writeDelaysDesc, writeDelayDesc, compactionsDesc,
+		compactionTimeDesc, levelSizeDesc, levelTablesDesc, openTablesDesc, blockCacheDesc} {
+		ch <- desc
+	}
+}
+
+func (statsCollector) Collect(ch chan<- prom.Metric) {
+	openStoresLock.Lock()
+	defer openStoresLock.Unlock()
+	for store, file := range openStores {
+		stats := &leveldb.DBStats{}
+		if err := store.db.Stats(stats); err != nil {
+			continue
+		}
+		ch <- prom.MustNewConstMetric(ioReadDesc, prom.CounterValue, float64(stats.IORead), file)
+		ch <- prom.MustNewConstMetric(ioWriteDesc, prom.CounterValue, float64(stats.IOWrite), file)
+		ch <- prom.MustNewConstMetric(writeDelaysDesc, prom.CounterValue, float64(stats.WriteDelayCount), file)
+		ch <- prom.MustNewConstMetric(writeDelayDesc, prom.CounterValue, stats.WriteDelayDuration.Seconds(), file)
+		ch <- prom.MustNewConstMetric(compactionsDesc, prom.CounterValue, float64(stats.MemComp), file, "memory")
+		ch <- prom.MustNewConstMetric(compactionsDesc, prom.CounterValue, float64(stats.Level0Comp), file, "level0")
+		ch <- prom.MustNewConstMetric(compactionsDesc, prom.CounterValue, float64(stats.NonLevel0Comp), file,
+			"nonlevel0")
+		for level, size := range stats.LevelSizes {
+			label := strconv.Itoa(level)
+			ch <- prom.MustNewConstMetric(levelSizeDesc, prom.GaugeValue, float64(size), file, label)
+			if level < len(stats.LevelTablesCounts) {
+				ch <- prom.MustNewConstMetric(levelTablesDesc, prom.GaugeValue,
+					float64(stats.LevelTablesCounts[level]), file, label)
+			}
+			if level < len(stats.LevelDurations) {
+				ch <- prom.MustNewConstMetric(compactionTimeDesc, prom.CounterValue,
+					stats.LevelDurations[level].Seconds(), file, label)
+			}
+		}
+		ch <- prom.MustNewConstMetric(openTablesDesc, prom.GaugeValue, float64(stats.OpenedTablesCount), file)
+		ch <- prom.MustNewConstMetric(blockCacheDesc, prom.GaugeValue, float64(stats.BlockCacheSize), file)
+	}
+}
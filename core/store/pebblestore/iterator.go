@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pebblestore
+
+import (
+	"github.com/cockroachdb/pebble"
+)
+
+//Iterator adapts pebble iterator to common.StoreIterator. Like leveldb iterator,
+//the first call of Next moves to the first item
+type Iterator struct {
+	iter    *pebble.Iterator
+	err     error
+	started bool
+}
+
+//Next item. If item available return true, otherwise return false
+func (self *Iterator) Next() bool {
+	if self.iter == nil {
+		return false
+	}
+	if !self.started {
+		self.started = true
+		return self.iter.First()
+	}
+	return self.iter.Next()
+}
+
+//First item. If item available return true, otherwise return false
+func (self *Iterator) First() bool {
+	if self.iter == nil {
+		return false
+	}
+	self.started = true
+	return self.iter.First()
+}
+
+//Seek moves to the first key which is greater than or equal to the given key
+func (self *Iterator) Seek(key []byte) bool {
+	if self.iter == nil {
+		return false
+	}
+	self.started = true
+	return self.iter.SeekGE(key)
+}
+
+//Key return the current item key
+func (self *Iterator) Key() []byte {
+	if self.iter == nil || !self.iter.Valid() {
+		return nil
+	}
+	return self.iter.Key()
+}
+
+//Value return the current item value
+func (self *Iterator) Value() []byte {
+	if self.iter == nil || !self.iter.Valid() {
+		return nil
+	}
+	return self.iter.Value()
+}
+
+//Release close iterator
+func (self *Iterator) Release() {
+	if self.iter == nil {
+		return
+	}
+	if err := self.iter.Close(); err != nil && self.err == nil {
+		self.err = err
+	}
+	self.iter = nil
+}
+
+//Error returns any accumulated error
+func (self *Iterator) Error() error {
+	if self.err != nil {
+		return self.err
+	}
+	if self.iter != nil {
+		return self.iter.Error()
+	}
+	return nil
+}
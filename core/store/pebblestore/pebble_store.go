@@ -0,0 +1,161 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pebblestore
+
+import (
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/ontio/ontology/core/store/common"
+)
+
+//Pebble store
+type PebbleStore struct {
+	db    *pebble.DB // Pebble instance
+	batch *pebble.Batch
+}
+
+// writes are not fsynced, the same as the default write options of leveldb store.
+var writeOptions = pebble.NoSync
+
+//NewPebbleStore return PebbleStore instance
+func NewPebbleStore(file string) (*PebbleStore, error) {
+	db, err := pebble.Open(file, &pebble.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &PebbleStore{
+		db:    db,
+		batch: nil,
+	}, nil
+}
+
+//NewMemPebbleStore return PebbleStore instance backed by memory, for test
+func NewMemPebbleStore() *PebbleStore {
+	db, err := pebble.Open("", &pebble.Options{FS: vfs.NewMem()})
+	if err != nil {
+		panic(err)
+	}
+	return &PebbleStore{
+		db:    db,
+		batch: nil,
+	}
+}
+
+//Put a key-value pair to pebble
+func (self *PebbleStore) Put(key []byte, value []byte) error {
+	return self.db.Set(key, value, writeOptions)
+}
+
+//Get the value of a key from pebble
+func (self *PebbleStore) Get(key []byte) ([]byte, error) {
+	dat, closer, err := self.db.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	// dat is only valid until closer is closed
+	val := make([]byte, len(dat))
+	copy(val, dat)
+	if err := closer.Close(); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+//Has return whether the key is exist in pebble
+func (self *PebbleStore) Has(key []byte) (bool, error) {
+	_, closer, err := self.db.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, closer.Close()
+}
+
+//Delete the the in pebble
+func (self *PebbleStore) Delete(key []byte) error {
+	return self.db.Delete(key, writeOptions)
+}
+
+//NewBatch start commit batch
+func (self *PebbleStore) NewBatch() {
+	if self.batch != nil {
+		self.batch.Close()
+	}
+	self.batch = self.db.NewBatch()
+}
+
+//BatchPut put a key-value pair to pebble batch
+func (self *PebbleStore) BatchPut(key []byte, value []byte) {
+	// Set of an indexed batch never fails
+	_ = self.batch.Set(key, value, nil)
+}
+
+//BatchDelete delete a key to pebble batch
+func (self *PebbleStore) BatchDelete(key []byte) {
+	_ = self.batch.Delete(key, nil)
+}
+
+//BatchCommit commit batch to pebble
+func (self *PebbleStore) BatchCommit() error {
+	err := self.batch.Commit(writeOptions)
+	if err != nil {
+		return err
+	}
+	err = self.batch.Close()
+	self.batch = nil
+	return err
+}
+
+//Close pebble
+func (self *PebbleStore) Close() error {
+	if self.batch != nil {
+		self.batch.Close()
+		self.batch = nil
+	}
+	return self.db.Close()
+}
+
+//NewIterator return a iterator of pebble with the key prefix
+func (self *PebbleStore) NewIterator(prefix []byte) common.StoreIterator {
+	opts := &pebble.IterOptions{}
+	if len(prefix) > 0 {
+		opts.LowerBound = prefix
+		opts.UpperBound = prefixLimit(prefix)
+	}
+	return &Iterator{iter: self.db.NewIter(opts)}
+}
+
+// prefixLimit returns the smallest key greater than all keys with the given prefix,
+// or nil if there is no such key
+func prefixLimit(prefix []byte) []byte {
+	limit := make([]byte, len(prefix))
+	copy(limit, prefix)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/store/overlaydb"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/smartcontract/event"
@@ -72,8 +73,11 @@ type LedgerStore interface {
 	PreExecuteContract(tx *types.Transaction) (*cstates.PreExecResult, error)
 	PreExecuteContractBatch(txes []*types.Transaction, atomic bool) ([]*cstates.PreExecResult, uint32, error)
 	PreExecuteEip155Tx(msg types2.Message) (*types3.ExecutionResult, error)
+	PreExecuteEip155TxAtHeight(msg types2.Message, height uint32) (*types3.ExecutionResult, error)
 	GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error)
 	GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error)
+	GetAddressTxs(addr common.Address, skip, limit uint32) ([]*scom.AddressTx, error)
+	GetContractEvents(contract common.Address, name string, startHeight, endHeight uint32, skip, limit uint32) ([]*scom.ContractEvent, error)
 	GetEthCode(hash common2.Hash) ([]byte, error)
 	GetEthState(address common2.Address, key common2.Hash) ([]byte, error)
 	GetEthAccount(address common2.Address) (*storage.EthAccount, error)
@@ -81,7 +85,10 @@ type LedgerStore interface {
 	GetCrossStatesRoot(height uint32) (common.Uint256, error)
 	GetCrossChainMsg(height uint32) (*types.CrossChainMsg, error)
 	GetCrossStatesProof(height uint32, key []byte) ([]byte, error)
+	GetStateProof(key []byte, height uint32) (*states.StateProof, error)
 	EnableBlockPrune(numBeforeCurr uint32)
+	PruneBlocks(numBeforeCurr uint32) (uint32, error)
 	//expose the cache db
 	GetCacheDB() *storage.CacheDB
+	GetCacheDBAtHeight(height uint32) (*storage.CacheDB, error)
 }
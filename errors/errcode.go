@@ -59,6 +59,8 @@ const (
 	ErrHigherNonceExist     ErrCode = 45022
 	ErrETHTxGaslimitExceed  ErrCode = 45023
 	ErrSameNonceExist       ErrCode = 45024
+	ErrTxPoolUnderpriced    ErrCode = 45025
+	ErrPayerTxPoolLimit     ErrCode = 45026
 )
 
 func (err ErrCode) Error() string {
@@ -113,6 +115,10 @@ func (err ErrCode) Error() string {
 		return "eth transaction gaslimit exceeded"
 	case ErrSameNonceExist:
 		return "eth transaction with same nonce existed"
+	case ErrTxPoolUnderpriced:
+		return "tx pool full, gas price too low to replace the cheapest transaction"
+	case ErrPayerTxPoolLimit:
+		return "too many transactions of the payer in tx pool"
 	}
 
 	return fmt.Sprintf("Unknown error? Error code = %d", err)
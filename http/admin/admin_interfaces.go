@@ -0,0 +1,217 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package admin
+
+import (
+	"errors"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	berr "github.com/ontio/ontology/http/base/error"
+	"github.com/ontio/ontology/http/base/rpc"
+)
+
+var errNoP2P = errors.New("p2p server is not started")
+
+func getStringParam(params []interface{}, index int) (string, bool) {
+	if len(params) <= index {
+		return "", false
+	}
+	str, ok := params[index].(string)
+	return str, ok && str != ""
+}
+
+func getUintParam(params []interface{}, index int) (uint64, bool) {
+	if len(params) <= index {
+		return 0, false
+	}
+	num, ok := params[index].(float64)
+	if !ok || num < 0 || num != float64(uint64(num)) {
+		return 0, false
+	}
+	return uint64(num), true
+}
+
+func responseError(errCode int64, err error) map[string]interface{} {
+	return rpc.ResponsePack(errCode, err.Error())
+}
+
+// AddPeer connects to the peer, params: [address]
+func (self *server) AddPeer(params []interface{}) map[string]interface{} {
+	addr, ok := getStringParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	log.Infof("[admin] add peer %s", addr)
+	if err := self.p2p.AddPeer(addr); err != nil {
+		return responseError(berr.INTERNAL_ERROR, err)
+	}
+	return rpc.ResponseSuccess(true)
+}
+
+// RemovePeer disconnects the peers, params: [peer id or address]
+func (self *server) RemovePeer(params []interface{}) map[string]interface{} {
+	peer, ok := getStringParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	log.Infof("[admin] remove peer %s", peer)
+	return rpc.ResponseSuccess(self.p2p.RemovePeer(peer))
+}
+
+// BanIP disconnects and rejects the peers from the ip, params: [ip]
+func (self *server) BanIP(params []interface{}) map[string]interface{} {
+	ip, ok := getStringParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	dropped, err := self.p2p.BanIP(ip)
+	if err != nil {
+		return responseError(berr.INVALID_PARAMS, err)
+	}
+	log.Infof("[admin] ban ip %s, dropped peers: %v", ip, dropped)
+	return rpc.ResponseSuccess(dropped)
+}
+
+// BanPeer disconnects and rejects the peer, params: [peer id]
+func (self *server) BanPeer(params []interface{}) map[string]interface{} {
+	id, ok := getStringParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	dropped, err := self.p2p.BanPeer(id)
+	if err != nil {
+		return responseError(berr.INVALID_PARAMS, err)
+	}
+	log.Infof("[admin] ban peer %s, dropped peers: %v", id, dropped)
+	return rpc.ResponseSuccess(dropped)
+}
+
+// UnbanIP params: [ip]
+func (self *server) UnbanIP(params []interface{}) map[string]interface{} {
+	ip, ok := getStringParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	log.Infof("[admin] unban ip %s", ip)
+	return rpc.ResponseSuccess(self.p2p.UnbanIP(ip))
+}
+
+// UnbanPeer params: [peer id]
+func (self *server) UnbanPeer(params []interface{}) map[string]interface{} {
+	id, ok := getStringParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	unbanned, err := self.p2p.UnbanPeer(id)
+	if err != nil {
+		return responseError(berr.INVALID_PARAMS, err)
+	}
+	log.Infof("[admin] unban peer %s", id)
+	return rpc.ResponseSuccess(unbanned)
+}
+
+// GetBanned returns the banned ips and peer ids
+func (self *server) GetBanned(params []interface{}) map[string]interface{} {
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	ips, ids := self.p2p.GetBanned()
+	return rpc.ResponseSuccess(map[string]interface{}{
+		"ips":   ips,
+		"peers": ids,
+	})
+}
+
+// ReloadReservedPeers reloads the reserved peers file
+func (self *server) ReloadReservedPeers(params []interface{}) map[string]interface{} {
+	if self.p2p == nil {
+		return responseError(berr.INTERNAL_ERROR, errNoP2P)
+	}
+	dropped, err := self.p2p.ReloadReservedPeers()
+	if err != nil {
+		return responseError(berr.INTERNAL_ERROR, err)
+	}
+	log.Infof("[admin] reserved peers reloaded, dropped peers: %v", dropped)
+	return rpc.ResponseSuccess(dropped)
+}
+
+// DropTx removes the transaction from the tx pool, params: [tx hash]
+func (self *server) DropTx(params []interface{}) map[string]interface{} {
+	str, ok := getStringParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return responseError(berr.INVALID_PARAMS, err)
+	}
+	log.Infof("[admin] drop tx %s", str)
+	return rpc.ResponseSuccess(self.txpool.DropTransaction(hash))
+}
+
+// FlushTxPool removes all the transactions from the tx pool
+func (self *server) FlushTxPool(params []interface{}) map[string]interface{} {
+	count := self.txpool.FlushTransactions()
+	log.Infof("[admin] tx pool flushed, %d transactions dropped", count)
+	return rpc.ResponseSuccess(count)
+}
+
+// SetGasPrice changes the local gas price floor of the tx pool, params: [gas price]
+func (self *server) SetGasPrice(params []interface{}) map[string]interface{} {
+	gasPrice, ok := getUintParam(params, 0)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	enforced := self.txpool.SetGasPrice(gasPrice)
+	log.Infof("[admin] gas price floor set to %d, enforced %d", gasPrice, enforced)
+	return rpc.ResponseSuccess(enforced)
+}
+
+// PruneBlocks prunes the history blocks, params: [number of recent blocks to preserve]
+func (self *server) PruneBlocks(params []interface{}) map[string]interface{} {
+	preserve, ok := getUintParam(params, 0)
+	if !ok || preserve > uint64(^uint32(0)) {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	log.Infof("[admin] prune blocks preserving %d recent ones", preserve)
+	pruned, err := self.ledger.PruneBlocks(uint32(preserve))
+	if err != nil {
+		return responseError(berr.INTERNAL_ERROR, err)
+	}
+	return rpc.ResponseSuccess(pruned)
+}
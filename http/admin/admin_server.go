@@ -0,0 +1,249 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package admin provides the rpc server for runtime node management, which is
+// served on a unix socket accessible by the node operator only, or on a http
+// port protected by a bearer token.
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/http/base/rpc"
+	"github.com/ontio/ontology/http/metrics"
+	"github.com/ontio/ontology/p2pserver"
+	"github.com/ontio/ontology/txnpool/proc"
+)
+
+const TOKEN_SIZE = 32
+
+type server struct {
+	mux     *rpc.ServeMux
+	p2p     *p2pserver.P2PServer // nil in solo mode
+	txpool  *proc.TXPoolServer
+	ledger  *ledger.Ledger
+	servers []*http.Server
+}
+
+var (
+	lock      sync.Mutex
+	defServer *server
+)
+
+// Start serves the admin rpc on the unix socket and the http port configured
+func Start(cfg *config.AdminRpcConfig, p2p *p2pserver.P2PServer, txpool *proc.TXPoolServer, ldg *ledger.Ledger) error {
+	if cfg.IpcPath == "" && cfg.HttpPort == 0 {
+		return errors.New("neither admin ipc path nor http port is configured")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if defServer != nil {
+		return errors.New("admin rpc already started")
+	}
+
+	s := newServer(p2p, txpool, ldg)
+	if cfg.IpcPath != "" {
+		listener, err := listenIpc(cfg.IpcPath)
+		if err != nil {
+			return err
+		}
+		s.serve(listener, s.mux)
+		log.Infof("admin rpc listening on %s", cfg.IpcPath)
+	}
+	if cfg.HttpPort != 0 {
+		token, err := loadOrCreateToken(cfg.TokenFile)
+		if err != nil {
+			s.close()
+			return err
+		}
+		listener, err := net.Listen("tcp", net.JoinHostPort(cfg.HttpAddr, strconv.Itoa(int(cfg.HttpPort))))
+		if err != nil {
+			s.close()
+			return err
+		}
+		s.serve(listener, tokenAuth(token, s.mux))
+		log.Infof("admin rpc listening on %s", listener.Addr())
+	}
+	defServer = s
+	return nil
+}
+
+// Stop closes the admin rpc servers, the unix socket file is removed
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+	if defServer != nil {
+		defServer.close()
+		defServer = nil
+	}
+}
+
+func newServer(p2p *p2pserver.P2PServer, txpool *proc.TXPoolServer, ldg *ledger.Ledger) *server {
+	s := &server{
+		mux:    rpc.NewServeMux(metrics.API_ADMIN),
+		p2p:    p2p,
+		txpool: txpool,
+		ledger: ldg,
+	}
+
+	s.mux.HandleFunc("addpeer", s.AddPeer)
+	s.mux.HandleFunc("removepeer", s.RemovePeer)
+	s.mux.HandleFunc("banip", s.BanIP)
+	s.mux.HandleFunc("banpeer", s.BanPeer)
+	s.mux.HandleFunc("unbanip", s.UnbanIP)
+	s.mux.HandleFunc("unbanpeer", s.UnbanPeer)
+	s.mux.HandleFunc("getbanned", s.GetBanned)
+	s.mux.HandleFunc("reloadreservedpeers", s.ReloadReservedPeers)
+	s.mux.HandleFunc("droptx", s.DropTx)
+	s.mux.HandleFunc("flushtxpool", s.FlushTxPool)
+	s.mux.HandleFunc("setgasprice", s.SetGasPrice)
+	s.mux.HandleFunc("pruneblocks", s.PruneBlocks)
+	return s
+}
+
+func (self *server) serve(listener net.Listener, handler http.Handler) {
+	srv := &http.Server{Handler: handler}
+	self.servers = append(self.servers, srv)
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("admin rpc serve on %s error: %s", listener.Addr(), err)
+		}
+	}()
+}
+
+func (self *server) close() {
+	for _, srv := range self.servers {
+		if err := srv.Close(); err != nil {
+			log.Errorf("admin rpc close error: %s", err)
+		}
+	}
+	self.servers = nil
+}
+
+// listens on the unix socket which only the owner can connect to. A stale
+// socket left by an unclean shutdown is removed. The socket is created in a
+// directory only the owner can access, and moved to the path once restricted
+// to the owner, so that it is never open to others.
+func listenIpc(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("admin ipc %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale admin ipc %s error: %s", path, err)
+		}
+	}
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".admin-ipc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	tmpPath := filepath.Join(dir, "ipc")
+	listener, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return &ipcListener{Listener: listener, path: path}, nil
+}
+
+// ipcListener removes the socket file on close, as the listener only knows the
+// path it was created at.
+type ipcListener struct {
+	net.Listener
+	path string
+}
+
+func (self *ipcListener) Close() error {
+	err := self.Listener.Close()
+	if err == nil {
+		_ = os.Remove(self.path)
+	}
+	return err
+}
+
+// reads the bearer token from the file, a random one is generated and saved
+// with owner only permission if the file does not exist.
+func loadOrCreateToken(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("admin token file %s is empty", path)
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+			log.Warnf("admin token file %s is accessible by others, mode %s", path, info.Mode().Perm())
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buf := make([]byte, TOKEN_SIZE)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := ioutil.WriteFile(path, []byte(token), 0600); err != nil {
+		return "", err
+	}
+	log.Infof("admin token generated in %s", path)
+	return token, nil
+}
+
+// rejects the requests without the bearer token
+func tokenAuth(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(auth, expected) != 1 {
+			log.Warnf("admin rpc unauthorized request from %s", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontio/ontology/common/config"
+	berr "github.com/ontio/ontology/http/base/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOrCreateToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "admin.token")
+	token, err := loadOrCreateToken(path)
+	assert.Nil(t, err)
+	assert.Equal(t, TOKEN_SIZE*2, len(token))
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := loadOrCreateToken(path)
+	assert.Nil(t, err)
+	assert.Equal(t, token, loaded)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(" \n"), 0600))
+	_, err = loadOrCreateToken(path)
+	assert.NotNil(t, err)
+}
+
+func TestTokenAuth(t *testing.T) {
+	handler := tokenAuth("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for auth, code := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Bearer secret": http.StatusOK,
+	} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, code, rec.Code, auth)
+	}
+}
+
+func TestIpcServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "admin.ipc")
+	// stale socket file
+	assert.Nil(t, ioutil.WriteFile(path, nil, 0600))
+	err = Start(&config.AdminRpcConfig{EnableAdminRpc: true, IpcPath: path}, nil, nil, nil)
+	assert.Nil(t, err)
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	// the temporary directory is removed
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	// in use
+	_, err = listenIpc(path)
+	assert.NotNil(t, err)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	call := func(body string) map[string]interface{} {
+		resp, err := client.Post("http://admin/", "application/json", strings.NewReader(body))
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		result := make(map[string]interface{})
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+	result := call(`{"jsonrpc":"2.0","method":"droptx","params":["abc"],"id":1}`)
+	assert.Equal(t, float64(berr.INVALID_PARAMS), result["error"])
+	result = call(`{"jsonrpc":"2.0","method":"banip","params":["127.0.0.1"],"id":2}`)
+	assert.Equal(t, float64(berr.INTERNAL_ERROR), result["error"])
+	assert.Equal(t, errNoP2P.Error(), result["result"])
+
+	Stop()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -24,11 +24,14 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/smartcontract/event"
 	types3 "github.com/ontio/ontology/smartcontract/service/evm/types"
 	cstate "github.com/ontio/ontology/smartcontract/states"
 	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/ontio/ontology/vm/evm"
 )
 
 const (
@@ -41,6 +44,11 @@ func GetHeaderByHeight(height uint32) (*types.Header, error) {
 	return ledger.DefLedger.GetHeaderByHeight(height)
 }
 
+//GetHeaderByHash from ledger
+func GetHeaderByHash(hash common.Uint256) (*types.Header, error) {
+	return ledger.DefLedger.GetHeaderByHash(hash)
+}
+
 //GetBlockByHeight from ledger
 func GetBlockByHeight(height uint32) (*types.Block, error) {
 	return ledger.DefLedger.GetBlockByHeight(height)
@@ -108,6 +116,17 @@ func GetEventNotifyByHeight(height uint32) ([]*event.ExecuteNotify, error) {
 	return ledger.DefLedger.GetEventNotifyByBlock(height)
 }
 
+//GetAddressTxs from ledger
+func GetAddressTxs(addr common.Address, skip, limit uint32) ([]*scom.AddressTx, error) {
+	return ledger.DefLedger.GetAddressTxs(addr, skip, limit)
+}
+
+//GetContractEvents from ledger
+func GetContractEvents(contract common.Address, name string, startHeight, endHeight uint32,
+	skip, limit uint32) ([]*scom.ContractEvent, error) {
+	return ledger.DefLedger.GetContractEvents(contract, name, startHeight, endHeight, skip, limit)
+}
+
 //GetMerkleProof from ledger
 func GetMerkleProof(proofHeight uint32, rootHeight uint32) ([]common.Uint256, error) {
 	return ledger.DefLedger.GetMerkleProof(proofHeight, rootHeight)
@@ -121,6 +140,14 @@ func GetCrossStatesProof(height uint32, key []byte) ([]byte, error) {
 	return ledger.DefLedger.GetCrossStatesProof(height, key)
 }
 
+func GetStateProof(key []byte, height uint32) (*states.StateProof, error) {
+	return ledger.DefLedger.GetStateProof(key, height)
+}
+
+func GetStateMerkleRoot(height uint32) (common.Uint256, error) {
+	return ledger.DefLedger.GetStateMerkleRoot(height)
+}
+
 func GetEthAccount(address common2.Address) (*storage.EthAccount, error) {
 	return ledger.DefLedger.GetEthAccount(address)
 }
@@ -137,3 +164,19 @@ func PreExecuteEip155Tx(msg types2.Message) (*types3.ExecutionResult, error) {
 	res, err := ledger.DefLedger.PreExecuteEip155Tx(msg)
 	return res, err
 }
+
+func PreExecuteEip155TxAtHeight(msg types2.Message, height uint32) (*types3.ExecutionResult, error) {
+	return ledger.DefLedger.PreExecuteEip155TxAtHeight(msg, height)
+}
+
+func GetCacheDBAtHeight(height uint32) (*storage.CacheDB, error) {
+	return ledger.DefLedger.GetCacheDBAtHeight(height)
+}
+
+func TraceEip155Call(msg types2.Message, height uint32, tracer evm.Tracer) (*types3.ExecutionResult, error) {
+	return ledger.DefLedger.TraceEip155Call(msg, height, tracer)
+}
+
+func TraceEip155Tx(txHash common.Uint256, tracer evm.Tracer) (*types3.ExecutionResult, error) {
+	return ledger.DefLedger.TraceEip155Tx(txHash, tracer)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/constants"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/tracing"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/types"
@@ -47,6 +48,10 @@ import (
 
 const MAX_SEARCH_HEIGHT uint32 = 100
 const MAX_REQUEST_BODY_SIZE = 1 << 20
+const DEFAULT_ADDRESS_TXS_LIMIT uint32 = 20
+const MAX_ADDRESS_TXS_LIMIT uint32 = 100
+const DEFAULT_CONTRACT_EVENTS_LIMIT uint32 = 20
+const MAX_CONTRACT_EVENTS_LIMIT uint32 = 100
 
 type BalanceOfRsp struct {
 	Ont    string `json:"ont"`
@@ -123,6 +128,20 @@ type Sig struct {
 	SigData []string
 }
 
+type AddressTxInfo struct {
+	TxHash  string
+	Height  uint32
+	TxIndex uint32
+}
+
+type ContractEventInfo struct {
+	TxHash          string
+	Height          uint32
+	TxIndex         uint32
+	ContractAddress string
+	States          interface{}
+}
+
 type CrossStatesProof struct {
 	Type      string
 	AuditPath string
@@ -263,7 +282,10 @@ func TransferCrossChainMsg(msg *types.CrossChainMsg, pks []keypair.PublicKey) st
 }
 
 func SendTxToPool(txn *types.Transaction) (ontErrors.ErrCode, string) {
-	if errCode, desc := bactor.AppendTxToPool(txn); errCode != ontErrors.ErrNoError {
+	span := tracing.StartTxSpan(txn.Hash(), "http.SendRawTransaction")
+	errCode, desc := bactor.AppendTxToPool(txn)
+	tracing.EndTxSpan(span, errCode)
+	if errCode != ontErrors.ErrNoError {
 		log.Warn("TxnPool verify error:", errCode.Error())
 		return errCode, desc
 	}
@@ -515,6 +537,62 @@ func GetGasPrice() (gasPrice uint64, height uint32, err error) {
 	return
 }
 
+//AddressTxsLimit return the page size of address transactions query, 0 means the default size
+func AddressTxsLimit(limit uint32) uint32 {
+	if limit == 0 {
+		return DEFAULT_ADDRESS_TXS_LIMIT
+	} else if limit > MAX_ADDRESS_TXS_LIMIT {
+		return MAX_ADDRESS_TXS_LIMIT
+	}
+	return limit
+}
+
+//GetAddressTxs return a page of the transactions touching the address, the latest first
+func GetAddressTxs(addr common.Address, skip, limit uint32) ([]AddressTxInfo, error) {
+	txs, err := bactor.GetAddressTxs(addr, skip, AddressTxsLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]AddressTxInfo, 0, len(txs))
+	for _, tx := range txs {
+		infos = append(infos, AddressTxInfo{
+			TxHash:  tx.TxHash.ToHexString(),
+			Height:  tx.Height,
+			TxIndex: tx.TxIndex,
+		})
+	}
+	return infos, nil
+}
+
+//ContractEventsLimit return the page size of contract events query, 0 means the default size
+func ContractEventsLimit(limit uint32) uint32 {
+	if limit == 0 {
+		return DEFAULT_CONTRACT_EVENTS_LIMIT
+	} else if limit > MAX_CONTRACT_EVENTS_LIMIT {
+		return MAX_CONTRACT_EVENTS_LIMIT
+	}
+	return limit
+}
+
+//GetContractEvents return a page of the notifications of the contract between the block heights, the earliest first
+func GetContractEvents(contract common.Address, name string, startHeight, endHeight, skip, limit uint32) ([]ContractEventInfo, error) {
+	events, err := bactor.GetContractEvents(contract, name, startHeight, endHeight, skip, ContractEventsLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]ContractEventInfo, 0, len(events))
+	for _, evt := range events {
+		infos = append(infos, ContractEventInfo{
+			TxHash:          evt.TxHash.ToHexString(),
+			Height:          evt.Height,
+			TxIndex:         evt.TxIndex,
+			ContractAddress: evt.Notify.ContractAddress.ToHexString(),
+			States:          evt.Notify.States,
+		})
+	}
+	return infos, nil
+}
+
 func GetBlockTransactions(block *types.Block) interface{} {
 	trans := make([]string, len(block.Transactions))
 	for i := 0; i < len(block.Transactions); i++ {
@@ -19,6 +19,7 @@
 package rest
 
 import (
+	"math"
 	"strconv"
 
 	"github.com/ontio/ontology/common"
@@ -428,6 +429,75 @@ func GetBalance(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+//get the events of a contract between the block heights, the earliest first
+func GetSmartCodeEventByContract(cmd map[string]interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog || !config.DefConfig.Common.EnableContractEventIndex {
+		return ResponsePack(berr.INVALID_METHOD)
+	}
+
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Contract"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	contract, err := common.AddressFromHexString(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	name, _ := cmd["Event"].(string)
+	page := [4]uint32{0, math.MaxUint32, 0, 0}
+	for i, key := range []string{"StartHeight", "EndHeight", "Skip", "Limit"} {
+		if param, ok := cmd[key].(string); ok && len(param) != 0 {
+			n, err := strconv.ParseUint(param, 10, 32)
+			if err != nil {
+				return ResponsePack(berr.INVALID_PARAMS)
+			}
+			page[i] = uint32(n)
+		}
+	}
+	events, err := bcomn.GetContractEvents(contract, name, page[0], page[1], page[2], page[3])
+	if err == scom.ErrContractEventNotIndexed {
+		return ResponsePack(berr.INVALID_PARAMS)
+	} else if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp["Result"] = events
+	return resp
+}
+
+//get the transactions touching an address, the latest first
+func GetAddressTxs(cmd map[string]interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableAddressIndex {
+		return ResponsePack(berr.INVALID_METHOD)
+	}
+
+	resp := ResponsePack(berr.SUCCESS)
+	addrBase58, ok := cmd["Addr"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := common.AddressFromBase58(addrBase58)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	var page [2]uint32
+	for i, name := range []string{"Skip", "Limit"} {
+		if param, ok := cmd[name].(string); ok && len(param) != 0 {
+			n, err := strconv.ParseUint(param, 10, 32)
+			if err != nil {
+				return ResponsePack(berr.INVALID_PARAMS)
+			}
+			page[i] = uint32(n)
+		}
+	}
+	txs, err := bcomn.GetAddressTxs(address, page[0], page[1])
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp["Result"] = txs
+	return resp
+}
+
 //get merkle proof by transaction hash
 func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
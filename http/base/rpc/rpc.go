@@ -27,12 +27,14 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	// fast json marshal/unmarshal
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
+	"github.com/ontio/ontology/http/metrics"
 )
 
 var (
@@ -46,25 +48,29 @@ type JReq struct {
 	ID      interface{}   `json:"id"`
 }
 
-func init() {
-	mainMux.m = make(map[string]func([]interface{}) map[string]interface{})
-}
-
 //an instance of the multiplexer
-var mainMux ServeMux
+var mainMux = NewServeMux(metrics.API_JSONRPC)
 
 //multiplexer that keeps track of every function to be called on specific rpc call
 type ServeMux struct {
 	sync.RWMutex
+	api             string
 	m               map[string]func([]interface{}) map[string]interface{}
 	defaultFunction func(http.ResponseWriter, *http.Request)
 }
 
+// NewServeMux creates a multiplexer separated from the main one, the api is
+// used to label the metrics of the calls served by it.
+func NewServeMux(api string) *ServeMux {
+	return &ServeMux{
+		api: api,
+		m:   make(map[string]func([]interface{}) map[string]interface{}),
+	}
+}
+
 //a function to register functions to be called for specific rpc calls
 func HandleFunc(pattern string, handler func([]interface{}) map[string]interface{}) {
-	mainMux.Lock()
-	defer mainMux.Unlock()
-	mainMux.m[pattern] = handler
+	mainMux.HandleFunc(pattern, handler)
 }
 
 //a function to be called if the request is not a HTTP JSON RPC call
@@ -75,6 +81,18 @@ func SetDefaultFunc(def func(http.ResponseWriter, *http.Request)) {
 // this is the function that should be called in order to answer an rpc call
 // should be registered like "http.HandleFunc("/", httpjsonrpc.Handle)"
 func Handle(w http.ResponseWriter, r *http.Request) {
+	mainMux.ServeHTTP(w, r)
+}
+
+// HandleFunc registers the function to be called for the rpc method
+func (mux *ServeMux) HandleFunc(pattern string, handler func([]interface{}) map[string]interface{}) {
+	mux.Lock()
+	defer mux.Unlock()
+	mux.m[pattern] = handler
+}
+
+// ServeHTTP answers the rpc call with the registered function
+func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
 		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
 		w.Header().Set("content-type", "application/json;charset=utf-8")
@@ -83,9 +101,9 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 	}
 	//JSON RPC commands should be POSTs
 	if r.Method != "POST" {
-		if mainMux.defaultFunction != nil {
+		if mux.defaultFunction != nil {
 			log.Info("HTTP JSON RPC Handle - Method!=\"POST\"")
-			mainMux.defaultFunction(w, r)
+			mux.defaultFunction(w, r)
 		} else {
 			log.Warn("HTTP JSON RPC Handle - Method!=\"POST\"")
 		}
@@ -93,14 +111,14 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 	}
 	//check if there is Request Body to read
 	if r.Body == nil {
-		mainMux.RLock()
-		if mainMux.defaultFunction != nil {
+		mux.RLock()
+		if mux.defaultFunction != nil {
 			log.Info("HTTP JSON RPC Handle - Request body is nil")
-			mainMux.defaultFunction(w, r)
+			mux.defaultFunction(w, r)
 		} else {
 			log.Warn("HTTP JSON RPC Handle - Request body is nil")
 		}
-		mainMux.RUnlock()
+		mux.RUnlock()
 		return
 	}
 	var request JReq
@@ -116,11 +134,14 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	//get the corresponding function
-	mainMux.RLock()
-	function, ok := mainMux.m[request.Method]
-	mainMux.RUnlock()
+	mux.RLock()
+	function, ok := mux.m[request.Method]
+	mux.RUnlock()
 	if ok {
+		start := time.Now()
 		response := function(request.Params)
+		errCode, _ := response["error"].(int64)
+		metrics.ObserveRpc(mux.api, request.Method, start, errCode != berr.SUCCESS)
 		data, err := json.Marshal(map[string]interface{}{
 			"jsonrpc": "2.0",
 			"error":   response["error"],
@@ -139,6 +160,7 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 	} else {
 		//if the function does not exist
 		log.Warn("HTTP JSON RPC Handle - No function to call for ", request.Method)
+		metrics.ObserveRpc(mux.api, metrics.METHOD_UNKNOWN, time.Now(), true)
 		data, err := json.Marshal(map[string]interface{}{
 			"error": berr.INVALID_METHOD,
 			"result": map[string]interface{}{
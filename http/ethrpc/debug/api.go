@@ -0,0 +1,192 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package debug
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	oComm "github.com/ontio/ontology/common"
+	bactor "github.com/ontio/ontology/http/base/actor"
+	"github.com/ontio/ontology/http/ethrpc/eth"
+	types2 "github.com/ontio/ontology/http/ethrpc/types"
+	types3 "github.com/ontio/ontology/smartcontract/service/evm/types"
+	"github.com/ontio/ontology/vm/evm"
+)
+
+const callTracer = "callTracer"
+
+// TraceConfig holds extra parameters to trace functions.
+type TraceConfig struct {
+	*evm.LogConfig
+	Tracer *string
+}
+
+// ExecutionResult groups all structured logs emitted by the EVM
+// while replaying a transaction in debug mode as well as transaction
+// execution status, the amount of gas used and the return value
+type ExecutionResult struct {
+	Gas         uint64         `json:"gas"`
+	Failed      bool           `json:"failed"`
+	ReturnValue string         `json:"returnValue"`
+	StructLogs  []StructLogRes `json:"structLogs"`
+}
+
+// StructLogRes stores a structured log emitted by the EVM while replaying a
+// transaction in debug mode
+type StructLogRes struct {
+	Pc      uint64             `json:"pc"`
+	Op      string             `json:"op"`
+	Gas     uint64             `json:"gas"`
+	GasCost uint64             `json:"gasCost"`
+	Depth   int                `json:"depth"`
+	Error   string             `json:"error,omitempty"`
+	Stack   *[]string          `json:"stack,omitempty"`
+	Memory  *[]string          `json:"memory,omitempty"`
+	Storage *map[string]string `json:"storage,omitempty"`
+}
+
+// DebugAPI is the debug_ prefixed set of APIs to trace the execution of evm transactions.
+type DebugAPI struct{}
+
+// NewDebugAPI creates an instance of the debug API.
+func NewDebugAPI() *DebugAPI {
+	return &DebugAPI{}
+}
+
+// TraceTransaction replays the committed transaction against the state of its parent block and returns the trace
+// of the given tracer, which needs the node to run in archive mode.
+func (api *DebugAPI) TraceTransaction(hash common.Hash, config *TraceConfig) (interface{}, error) {
+	_, tx, err := bactor.GetTxnWithHeightByTxHash(oComm.Uint256(hash))
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %s not found", hash.Hex())
+	}
+	eiptx, err := tx.GetEIP155Tx()
+	if err != nil {
+		return nil, err
+	}
+	tracer, err := newTracer(config)
+	if err != nil {
+		return nil, err
+	}
+	res, err := bactor.TraceEip155Tx(oComm.Uint256(hash), tracer)
+	if err != nil {
+		return nil, err
+	}
+	return traceResult(tracer, res, eiptx.Gas())
+}
+
+// TraceCall executes the call against the state of the given block and returns the trace of the given tracer.
+func (api *DebugAPI) TraceCall(args types2.CallArgs, blockNum types2.BlockHeight, config *TraceConfig) (interface{}, error) {
+	current := bactor.GetCurrentBlockHeight()
+	height := blockNum.Resolve(current)
+	if height > current {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
+	tracer, err := newTracer(config)
+	if err != nil {
+		return nil, err
+	}
+	msg := args.AsMessage(eth.RPCGasCap)
+	res, err := bactor.TraceEip155Call(msg, height, tracer)
+	if err != nil {
+		return nil, err
+	}
+	return traceResult(tracer, res, msg.Gas())
+}
+
+func newTracer(config *TraceConfig) (evm.Tracer, error) {
+	if config == nil || config.Tracer == nil || *config.Tracer == "" {
+		var logConfig *evm.LogConfig
+		if config != nil {
+			logConfig = config.LogConfig
+		}
+		return evm.NewStructLogger(logConfig), nil
+	}
+	if *config.Tracer == callTracer {
+		return evm.NewCallTracer(), nil
+	}
+	return nil, fmt.Errorf("tracer %s is not supported", *config.Tracer)
+}
+
+func traceResult(tracer evm.Tracer, res *types3.ExecutionResult, gas uint64) (interface{}, error) {
+	switch tracer := tracer.(type) {
+	case *evm.StructLogger:
+		returnVal := fmt.Sprintf("%x", res.Return())
+		if len(res.Revert()) > 0 {
+			returnVal = fmt.Sprintf("%x", res.Revert())
+		}
+		return &ExecutionResult{
+			Gas:         res.UsedGas,
+			Failed:      res.Failed(),
+			ReturnValue: returnVal,
+			StructLogs:  FormatLogs(tracer.StructLogs()),
+		}, nil
+	case *evm.CallTracer:
+		result := tracer.Result()
+		if result == nil {
+			return nil, fmt.Errorf("nothing is traced")
+		}
+		result.Gas = hexutil.Uint64(gas)
+		result.GasUsed = hexutil.Uint64(res.UsedGas)
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported tracer %T", tracer)
+	}
+}
+
+// FormatLogs formats EVM returned structured logs for json output
+func FormatLogs(logs []evm.StructLog) []StructLogRes {
+	formatted := make([]StructLogRes, len(logs))
+	for index, trace := range logs {
+		formatted[index] = StructLogRes{
+			Pc:      trace.Pc,
+			Op:      trace.Op.String(),
+			Gas:     trace.Gas,
+			GasCost: trace.GasCost,
+			Depth:   trace.Depth,
+			Error:   trace.ErrorString(),
+		}
+		if trace.Stack != nil {
+			stack := make([]string, len(trace.Stack))
+			for i, stackValue := range trace.Stack {
+				stack[i] = fmt.Sprintf("%x", common.LeftPadBytes(stackValue.Bytes(), 32))
+			}
+			formatted[index].Stack = &stack
+		}
+		if trace.Memory != nil {
+			memory := make([]string, 0, (len(trace.Memory)+31)/32)
+			for i := 0; i+32 <= len(trace.Memory); i += 32 {
+				memory = append(memory, fmt.Sprintf("%x", trace.Memory[i:i+32]))
+			}
+			formatted[index].Memory = &memory
+		}
+		if trace.Storage != nil {
+			storage := make(map[string]string)
+			for i, storageValue := range trace.Storage {
+				storage[fmt.Sprintf("%x", i)] = fmt.Sprintf("%x", storageValue)
+			}
+			formatted[index].Storage = &storage
+		}
+	}
+	return formatted
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package debug
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	oComm "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	otypes "github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/events"
+	types2 "github.com/ontio/ontology/http/ethrpc/types"
+	"github.com/ontio/ontology/vm/evm"
+	"github.com/stretchr/testify/assert"
+)
+
+// the runtime code stores 42 at slot 0, and reverts if the first word of the call data is not zero
+var (
+	testRuntimeCode = common.FromHex("602a600055600035600c57005b60006000fd")
+	testInitCode    = append(common.FromHex("6012600c60003960126000f3"), testRuntimeCode...)
+)
+
+// testBookkeeper signs the blocks of the solo test ledger
+var testBookkeeper *account.Account
+
+func TestMain(m *testing.M) {
+	events.Init()
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		panic(err)
+	}
+	testBookkeeper = account.NewAccount("")
+	config.DefConfig.Common.EnableArchive = true
+	config.DefConfig.Genesis = config.NewGenesisConfig()
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_SOLO
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(testBookkeeper.PublicKey))}
+	bookkeepers := []keypair.PublicKey{testBookkeeper.PublicKey}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	if err != nil {
+		panic(err)
+	}
+	ledger.DefLedger, err = ledger.InitLedger(dir, 0, bookkeepers, block)
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	ledger.DefLedger.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// submitTestBlock submits the next block of the test ledger with the eip155 transactions.
+func submitTestBlock(t *testing.T, eiptxs ...*types.Transaction) {
+	var txs []*otypes.Transaction
+	var hashes []oComm.Uint256
+	for _, eiptx := range eiptxs {
+		tx, err := otypes.TransactionFromEIP155(eiptx)
+		assert.Nil(t, err)
+		txs = append(txs, tx)
+		hashes = append(hashes, tx.Hash())
+	}
+
+	prevHash := ledger.DefLedger.GetCurrentBlockHash()
+	prevHeader, err := ledger.DefLedger.GetHeaderByHash(prevHash)
+	assert.Nil(t, err)
+	height := prevHeader.Height + 1
+	txRoot := oComm.ComputeMerkleRoot(hashes)
+	header := &otypes.Header{
+		PrevBlockHash:    prevHash,
+		TransactionsRoot: txRoot,
+		BlockRoot:        ledger.DefLedger.GetBlockRootWithNewTxRoots(height, []oComm.Uint256{txRoot}),
+		Timestamp:        prevHeader.Timestamp + 1,
+		Height:           height,
+		NextBookkeeper:   testBookkeeper.Address,
+	}
+	block := &otypes.Block{Header: header, Transactions: txs}
+	blockHash := block.Hash()
+	sig, err := signature.Sign(testBookkeeper, blockHash[:])
+	assert.Nil(t, err)
+	header.Bookkeepers = []keypair.PublicKey{testBookkeeper.PublicKey}
+	header.SigData = [][]byte{sig}
+
+	result, err := ledger.DefLedger.ExecuteBlock(block)
+	assert.Nil(t, err)
+	assert.Nil(t, ledger.DefLedger.SubmitBlock(block, nil, result))
+}
+
+func TestTrace(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	contract := crypto.CreateAddress(from, 0)
+	signer := types.NewEIP155Signer(big.NewInt(int64(config.DefConfig.P2PNode.EVMChainId)))
+	deployTx, err := types.SignTx(types.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(0), testInitCode), signer, key)
+	assert.Nil(t, err)
+	callTx, err := types.SignTx(types.NewTransaction(1, contract, big.NewInt(0), 100000, big.NewInt(0), make([]byte, 32)), signer, key)
+	assert.Nil(t, err)
+
+	deployHeight := ledger.DefLedger.GetCurrentBlockHeight()
+	submitTestBlock(t, deployTx)
+	submitTestBlock(t, callTx)
+	api := NewDebugAPI()
+
+	// struct logger of the deployment
+	res, err := api.TraceTransaction(deployTx.Hash(), nil)
+	assert.Nil(t, err)
+	result := res.(*ExecutionResult)
+	assert.False(t, result.Failed)
+	assert.Equal(t, hex.EncodeToString(testRuntimeCode), result.ReturnValue)
+	assert.Equal(t, "RETURN", result.StructLogs[len(result.StructLogs)-1].Op)
+
+	// struct logger of the call, with storage
+	res, err = api.TraceTransaction(callTx.Hash(), nil)
+	assert.Nil(t, err)
+	result = res.(*ExecutionResult)
+	assert.False(t, result.Failed)
+	var sstore *StructLogRes
+	for i := range result.StructLogs {
+		if result.StructLogs[i].Op == "SSTORE" {
+			sstore = &result.StructLogs[i]
+		}
+	}
+	assert.NotNil(t, sstore)
+	assert.Equal(t, 1, sstore.Depth)
+	// the stack is listed from the bottom
+	assert.Equal(t, []string{
+		"000000000000000000000000000000000000000000000000000000000000002a",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+	}, *sstore.Stack)
+	assert.Equal(t, map[string]string{
+		"0000000000000000000000000000000000000000000000000000000000000000": "000000000000000000000000000000000000000000000000000000000000002a",
+	}, *sstore.Storage)
+	assert.Equal(t, "STOP", result.StructLogs[len(result.StructLogs)-1].Op)
+
+	// call tracer of the call
+	tracerName := callTracer
+	res, err = api.TraceTransaction(callTx.Hash(), &TraceConfig{Tracer: &tracerName})
+	assert.Nil(t, err)
+	frame := res.(*evm.CallFrame)
+	assert.Equal(t, "CALL", frame.Type)
+	assert.Equal(t, from, frame.From)
+	assert.Equal(t, contract, frame.To)
+	assert.Equal(t, hexutil.Uint64(callTx.Gas()), frame.Gas)
+	assert.Empty(t, frame.Error)
+
+	// reverted call at the latest block
+	input := hexutil.Bytes(common.LeftPadBytes([]byte{1}, 32))
+	args := types2.CallArgs{From: &from, To: &contract, Data: &input}
+	res, err = api.TraceCall(args, types2.BlockHeight{}, nil)
+	assert.Nil(t, err)
+	result = res.(*ExecutionResult)
+	assert.True(t, result.Failed)
+	assert.Equal(t, "REVERT", result.StructLogs[len(result.StructLogs)-1].Op)
+
+	res, err = api.TraceCall(args, types2.BlockHeight{}, &TraceConfig{Tracer: &tracerName})
+	assert.Nil(t, err)
+	frame = res.(*evm.CallFrame)
+	assert.Equal(t, "CALL", frame.Type)
+	assert.Equal(t, contract, frame.To)
+	assert.Equal(t, "execution reverted", frame.Error)
+	assert.True(t, frame.GasUsed > 0)
+
+	// the contract does not exist before it is deployed, and 0x0 is the genesis block
+	var blockNum types2.BlockHeight
+	assert.Nil(t, json.Unmarshal([]byte(`"0x0"`), &blockNum))
+	res, err = api.TraceCall(args, blockNum, nil)
+	assert.Nil(t, err)
+	result = res.(*ExecutionResult)
+	assert.False(t, result.Failed)
+	assert.Equal(t, 0, len(result.StructLogs))
+	res, err = api.TraceCall(args, types2.BlockHeight{Height: deployHeight, Explicit: true}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(res.(*ExecutionResult).StructLogs))
+
+	_, err = api.TraceCall(args, types2.BlockHeight{Height: deployHeight + 3, Explicit: true}, nil)
+	assert.NotNil(t, err)
+	unknown := "prestateTracer"
+	_, err = api.TraceCall(args, types2.BlockHeight{}, &TraceConfig{Tracer: &unknown})
+	assert.NotNil(t, err)
+}
+
+func TestFormatLogs(t *testing.T) {
+	logs := FormatLogs([]evm.StructLog{{
+		Pc:      1,
+		Op:      evm.MSTORE,
+		Gas:     100,
+		GasCost: 3,
+		Memory:  make([]byte, 64),
+		Stack:   []*big.Int{big.NewInt(1)},
+		Depth:   1,
+	}})
+	assert.Equal(t, 1, len(logs))
+	assert.Equal(t, "MSTORE", logs[0].Op)
+	assert.Equal(t, 2, len(*logs[0].Memory))
+	assert.Equal(t, []string{"0000000000000000000000000000000000000000000000000000000000000001"}, *logs[0].Stack)
+	assert.Nil(t, logs[0].Storage)
+	data, err := json.Marshal(logs[0])
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "error")
+}
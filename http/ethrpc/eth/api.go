@@ -42,7 +42,9 @@ import (
 	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/service/evm"
 	types3 "github.com/ontio/ontology/smartcontract/service/evm/types"
+	"github.com/ontio/ontology/smartcontract/service/native/ong"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/storage"
 	errors2 "github.com/ontio/ontology/vm/evm/errors"
 	"github.com/ontio/ontology/vm/evm/params"
 )
@@ -51,6 +53,11 @@ const (
 	eth65           = 65
 	ProtocolVersion = eth65
 	RPCGasCap       = config.DEFAULT_ETH_TX_MAX_GAS_LIMIT
+
+	// MaxProofStorageKeys is the max number of storage keys of an eth_getProof call
+	MaxProofStorageKeys = 32
+	// MaxProofResponseSize is the max total size in bytes of the encoded proofs returned by an eth_getProof call
+	MaxProofResponseSize = 4 * 1024 * 1024
 )
 
 type TxPoolService interface {
@@ -76,9 +83,60 @@ func (api *EthereumAPI) BlockNumber() (hexutil.Uint64, error) {
 	return hexutil.Uint64(height), nil
 }
 
-func (api *EthereumAPI) GetBalance(address common.Address, _ rpc.BlockNumberOrHash) (*hexutil.Big, error) {
-	balance, err := getOngBalance(address)
-	return (*hexutil.Big)(big.NewInt(int64(balance))), err
+func (api *EthereumAPI) GetBalance(address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	height, latest, err := stateHeightByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if latest {
+		balance, err := getOngBalance(address)
+		return (*hexutil.Big)(big.NewInt(int64(balance))), err
+	}
+	cache, err := bactor.GetCacheDBAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := ong.OngBalanceHandle{}.GetBalance(cache, utils2.EthToOntAddr(address))
+	if err != nil {
+		return nil, fmt.Errorf("get ong balance error:%s", err)
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+// stateHeight returns the block height of the requested state, latest is true if the current state is requested
+func stateHeight(blockNum types2.BlockNumber) (height uint32, latest bool) {
+	current := bactor.GetCurrentBlockHeight()
+	if blockNum.IsLatest() || blockNum.IsPending() || uint32(blockNum) >= current {
+		return current, true
+	}
+	return uint32(blockNum), false
+}
+
+func stateHeightByNumberOrHash(blockNrOrHash rpc.BlockNumberOrHash) (uint32, bool, error) {
+	current := bactor.GetCurrentBlockHeight()
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header, err := bactor.GetHeaderByHash(oComm.Uint256(hash))
+		if err != nil {
+			return 0, false, err
+		}
+		if header == nil {
+			return 0, false, fmt.Errorf("block %s not found", hash.String())
+		}
+		return header.Height, header.Height >= current, nil
+	}
+	number, ok := blockNrOrHash.Number()
+	if !ok || number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber || uint32(number) >= current {
+		return current, true, nil
+	}
+	return uint32(number), false, nil
+}
+
+func stateDBAtHeight(height uint32) (*storage.StateDB, error) {
+	cache, err := bactor.GetCacheDBAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewStateDB(cache, common.Hash{}, common.Hash{}, ong.OngBalanceHandle{}), nil
 }
 
 func getOngBalance(address common.Address) (uint64, error) {
@@ -130,7 +188,19 @@ func (api *EthereumAPI) Accounts() ([]common.Address, error) {
 }
 
 func (api *EthereumAPI) GetStorageAt(address common.Address, key string, blockNum types2.BlockNumber) (hexutil.Bytes, error) {
-	return bactor.GetEthStorage(address, common.HexToHash(key))
+	height, latest := stateHeight(blockNum)
+	if latest {
+		return bactor.GetEthStorage(address, common.HexToHash(key))
+	}
+	statedb, err := stateDBAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	value := statedb.GetState(address, common.HexToHash(key))
+	if err := statedb.DbErr(); err != nil {
+		return nil, err
+	}
+	return value[:], nil
 }
 
 func (api *EthereumAPI) GetTransactionCount(address common.Address, blockNum types2.BlockNumber) (*hexutil.Uint64, error) {
@@ -180,6 +250,17 @@ func (api *EthereumAPI) GetUncleCountByBlockNumber(number int64) hexutil.Uint {
 }
 
 func (api *EthereumAPI) GetCode(address common.Address, blockNumber types2.BlockNumber) (hexutil.Bytes, error) {
+	if height, latest := stateHeight(blockNumber); !latest {
+		statedb, err := stateDBAtHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		code := statedb.GetCode(address)
+		if err := statedb.DbErr(); err != nil {
+			return nil, err
+		}
+		return code, nil
+	}
 	account, err := bactor.GetEthAccount(address)
 	if err != nil {
 		return nil, err
@@ -207,7 +288,6 @@ func (api *EthereumAPI) GetTransactionLogs(txHash common.Hash) ([]*types.Log, er
 }
 
 func generateLog(rawNotify *event.ExecuteNotify) ([]*types.Log, *common.Hash, *otypes.Transaction, uint32, error) {
-	var res []*types.Log
 	txHash := rawNotify.TxHash
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(txHash)
 	if err != nil {
@@ -215,35 +295,14 @@ func generateLog(rawNotify *event.ExecuteNotify) ([]*types.Log, *common.Hash, *o
 	}
 	hash := bactor.GetBlockHashFromStore(height)
 	ethHash := utils2.OntToEthHash(hash)
-	for idx, n := range rawNotify.Notify {
+	for _, n := range rawNotify.Notify {
 		if !n.IsEvm {
 			return nil, nil, nil, 0, fmt.Errorf("not support tx type %v", rawNotify.TxHash.ToHexString())
 		}
-		states, ok := n.States.(string)
-		if ok {
-			data, err := hexutil.Decode(states)
-			if err != nil {
-				return nil, nil, nil, 0, err
-			}
-			source := oComm.NewZeroCopySource(data)
-			var storageLog otypes.StorageLog
-			err = storageLog.Deserialization(source)
-			if err != nil {
-				return nil, nil, nil, 0, err
-			}
-			log := &types.Log{
-				Address:     storageLog.Address,
-				Topics:      storageLog.Topics,
-				Data:        storageLog.Data,
-				BlockNumber: uint64(height),
-				TxHash:      utils2.OntToEthHash(txHash),
-				TxIndex:     uint(rawNotify.TxIndex),
-				BlockHash:   ethHash,
-				Index:       uint(idx),
-				Removed:     false,
-			}
-			res = append(res, log)
-		}
+	}
+	res, err := utils2.EthLogsFromNotify(rawNotify, height, ethHash, 0)
+	if err != nil {
+		return nil, nil, nil, 0, err
 	}
 	return res, &ethHash, tx, height, nil
 }
@@ -278,7 +337,13 @@ func (api *EthereumAPI) SendRawTransaction(data hexutil.Bytes) (common.Hash, err
 
 func (api *EthereumAPI) Call(args types2.CallArgs, blockNumber types2.BlockNumber, _ *map[common.Address]types2.Account) (hexutil.Bytes, error) {
 	msg := args.AsMessage(RPCGasCap)
-	res, err := bactor.PreExecuteEip155Tx(msg)
+	var res *types3.ExecutionResult
+	var err error
+	if height, latest := stateHeight(blockNumber); latest {
+		res, err = bactor.PreExecuteEip155Tx(msg)
+	} else {
+		res, err = bactor.PreExecuteEip155TxAtHeight(msg, height)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -573,6 +638,83 @@ func (api *EthereumAPI) GetUncleByBlockNumberAndIndex(_ hexutil.Uint, _ hexutil.
 	return nil
 }
 
-func (api *EthereumAPI) GetProof(address common.Address, storageKeys []string, block types2.BlockNumber) (*types2.AccountResult, error) {
-	return nil, fmt.Errorf("eth_getProof is not supported")
+// GetProof returns the account and storage values with proofs against the state merkle root of the block, the root
+// is returned as storageHash. AccountProof contains the proofs of eth account and ong balance.
+//
+// The state merkle tree only commits to the write sets of blocks, so a proof carries the write sets from the last
+// write of a key to the block. A key never written since the tree started can not be proven absent, and the call
+// fails for it. A key last written ledgerstore.MAX_STATE_PROOF_BLOCKS blocks or more before the block, as the
+// account of an idle address, can not be proven either, the error tells the heights it can be proven at. The number
+// of storage keys and the total size of the proofs are limited by MaxProofStorageKeys and MaxProofResponseSize.
+func (api *EthereumAPI) GetProof(address common.Address, storageKeys []string, blockNum types2.BlockNumber) (*types2.AccountResult, error) {
+	if len(storageKeys) > MaxProofStorageKeys {
+		return nil, fmt.Errorf("too many storage keys: %d, max: %d", len(storageKeys), MaxProofStorageKeys)
+	}
+	height := uint32(blockNum)
+	if blockNum.IsLatest() || blockNum.IsPending() {
+		height = bactor.GetCurrentBlockHeight()
+	}
+	root, err := bactor.GetStateMerkleRoot(height)
+	if err != nil {
+		return nil, fmt.Errorf("get state merkle root of block %d error: %s", height, err)
+	}
+
+	size := 0
+	accountProof, value, err := getStateProof(utils2.EthAccountKey(address), height, &size)
+	if err != nil {
+		return nil, err
+	}
+	account, err := utils2.DecodeEthAccount(value)
+	if err != nil {
+		return nil, err
+	}
+	balanceProof, value, err := getStateProof(utils2.OngBalanceKey(address), height, &size)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := utils2.DecodeOngBalance(value)
+	if err != nil {
+		return nil, err
+	}
+
+	storageProof := make([]types2.StorageResult, 0, len(storageKeys))
+	for _, key := range storageKeys {
+		proof, value, err := getStateProof(utils2.EthStorageKey(address, common.HexToHash(key)), height, &size)
+		if err != nil {
+			return nil, err
+		}
+		storageProof = append(storageProof, types2.StorageResult{
+			Key:   key,
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(value)),
+			Proof: []string{proof},
+		})
+	}
+
+	return &types2.AccountResult{
+		Address:      address,
+		AccountProof: []string{accountProof, balanceProof},
+		Balance:      (*hexutil.Big)(new(big.Int).SetUint64(balance)),
+		CodeHash:     account.CodeHash,
+		Nonce:        hexutil.Uint64(account.Nonce),
+		StorageHash:  common.Hash(root),
+		StorageProof: storageProof,
+	}, nil
+}
+
+// getStateProof returns the encoded proof of key at height and its value, size accumulates the encoded proof sizes
+// of a call
+func getStateProof(key []byte, height uint32, size *int) (string, []byte, error) {
+	proof, err := bactor.GetStateProof(key, height)
+	if err != nil {
+		if err == common2.ErrNotFound {
+			return "", nil, fmt.Errorf("key %x was never written since the state merkle tree started, its absence can not be proven", key)
+		}
+		return "", nil, err
+	}
+	encoded := utils2.EncodeStateProof(proof)
+	*size += len(encoded)
+	if *size > MaxProofResponseSize {
+		return "", nil, fmt.Errorf("proofs exceed the max response size %d", MaxProofResponseSize)
+	}
+	return encoded, proof.Value, nil
 }
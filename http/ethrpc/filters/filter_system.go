@@ -0,0 +1,278 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package filters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ontio/ontology/common/log"
+	otypes "github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/events/message"
+	bactor "github.com/ontio/ontology/http/base/actor"
+	"github.com/ontio/ontology/http/ethrpc/utils"
+)
+
+// Type determines the kind of filter and is used to put the filter in to
+// the correct bucket when added.
+type Type byte
+
+const (
+	// UnknownSubscription indicates an unknown subscription type
+	UnknownSubscription Type = iota
+	// LogsSubscription queries for new logs
+	LogsSubscription
+	// PendingTransactionsSubscription queries tx hashes for pending
+	// transactions entering the pending state
+	PendingTransactionsSubscription
+	// BlocksSubscription queries blocks that are committed to the ledger
+	BlocksSubscription
+	// LastIndexSubscription keeps track of the last index
+	LastIndexSubscription
+)
+
+const (
+	// pendingTxPollInterval is the interval of checking the txpool for new pending transactions
+	pendingTxPollInterval = time.Second
+	// blockChanSize is the size of channel listening to committed blocks
+	blockChanSize = 10
+)
+
+// TxPoolService provides the pending eip155 transactions of txpool
+type TxPoolService interface {
+	PendingEIPTransactions() []*types.Transaction
+}
+
+type subscription struct {
+	id        rpc.ID
+	typ       Type
+	created   time.Time
+	logsCrit  FilterCriteria
+	logs      chan []*types.Log
+	hashes    chan []common.Hash
+	blocks    chan *otypes.Block
+	installed chan struct{} // closed when the filter is installed
+	err       chan error    // closed when the filter is uninstalled
+}
+
+// EventSystem creates subscriptions, processes the committed blocks and pending
+// transactions and broadcasts them to the subscriptions which match the subscription criteria.
+type EventSystem struct {
+	txpool TxPoolService
+
+	install   chan *subscription // install filter for event notification
+	uninstall chan *subscription // remove filter for event notification
+	blockCh   chan *otypes.Block // channel to receive committed blocks
+}
+
+// NewEventSystem creates a new manager that listens for block commit events on the
+// actor event hub and polls the txpool for pending transactions.
+func NewEventSystem(txpool TxPoolService) *EventSystem {
+	es := &EventSystem{
+		txpool:    txpool,
+		install:   make(chan *subscription),
+		uninstall: make(chan *subscription),
+		blockCh:   make(chan *otypes.Block, blockChanSize),
+	}
+	bactor.SubscribeEvent(message.TOPIC_SAVE_BLOCK_COMPLETE, es.onBlockCommitted)
+
+	go es.eventLoop()
+	return es
+}
+
+func (es *EventSystem) onBlockCommitted(v interface{}) {
+	if block, ok := v.(otypes.Block); ok {
+		es.blockCh <- &block
+	}
+}
+
+// Subscription is created when the client registers itself for a particular event.
+type Subscription struct {
+	ID        rpc.ID
+	f         *subscription
+	es        *EventSystem
+	unsubOnce sync.Once
+}
+
+// Err returns a channel that is closed when unsubscribed.
+func (sub *Subscription) Err() <-chan error {
+	return sub.f.err
+}
+
+// Unsubscribe uninstalls the subscription from the event broadcast loop.
+func (sub *Subscription) Unsubscribe() {
+	sub.unsubOnce.Do(func() {
+	uninstallLoop:
+		for {
+			// write uninstall request and consume logs/hashes. This prevents
+			// the eventLoop broadcast method to deadlock when writing to the
+			// filter event channel while the subscription loop is waiting for
+			// this method to return (and thus not reading these events).
+			select {
+			case sub.es.uninstall <- sub.f:
+				break uninstallLoop
+			case <-sub.f.logs:
+			case <-sub.f.hashes:
+			case <-sub.f.blocks:
+			}
+		}
+
+		// wait for filter to be uninstalled in work loop before returning
+		// this ensures that the manager won't use the event channel which
+		// will probably be closed by the client asap after this method returns.
+		<-sub.Err()
+	})
+}
+
+// subscribe installs the subscription in the event broadcast loop.
+func (es *EventSystem) subscribe(sub *subscription) *Subscription {
+	es.install <- sub
+	<-sub.installed
+	return &Subscription{ID: sub.id, f: sub, es: es}
+}
+
+// SubscribeLogs creates a subscription that will write all logs matching the
+// given criteria to the given logs channel.
+func (es *EventSystem) SubscribeLogs(crit FilterCriteria, logs chan []*types.Log) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       LogsSubscription,
+		logsCrit:  crit,
+		created:   time.Now(),
+		logs:      logs,
+		hashes:    make(chan []common.Hash),
+		blocks:    make(chan *otypes.Block),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeNewBlocks creates a subscription that writes the block that is
+// committed to the ledger.
+func (es *EventSystem) SubscribeNewBlocks(blocks chan *otypes.Block) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       BlocksSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		blocks:    blocks,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribePendingTxs creates a subscription that writes transaction hashes for
+// transactions that enter the transaction pool.
+func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       PendingTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    hashes,
+		blocks:    make(chan *otypes.Block),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+type filterIndex map[Type]map[rpc.ID]*subscription
+
+// handleBlock broadcasts the block and the logs of a committed block to the subscriptions.
+func (es *EventSystem) handleBlock(filters filterIndex, block *otypes.Block) {
+	for _, f := range filters[BlocksSubscription] {
+		f.blocks <- block
+	}
+	if len(filters[LogsSubscription]) == 0 {
+		return
+	}
+	height := block.Header.Height
+	logs, err := getBlockLogs(height, utils.OntToEthHash(block.Hash()))
+	if err != nil {
+		log.Errorf("[ethrpc] get logs of block %d error: %s", height, err)
+		return
+	}
+	if len(logs) == 0 {
+		return
+	}
+	for _, f := range filters[LogsSubscription] {
+		crit := f.logsCrit
+		matched := filterLogs(logs, blockBound(crit.FromBlock), blockBound(crit.ToBlock), crit.Addresses, crit.Topics)
+		if len(matched) > 0 {
+			f.logs <- matched
+		}
+	}
+}
+
+// handlePendingTxs broadcasts the transactions which entered the txpool since last check.
+func (es *EventSystem) handlePendingTxs(filters filterIndex, known map[common.Hash]bool) map[common.Hash]bool {
+	current := make(map[common.Hash]bool)
+	var hashes []common.Hash
+	for _, tx := range es.txpool.PendingEIPTransactions() {
+		hash := tx.Hash()
+		current[hash] = true
+		if !known[hash] {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(hashes) > 0 {
+		for _, f := range filters[PendingTransactionsSubscription] {
+			f.hashes <- hashes
+		}
+	}
+	return current
+}
+
+// eventLoop (un)installs filters and processes blocks and pending transactions.
+func (es *EventSystem) eventLoop() {
+	index := make(filterIndex)
+	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
+		index[i] = make(map[rpc.ID]*subscription)
+	}
+	ticker := time.NewTicker(pendingTxPollInterval)
+	defer ticker.Stop()
+	var knownPending map[common.Hash]bool
+
+	for {
+		select {
+		case block := <-es.blockCh:
+			es.handleBlock(index, block)
+		case <-ticker.C:
+			if len(index[PendingTransactionsSubscription]) > 0 {
+				knownPending = es.handlePendingTxs(index, knownPending)
+			}
+		case f := <-es.install:
+			if f.typ == PendingTransactionsSubscription && len(index[PendingTransactionsSubscription]) == 0 {
+				// only report transactions which enter the pool after the first subscription
+				knownPending = es.handlePendingTxs(index, nil)
+			}
+			index[f.typ][f.id] = f
+			close(f.installed)
+		case f := <-es.uninstall:
+			delete(index[f.typ], f.id)
+			close(f.err)
+		}
+	}
+}
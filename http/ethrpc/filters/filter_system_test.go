@@ -0,0 +1,298 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package filters
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	oComm "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	otypes "github.com/ontio/ontology/core/types"
+	cutils "github.com/ontio/ontology/core/utils"
+	"github.com/ontio/ontology/events"
+	"github.com/ontio/ontology/http/ethrpc/utils"
+	"github.com/ontio/ontology/smartcontract/event"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTxPool struct {
+	sync.Mutex
+	txs []*types.Transaction
+}
+
+func (pool *mockTxPool) PendingEIPTransactions() []*types.Transaction {
+	pool.Lock()
+	defer pool.Unlock()
+	return append([]*types.Transaction{}, pool.txs...)
+}
+
+func (pool *mockTxPool) add(tx *types.Transaction) {
+	pool.Lock()
+	defer pool.Unlock()
+	pool.txs = append(pool.txs, tx)
+}
+
+func newTestTx(nonce uint64) *types.Transaction {
+	return types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 21000, big.NewInt(500), nil)
+}
+
+// testBookkeeper signs the blocks of the solo test ledger
+var testBookkeeper *account.Account
+
+func TestMain(m *testing.M) {
+	events.Init()
+	dir, err := ioutil.TempDir("", "filters")
+	if err != nil {
+		panic(err)
+	}
+	testBookkeeper = account.NewAccount("")
+	config.DefConfig.Genesis = config.NewGenesisConfig()
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_SOLO
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(testBookkeeper.PublicKey))}
+	bookkeepers := []keypair.PublicKey{testBookkeeper.PublicKey}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	if err != nil {
+		panic(err)
+	}
+	ledger.DefLedger, err = ledger.InitLedger(dir, 0, bookkeepers, block)
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	ledger.DefLedger.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// submitTestBlock submits the next block of the test ledger, with a transaction emitting the evm logs.
+func submitTestBlock(t *testing.T, logs ...*otypes.StorageLog) *otypes.Block {
+	mutable := cutils.BuildNativeTransaction(nutils.OntContractAddress, "name", []byte{})
+	mutable.Nonce = uint32(time.Now().UnixNano())
+	mutable.Payer = testBookkeeper.Address
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+
+	prevHash := ledger.DefLedger.GetCurrentBlockHash()
+	prevHeader, err := ledger.DefLedger.GetHeaderByHash(prevHash)
+	assert.Nil(t, err)
+	height := prevHeader.Height + 1
+	txRoot := oComm.ComputeMerkleRoot([]oComm.Uint256{tx.Hash()})
+	header := &otypes.Header{
+		PrevBlockHash:    prevHash,
+		TransactionsRoot: txRoot,
+		BlockRoot:        ledger.DefLedger.GetBlockRootWithNewTxRoots(height, []oComm.Uint256{txRoot}),
+		Timestamp:        prevHeader.Timestamp + 1,
+		Height:           height,
+		NextBookkeeper:   testBookkeeper.Address,
+	}
+	block := &otypes.Block{Header: header, Transactions: []*otypes.Transaction{tx}}
+	blockHash := block.Hash()
+	sig, err := signature.Sign(testBookkeeper, blockHash[:])
+	assert.Nil(t, err)
+	header.Bookkeepers = []keypair.PublicKey{testBookkeeper.PublicKey}
+	header.SigData = [][]byte{sig}
+
+	result, err := ledger.DefLedger.ExecuteBlock(block)
+	assert.Nil(t, err)
+	for _, notify := range result.Notify {
+		if notify.TxHash == tx.Hash() {
+			for _, log := range logs {
+				notify.Notify = append(notify.Notify, event.NotifyEventInfoFromEvmLog(log))
+			}
+		}
+	}
+	assert.Nil(t, ledger.DefLedger.SubmitBlock(block, nil, result))
+	return block
+}
+
+func TestPendingTxFilter(t *testing.T) {
+	pool := &mockTxPool{}
+	pool.add(newTestTx(0))
+	api := NewPublicFilterAPI(pool, 0)
+
+	id := api.NewPendingTransactionFilter()
+	tx := newTestTx(1)
+	pool.add(tx)
+
+	var hashes []common.Hash
+	timeout := time.After(5 * time.Second)
+	for len(hashes) == 0 {
+		select {
+		case <-timeout:
+			t.Fatal("pending transaction not received")
+		case <-time.After(100 * time.Millisecond):
+		}
+		changes, err := api.GetFilterChanges(id)
+		assert.Nil(t, err)
+		hashes = append(hashes, changes.([]common.Hash)...)
+	}
+	assert.Equal(t, []common.Hash{tx.Hash()}, hashes)
+
+	assert.True(t, api.UninstallFilter(id))
+	assert.False(t, api.UninstallFilter(id))
+	_, err := api.GetFilterChanges(id)
+	assert.NotNil(t, err)
+}
+
+func TestPendingTxSubscription(t *testing.T) {
+	pool := &mockTxPool{}
+	server := rpc.NewServer()
+	assert.Nil(t, server.RegisterName("eth", NewPublicFilterAPI(pool, 0)))
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	hashes := make(chan common.Hash)
+	sub, err := client.EthSubscribe(context.Background(), hashes, "newPendingTransactions")
+	assert.Nil(t, err)
+	defer sub.Unsubscribe()
+
+	tx := newTestTx(2)
+	pool.add(tx)
+	select {
+	case hash := <-hashes:
+		assert.Equal(t, tx.Hash(), hash)
+	case err := <-sub.Err():
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending transaction not notified")
+	}
+}
+
+func TestFilterTimeout(t *testing.T) {
+	pool := &mockTxPool{}
+	api := newPublicFilterAPI(pool, 0, 50*time.Millisecond)
+	id := api.NewPendingTransactionFilter()
+	for i := uint64(0); i < 10; i++ {
+		pool.add(newTestTx(10 + i))
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-timeout:
+			t.Fatal("inactive filter not removed")
+		case <-time.After(20 * time.Millisecond):
+		}
+		api.filtersMu.Lock()
+		_, found := api.filters[id]
+		api.filtersMu.Unlock()
+		if !found {
+			break
+		}
+	}
+	_, err := api.GetFilterChanges(id)
+	assert.NotNil(t, err)
+}
+
+func TestBlockAndLogFilters(t *testing.T) {
+	addr1 := common.BigToAddress(common.Big1)
+	addr2 := common.BigToAddress(common.Big2)
+	topic := common.BigToHash(common.Big3)
+	api := NewPublicFilterAPI(&mockTxPool{}, 0)
+
+	blockID := api.NewBlockFilter()
+	logID, err := api.NewFilter(FilterCriteria{Addresses: []common.Address{addr1}})
+	assert.Nil(t, err)
+	unmatchedID, err := api.NewFilter(FilterCriteria{Topics: [][]common.Hash{{common.BigToHash(common.Big0)}}})
+	assert.Nil(t, err)
+
+	block1 := submitTestBlock(t,
+		&otypes.StorageLog{Address: addr1, Topics: []common.Hash{topic}, Data: []byte{1}},
+		&otypes.StorageLog{Address: addr2, Topics: []common.Hash{topic}, Data: []byte{2}})
+	block2 := submitTestBlock(t, &otypes.StorageLog{Address: addr1, Data: []byte{3}})
+
+	var hashes []common.Hash
+	var logs []*types.Log
+	timeout := time.After(5 * time.Second)
+	for len(hashes) < 2 || len(logs) < 2 {
+		select {
+		case <-timeout:
+			t.Fatal("committed blocks not received")
+		case <-time.After(100 * time.Millisecond):
+		}
+		changes, err := api.GetFilterChanges(blockID)
+		assert.Nil(t, err)
+		hashes = append(hashes, changes.([]common.Hash)...)
+		changes, err = api.GetFilterChanges(logID)
+		assert.Nil(t, err)
+		logs = append(logs, changes.([]*types.Log)...)
+	}
+	assert.Equal(t, []common.Hash{utils.OntToEthHash(block1.Hash()), utils.OntToEthHash(block2.Hash())}, hashes)
+	assert.Equal(t, 2, len(logs))
+	assert.Equal(t, addr1, logs[0].Address)
+	assert.Equal(t, []common.Hash{topic}, logs[0].Topics)
+	assert.Equal(t, []byte{1}, logs[0].Data)
+	assert.Equal(t, uint64(block1.Header.Height), logs[0].BlockNumber)
+	assert.Equal(t, utils.OntToEthHash(block1.Hash()), logs[0].BlockHash)
+	assert.Equal(t, utils.OntToEthHash(block1.Transactions[0].Hash()), logs[0].TxHash)
+	assert.Equal(t, []byte{3}, logs[1].Data)
+	assert.Equal(t, uint64(block2.Header.Height), logs[1].BlockNumber)
+
+	changes, err := api.GetFilterChanges(unmatchedID)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(changes.([]*types.Log)))
+
+	// the criteria without block range matches the latest block
+	filterLogs, err := api.GetFilterLogs(logID)
+	assert.Nil(t, err)
+	assert.Equal(t, logs[1:], filterLogs)
+}
+
+func TestLogsSubscription(t *testing.T) {
+	addr := common.BigToAddress(common.Big1)
+	server := rpc.NewServer()
+	assert.Nil(t, server.RegisterName("eth", NewPublicFilterAPI(&mockTxPool{}, 0)))
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	logs := make(chan types.Log)
+	sub, err := client.EthSubscribe(context.Background(), logs, "logs", map[string]interface{}{"address": addr})
+	assert.Nil(t, err)
+	defer sub.Unsubscribe()
+
+	submitTestBlock(t, &otypes.StorageLog{Address: common.BigToAddress(common.Big2)})
+	block := submitTestBlock(t, &otypes.StorageLog{Address: addr, Data: []byte{1}})
+	select {
+	case log := <-logs:
+		assert.Equal(t, addr, log.Address)
+		assert.Equal(t, []byte{1}, log.Data)
+		assert.Equal(t, utils.OntToEthHash(block.Hash()), log.BlockHash)
+	case err := <-sub.Err():
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("log not notified")
+	}
+}
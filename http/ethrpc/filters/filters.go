@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package filters
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	oComm "github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	bactor "github.com/ontio/ontology/http/base/actor"
+	types2 "github.com/ontio/ontology/http/ethrpc/types"
+	"github.com/ontio/ontology/http/ethrpc/utils"
+)
+
+// Filter can be used to retrieve and filter logs.
+type Filter struct {
+	addresses []common.Address
+	topics    [][]common.Hash
+
+	block      common.Hash // Block hash if filtering a single block
+	begin, end uint32      // Range interval if filtering multiple blocks
+}
+
+// NewRangeFilter creates a new filter which inspects the blocks from begin to end
+// for matching logs.
+func NewRangeFilter(begin, end uint32, addresses []common.Address, topics [][]common.Hash) *Filter {
+	return &Filter{
+		addresses: addresses,
+		topics:    topics,
+		begin:     begin,
+		end:       end,
+	}
+}
+
+// NewBlockFilter creates a new filter which directly inspects the contents of
+// a block to figure out whether it is interesting or not.
+func NewBlockFilter(block common.Hash, addresses []common.Address, topics [][]common.Hash) *Filter {
+	return &Filter{
+		addresses: addresses,
+		topics:    topics,
+		block:     block,
+	}
+}
+
+// Logs searches the ledger for matching log entries.
+func (f *Filter) Logs() ([]*types.Log, error) {
+	if f.block != (common.Hash{}) {
+		header, err := bactor.GetHeaderByHash(utils.EthToOntHash(f.block))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, fmt.Errorf("block: %v not found", f.block.String())
+		}
+		return f.blockLogs(header.Height, f.block)
+	}
+
+	var logs []*types.Log
+	for height := f.begin; height <= f.end; height++ {
+		hash := bactor.GetBlockHashFromStore(height)
+		if hash == oComm.UINT256_EMPTY {
+			break
+		}
+		found, err := f.blockLogs(height, utils.OntToEthHash(hash))
+		if err != nil {
+			return logs, err
+		}
+		logs = append(logs, found...)
+	}
+	return logs, nil
+}
+
+// blockLogs returns the logs matching the filter criteria within a single block.
+func (f *Filter) blockLogs(height uint32, blockHash common.Hash) ([]*types.Log, error) {
+	logs, err := getBlockLogs(height, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return filterLogs(logs, nil, nil, f.addresses, f.topics), nil
+}
+
+// getBlockLogs returns all the evm logs of the block at given height.
+func getBlockLogs(height uint32, blockHash common.Hash) ([]*types.Log, error) {
+	notifies, err := bactor.GetEventNotifyByHeight(height)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var logs []*types.Log
+	for _, notify := range notifies {
+		found, err := utils.EthLogsFromNotify(notify, height, blockHash, uint(len(logs)))
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, found...)
+	}
+	return logs, nil
+}
+
+// resolveBlockRange converts the block numbers of a filter criteria to block heights,
+// unspecified, latest and pending block numbers resolve to the current block height.
+func resolveBlockRange(from, to *types2.BlockHeight) (uint32, uint32) {
+	current := bactor.GetCurrentBlockHeight()
+	resolve := func(bh *types2.BlockHeight) uint32 {
+		if bh == nil {
+			return current
+		}
+		return bh.Resolve(current)
+	}
+	return resolve(from), resolve(to)
+}
+
+// blockBound returns the explicit block height of a filter criteria bound, or nil
+// if the bound follows the latest block.
+func blockBound(bh *types2.BlockHeight) *uint32 {
+	if bh == nil || !bh.Explicit {
+		return nil
+	}
+	height := bh.Height
+	return &height
+}
+
+func includes(addresses []common.Address, a common.Address) bool {
+	for _, addr := range addresses {
+		if addr == a {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLogs creates a slice of logs matching the given criteria.
+func filterLogs(logs []*types.Log, fromBlock, toBlock *uint32, addresses []common.Address, topics [][]common.Hash) []*types.Log {
+	var ret []*types.Log
+Logs:
+	for _, log := range logs {
+		if fromBlock != nil && uint64(*fromBlock) > log.BlockNumber {
+			continue
+		}
+		if toBlock != nil && uint64(*toBlock) < log.BlockNumber {
+			continue
+		}
+
+		if len(addresses) > 0 && !includes(addresses, log.Address) {
+			continue
+		}
+		// If the to filtered topics is greater than the amount of topics in logs, skip.
+		if len(topics) > len(log.Topics) {
+			continue Logs
+		}
+		for i, sub := range topics {
+			match := len(sub) == 0 // empty rule set == wildcard
+			for _, topic := range sub {
+				if log.Topics[i] == topic {
+					match = true
+					break
+				}
+			}
+			if !match {
+				continue Logs
+			}
+		}
+		ret = append(ret, log)
+	}
+	return ret
+}
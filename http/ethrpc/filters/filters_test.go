@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ontio/ontology/core/ledger"
+	otypes "github.com/ontio/ontology/core/types"
+	types2 "github.com/ontio/ontology/http/ethrpc/types"
+	"github.com/ontio/ontology/http/ethrpc/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterCriteriaUnmarshal(t *testing.T) {
+	var crit FilterCriteria
+	err := json.Unmarshal([]byte(`{"fromBlock":"0x10","toBlock":"latest","address":"0x0000000000000000000000000000000000000001","topics":[null,["0x0000000000000000000000000000000000000000000000000000000000000002","0x0000000000000000000000000000000000000000000000000000000000000003"]]}`), &crit)
+	assert.Nil(t, err)
+	assert.Nil(t, crit.BlockHash)
+	assert.Equal(t, types2.BlockHeight{Height: 16, Explicit: true}, *crit.FromBlock)
+	assert.False(t, crit.ToBlock.Explicit)
+	assert.Equal(t, []common.Address{common.BigToAddress(common.Big1)}, crit.Addresses)
+	assert.Equal(t, 2, len(crit.Topics))
+	assert.Nil(t, crit.Topics[0])
+	assert.Equal(t, []common.Hash{common.BigToHash(common.Big2), common.BigToHash(common.Big3)}, crit.Topics[1])
+
+	err = json.Unmarshal([]byte(`{"blockHash":"0x0000000000000000000000000000000000000000000000000000000000000001","fromBlock":"0x1"}`), &crit)
+	assert.NotNil(t, err)
+
+	err = json.Unmarshal([]byte(`{"address":["0x01"]}`), &crit)
+	assert.NotNil(t, err)
+
+	crit = FilterCriteria{}
+	err = json.Unmarshal([]byte(`{"fromBlock":"0x0","toBlock":"earliest"}`), &crit)
+	assert.Nil(t, err)
+	assert.Equal(t, types2.BlockHeight{Height: 0, Explicit: true}, *crit.FromBlock)
+	assert.Equal(t, types2.BlockHeight{Height: 0, Explicit: true}, *crit.ToBlock)
+
+	err = json.Unmarshal([]byte(`{"fromBlock":"0x100000000"}`), &crit)
+	assert.NotNil(t, err)
+}
+
+func TestGetLogs(t *testing.T) {
+	// blocks of other tests share the ledger
+	addr := common.HexToAddress("0x0000000000000000000000000000000000000101")
+	topic := common.BigToHash(common.Big2)
+	api := NewPublicFilterAPI(&mockTxPool{}, 0)
+	getLogs := func(crit string) ([]*types.Log, error) {
+		var c FilterCriteria
+		assert.Nil(t, json.Unmarshal([]byte(crit), &c))
+		return api.GetLogs(c)
+	}
+
+	start := ledger.DefLedger.GetCurrentBlockHeight()
+	block := submitTestBlock(t, &otypes.StorageLog{Address: addr, Topics: []common.Hash{topic}, Data: []byte{1}})
+	submitTestBlock(t)
+	height := block.Header.Height
+
+	logs, err := getLogs(fmt.Sprintf(`{"fromBlock":"0x0","toBlock":"0x%x","address":"%s"}`, height, addr.Hex()))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(logs))
+	assert.Equal(t, addr, logs[0].Address)
+	assert.Equal(t, []common.Hash{topic}, logs[0].Topics)
+	assert.Equal(t, []byte{1}, logs[0].Data)
+	assert.Equal(t, uint64(height), logs[0].BlockNumber)
+	assert.Equal(t, utils.OntToEthHash(block.Hash()), logs[0].BlockHash)
+
+	logs, err = getLogs(fmt.Sprintf(`{"fromBlock":"earliest","toBlock":"latest","address":"%s"}`, addr.Hex()))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(logs))
+
+	// the genesis block only
+	logs, err = getLogs(`{"fromBlock":"0x0","toBlock":"0x0"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(logs))
+
+	logs, err = getLogs(fmt.Sprintf(`{"fromBlock":"0x%x"}`, height+1))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(logs))
+
+	_, err = getLogs(fmt.Sprintf(`{"fromBlock":"0x%x","toBlock":"0x%x"}`, height, start))
+	assert.NotNil(t, err)
+
+	api.maxBlockRange = 1
+	_, err = getLogs(`{"fromBlock":"0x0"}`)
+	assert.NotNil(t, err)
+}
+
+func TestFilterLogs(t *testing.T) {
+	addr1 := common.BigToAddress(common.Big1)
+	addr2 := common.BigToAddress(common.Big2)
+	topic1 := common.BigToHash(common.Big1)
+	topic2 := common.BigToHash(common.Big2)
+	topic3 := common.BigToHash(common.Big3)
+	logs := []*types.Log{
+		{Address: addr1, Topics: []common.Hash{topic1}, BlockNumber: 1},
+		{Address: addr1, Topics: []common.Hash{topic1, topic2}, BlockNumber: 2},
+		{Address: addr2, Topics: []common.Hash{topic2, topic3}, BlockNumber: 3},
+		{Address: addr2, Topics: []common.Hash{topic3}, BlockNumber: 4},
+	}
+
+	assert.Equal(t, logs, filterLogs(logs, nil, nil, nil, nil))
+	assert.Equal(t, logs[:2], filterLogs(logs, nil, nil, []common.Address{addr1}, nil))
+	assert.Equal(t, logs[:2], filterLogs(logs, nil, nil, nil, [][]common.Hash{{topic1}}))
+	assert.Equal(t, logs[1:3], filterLogs(logs, nil, nil, nil, [][]common.Hash{nil, {topic2, topic3}}))
+	assert.Equal(t, logs[2:3], filterLogs(logs, nil, nil, []common.Address{addr2}, [][]common.Hash{{topic2}}))
+	assert.Nil(t, filterLogs(logs, nil, nil, []common.Address{addr2}, [][]common.Hash{{topic1}}))
+
+	from, to := uint32(2), uint32(3)
+	assert.Equal(t, logs[1:3], filterLogs(logs, &from, &to, nil, nil))
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ethrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ontio/ontology/http/metrics"
+)
+
+const (
+	maxRequestContentLength = 1024 * 1024 * 5 // the same as the http server of go-ethereum rpc
+	batchMethod             = "batch"         // method label of batch requests
+)
+
+// errorResponseMark starts the error member of the responses encoded by go-ethereum rpc, the quotes
+// can not appear unescaped in json strings, so a result can only contain it in an object
+var errorResponseMark = []byte(`"error":{"code":`)
+
+// metricsHandler records the latency and errors of eth rpc requests served over http
+type metricsHandler struct {
+	handler http.Handler
+	methods map[string]bool // methods of the registered apis, the others are labeled unknown
+}
+
+// newMetricsHandler wraps handler serving apis, the method names of apis are derived the
+// same way as go-ethereum rpc does
+func newMetricsHandler(handler http.Handler, apis []rpc.API) *metricsHandler {
+	methods := make(map[string]bool)
+	for _, api := range apis {
+		typ := reflect.TypeOf(api.Service)
+		for i := 0; i < typ.NumMethod(); i++ {
+			name := []rune(typ.Method(i).Name)
+			name[0] = unicode.ToLower(name[0])
+			methods[api.Namespace+"_"+string(name)] = true
+		}
+	}
+	return &metricsHandler{handler: handler, methods: methods}
+}
+
+type jsonRequest struct {
+	Method string `json:"method"`
+}
+
+// errorResponseWriter finds the error responses while writing, without keeping the response
+type errorResponseWriter struct {
+	http.ResponseWriter
+	tail   []byte // the end of the written data which may start an error mark
+	failed bool
+}
+
+func (self *errorResponseWriter) WriteHeader(code int) {
+	self.failed = self.failed || code >= http.StatusBadRequest
+	self.ResponseWriter.WriteHeader(code)
+}
+
+func (self *errorResponseWriter) Write(data []byte) (int, error) {
+	if !self.failed {
+		keep := len(errorResponseMark) - 1
+		head := data
+		if len(head) > keep {
+			head = head[:keep]
+		}
+		joined := append(self.tail, head...)
+		self.failed = bytes.Contains(joined, errorResponseMark) || bytes.Contains(data, errorResponseMark)
+		if len(data) >= keep {
+			joined = data[len(data)-keep:]
+		} else if len(joined) > keep {
+			joined = joined[len(joined)-keep:]
+		}
+		self.tail = append(self.tail[:0], joined...)
+	}
+	return self.ResponseWriter.Write(data)
+}
+
+func (self *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Body == nil {
+		self.handler.ServeHTTP(w, r)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestContentLength+1))
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	start := time.Now()
+	writer := &errorResponseWriter{ResponseWriter: w}
+	self.handler.ServeHTTP(writer, r)
+
+	method := batchMethod
+	if data := bytes.TrimSpace(body); len(data) == 0 || data[0] != '[' {
+		req := &jsonRequest{}
+		method = metrics.METHOD_UNKNOWN
+		if json.Unmarshal(data, req) == nil && self.methods[req.Method] {
+			method = req.Method
+		}
+	}
+	metrics.ObserveRpc(metrics.API_ETHRPC, method, start, writer.failed)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ethrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ontio/ontology/http/ethrpc/web3"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// rpcMetricValue return the value of counter or the sample count of histogram with the labels of eth rpc method
+func rpcMetricValue(t *testing.T, name, method string) float64 {
+	families, err := prom.DefaultGatherer.Gather()
+	assert.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["api"] != "ethrpc" || labels["method"] != method {
+				continue
+			}
+			if metric.GetHistogram() != nil {
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestMetricsHandler(t *testing.T) {
+	apis := []rpc.API{{Namespace: "web3", Service: web3.NewAPI()}}
+	server := rpc.NewServer()
+	assert.Nil(t, server.RegisterName("web3", web3.NewAPI()))
+	handler := newMetricsHandler(server, apis)
+
+	call := func(body string) string {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	resp := call(`{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion","params":[]}`)
+	assert.Contains(t, resp, "Ontology")
+	assert.Equal(t, float64(1), rpcMetricValue(t, "ontology_rpc_request_duration_seconds", "web3_clientVersion"))
+	assert.Equal(t, float64(0), rpcMetricValue(t, "ontology_rpc_errors_total", "web3_clientVersion"))
+
+	call(`{"jsonrpc":"2.0","id":2,"method":"web3_sha3","params":["xyz"]}`)
+	assert.Equal(t, float64(1), rpcMetricValue(t, "ontology_rpc_errors_total", "web3_sha3"))
+
+	call(`{"jsonrpc":"2.0","id":3,"method":"web3_noSuchMethod","params":[]}`)
+	assert.Equal(t, float64(0), rpcMetricValue(t, "ontology_rpc_request_duration_seconds", "web3_noSuchMethod"))
+	assert.Equal(t, float64(1), rpcMetricValue(t, "ontology_rpc_errors_total", "unknown"))
+
+	resp = call(`[{"jsonrpc":"2.0","id":4,"method":"web3_clientVersion","params":[]},` +
+		`{"jsonrpc":"2.0","id":5,"method":"web3_sha3","params":["xyz"]}]`)
+	assert.Contains(t, resp, "Ontology")
+	assert.Equal(t, float64(1), rpcMetricValue(t, "ontology_rpc_errors_total", "batch"))
+}
+
+func TestErrorResponseWriter(t *testing.T) {
+	write := func(chunks ...string) bool {
+		writer := &errorResponseWriter{ResponseWriter: httptest.NewRecorder()}
+		for _, chunk := range chunks {
+			writer.Write([]byte(chunk))
+		}
+		return writer.failed
+	}
+	assert.False(t, write(`{"jsonrpc":"2.0","id":1,"result":"\"error\":{\"code\":"}`))
+	assert.True(t, write(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"x"}}`))
+	assert.True(t, write(`{"jsonrpc":"2.0","id":1,"err`, `or":{"co`, `de":-32000,"message":"x"}}`))
+	assert.True(t, write(`{"jsonrpc":"2.0","id":1,"error":{"c`, `o`, `d`, `e":-32000}}`))
+}
@@ -23,32 +23,48 @@ import (
 
 	"github.com/ethereum/go-ethereum/rpc"
 	cfg "github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/http/ethrpc/debug"
 	"github.com/ontio/ontology/http/ethrpc/eth"
+	"github.com/ontio/ontology/http/ethrpc/filters"
 	"github.com/ontio/ontology/http/ethrpc/net"
+	txpool2 "github.com/ontio/ontology/http/ethrpc/txpool"
 	"github.com/ontio/ontology/http/ethrpc/web3"
 	tp "github.com/ontio/ontology/txnpool/proc"
 )
 
 func StartEthServer(txpool *tp.TXPoolServer) error {
-	ethAPI := eth.NewEthereumAPI(txpool)
+	apis := []rpc.API{
+		{Namespace: "eth", Service: eth.NewEthereumAPI(txpool)},
+		{Namespace: "eth", Service: filters.NewPublicFilterAPI(txpool, uint32(cfg.DefConfig.Rpc.EthLogsMaxBlockRange))},
+		{Namespace: "net", Service: net.NewPublicNetAPI()},
+		{Namespace: "web3", Service: web3.NewAPI()},
+		{Namespace: "txpool", Service: txpool2.NewPublicTxPoolAPI(txpool)},
+	}
+	if cfg.DefConfig.Rpc.EnableEthDebugApi {
+		apis = append(apis, rpc.API{Namespace: "debug", Service: debug.NewDebugAPI()})
+	}
 	server := rpc.NewServer()
-	err := server.RegisterName("eth", ethAPI)
-	if err != nil {
-		return err
+	for _, api := range apis {
+		err := server.RegisterName(api.Namespace, api.Service)
+		if err != nil {
+			return err
+		}
 	}
-	netRpcService := net.NewPublicNetAPI()
-	err = server.RegisterName("net", netRpcService)
-	if err != nil {
-		return err
+	if cfg.DefConfig.Rpc.EthWsPort != 0 {
+		go startEthWsServer(server)
 	}
-	web3API := web3.NewAPI()
-	err = server.RegisterName("web3", web3API)
-	if err != nil {
-		return err
+	var handler http.Handler = server
+	if cfg.DefConfig.Metrics.EnableMetrics {
+		handler = newMetricsHandler(server, apis)
 	}
-	err = http.ListenAndServe(":"+strconv.Itoa(int(cfg.DefConfig.Rpc.EthJsonPort)), server)
+	return http.ListenAndServe(":"+strconv.Itoa(int(cfg.DefConfig.Rpc.EthJsonPort)), handler)
+}
+
+// startEthWsServer serves the eth rpc over websocket, which supports eth_subscribe
+func startEthWsServer(server *rpc.Server) {
+	err := http.ListenAndServe(":"+strconv.Itoa(int(cfg.DefConfig.Rpc.EthWsPort)), server.WebsocketHandler(cfg.DefConfig.Rpc.EthWsOrigins))
 	if err != nil {
-		return err
+		log.Errorf("[ethrpc] start websocket server error: %s", err)
 	}
-	return nil
 }
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package txpool
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	types2 "github.com/ontio/ontology/http/ethrpc/types"
+	utils2 "github.com/ontio/ontology/http/ethrpc/utils"
+)
+
+type TxPoolService interface {
+	EIPTxPoolStatus() (pending int, queued int)
+	EIPTxPoolContent() (pending, queued map[common.Address][]*types.Transaction)
+}
+
+// PublicTxPoolAPI offers the txpool_ prefixed APIs to inspect the EIP155 transactions in the tx pool,
+// the pending ones are ready to be packed and the queued ones wait for the missing nonce.
+type PublicTxPoolAPI struct {
+	txpool TxPoolService
+}
+
+// NewPublicTxPoolAPI creates a new tx pool service that gives information about the transaction pool.
+func NewPublicTxPoolAPI(txpool TxPoolService) *PublicTxPoolAPI {
+	return &PublicTxPoolAPI{txpool: txpool}
+}
+
+// Status returns the number of pending and queued transactions in the pool.
+func (api *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
+	pending, queued := api.txpool.EIPTxPoolStatus()
+	return map[string]hexutil.Uint{
+		"pending": hexutil.Uint(pending),
+		"queued":  hexutil.Uint(queued),
+	}
+}
+
+// Content returns the transactions contained within the transaction pool.
+func (api *PublicTxPoolAPI) Content() (map[string]map[string]map[string]*types2.Transaction, error) {
+	pending, queued := api.txpool.EIPTxPoolContent()
+	pendingContent, err := formatContent(pending)
+	if err != nil {
+		return nil, err
+	}
+	queuedContent, err := formatContent(queued)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]map[string]map[string]*types2.Transaction{
+		"pending": pendingContent,
+		"queued":  queuedContent,
+	}, nil
+}
+
+func formatContent(content map[common.Address][]*types.Transaction) (map[string]map[string]*types2.Transaction, error) {
+	ret := make(map[string]map[string]*types2.Transaction, len(content))
+	for account, txs := range content {
+		dump := make(map[string]*types2.Transaction, len(txs))
+		for _, tx := range txs {
+			rpcTx, err := utils2.NewTransaction(tx, tx.Hash(), common.Hash{}, 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			dump[fmt.Sprintf("%d", tx.Nonce())] = rpcTx
+		}
+		ret[account.Hex()] = dump
+	}
+	return ret, nil
+}
@@ -106,3 +106,48 @@ func (bn BlockNumber) IsPending() bool {
 func (bn BlockNumber) Int64() int64 {
 	return int64(bn)
 }
+
+// BlockHeight represents a block number argument which keeps the explicit block
+// heights apart from the tags. Unlike BlockNumber, "earliest" and 0x0 are the
+// genesis block, the zero value and "latest" or "pending" are the current block.
+type BlockHeight struct {
+	Height   uint32
+	Explicit bool
+}
+
+// UnmarshalJSON parses the given JSON fragment into a BlockHeight, the block
+// number must fit in uint32.
+func (bh *BlockHeight) UnmarshalJSON(data []byte) error {
+	input := strings.TrimSpace(string(data))
+	if len(input) >= 2 && input[0] == '"' && input[len(input)-1] == '"' {
+		input = input[1 : len(input)-1]
+	}
+
+	switch input {
+	case "earliest":
+		*bh = BlockHeight{Height: 0, Explicit: true}
+		return nil
+	case "latest", "pending":
+		*bh = BlockHeight{}
+		return nil
+	}
+
+	blckNum, err := hexutil.DecodeUint64(input)
+	if err != nil {
+		return err
+	}
+	if blckNum > math.MaxUint32 {
+		return fmt.Errorf("blocknumber too high")
+	}
+
+	*bh = BlockHeight{Height: uint32(blckNum), Explicit: true}
+	return nil
+}
+
+// Resolve returns the block height, the current height if it is not explicit.
+func (bh BlockHeight) Resolve(current uint32) uint32 {
+	if !bh.Explicit {
+		return current
+	}
+	return bh.Height
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	oComm "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	types3 "github.com/ontio/ontology/http/ethrpc/types"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/storage"
+)
+
+// EthAccountKey returns the state key of eth account, which holds nonce and code hash
+func EthAccountKey(addr common.Address) []byte {
+	return append([]byte{byte(scom.ST_ETH_ACCOUNT)}, addr[:]...)
+}
+
+// EthStorageKey returns the state key of a storage slot of eth contract
+func EthStorageKey(addr common.Address, slot common.Hash) []byte {
+	key := append([]byte{byte(scom.ST_STORAGE)}, addr[:]...)
+	return append(key, slot[:]...)
+}
+
+// OngBalanceKey returns the state key of ong balance of account
+func OngBalanceKey(addr common.Address) []byte {
+	key := append([]byte{byte(scom.ST_STORAGE)}, utils.OngContractAddress[:]...)
+	return append(key, addr[:]...)
+}
+
+// DecodeEthAccount decodes the raw state value of eth account, an empty value is an empty account
+func DecodeEthAccount(value []byte) (*storage.EthAccount, error) {
+	account := new(storage.EthAccount)
+	if len(value) == 0 {
+		return account, nil
+	}
+	if err := account.Deserialization(oComm.NewZeroCopySource(value)); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// DecodeOngBalance decodes the raw state value of ong balance
+func DecodeOngBalance(value []byte) (uint64, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	item := new(states.StorageItem)
+	if err := item.Deserialization(oComm.NewZeroCopySource(value)); err != nil {
+		return 0, err
+	}
+	if len(item.Value) < 8 {
+		return 0, fmt.Errorf("invalid ong balance")
+	}
+	return binary.LittleEndian.Uint64(item.Value), nil
+}
+
+// EncodeStateProof encodes state proof to hex string, a nil proof is encoded to empty string
+func EncodeStateProof(proof *states.StateProof) string {
+	if proof == nil {
+		return ""
+	}
+	return hexutil.Encode(oComm.SerializeToBytes(proof))
+}
+
+// DecodeStateProof decodes state proof from hex string
+func DecodeStateProof(data string) (*states.StateProof, error) {
+	raw, err := hexutil.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	proof := new(states.StateProof)
+	if err := proof.Deserialization(oComm.NewZeroCopySource(raw)); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// VerifyAccountProof verifies the result of eth_getProof against the state merkle root of block height, which is
+// signed by consensus as the PrevExecMerkleRoot of the next block. AccountProof holds the proofs of eth account
+// and ong balance, every storage result holds the proof of its slot.
+// Every proof must be present, the absence of a key never written since the state merkle tree started can not be
+// proven, so such a result is rejected.
+func VerifyAccountProof(result *types3.AccountResult, root oComm.Uint256, height, stateHashCheckHeight uint32) error {
+	if len(result.AccountProof) != 2 {
+		return fmt.Errorf("account proof should contain account and balance proofs")
+	}
+	verify := func(data string, key []byte) ([]byte, error) {
+		if data == "" {
+			return nil, fmt.Errorf("missing proof")
+		}
+		proof, err := DecodeStateProof(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode proof error: %s", err)
+		}
+		if !bytes.Equal(proof.Key, key) {
+			return nil, fmt.Errorf("proof key mismatch")
+		}
+		if proof.Height != height {
+			return nil, fmt.Errorf("proof height %d mismatch with %d", proof.Height, height)
+		}
+		if err := proof.Verify(root, stateHashCheckHeight); err != nil {
+			return nil, err
+		}
+		return proof.Value, nil
+	}
+
+	value, err := verify(result.AccountProof[0], EthAccountKey(result.Address))
+	if err != nil {
+		return fmt.Errorf("verify account proof error: %s", err)
+	}
+	account, err := DecodeEthAccount(value)
+	if err != nil {
+		return err
+	}
+	if uint64(result.Nonce) != account.Nonce || result.CodeHash != account.CodeHash {
+		return fmt.Errorf("account mismatch with proof")
+	}
+
+	value, err = verify(result.AccountProof[1], OngBalanceKey(result.Address))
+	if err != nil {
+		return fmt.Errorf("verify balance proof error: %s", err)
+	}
+	balance, err := DecodeOngBalance(value)
+	if err != nil {
+		return err
+	}
+	if result.Balance == nil || result.Balance.ToInt().Cmp(new(big.Int).SetUint64(balance)) != 0 {
+		return fmt.Errorf("balance mismatch with proof")
+	}
+
+	for _, storageResult := range result.StorageProof {
+		if len(storageResult.Proof) != 1 {
+			return fmt.Errorf("storage proof of key %s should contain one proof", storageResult.Key)
+		}
+		value, err := verify(storageResult.Proof[0], EthStorageKey(result.Address, common.HexToHash(storageResult.Key)))
+		if err != nil {
+			return fmt.Errorf("verify storage proof of key %s error: %s", storageResult.Key, err)
+		}
+		if storageResult.Value == nil || storageResult.Value.ToInt().Cmp(new(big.Int).SetBytes(value)) != 0 {
+			return fmt.Errorf("storage value of key %s mismatch with proof", storageResult.Key)
+		}
+	}
+	return nil
+}
@@ -18,6 +18,7 @@
 package utils
 
 import (
+	"fmt"
 	"math/big"
 	"reflect"
 
@@ -28,6 +29,7 @@ import (
 	sysconfig "github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/core/types"
 	types3 "github.com/ontio/ontology/http/ethrpc/types"
+	"github.com/ontio/ontology/smartcontract/event"
 )
 
 func EthBlockFromOntology(block *types.Block, fullTx bool) map[string]interface{} {
@@ -72,11 +74,16 @@ func OntTxToEthTx(tx types.Transaction, blockHash common.Hash, blockNumber, inde
 	return NewTransaction(eip155Tx, common.Hash(tx.Hash()), blockHash, blockNumber, index)
 }
 
-func FormatBlock(block types.Block, gasLimit uint64, gasUsed *big.Int, transactions interface{}) map[string]interface{} {
-	size := len(block.ToArray())
-	header := block.Header
+// EthHeaderFromOntology returns the ethereum style header of block, as notified to newHeads subscribers.
+func EthHeaderFromOntology(block *types.Block) map[string]interface{} {
+	hash := block.Hash()
+	_, gasUsed, _ := EthTransactionsFromOntology(block.Transactions, common.BytesToHash(hash.ToArray()), uint64(block.Header.Height))
+	return FormatHeader(block.Header, 0, gasUsed)
+}
+
+func FormatHeader(header *types.Header, gasLimit uint64, gasUsed *big.Int) map[string]interface{} {
 	hash := header.Hash()
-	ret := map[string]interface{}{
+	return map[string]interface{}{
 		"number":           hexutil.Uint64(header.Height),
 		"hash":             hexutil.Bytes(hash[:]),
 		"parentHash":       hexutil.Bytes(header.PrevBlockHash[:]),
@@ -90,13 +97,18 @@ func FormatBlock(block types.Block, gasLimit uint64, gasUsed *big.Int, transacti
 		"difficulty":       hexutil.Uint64(0),
 		"totalDifficulty":  hexutil.Uint64(0),
 		"extraData":        hexutil.Bytes{},
-		"size":             hexutil.Uint64(size),
 		"gasLimit":         hexutil.Uint64(gasLimit), // TODO Static gas limit
 		"gasUsed":          (*hexutil.Big)(gasUsed),
 		"timestamp":        hexutil.Uint64(header.Timestamp),
-		"uncles":           []string{},
 		"receiptsRoot":     common.Hash{},
 	}
+}
+
+func FormatBlock(block types.Block, gasLimit uint64, gasUsed *big.Int, transactions interface{}) map[string]interface{} {
+	size := len(block.ToArray())
+	ret := FormatHeader(block.Header, gasLimit, gasUsed)
+	ret["size"] = hexutil.Uint64(size)
+	ret["uncles"] = []string{}
 	if !reflect.ValueOf(transactions).IsNil() {
 		switch transactions.(type) {
 		case []common.Hash:
@@ -155,3 +167,43 @@ func NewTransaction(tx *types2.Transaction, txHash, blockHash common.Hash, block
 func GetChainId() uint32 {
 	return sysconfig.DefConfig.P2PNode.EVMChainId
 }
+
+// EthLogsFromNotify converts the evm notifications of an executed transaction to ethereum logs.
+// Notifications of other vm types are skipped, logIndex is the index of the first returned log in block.
+func EthLogsFromNotify(notify *event.ExecuteNotify, height uint32, blockHash common.Hash, logIndex uint) ([]*types2.Log, error) {
+	var logs []*types2.Log
+	for _, n := range notify.Notify {
+		if !n.IsEvm {
+			continue
+		}
+		var raw []byte
+		switch states := n.States.(type) {
+		case string:
+			data, err := hexutil.Decode(states)
+			if err != nil {
+				return nil, err
+			}
+			raw = data
+		case hexutil.Bytes:
+			raw = states
+		default:
+			return nil, fmt.Errorf("invalid evm notify states of tx %s", notify.TxHash.ToHexString())
+		}
+		var storageLog types.StorageLog
+		if err := storageLog.Deserialization(oComm.NewZeroCopySource(raw)); err != nil {
+			return nil, err
+		}
+		logs = append(logs, &types2.Log{
+			Address:     storageLog.Address,
+			Topics:      storageLog.Topics,
+			Data:        storageLog.Data,
+			BlockNumber: uint64(height),
+			TxHash:      OntToEthHash(notify.TxHash),
+			TxIndex:     uint(notify.TxIndex),
+			BlockHash:   blockHash,
+			Index:       logIndex,
+		})
+		logIndex++
+	}
+	return logs, nil
+}
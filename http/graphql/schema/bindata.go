@@ -96,7 +96,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _schemaGraphql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x55\x4d\x6f\xdb\x30\x0c\xbd\xfb\x57\xb0\xc8\xa5\xbb\xf4\xd0\xb5\xc5\xe0\x5b\xb3\x16\x48\xd1\xaf\x6c\xc9\x36\x0c\x45\x31\x30\x32\x6b\x0b\xb1\x25\x4f\x94\xd3\x18\x45\xff\xfb\x20\xcb\x76\xe5\xc4\x1d\xd0\x53\x22\xea\xf1\xe9\x3d\x92\x92\x27\xb0\x42\xa6\xd3\x2f\xa0\x0d\x64\xb4\x05\xb6\x46\xaa\x14\x30\x49\x0c\x31\x47\x2c\x30\x47\x03\xe7\xed\x72\x12\x62\xf4\x13\x64\xc8\xd9\xf1\xe9\x59\x07\x9b\xb9\xff\xbb\x98\xb2\x5a\xe5\x52\xc0\x9a\xea\x0e\x36\xaf\x56\xd7\x54\x47\xdd\xf2\x87\x54\xf6\xf3\x71\x34\x81\x4a\x2a\x7b\x76\x02\xa4\x84\x4e\x28\x01\xe4\x96\x25\x04\x9e\x9d\x44\x11\xa9\xaa\x80\xe5\x76\x59\x97\x04\x2f\x11\x00\xc0\xd5\xdd\xcf\xfb\xeb\xcb\x3f\x77\x97\xf7\xe1\xf2\xd7\xf9\xe2\xb6\x59\x5f\x5c\xce\x6f\xee\x7f\xf7\xdb\xed\xb2\xd9\x7e\x8d\x22\xa9\x2c\x99\x27\x14\x04\x73\xac\x73\x8d\x49\x4b\xea\x54\x40\x0c\x8b\x46\xc3\x81\x43\x5a\x77\xe2\x95\xda\xe8\x35\x7d\x75\x9b\xb2\x28\x73\x2a\x48\x59\x1e\x49\xdd\xcf\xbc\xa0\x32\xd7\xf5\x47\x32\x5d\x64\x53\x38\xa3\xc3\x98\xc2\x62\x17\x45\x86\xa5\x56\xc3\x20\x56\x36\xd3\x66\x18\xa3\x02\x65\x3e\x0c\x25\xc4\x62\xa0\x76\x02\xd6\xa0\x62\x14\x56\x6a\xe5\x9a\x50\x09\x5b\x19\x72\xdd\xd4\xca\xea\x5c\xa7\xb5\x77\xb4\x0c\x60\x2f\x43\x1d\xbe\xab\xfe\x00\x37\x26\x71\x33\x1d\xad\x7c\xad\x04\x0d\x21\x76\xeb\x5d\xfa\xb6\xfa\x58\x8a\x3c\x37\x72\x17\x99\x22\xdf\xc8\x42\xda\x61\xb4\xc4\x9a\x4c\xdc\x0d\x6a\x1f\x73\x95\x8d\xbb\x12\xfb\x28\xcb\x94\x63\x78\x58\xc8\xf4\xe0\xf1\x20\xf2\xfa\x48\xa6\x59\x40\xd8\x35\x6c\x21\xd3\xd6\x16\xcb\xf4\x02\x2d\xba\x3c\x5f\xa6\xc7\xf6\x88\x66\x94\x1d\x9f\x1f\xea\x2e\x7e\x3b\x20\x9b\xc0\x34\xd7\x62\xfd\xbf\x4a\x7a\x80\x3f\x6c\x02\xcb\x8c\x20\x23\x4c\xc8\x38\xa4\xcd\x24\xc3\xca\x01\x8e\x5a\xb9\x6e\x27\x86\x59\xf3\xdb\x7a\xf0\x49\x41\xdf\x38\xc8\x03\xa9\x44\x5e\x25\x94\x78\x82\x10\x15\xc3\x43\xd0\x45\xa7\xbf\x11\xec\xb9\x41\x3a\x96\x50\x0b\x7a\x42\x2f\xba\x05\x85\xaa\xdb\xfe\xf7\xb2\x7d\xe6\xd1\xf8\x6c\x84\x6e\x91\xb3\xd1\xa4\x70\x76\x02\x7c\x69\x68\x23\x75\xd5\xf9\x73\x28\x8f\x77\x1b\xb3\xf1\x1c\x51\x19\x43\xca\x76\x29\x4d\xd3\x8f\x46\x07\x20\xac\xa8\x2c\x88\x2d\x16\x65\x58\xce\x94\x14\x19\xb4\x7d\x3d\x3b\xcc\x28\x43\x41\x66\x9d\xbb\xd6\x10\x81\xd1\xda\xc2\xb3\xb4\x19\xe4\x84\x1b\x62\x78\x32\xba\x68\xe8\xb8\x27\xb7\x7a\xaf\xe3\xcd\xdf\xef\x5a\xdb\x11\x57\x83\x96\x37\xfc\x23\x23\x63\xb7\xfc\x4e\xba\xd0\x8a\x49\x71\xc5\x90\xa0\xc5\xb1\xdc\x1e\xe1\x6f\x80\x7f\x85\x87\x0e\xab\xdc\xca\xee\x9b\xe1\x28\x84\x56\xba\x65\x55\x3a\x21\x86\xe7\x4c\x83\x40\xd5\x17\x0e\x14\x6d\x6d\x78\x88\x5b\x4f\xb5\x5e\xaf\x89\xca\xc1\x45\x1e\x4a\xdd\x67\xed\x19\xf7\x6a\xd6\xb3\x0d\xaf\x67\x40\xc8\x32\x55\xd8\xdd\xc7\x8f\xb1\x8f\x3d\x08\xdd\xb3\x31\xc5\x1c\x95\xe8\xbe\x4d\x5a\xd9\xb7\xa2\xf9\x40\x3a\x0c\xbc\xf7\xfc\x7c\xab\xc8\xd4\x2d\x4b\x4a\xb6\x79\x22\xa6\xf5\xac\x41\x1f\xee\x24\x7d\x8a\xfd\x13\xb2\x0b\x46\xce\x0e\x83\x2b\x34\x0a\xf3\xa0\x3d\xbe\xb7\xf7\x3a\x25\xbb\xdc\xee\xd0\x04\x8f\x46\x4f\xe6\x7d\x1f\xba\x41\x78\xeb\xa0\x3b\xd2\x6f\x34\xce\x58\x64\x54\x60\xeb\xea\xaf\x73\x18\x7b\xa3\xd1\x6b\xf4\x2f\x00\x00\xff\xff\x28\x4d\x93\xb3\x8d\x08\x00\x00")
+var _schemaGraphql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x94\x56\xcd\x6e\xe3\x36\x10\xbe\xeb\x29\xc6\xf0\x25\x01\x52\x1f\xb6\xbb\x41\xa1\xdb\x26\x31\x90\x60\x37\x89\x9b\xb8\x2d\x8a\x20\x28\x68\x69\x2c\xb1\x96\x48\x2f\x87\x72\x24\x04\xfb\xee\x05\x7f\x24\x93\xb2\xda\x62\x4f\x36\xc9\x6f\xbe\xf9\x9f\xd1\x1c\x36\x8c\xf0\xd3\x2f\x20\x15\x94\xd8\x02\x69\xc5\x45\x01\x2c\xcf\x15\x12\x25\x94\xb1\x8a\x29\xf8\xec\x8f\xf3\x10\x23\xb7\x50\x32\x2a\x3f\x7c\xba\xec\x61\xb7\xe6\xff\x18\xb3\x6f\x36\x15\xcf\x60\x87\x5d\x0f\x5b\x35\x9b\x2f\xd8\x25\xfd\xf1\x37\x2e\xf4\xcf\x1f\x92\x39\x34\x5c\xe8\xcb\x8f\x80\x22\x93\x39\xe6\xc0\xc8\xb3\x84\xc0\xcb\x8f\x49\x82\xa2\xa9\x61\xdd\xae\xbb\x3d\xc2\x7b\x02\x00\x70\xf7\xf0\xfb\xe3\x97\xe5\x5f\x0f\xcb\xc7\xf0\xf8\xc7\xe7\xe7\x7b\x7b\xbe\x59\xae\xbe\x3e\xfe\x39\x3c\xfb\xa3\x7d\xfe\x9e\x24\x5c\x68\x54\x5b\x96\x21\xac\x58\x57\x49\x96\x7b\x52\x63\x05\xa4\xf0\x6c\x6d\x98\x19\xa4\x36\x1a\xef\xc4\x41\xee\xf0\xda\x3c\xf2\x7a\x5f\x61\x8d\x42\xd3\x84\xe8\xa9\xe4\x0d\xee\x2b\xd9\xfd\x88\x24\x00\xc0\xa1\x36\x8e\xc6\x77\x82\xd5\x63\x14\x2a\xe2\x52\xc4\x97\xac\xd1\xa5\x54\xf1\x1d\xd6\x8c\x57\xf1\x55\x8e\x94\x45\xd6\xce\x41\x2b\x26\x88\x65\x9a\x4b\x61\x92\xd0\x64\xba\x51\x68\xb2\x29\x85\x96\x95\x2c\x3a\xe7\xd1\x3a\x80\xbd\xc7\x76\xb8\xac\x3a\x05\xa6\x4c\x52\x5b\x1d\xde\x7c\x29\x32\x8c\x21\xba\x75\x5e\xba\xb4\xba\xbb\x82\xd1\x4a\xf1\x31\xb2\x60\xf4\x95\xd7\x5c\xc7\xb7\x7b\xd6\xa1\x4a\xfb\x42\x1d\xee\x4c\x64\xd3\x3e\xc4\xee\x96\x78\x41\x29\xbc\x3c\xf3\x62\xf6\x3a\x4b\x9c\x7d\xc8\x8b\x32\x20\xec\x13\xf6\xcc\x0b\xef\x16\xf1\xe2\x86\x69\x66\xe4\x5c\x98\x5e\xbd\x0a\x5b\xca\x86\xcf\x15\x75\x7f\x7f\x1f\x91\xcd\xe1\xaa\x92\xd9\xee\xbf\x22\xe9\x00\x4e\xd9\x1c\xd6\x25\x42\x89\x2c\x47\x65\x90\xba\xe4\x04\x1b\x03\x58\x78\x73\xcd\x4b\x0a\xb7\xf6\x77\x96\x04\x42\x41\xde\x28\x90\x03\x2e\xb2\xaa\xc9\x31\x77\x04\x21\x2a\x85\x97\x20\x8b\xb3\x57\x6f\xb0\xe3\x06\x6e\x58\x42\x5b\x98\x23\x74\x46\x7b\x50\x68\xb5\xcf\xff\x60\xb6\x93\x5c\x4c\xd7\x46\xe8\x2d\xa3\x72\x52\x28\xac\x9d\x00\xbf\x57\x78\xe0\xb2\xe9\xfd\x33\x28\x87\x37\x0f\xb7\xd3\x32\x59\xa3\x14\x0a\xdd\x8b\xd8\xa4\x2f\x26\x0b\x20\x8c\x28\xaf\x91\x34\xab\xf7\x61\x38\x0b\x14\xa8\x98\x1e\xe2\xd9\x63\x26\x19\x6a\x54\xbb\xca\xa4\x06\x11\x94\x94\x1a\xde\xb8\x2e\xa1\x42\x76\x40\x82\xad\x92\xb5\xa5\xa3\x81\x5c\xcb\x93\x8c\xdb\xbf\x4f\x52\xea\x09\xaf\xa2\x94\x5b\xfe\x89\x92\xd1\x2d\xfd\x8b\x78\x26\x05\xa1\xa0\x86\x20\x67\x9a\x4d\xc9\x0e\x08\xd7\x01\x6e\x0a\xc7\x1e\x36\x95\xe6\xfd\xce\x30\x14\x99\x14\xd2\xb3\x0a\x99\x23\xc1\x5b\x29\x21\x63\x62\x08\x1c\x08\x6c\x75\xa8\xc4\x9c\xaf\xa4\xdc\xed\x10\xf7\x51\x23\xc7\xa6\x9e\xb2\x0e\x8c\x27\x31\x1b\xd8\xe2\xf6\x0c\x08\x89\x17\x82\xf5\xfd\xf8\x63\xec\x53\x03\xa1\x1f\x1b\x57\xac\x62\x22\xeb\x77\x93\x14\xfa\x18\x34\x77\x51\xc4\x17\x13\xe3\x67\x0e\x0f\x52\xf3\x6d\x07\x9c\x80\x09\xc0\x03\x0a\x0d\x58\x73\xad\x31\x87\x4d\x07\xcc\x98\xab\x15\xcb\xb4\x53\xe9\xd1\xfd\x0e\x71\x4f\x3e\x86\xd3\xc1\xfc\x9b\xa4\x18\x56\x2d\x69\xa6\x91\x5c\xf2\xd1\x69\xf3\x6e\xda\x87\xd3\x55\xb6\x52\xb8\x6c\x31\x7b\x42\x6a\x2a\x0d\xef\x47\xec\xc9\xac\x9e\x2c\x98\x48\xb9\x72\x24\xbd\xf2\x16\xb3\xc6\x14\xb3\x33\xc0\x3d\x8e\xb6\x9f\x75\x36\x85\x17\xe7\xf5\x30\xb3\xae\xbd\xdf\x4b\x1b\x2d\x4e\x47\x67\x28\x8c\x5d\xa4\xc5\xcd\xb4\xa0\x87\x9c\x7f\x31\xd5\xbb\xef\xa1\xdb\xd1\x1e\x9b\xf6\xf8\x5a\x0a\x6a\x6a\xcc\xe3\x24\x4f\x1b\x6d\x95\xf9\xf4\xac\x5b\xaf\x68\xbc\x2e\xc7\xf5\xe1\x8c\xb9\x13\x39\xb6\xa7\x3b\xeb\xd7\x06\x55\x5f\x08\x05\x6a\xbb\x57\xae\xba\x5b\x4b\x71\x36\x62\x3a\x4f\xdd\xde\x19\x83\x19\x95\x67\x81\x11\x93\x30\x07\x3a\xe1\x3b\x5a\x5d\xa0\x5e\xb7\x23\x9a\x60\xd3\x0c\x64\xae\x59\xce\xcc\xf4\x38\x56\xaa\x51\xe9\x1e\x86\xba\x79\x42\xdd\x28\xe1\x92\x1a\xaf\x39\xd9\x64\xa5\xf9\xd8\x34\x2f\x7e\x08\x5d\xd8\x43\x65\x8a\x57\xc3\x96\x2b\xd2\x0b\x78\xc2\x6f\x0d\x57\x48\x21\x0e\xb8\x89\xe2\xa2\x37\x66\xc8\x04\x8d\xec\xb9\x00\xda\xf1\x61\xbc\x5f\x40\x15\x7e\x81\x9c\xa7\xf0\x32\x48\x86\x89\xbd\x6f\x34\x0b\xbe\x8d\xe6\xf0\x8c\x22\xef\x37\x6a\x7b\x6c\x00\xf6\x16\x7a\x64\x17\x40\x0b\x7b\x29\xab\x0b\x50\xa1\xd7\x6d\xb0\xe7\x08\x45\xfe\xc4\xde\x82\x88\x9e\xe9\x76\xe8\x93\xf3\xd1\xa4\x5f\x29\xfc\xc9\xd5\x3c\xfe\xbf\x7e\xb3\x9f\x64\xa3\x21\x93\xb5\xe9\x1a\x13\x5a\xae\x87\xed\xba\x74\x34\x23\x6d\xd1\x40\x08\xbe\xa0\x9a\x0d\x65\x8a\xef\xa3\x28\x98\x09\x60\x47\x29\x01\xb1\x03\xe6\xa0\x25\x54\x98\x17\xfd\xca\x17\xf8\x66\x4b\xcc\x97\xdd\x78\x07\xd8\xc6\xf4\x7d\x7d\x01\x52\x54\x5d\xd8\xe8\x7e\x8c\x14\xfc\x80\x62\x40\x13\xf0\x2d\x30\xd1\x2d\xa2\xf9\x68\x9b\xfb\x6c\xc0\x1c\xb3\x38\x7b\x3d\x4f\xe3\x11\x60\x3d\xa2\xac\xc4\x9a\x79\x37\xbe\x99\x3e\x4b\x5d\xbb\xd9\x8b\xda\x27\x3b\x1d\xd2\x6e\xaf\x29\x88\x40\x1a\xc5\x23\xf9\x9e\xfc\x33\x00\xae\x48\x7c\xae\x7e\x0d\x00\x00")
 
 func schemaGraphqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -111,7 +111,7 @@ func schemaGraphql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "schema.graphql", size: 2189, mode: os.FileMode(438), modTime: time.Unix(1598341445, 0)}
+	info := bindataFileInfo{name: "schema.graphql", size: 3454, mode: os.FileMode(438), modTime: time.Unix(1792326392, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
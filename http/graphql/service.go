@@ -19,6 +19,9 @@
 package graphql
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"strconv"
@@ -30,9 +33,11 @@ import (
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/types"
+	ontErrors "github.com/ontio/ontology/errors"
 	"github.com/ontio/ontology/http/base/actor"
 	comm "github.com/ontio/ontology/http/base/common"
 	"github.com/ontio/ontology/http/graphql/schema"
+	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 	"golang.org/x/net/netutil"
 )
@@ -306,6 +311,110 @@ func (self *resolver) GetBalance(args struct{ Addr Addr }) (*balance, error) {
 	}, nil
 }
 
+var errAddressIndexDisabled = errors.New("address index is not enabled, restart node with --enable-address-index")
+
+type addressTx struct {
+	Hash    H256
+	Height  Uint32
+	TxIndex Uint32
+}
+
+func (self *resolver) GetAddressTxs(args struct {
+	Addr  Addr
+	Skip  *Uint32
+	Limit *Uint32
+}) ([]*addressTx, error) {
+	if !config.DefConfig.Common.EnableAddressIndex {
+		return nil, errAddressIndexDisabled
+	}
+	var skip, limit uint32
+	if args.Skip != nil {
+		skip = uint32(*args.Skip)
+	}
+	if args.Limit != nil {
+		limit = uint32(*args.Limit)
+	}
+	txs, err := actor.GetAddressTxs(args.Addr.Address, skip, comm.AddressTxsLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*addressTx, 0, len(txs))
+	for _, tx := range txs {
+		result = append(result, &addressTx{Hash: H256(tx.TxHash), Height: Uint32(tx.Height), TxIndex: Uint32(tx.TxIndex)})
+	}
+	return result, nil
+}
+
+type notify struct {
+	ContractAddress Addr
+	States          string
+}
+
+func newNotifies(evts []*event.NotifyEventInfo) ([]*notify, error) {
+	notifies := make([]*notify, 0, len(evts))
+	for _, evt := range evts {
+		states, err := json.Marshal(evt.States)
+		if err != nil {
+			return nil, err
+		}
+		notifies = append(notifies, &notify{ContractAddress: Addr{evt.ContractAddress}, States: string(states)})
+	}
+	return notifies, nil
+}
+
+type preExecResult struct {
+	State  Uint32
+	Gas    Uint64
+	Result string
+	Notify []*notify
+}
+
+func decodeRawTx(raw string) (*types.Transaction, error) {
+	data, err := common.HexToBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex string: %s", err)
+	}
+	return types.TransactionFromRawBytes(data)
+}
+
+func (self *resolver) SendRawTransaction(args struct{ Tx string }) (H256, error) {
+	txn, err := decodeRawTx(args.Tx)
+	if err != nil {
+		return H256{}, err
+	}
+	if errCode, desc := comm.SendTxToPool(txn); errCode != ontErrors.ErrNoError {
+		return H256{}, fmt.Errorf("send transaction error %d: %s", errCode, desc)
+	}
+
+	return H256(txn.Hash()), nil
+}
+
+func (self *resolver) PreExecute(args struct{ Tx string }) (*preExecResult, error) {
+	txn, err := decodeRawTx(args.Tx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := actor.PreExecuteContract(txn)
+	if err != nil {
+		return nil, err
+	}
+	result, err := json.Marshal(res.Result)
+	if err != nil {
+		return nil, err
+	}
+	notifies, err := newNotifies(res.Notify)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preExecResult{
+		State:  Uint32(res.State),
+		Gas:    Uint64(res.Gas),
+		Result: string(result),
+		Notify: notifies,
+	}, nil
+}
+
 func StartServer(cfg *config.GraphQLConfig) {
 	if !cfg.EnableGraphQL || cfg.GraphQLPort == 0 {
 		return
@@ -317,6 +426,8 @@ func StartServer(cfg *config.GraphQLConfig) {
 	}))
 
 	serverMut.Handle("/query", &relay.Handler{Schema: ontSchema})
+	serverMut.Handle("/subscriptions", newSubscriptionHandler(ontSchema, cfg.WsOrigins))
+	hub.start()
 
 	server := &http.Server{Handler: serverMut}
 	listener, err := net.Listen("tcp", ":"+strconv.Itoa(int(cfg.GraphQLPort)))
@@ -0,0 +1,385 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/http/base/actor"
+	"github.com/ontio/ontology/smartcontract/event"
+)
+
+const subscriberChanSize = 64
+
+type contractEvent struct {
+	TxHash      H256
+	State       Uint32
+	GasConsumed Uint64
+	Notify      []*notify
+}
+
+// eventHub fans out committed blocks and contract events to graphql subscribers.
+// Slow subscribers drop events instead of blocking the event actor.
+type eventHub struct {
+	once      sync.Once
+	lock      sync.RWMutex
+	nextId    uint64
+	blockSubs map[uint64]chan *block
+	eventSubs map[uint64]chan *event.ExecuteNotify
+}
+
+var hub = &eventHub{
+	blockSubs: make(map[uint64]chan *block),
+	eventSubs: make(map[uint64]chan *event.ExecuteNotify),
+}
+
+func (self *eventHub) start() {
+	self.once.Do(func() {
+		actor.SubscribeEvent(message.TOPIC_SAVE_BLOCK_COMPLETE, self.onBlock)
+		actor.SubscribeEvent(message.TOPIC_SMART_CODE_EVENT, self.onSmartCodeEvent)
+	})
+}
+
+func (self *eventHub) onBlock(v interface{}) {
+	blk, ok := v.(types.Block)
+	if !ok {
+		return
+	}
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if len(self.blockSubs) == 0 {
+		return
+	}
+	b := NewBlock(&blk)
+	for _, ch := range self.blockSubs {
+		select {
+		case ch <- b:
+		default:
+		}
+	}
+}
+
+func (self *eventHub) onSmartCodeEvent(v interface{}) {
+	evt, ok := v.(types.SmartCodeEvent)
+	if !ok {
+		return
+	}
+	notify, ok := evt.Result.(*event.ExecuteNotify)
+	if !ok {
+		return
+	}
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	for _, ch := range self.eventSubs {
+		select {
+		case ch <- notify:
+		default:
+		}
+	}
+}
+
+func (self *eventHub) subscribeBlock(ctx context.Context) <-chan *block {
+	ch := make(chan *block, subscriberChanSize)
+	self.lock.Lock()
+	id := self.nextId
+	self.nextId += 1
+	self.blockSubs[id] = ch
+	self.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		self.lock.Lock()
+		delete(self.blockSubs, id)
+		close(ch)
+		self.lock.Unlock()
+	}()
+	return ch
+}
+
+func (self *eventHub) subscribeEvent(ctx context.Context) <-chan *event.ExecuteNotify {
+	ch := make(chan *event.ExecuteNotify, subscriberChanSize)
+	self.lock.Lock()
+	id := self.nextId
+	self.nextId += 1
+	self.eventSubs[id] = ch
+	self.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		self.lock.Lock()
+		delete(self.eventSubs, id)
+		close(ch)
+		self.lock.Unlock()
+	}()
+	return ch
+}
+
+func (self *resolver) NewBlock(ctx context.Context) <-chan *block {
+	return hub.subscribeBlock(ctx)
+}
+
+func (self *resolver) ContractEvent(ctx context.Context, args struct{ Contracts *[]Addr }) <-chan *contractEvent {
+	var contracts map[common.Address]bool
+	if args.Contracts != nil && len(*args.Contracts) != 0 {
+		contracts = make(map[common.Address]bool)
+		for _, addr := range *args.Contracts {
+			contracts[addr.Address] = true
+		}
+	}
+
+	events := hub.subscribeEvent(ctx)
+	out := make(chan *contractEvent, subscriberChanSize)
+	go func() {
+		defer close(out)
+		for notify := range events {
+			evt, err := filterContractEvent(notify, contracts)
+			if err != nil {
+				log.Warnf("graphql: encode contract event %s error: %s", notify.TxHash.ToHexString(), err)
+				continue
+			}
+			if evt == nil {
+				continue
+			}
+			select {
+			case out <- evt:
+			case <-ctx.Done():
+				// drain the hub channel until it is closed
+			}
+		}
+	}()
+	return out
+}
+
+// filterContractEvent keeps the notifies emitted by the given contracts, returns nil if none of
+// them match. A nil contracts set matches every contract.
+func filterContractEvent(notify *event.ExecuteNotify, contracts map[common.Address]bool) (*contractEvent, error) {
+	evts := notify.Notify
+	if contracts != nil {
+		evts = make([]*event.NotifyEventInfo, 0, len(notify.Notify))
+		for _, n := range notify.Notify {
+			if contracts[n.ContractAddress] {
+				evts = append(evts, n)
+			}
+		}
+		if len(evts) == 0 {
+			return nil, nil
+		}
+	}
+	notifies, err := newNotifies(evts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contractEvent{
+		TxHash:      H256(notify.TxHash),
+		State:       Uint32(notify.State),
+		GasConsumed: Uint64(notify.GasConsumed),
+		Notify:      notifies,
+	}, nil
+}
+
+// message types of the graphql-ws protocol used by subscriptions-transport-ws clients
+const (
+	gqlConnectionInit      = "connection_init"
+	gqlConnectionAck       = "connection_ack"
+	gqlConnectionError     = "connection_error"
+	gqlConnectionTerminate = "connection_terminate"
+	gqlStart               = "start"
+	gqlStop                = "stop"
+	gqlData                = "data"
+	gqlError               = "error"
+	gqlComplete            = "complete"
+)
+
+type wsMessage struct {
+	Id      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsStartPayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type subscriptionHandler struct {
+	schema   *graphql.Schema
+	upgrader *websocket.Upgrader
+}
+
+// newSubscriptionHandler serves the websocket to the browser pages of the origins,
+// only localhost if no origin given, "*" allows any origin. The operations on the
+// websocket include queries and mutations, such as sendRawTransaction.
+func newSubscriptionHandler(schema *graphql.Schema, origins []string) *subscriptionHandler {
+	return &subscriptionHandler{
+		schema: schema,
+		upgrader: &websocket.Upgrader{
+			Subprotocols: []string{"graphql-ws"},
+			CheckOrigin:  originChecker(origins),
+		},
+	}
+}
+
+func originChecker(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// not from browser
+			return true
+		}
+		if len(origins) == 0 {
+			u, err := url.Parse(origin)
+			if err != nil {
+				return false
+			}
+			host := u.Hostname()
+			if ip := net.ParseIP(host); ip != nil {
+				return ip.IsLoopback()
+			}
+			return host == "localhost"
+		}
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		log.Warnf("graphql: rejected websocket from origin %s", origin)
+		return false
+	}
+}
+
+func (self *subscriptionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := self.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debugf("graphql: websocket upgrade error: %s", err)
+		return
+	}
+	sess := &wsSession{
+		conn:   conn,
+		schema: self.schema,
+		ops:    make(map[string]context.CancelFunc),
+	}
+	sess.serve()
+}
+
+type wsSession struct {
+	conn      *websocket.Conn
+	schema    *graphql.Schema
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	ops       map[string]context.CancelFunc
+}
+
+func (self *wsSession) write(id, typ string, payload interface{}) error {
+	msg := wsMessage{Id: id, Type: typ}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = data
+	}
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+	return self.conn.WriteJSON(msg)
+}
+
+func (self *wsSession) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		self.conn.Close()
+	}()
+
+	for {
+		var msg wsMessage
+		if err := self.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case gqlConnectionInit:
+			self.write("", gqlConnectionAck, nil)
+		case gqlConnectionTerminate:
+			return
+		case gqlStart:
+			var payload wsStartPayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				self.write(msg.Id, gqlError, map[string]string{"message": err.Error()})
+				continue
+			}
+			self.start(ctx, msg.Id, &payload)
+		case gqlStop:
+			self.stop(msg.Id)
+		default:
+			self.write(msg.Id, gqlConnectionError, map[string]string{"message": "unknown message type: " + msg.Type})
+		}
+	}
+}
+
+func (self *wsSession) start(parent context.Context, id string, payload *wsStartPayload) {
+	ctx, cancel := context.WithCancel(parent)
+	self.lock.Lock()
+	if _, ok := self.ops[id]; ok {
+		self.lock.Unlock()
+		cancel()
+		self.write(id, gqlError, map[string]string{"message": "duplicated operation id: " + id})
+		return
+	}
+	self.ops[id] = cancel
+	self.lock.Unlock()
+
+	responses, err := self.schema.Subscribe(ctx, payload.Query, payload.OperationName, payload.Variables)
+	if err != nil {
+		self.stop(id)
+		self.write(id, gqlError, map[string]string{"message": err.Error()})
+		return
+	}
+	go func() {
+		for resp := range responses {
+			if err := self.write(id, gqlData, resp); err != nil {
+				break
+			}
+		}
+		self.stop(id)
+		self.write(id, gqlComplete, nil)
+	}()
+}
+
+func (self *wsSession) stop(id string) {
+	self.lock.Lock()
+	cancel, ok := self.ops[id]
+	delete(self.ops, id)
+	self.lock.Unlock()
+	if ok {
+		cancel()
+	}
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+	cutils "github.com/ontio/ontology/core/utils"
+	"github.com/ontio/ontology/http/base/actor"
+	"github.com/ontio/ontology/smartcontract/event"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	tcomn "github.com/ontio/ontology/txnpool/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitSubs waits for the resolvers to register count subscriptions to the hub
+func waitSubs(t *testing.T, subs func() int, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		hub.lock.RLock()
+		n := subs()
+		hub.lock.RUnlock()
+		if n == count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d subscriptions registered to the hub, expected %d", n, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestContractEventSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	contract := common.AddressFromVmCode([]byte{1})
+	other := common.AddressFromVmCode([]byte{2})
+	query := `subscription($addr: Address!) { contractEvent(contracts: [$addr]) { txHash notify { contractAddress states } } }`
+	responses, err := ontSchema.Subscribe(ctx, query, "", map[string]interface{}{"addr": contract.ToBase58()})
+	assert.Nil(t, err)
+
+	waitSubs(t, func() int { return len(hub.eventSubs) }, 1)
+
+	hub.onSmartCodeEvent(types.SmartCodeEvent{Result: &event.ExecuteNotify{
+		TxHash: common.Uint256{1},
+		Notify: []*event.NotifyEventInfo{{ContractAddress: other, States: "skip"}},
+	}})
+	hub.onSmartCodeEvent(types.SmartCodeEvent{Result: &event.ExecuteNotify{
+		TxHash: common.Uint256{2},
+		Notify: []*event.NotifyEventInfo{
+			{ContractAddress: other, States: "skip"},
+			{ContractAddress: contract, States: "hit"},
+		},
+	}})
+
+	var resp *graphql.Response
+	select {
+	case r := <-responses:
+		resp = r.(*graphql.Response)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no contract event received")
+	}
+	assert.Empty(t, resp.Errors)
+	assert.Contains(t, string(resp.Data), common.Uint256{2}.ToHexString())
+	assert.Contains(t, string(resp.Data), contract.ToBase58())
+	assert.NotContains(t, string(resp.Data), "skip")
+}
+
+func TestSubscriptionOrigin(t *testing.T) {
+	check := func(origins []string, origin string) bool {
+		r := httptest.NewRequest("GET", "/subscriptions", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return originChecker(origins)(r)
+	}
+
+	assert.True(t, check(nil, ""))
+	assert.True(t, check(nil, "http://localhost:8080"))
+	assert.True(t, check(nil, "http://127.0.0.1"))
+	assert.False(t, check(nil, "https://evil.example"))
+	assert.True(t, check([]string{"https://app.example"}, "https://APP.example"))
+	assert.False(t, check([]string{"https://app.example"}, "http://localhost"))
+	assert.True(t, check([]string{"*"}, "https://evil.example"))
+}
+
+// testTxPool accepts the transactions sent asynchronously
+type testTxPool struct {
+	tcomn.TxPoolService
+	lock sync.Mutex
+	txs  []*types.Transaction
+}
+
+func (self *testTxPool) AppendTransactionAsync(sender tcomn.SenderType, txn *types.Transaction) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.txs = append(self.txs, txn)
+}
+
+func TestWebsocketSubscription(t *testing.T) {
+	pool := &testTxPool{}
+	actor.SetTxPoolService(pool)
+	actor.DisableSyncVerifyTx = true
+	defer func() {
+		actor.SetTxPoolService(nil)
+		actor.DisableSyncVerifyTx = false
+	}()
+
+	server := httptest.NewServer(newSubscriptionHandler(ontSchema, nil))
+	defer server.Close()
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+	assert.Equal(t, "graphql-ws", conn.Subprotocol())
+
+	send := func(id, typ string, payload interface{}) {
+		msg := wsMessage{Id: id, Type: typ}
+		if payload != nil {
+			msg.Payload, err = json.Marshal(payload)
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, conn.WriteJSON(msg))
+	}
+	recv := func() wsMessage {
+		var msg wsMessage
+		assert.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read websocket: %s", err)
+		}
+		return msg
+	}
+
+	send("", gqlConnectionInit, nil)
+	assert.Equal(t, gqlConnectionAck, recv().Type)
+
+	// subscription of new blocks
+	send("1", gqlStart, wsStartPayload{Query: `subscription { newBlock { header { height } } }`})
+	waitSubs(t, func() int { return len(hub.blockSubs) }, 1)
+	hub.onBlock(types.Block{Header: &types.Header{Height: 7}})
+	msg := recv()
+	assert.Equal(t, "1", msg.Id)
+	assert.Equal(t, gqlData, msg.Type)
+	assert.JSONEq(t, `{"data":{"newBlock":{"header":{"height":7}}}}`, string(msg.Payload))
+
+	// the id of a running operation is not reused
+	send("1", gqlStart, wsStartPayload{Query: `subscription { newBlock { header { height } } }`})
+	msg = recv()
+	assert.Equal(t, "1", msg.Id)
+	assert.Equal(t, gqlError, msg.Type)
+	assert.Contains(t, string(msg.Payload), "duplicated operation id")
+
+	send("1", gqlStop, nil)
+	msg = recv()
+	assert.Equal(t, "1", msg.Id)
+	assert.Equal(t, gqlComplete, msg.Type)
+	waitSubs(t, func() int { return len(hub.blockSubs) }, 0)
+
+	// mutations complete after their single result
+	mutable := cutils.BuildNativeTransaction(nutils.OntContractAddress, "name", []byte{})
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	send("2", gqlStart, wsStartPayload{
+		Query:     `mutation($tx: String!) { sendRawTransaction(tx: $tx) }`,
+		Variables: map[string]interface{}{"tx": common.ToHexString(tx.ToArray())},
+	})
+	msg = recv()
+	assert.Equal(t, "2", msg.Id)
+	assert.Equal(t, gqlData, msg.Type)
+	assert.Contains(t, string(msg.Payload), tx.Hash().ToHexString())
+	assert.Equal(t, gqlComplete, recv().Type)
+	pool.lock.Lock()
+	assert.Equal(t, 1, len(pool.txs))
+	pool.lock.Unlock()
+
+	send("3", gqlStart, wsStartPayload{Query: `mutation { preExecute(tx: "zz") { state } }`})
+	msg = recv()
+	assert.Equal(t, "3", msg.Id)
+	assert.Equal(t, gqlData, msg.Type)
+	assert.Contains(t, string(msg.Payload), "invalid hex string")
+	assert.Equal(t, gqlComplete, recv().Type)
+
+	// the operation id can be used again once completed
+	send("2", gqlStart, wsStartPayload{Query: `subscription { newBlock { header { height } } }`})
+	waitSubs(t, func() int { return len(hub.blockSubs) }, 1)
+
+	// invalid queries complete after the errors
+	send("4", gqlStart, wsStartPayload{Query: `subscription { unknown }`})
+	msg = recv()
+	assert.Equal(t, "4", msg.Id)
+	assert.Equal(t, gqlData, msg.Type)
+	assert.Contains(t, string(msg.Payload), "unknown")
+	assert.Equal(t, gqlComplete, recv().Type)
+
+	send("", "unknown", nil)
+	assert.Equal(t, gqlConnectionError, recv().Type)
+
+	// the subscriptions of the connection are cancelled on terminate
+	send("", gqlConnectionTerminate, nil)
+	waitSubs(t, func() int { return len(hub.blockSubs) }, 0)
+}
@@ -20,6 +20,7 @@ package jsonrpc
 
 import (
 	"encoding/hex"
+	"math"
 
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
@@ -394,6 +395,47 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 	return rpc.ResponsePack(berr.INVALID_PARAMS, "")
 }
 
+//get the events of a contract, params: contract, [event name], [start height], [end height], [skip], [limit]
+func GetSmartCodeEventByContract(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog || !config.DefConfig.Common.EnableContractEventIndex {
+		return rpc.ResponsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 1 {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	contract, err := common.AddressFromHexString(str)
+	if err != nil {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	var name string
+	if len(params) > 1 {
+		name, ok = params[1].(string)
+		if !ok {
+			return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+		}
+	}
+	// start height, end height, skip, limit
+	page := [4]uint32{0, math.MaxUint32, 0, 0}
+	for i := 2; i < len(params) && i-2 < len(page); i++ {
+		n, ok := params[i].(float64)
+		if !ok || n < 0 || n > math.MaxUint32 {
+			return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+		}
+		page[i-2] = uint32(n)
+	}
+	events, err := bcomn.GetContractEvents(contract, name, page[0], page[1], page[2], page[3])
+	if err == scom.ErrContractEventNotIndexed {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	} else if err != nil {
+		return rpc.ResponsePack(berr.INTERNAL_ERROR, "")
+	}
+	return rpc.ResponseSuccess(events)
+}
+
 //get block height by transaction hash
 func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -464,6 +506,37 @@ func GetOep4Balance(params []interface{}) map[string]interface{} {
 	return rpc.ResponseSuccess(rsp)
 }
 
+//get the transactions touching an address, params: address, [skip], [limit]
+func GetAddressTxs(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableAddressIndex {
+		return rpc.ResponsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 1 {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	addr, err := common.AddressFromBase58(str)
+	if err != nil {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	var page [2]uint32
+	for i := 1; i < len(params) && i <= len(page); i++ {
+		n, ok := params[i].(float64)
+		if !ok || n < 0 || n > math.MaxUint32 {
+			return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+		}
+		page[i-1] = uint32(n)
+	}
+	txs, err := bcomn.GetAddressTxs(addr, page[0], page[1])
+	if err != nil {
+		return rpc.ResponsePack(berr.INTERNAL_ERROR, "")
+	}
+	return rpc.ResponseSuccess(txs)
+}
+
 func parseAddressParam(params []interface{}) ([]common.Address, error) {
 	res := make([]common.Address, len(params))
 	var err error
@@ -51,7 +51,9 @@ func StartRPCServer() error {
 	rpc.HandleFunc("getmempooltxstate", GetMemPoolTxState)
 	rpc.HandleFunc("getmempooltxhashlist", GetMemPoolTxHashList)
 	rpc.HandleFunc("getsmartcodeevent", GetSmartCodeEvent)
+	rpc.HandleFunc("getsmartcodeeventbycontract", GetSmartCodeEventByContract)
 	rpc.HandleFunc("getblockheightbytxhash", GetBlockHeightByTxHash)
+	rpc.HandleFunc("getaddresstxs", GetAddressTxs)
 
 	rpc.HandleFunc("getbalance", GetBalance)
 	rpc.HandleFunc("getoep4balance", GetOep4Balance)
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/consensus/vbft"
 	bactor "github.com/ontio/ontology/http/base/actor"
 	"github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
@@ -84,3 +85,53 @@ func SetDebugInfo(params []interface{}) map[string]interface{} {
 	}
 	return rpc.ResponsePack(berr.SUCCESS, true)
 }
+
+// SetModuleLogLevel sets the log level of a module, a negative level makes the module log with the global level again
+func SetModuleLogLevel(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	module, ok := params[0].(string)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	level, ok := params[1].(float64)
+	if !ok {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+	}
+	var err error
+	if level < 0 {
+		err = log.ResetModuleLevel(module)
+	} else {
+		err = log.SetModuleLevel(module, int(level))
+	}
+	if err != nil {
+		return rpc.ResponsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return rpc.ResponsePack(berr.SUCCESS, true)
+}
+
+// GetLogLevel returns the global log level and the log levels of modules set separately
+func GetLogLevel(params []interface{}) map[string]interface{} {
+	return rpc.ResponseSuccess(map[string]interface{}{
+		"global":  log.Log().GetDebugLevel(),
+		"modules": log.ModuleLevels(),
+	})
+}
+
+// GetVbftEvidence returns the equivocation evidences of vbft peers, from the optional block height
+func GetVbftEvidence(params []interface{}) map[string]interface{} {
+	var from uint32
+	if len(params) > 0 {
+		height, ok := params[0].(float64)
+		if !ok || height < 0 {
+			return rpc.ResponsePack(berr.INVALID_PARAMS, "")
+		}
+		from = uint32(height)
+	}
+	evidences, err := vbft.ReadEvidences(vbft.EvidencePath(), from)
+	if err != nil {
+		return rpc.ResponsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	return rpc.ResponseSuccess(evidences)
+}
@@ -43,6 +43,9 @@ func StartLocalServer() error {
 	rpc.HandleFunc("startconsensus", StartConsensus)
 	rpc.HandleFunc("stopconsensus", StopConsensus)
 	rpc.HandleFunc("setdebuginfo", SetDebugInfo)
+	rpc.HandleFunc("setmoduleloglevel", SetModuleLogLevel)
+	rpc.HandleFunc("getloglevel", GetLogLevel)
+	rpc.HandleFunc("getvbftevidence", GetVbftEvidence)
 
 	// TODO: only listen to local host
 	err := http.ListenAndServe(LOCAL_HOST+":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpLocalPort)), nil)
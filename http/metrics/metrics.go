@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package metrics provides the standalone prometheus metrics server and the metrics of rpc servers
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// rpc apis used as the api label of rpc metrics
+const (
+	API_JSONRPC   = "jsonrpc"
+	API_RESTFUL   = "restful"
+	API_WEBSOCKET = "websocket"
+	API_ETHRPC    = "ethrpc"
+	API_ADMIN     = "admin"
+)
+
+// METHOD_UNKNOWN is the method label of requests to unknown methods, so that the label values are bounded
+const METHOD_UNKNOWN = "unknown"
+
+var (
+	rpcDurationMetric = prom.NewHistogramVec(prom.HistogramOpts{
+		Name: "ontology_rpc_request_duration_seconds",
+		Help: "ontology rpc request latency",
+	}, []string{"api", "method"})
+
+	rpcErrorsMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_rpc_errors_total",
+		Help: "ontology rpc requests responded with error",
+	}, []string{"api", "method"})
+)
+
+func init() {
+	prom.MustRegister(rpcDurationMetric, rpcErrorsMetric)
+}
+
+// ObserveRpc records the latency of a rpc request started at start, and counts it if failed
+func ObserveRpc(api, method string, start time.Time, failed bool) {
+	rpcDurationMetric.WithLabelValues(api, method).Observe(time.Since(start).Seconds())
+	if failed {
+		rpcErrorsMetric.WithLabelValues(api, method).Inc()
+	}
+}
+
+// StartServer serves the metrics registered to the default prometheus registry on the metrics port
+func StartServer(cfg *config.MetricsConfig) {
+	if !cfg.EnableMetrics || cfg.MetricsPort == 0 {
+		return
+	}
+	serverMux := http.NewServeMux()
+	serverMux.Handle("/metrics", promhttp.Handler())
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(int(cfg.MetricsPort)))
+	if err != nil {
+		log.Errorf("start metrics server error: %s", err)
+		return
+	}
+	log.Infof("start metrics service on %d", cfg.MetricsPort)
+	log.Error((&http.Server{Handler: serverMux}).Serve(listener))
+}
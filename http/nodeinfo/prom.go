@@ -19,6 +19,7 @@
 package nodeinfo
 
 import (
+	"sync"
 	"time"
 
 	"github.com/ontio/ontology/common/config"
@@ -64,8 +65,22 @@ var (
 var (
 	metrics = []prom.Collector{nodePortMetric, blockHeightMetric, inboundsCountMetric,
 		outboundsCountMetric, peerStatusMetric, reconnectCountMetric}
+
+	metricOnce sync.Once
+	metricErr  error
 )
 
+// StartMetric registers the p2p metrics and starts updating them, it is shared by the http info server and the
+// standalone metrics server
+func StartMetric(n p2p.P2P) error {
+	metricOnce.Do(func() {
+		if metricErr = initMetric(); metricErr == nil {
+			go updateMetric(n)
+		}
+	})
+	return metricErr
+}
+
 func initMetric() error {
 	for _, curMetric := range metrics {
 		if err := prom.Register(curMetric); err != nil {
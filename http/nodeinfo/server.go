@@ -117,12 +117,11 @@ func StartServer(n p2p.P2P) {
 
 	http.HandleFunc("/info", viewHandler)
 	// prom related
-	if err := initMetric(); err != nil {
+	if err := StartMetric(n); err != nil {
 		panic("init prometheus metrics fail")
 	}
 
 	http.Handle("/metrics", promhttp.Handler())
-	go updateMetric(n)
 
 	http.ListenAndServe(":"+strconv.Itoa(port), nil)
 }
@@ -36,6 +36,7 @@ import (
 	"github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
 	"github.com/ontio/ontology/http/base/rest"
+	"github.com/ontio/ontology/http/metrics"
 	"golang.org/x/net/netutil"
 )
 
@@ -65,9 +66,11 @@ const (
 	GET_TX                = "/api/v1/transaction/:hash"
 	GET_STORAGE           = "/api/v1/storage/:hash/:key"
 	GET_BALANCE           = "/api/v1/balance/:addr"
+	GET_ADDRESS_TXS       = "/api/v1/address/transactions/:addr"
 	GET_CONTRACT_STATE    = "/api/v1/contract/:hash"
 	GET_SMTCOCE_EVT_TXS   = "/api/v1/smartcode/event/transactions/:height"
 	GET_SMTCOCE_EVTS      = "/api/v1/smartcode/event/txhash/:hash"
+	GET_SMTCOCE_EVTS_CTRT = "/api/v1/smartcode/event/contract/:contract"
 	GET_BLK_HGT_BY_TXHASH = "/api/v1/block/height/txhash/:hash"
 	GET_MERKLE_PROOF      = "/api/v1/merkleproof/:hash"
 	GET_GAS_PRICE         = "/api/v1/gasprice"
@@ -161,9 +164,11 @@ func (this *restServer) registryMethod() {
 		GET_CONTRACT_STATE:    {name: "getcontract", handler: rest.GetContractState},
 		GET_SMTCOCE_EVT_TXS:   {name: "getsmartcodeeventbyheight", handler: rest.GetSmartCodeEventTxsByHeight},
 		GET_SMTCOCE_EVTS:      {name: "getsmartcodeeventbyhash", handler: rest.GetSmartCodeEventByTxHash},
+		GET_SMTCOCE_EVTS_CTRT: {name: "getsmartcodeeventbycontract", handler: rest.GetSmartCodeEventByContract},
 		GET_BLK_HGT_BY_TXHASH: {name: "getblockheightbytxhash", handler: rest.GetBlockHeightByTxHash},
 		GET_STORAGE:           {name: "getstorage", handler: rest.GetStorage},
 		GET_BALANCE:           {name: "getbalance", handler: rest.GetBalance},
+		GET_ADDRESS_TXS:       {name: "getaddresstxs", handler: rest.GetAddressTxs},
 		GET_ALLOWANCE:         {name: "getallowance", handler: rest.GetAllowance},
 		GET_MERKLE_PROOF:      {name: "getmerkleproof", handler: rest.GetMerkleProof},
 		GET_GAS_PRICE:         {name: "getgasprice", handler: rest.GetGasPrice},
@@ -201,12 +206,16 @@ func (this *restServer) getPath(url string) string {
 		return GET_SMTCOCE_EVT_TXS
 	} else if strings.Contains(url, strings.TrimRight(GET_SMTCOCE_EVTS, ":hash")) {
 		return GET_SMTCOCE_EVTS
+	} else if strings.Contains(url, strings.TrimRight(GET_SMTCOCE_EVTS_CTRT, ":contract")) {
+		return GET_SMTCOCE_EVTS_CTRT
 	} else if strings.Contains(url, strings.TrimRight(GET_BLK_HGT_BY_TXHASH, ":hash")) {
 		return GET_BLK_HGT_BY_TXHASH
 	} else if strings.Contains(url, strings.TrimRight(GET_STORAGE, ":hash/:key")) {
 		return GET_STORAGE
 	} else if strings.Contains(url, strings.TrimRight(GET_BALANCE, ":addr")) {
 		return GET_BALANCE
+	} else if strings.Contains(url, strings.TrimRight(GET_ADDRESS_TXS, ":addr")) {
+		return GET_ADDRESS_TXS
 	} else if strings.Contains(url, strings.TrimRight(GET_MERKLE_PROOF, ":hash")) {
 		return GET_MERKLE_PROOF
 	} else if strings.Contains(url, strings.TrimRight(GET_ALLOWANCE, ":asset/:from/:to")) {
@@ -246,10 +255,17 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 		req["Height"] = getParam(r, "height")
 	case GET_SMTCOCE_EVTS:
 		req["Hash"] = getParam(r, "hash")
+	case GET_SMTCOCE_EVTS_CTRT:
+		req["Contract"], req["Event"] = getParam(r, "contract"), r.FormValue("event")
+		req["StartHeight"], req["EndHeight"] = r.FormValue("start"), r.FormValue("end")
+		req["Skip"], req["Limit"] = r.FormValue("skip"), r.FormValue("limit")
 	case GET_BLK_HGT_BY_TXHASH:
 		req["Hash"] = getParam(r, "hash")
 	case GET_BALANCE:
 		req["Addr"] = getParam(r, "addr")
+	case GET_ADDRESS_TXS:
+		req["Addr"] = getParam(r, "addr")
+		req["Skip"], req["Limit"] = r.FormValue("skip"), r.FormValue("limit")
 	case GET_MERKLE_PROOF:
 		req["Hash"] = getParam(r, "hash")
 	case GET_ALLOWANCE:
@@ -275,15 +291,20 @@ func (this *restServer) initGetHandler() {
 			var req = make(map[string]interface{})
 			var resp map[string]interface{}
 
+			start := time.Now()
+			method := metrics.METHOD_UNKNOWN
 			url := this.getPath(r.URL.Path)
 			if h, ok := this.getMap[url]; ok {
 				req = this.getParams(r, url, req)
 				resp = h.handler(req)
 				resp["Action"] = h.name
+				method = h.name
 			} else {
 				resp = rest.ResponsePack(berr.INVALID_METHOD)
 			}
 			this.response(w, resp)
+			errCode, _ := resp["Error"].(int64)
+			metrics.ObserveRpc(metrics.API_RESTFUL, method, start, errCode != berr.SUCCESS)
 		})
 	}
 }
@@ -297,8 +318,11 @@ func (this *restServer) initPostHandler() {
 			var req = make(map[string]interface{})
 			var resp map[string]interface{}
 
+			start := time.Now()
+			method := metrics.METHOD_UNKNOWN
 			url := this.getPath(r.URL.Path)
 			if h, ok := this.postMap[url]; ok {
+				method = h.name
 				if err := decoder.Decode(&req); err == nil {
 					req = this.getParams(r, url, req)
 					resp = h.handler(req)
@@ -311,6 +335,8 @@ func (this *restServer) initPostHandler() {
 				resp = rest.ResponsePack(berr.INVALID_METHOD)
 			}
 			this.response(w, resp)
+			errCode, _ := resp["Error"].(int64)
+			metrics.ObserveRpc(metrics.API_RESTFUL, method, start, errCode != berr.SUCCESS)
 		})
 	}
 	//Options
@@ -35,6 +35,7 @@ import (
 	"github.com/ontio/ontology/common/log"
 	Err "github.com/ontio/ontology/http/base/error"
 	"github.com/ontio/ontology/http/base/rest"
+	"github.com/ontio/ontology/http/metrics"
 	"github.com/ontio/ontology/http/websocket/session"
 )
 
@@ -178,32 +179,33 @@ func (self *WsServer) registryMethod() {
 		return resp
 	}
 	actionMap := map[string]Handler{
-		"getblockheightbytxhash":    {handler: rest.GetBlockHeightByTxHash},
-		"getsmartcodeeventbyhash":   {handler: rest.GetSmartCodeEventByTxHash},
-		"getsmartcodeeventbyheight": {handler: rest.GetSmartCodeEventTxsByHeight},
-		"getcontract":               {handler: rest.GetContractState},
-		"getbalance":                {handler: rest.GetBalance},
-		"getconnectioncount":        {handler: rest.GetConnectionCount},
-		"getblockbyheight":          {handler: rest.GetBlockByHeight},
-		"getblockhash":              {handler: rest.GetBlockHash},
-		"getblockbyhash":            {handler: rest.GetBlockByHash},
-		"getblockheight":            {handler: rest.GetBlockHeight},
-		"gettransaction":            {handler: rest.GetTransactionByHash},
-		"sendrawtransaction":        {handler: rest.SendRawTransaction, pushFlag: true},
-		"heartbeat":                 {handler: heartbeat},
-		"subscribe":                 {handler: subscribe},
-		"getstorage":                {handler: rest.GetStorage},
-		"getallowance":              {handler: rest.GetAllowance},
-		"getmerkleproof":            {handler: rest.GetMerkleProof},
-		"getblocktxsbyheight":       {handler: rest.GetBlockTxsByHeight},
-		"getgasprice":               {handler: rest.GetGasPrice},
-		"getunboundong":             {handler: rest.GetUnboundOng},
-		"getgrantong":               {handler: rest.GetGrantOng},
-		"getmempooltxcount":         {handler: rest.GetMemPoolTxCount},
-		"getmempooltxstate":         {handler: rest.GetMemPoolTxState},
-		"getmempooltxhashlist":      {handler: rest.GetMemPoolTxHashList},
-		"getversion":                {handler: rest.GetNodeVersion},
-		"getnetworkid":              {handler: rest.GetNetworkId},
+		"getblockheightbytxhash":      {handler: rest.GetBlockHeightByTxHash},
+		"getsmartcodeeventbyhash":     {handler: rest.GetSmartCodeEventByTxHash},
+		"getsmartcodeeventbyheight":   {handler: rest.GetSmartCodeEventTxsByHeight},
+		"getsmartcodeeventbycontract": {handler: rest.GetSmartCodeEventByContract},
+		"getcontract":                 {handler: rest.GetContractState},
+		"getbalance":                  {handler: rest.GetBalance},
+		"getconnectioncount":          {handler: rest.GetConnectionCount},
+		"getblockbyheight":            {handler: rest.GetBlockByHeight},
+		"getblockhash":                {handler: rest.GetBlockHash},
+		"getblockbyhash":              {handler: rest.GetBlockByHash},
+		"getblockheight":              {handler: rest.GetBlockHeight},
+		"gettransaction":              {handler: rest.GetTransactionByHash},
+		"sendrawtransaction":          {handler: rest.SendRawTransaction, pushFlag: true},
+		"heartbeat":                   {handler: heartbeat},
+		"subscribe":                   {handler: subscribe},
+		"getstorage":                  {handler: rest.GetStorage},
+		"getallowance":                {handler: rest.GetAllowance},
+		"getmerkleproof":              {handler: rest.GetMerkleProof},
+		"getblocktxsbyheight":         {handler: rest.GetBlockTxsByHeight},
+		"getgasprice":                 {handler: rest.GetGasPrice},
+		"getunboundong":               {handler: rest.GetUnboundOng},
+		"getgrantong":                 {handler: rest.GetGrantOng},
+		"getmempooltxcount":           {handler: rest.GetMemPoolTxCount},
+		"getmempooltxstate":           {handler: rest.GetMemPoolTxState},
+		"getmempooltxhashlist":        {handler: rest.GetMemPoolTxHashList},
+		"getversion":                  {handler: rest.GetNodeVersion},
+		"getnetworkid":                {handler: rest.GetNetworkId},
 
 		"getsessioncount": {handler: getsessioncount},
 	}
@@ -321,8 +323,10 @@ func (self *WsServer) OnDataHandle(curSession *session.Session, bysMsg []byte, r
 	if !ok {
 		resp := rest.ResponsePack(Err.INVALID_METHOD)
 		curSession.Send(marshalResp(resp))
+		metrics.ObserveRpc(metrics.API_WEBSOCKET, metrics.METHOD_UNKNOWN, time.Now(), true)
 		return false
 	}
+	start := time.Now()
 	if !self.IsValidMsg(req) {
 		resp := rest.ResponsePack(Err.INVALID_PARAMS)
 		curSession.Send(marshalResp(resp))
@@ -355,6 +359,8 @@ func (self *WsServer) OnDataHandle(curSession *session.Session, bysMsg []byte, r
 		}
 	}
 	curSession.Send(marshalResp(resp))
+	errCode, _ := resp["Error"].(int64)
+	metrics.ObserveRpc(metrics.API_WEBSOCKET, actionName, start, errCode != Err.SUCCESS)
 
 	return true
 }
@@ -40,15 +40,18 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/tracing"
 	"github.com/ontio/ontology/consensus"
 	"github.com/ontio/ontology/core/genesis"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/events"
+	"github.com/ontio/ontology/http/admin"
 	bactor "github.com/ontio/ontology/http/base/actor"
 	"github.com/ontio/ontology/http/ethrpc"
 	"github.com/ontio/ontology/http/graphql"
 	"github.com/ontio/ontology/http/jsonrpc"
 	"github.com/ontio/ontology/http/localrpc"
+	"github.com/ontio/ontology/http/metrics"
 	"github.com/ontio/ontology/http/nodeinfo"
 	"github.com/ontio/ontology/http/restful"
 	"github.com/ontio/ontology/http/websocket"
@@ -56,6 +59,7 @@ import (
 	netreqactor "github.com/ontio/ontology/p2pserver/actor/req"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
 	"github.com/ontio/ontology/txnpool"
+	tc "github.com/ontio/ontology/txnpool/common"
 	"github.com/ontio/ontology/txnpool/proc"
 	"github.com/urfave/cli"
 )
@@ -73,6 +77,9 @@ func setupAPP() *cli.App {
 		cmd.ContractCommand,
 		cmd.ImportCommand,
 		cmd.ExportCommand,
+		cmd.MigrateCommand,
+		cmd.SnapshotCommand,
+		cmd.DBCommand,
 		cmd.TxCommond,
 		cmd.SigTxCommand,
 		cmd.MultiSigAddrCommand,
@@ -84,10 +91,18 @@ func setupAPP() *cli.App {
 		//common setting
 		utils.ConfigFlag,
 		utils.LogLevelFlag,
+		utils.ModuleLogLevelFlag,
+		utils.LogFormatFlag,
 		utils.LogDirFlag,
 		utils.DisableLogFileFlag,
 		utils.DisableEventLogFlag,
+		utils.EnableStateProofFlag,
+		utils.EnableArchiveFlag,
+		utils.EnableAddressIndexFlag,
+		utils.EnableContractEventIndexFlag,
+		utils.StateUndoDepthFlag,
 		utils.DataDirFlag,
+		utils.DBBackendFlag,
 		utils.ETHTxGasLimitFlag,
 		utils.WasmVerifyMethodFlag,
 		//account setting
@@ -97,12 +112,18 @@ func setupAPP() *cli.App {
 		//consensus setting
 		utils.EnableConsensusFlag,
 		utils.MaxTxInBlockFlag,
+		utils.EnablePipelineFlag,
 		//txpool setting
 		utils.GasPriceFlag,
 		utils.GasLimitFlag,
+		utils.TxPoolCapacityFlag,
+		utils.TxPoolPayerSlotsFlag,
+		utils.TxPoolQueueLifetimeFlag,
 		utils.TxpoolPreExecDisableFlag,
 		utils.DisableSyncVerifyTxFlag,
 		utils.DisableBroadcastNetTxFlag,
+		utils.EnableTxPoolJournalFlag,
+		utils.TxPoolRejournalFlag,
 		//p2p setting
 		utils.ReservedPeersOnlyFlag,
 		utils.ReservedPeersFileFlag,
@@ -119,6 +140,11 @@ func setupAPP() *cli.App {
 		utils.RPCDisabledFlag,
 		utils.RPCPortFlag,
 		utils.ETHRPCPortFlag,
+		utils.ETHWSEnableFlag,
+		utils.ETHWSPortFlag,
+		utils.ETHWSOriginsFlag,
+		utils.ETHLogsMaxBlockRangeFlag,
+		utils.ETHDebugApiEnableFlag,
 		utils.RPCLocalEnableFlag,
 		utils.RPCLocalProtFlag,
 		//rest setting
@@ -129,9 +155,23 @@ func setupAPP() *cli.App {
 		utils.GraphQLEnableFlag,
 		utils.GraphQLPortFlag,
 		utils.GraphQLMaxConnsFlag,
+		utils.GraphQLWsOriginsFlag,
 		//ws setting
 		utils.WsEnabledFlag,
 		utils.WsPortFlag,
+		//metrics setting
+		utils.MetricsEnableFlag,
+		utils.MetricsPortFlag,
+		//tracing setting
+		utils.TracingEnableFlag,
+		utils.TracingEndpointFlag,
+		utils.TracingFileFlag,
+		//admin rpc setting
+		utils.AdminRpcEnableFlag,
+		utils.AdminRpcIpcFlag,
+		utils.AdminRpcAddrFlag,
+		utils.AdminRpcPortFlag,
+		utils.AdminRpcTokenFileFlag,
 	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -162,6 +202,10 @@ func startOntology(ctx *cli.Context) {
 		log.Errorf("initConfig error: %s", err)
 		return
 	}
+	if err := initTracing(); err != nil {
+		log.Errorf("initTracing error: %s", err)
+		return
+	}
 	acc, err := initAccount(ctx)
 	if err != nil {
 		log.Errorf("initWallet error: %s", err)
@@ -207,6 +251,12 @@ func startOntology(ctx *cli.Context) {
 	initRestful(ctx)
 	initWs(ctx)
 	initNodeInfo(ctx, p2pSvr)
+	initMetrics(ctx, p2pSvr)
+	err = initAdminRpc(p2pSvr, txpool, ldg)
+	if err != nil {
+		log.Errorf("initAdminRpc error: %s", err)
+		return
+	}
 
 	go logCurrBlockHeight()
 	waitToExit(ldg)
@@ -215,6 +265,14 @@ func startOntology(ctx *cli.Context) {
 func initLog(ctx *cli.Context) {
 	//init log module
 	logLevel := ctx.GlobalInt(utils.GetFlagName(utils.LogLevelFlag))
+	if err := log.SetFormat(ctx.GlobalString(utils.GetFlagName(utils.LogFormatFlag))); err != nil {
+		cmd.PrintErrorMsg(err.Error())
+		os.Exit(1)
+	}
+	if err := log.SetModuleLevels(ctx.GlobalString(utils.GetFlagName(utils.ModuleLogLevelFlag))); err != nil {
+		cmd.PrintErrorMsg(err.Error())
+		os.Exit(1)
+	}
 	//if true, the log will not be output to the file
 	disableLogFile := ctx.GlobalBool(utils.GetFlagName(utils.DisableLogFileFlag))
 	if disableLogFile {
@@ -299,6 +357,18 @@ func initTxPool(ctx *cli.Context) (*proc.TXPoolServer, error) {
 	bactor.SetTxnPoolPid(txPoolServer.GetPID())
 	bactor.SetTxPoolService(proc.NewTxPoolService(txPoolServer))
 
+	if ctx.GlobalBool(utils.GetFlagName(utils.EnableTxPoolJournalFlag)) {
+		dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+		rejournal := time.Duration(ctx.GlobalUint(utils.GetFlagName(utils.TxPoolRejournalFlag))) * time.Second
+		if rejournal == 0 {
+			return nil, fmt.Errorf("invalid txpool rejournal interval")
+		}
+		err = txPoolServer.StartJournal(filepath.Join(dbDir, tc.TX_JOURNAL_FILE), rejournal)
+		if err != nil {
+			return nil, fmt.Errorf("init txpool journal error: %s", err)
+		}
+	}
+
 	log.Infof("TxPool init success")
 	return txPoolServer, nil
 }
@@ -454,6 +524,43 @@ func initNodeInfo(ctx *cli.Context, p2pSvr *p2pserver.P2PServer) {
 	log.Infof("Nodeinfo init success")
 }
 
+func initMetrics(ctx *cli.Context, p2pSvr *p2pserver.P2PServer) {
+	if !config.DefConfig.Metrics.EnableMetrics {
+		return
+	}
+	// testmode has no p2pserver, see initNodeInfo
+	if !ctx.Bool(utils.GetFlagName(utils.EnableTestModeFlag)) {
+		if err := nodeinfo.StartMetric(p2pSvr.GetNetwork()); err != nil {
+			log.Errorf("init p2p metrics error: %s", err)
+		}
+	}
+	go metrics.StartServer(config.DefConfig.Metrics)
+
+	log.Infof("Metrics init success")
+}
+
+func initAdminRpc(p2pSvr *p2pserver.P2PServer, txpool *proc.TXPoolServer, ldg *ledger.Ledger) error {
+	if !config.DefConfig.AdminRpc.EnableAdminRpc {
+		return nil
+	}
+	if err := admin.Start(config.DefConfig.AdminRpc, p2pSvr, txpool, ldg); err != nil {
+		return err
+	}
+	log.Infof("Admin rpc init success")
+	return nil
+}
+
+func initTracing() error {
+	if !config.DefConfig.Tracing.EnableTracing {
+		return nil
+	}
+	if err := tracing.Start(config.DefConfig.Tracing); err != nil {
+		return err
+	}
+	log.Infof("Tracing init success")
+	return nil
+}
+
 func logCurrBlockHeight() {
 	ticker := time.NewTicker(config.DEFAULT_GEN_BLOCK_TIME * time.Second)
 	defer ticker.Stop()
@@ -487,7 +594,9 @@ func waitToExit(db *ledger.Ledger) {
 		for sig := range sc {
 			log.Infof("Ontology received exit signal: %v.", sig.String())
 			log.Infof("closing ledger...")
+			admin.Stop()
 			db.Close()
+			tracing.Stop()
 			close(exit)
 			break
 		}
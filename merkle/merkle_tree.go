@@ -238,6 +238,28 @@ func (self *CompactMerkleTree) merkleRoot(n uint32) common.Uint256 {
 	return self.hasher._hash_fold(hashes)
 }
 
+// HashesOfSize returns the compact hashes of D[0:n] loaded from hash store, which can be used to
+// rebuild the tree of size n with NewTree
+func (self *CompactMerkleTree) HashesOfSize(n uint32) ([]common.Uint256, error) {
+	if self.treeSize < n {
+		return nil, errors.New("not available yet")
+	} else if n == self.treeSize {
+		return append([]common.Uint256{}, self.hashes...), nil
+	} else if self.hashStore == nil {
+		return nil, errors.New("hash store not available")
+	}
+	hashespos := getSubTreePos(n)
+	hashes := make([]common.Uint256, len(hashespos), len(hashespos))
+	for i, pos := range hashespos {
+		hash, err := self.hashStore.GetHash(pos - 1)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hash
+	}
+	return hashes, nil
+}
+
 // ConsistencyProof returns consistency proof
 func (self *CompactMerkleTree) ConsistencyProof(m, n uint32) []common.Uint256 {
 	if m > n || self.treeSize < n || self.hashStore == nil {
@@ -78,6 +78,23 @@ func TestCompactMerkleTree_GetRootWithNewLeaves(t *testing.T) {
 	}
 }
 
+func TestCompactMerkleTree_HashesOfSize(t *testing.T) {
+	N := uint32(100)
+	tree := NewTree(0, nil, NewMemHashStore())
+	roots := make([]common.Uint256, 0, N)
+	for i := uint32(0); i < N; i++ {
+		tree.Append([]byte{byte(i)})
+		roots = append(roots, tree.Root())
+	}
+	for n := uint32(1); n <= N; n++ {
+		hashes, err := tree.HashesOfSize(n)
+		assert.Nil(t, err)
+		assert.Equal(t, roots[n-1], NewTree(n, hashes, nil).Root())
+	}
+	_, err := tree.HashesOfSize(N + 1)
+	assert.NotNil(t, err)
+}
+
 func TestMerkle(t *testing.T) {
 	hasher := TreeHasher{}
 	leafs := []common.Uint256{hasher.hash_leaf([]byte{1}),
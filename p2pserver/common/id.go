@@ -67,6 +67,15 @@ func (self *PeerId) ToHexString() string {
 	return self.val.ToHexString()
 }
 
+// PeerIdFromHexString parses the peer id from the string returned by ToHexString
+func PeerIdFromHexString(s string) (PeerId, error) {
+	val, err := common.AddressFromHexString(s)
+	if err != nil {
+		return PeerId{}, err
+	}
+	return PeerId{val: val}, nil
+}
+
 type PeerKeyId struct {
 	PublicKey keypair.PublicKey
 
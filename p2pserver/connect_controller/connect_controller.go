@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -36,6 +37,8 @@ const OUTBOUND_INDEX = 1
 
 var ErrHandshakeSelf = errors.New("the node handshake with itself")
 
+var ErrInvalidIP = errors.New("invalid ip address")
+
 type connectedPeer struct {
 	connectId uint64
 	addr      string
@@ -53,6 +56,8 @@ type ConnectController struct {
 	inboundListenAddress *strset.Set    // in bound listen address
 	connecting           *strset.Set
 	peers                map[common.PeerId]*connectedPeer // all connected peers
+	bannedIps            *strset.Set
+	bannedIds            map[common.PeerId]bool
 
 	ownListenAddr string
 	nextConnectId uint64
@@ -70,6 +75,8 @@ func NewConnectController(peerInfo *peer.PeerInfo, keyid *common.PeerKeyId,
 		inboundListenAddress: strset.New(),
 		connecting:           strset.New(),
 		peers:                make(map[common.PeerId]*connectedPeer),
+		bannedIps:            strset.New(),
+		bannedIds:            make(map[common.PeerId]bool),
 		logger:               logger,
 	}
 
@@ -159,6 +166,108 @@ func (self *ConnectController) checkReservedPeers(remoteAddr string) error {
 	return fmt.Errorf("the remote addr: %s not in reserved list", remoteAddr)
 }
 
+// BanIP rejects the connections from or to the ip until it is unbanned
+func (self *ConnectController) BanIP(ip string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ErrInvalidIP
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.bannedIps.Add(parsed.String())
+	return nil
+}
+
+// UnbanIP returns false if the ip is not banned
+func (self *ConnectController) UnbanIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if !self.bannedIps.Has(parsed.String()) {
+		return false
+	}
+	self.bannedIps.Remove(parsed.String())
+	return true
+}
+
+// BanPeer rejects the handshake with the peer id until it is unbanned
+func (self *ConnectController) BanPeer(id common.PeerId) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.bannedIds[id] = true
+}
+
+// UnbanPeer returns false if the peer id is not banned
+func (self *ConnectController) UnbanPeer(id common.PeerId) bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if !self.bannedIds[id] {
+		return false
+	}
+	delete(self.bannedIds, id)
+	return true
+}
+
+// BannedIPs returns the sorted list of banned ips
+func (self *ConnectController) BannedIPs() []string {
+	self.mutex.Lock()
+	ips := self.bannedIps.List()
+	self.mutex.Unlock()
+	sort.Strings(ips)
+	return ips
+}
+
+// BannedPeers returns the list of banned peer ids
+func (self *ConnectController) BannedPeers() []common.PeerId {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	ids := make([]common.PeerId, 0, len(self.bannedIds))
+	for id := range self.bannedIds {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (self *ConnectController) checkBannedAddr(addr string) error {
+	ip, err := common.ParseIPAddr(addr)
+	if err != nil {
+		return err
+	}
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.bannedIps.Has(ip) {
+		return fmt.Errorf("the remote ip: %s is banned", ip)
+	}
+	return nil
+}
+
+func (self *ConnectController) checkBannedPeer(id common.PeerId) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.bannedIds[id] {
+		return fmt.Errorf("the remote peer: %s is banned", id.ToHexString())
+	}
+	return nil
+}
+
+// CheckConnectedPeer returns error if the connected peer should be dropped
+// since it is banned or not in the reserved list any more
+func (self *ConnectController) CheckConnectedPeer(id common.PeerId, addr string) error {
+	if err := self.checkBannedPeer(id); err != nil {
+		return err
+	}
+	if err := self.checkBannedAddr(addr); err != nil {
+		return err
+	}
+	return self.checkReservedPeers(addr)
+}
+
 func (self *ConnectController) getInboundCountWithIp(ip string) uint {
 	self.mutex.Lock()
 	defer self.mutex.Unlock()
@@ -238,6 +347,9 @@ func (self *ConnectController) afterHandshakeCheck(remotePeer *peer.PeerInfo, re
 	if err := self.isHandWithSelf(remotePeer, remoteAddr); err != nil {
 		return err
 	}
+	if err := self.checkBannedPeer(remotePeer.Id); err != nil {
+		return err
+	}
 
 	return self.checkPeerIdAndIP(remotePeer, remoteAddr)
 }
@@ -247,6 +359,9 @@ func (self *ConnectController) beforeHandshakeCheck(addr string, index int) erro
 	if err != nil {
 		return err
 	}
+	if err := self.checkBannedAddr(addr); err != nil {
+		return err
+	}
 
 	if self.hasBoundAddr(addr) {
 		return fmt.Errorf("peer %s already in connection records", addr)
@@ -151,6 +151,48 @@ func TestConnectController_AcceptConnect_MaxInBound(t *testing.T) {
 	assert.Equal(t, server.inoutbounds[INBOUND_INDEX].Size(), 0)
 }
 
+func TestConnectController_Ban(t *testing.T) {
+	trans := NewTransport(t)
+	server := NewNode(NewConnCtrlOption())
+	client := NewNode(NewConnCtrlOption())
+
+	accept := func() (net.Conn, error) {
+		conn1, conn2 := trans.Pipe()
+		defer conn1.Close()
+		go func() {
+			_, _ = handshake.HandshakeClient(client.peerInfo, client.Key, conn1)
+		}()
+		_, conn, err := server.AcceptConnect(conn2)
+		if err != nil {
+			_ = conn2.Close()
+		}
+		return conn, err
+	}
+
+	assert.Equal(t, ErrInvalidIP, server.BanIP("127.0.0"))
+	assert.Nil(t, server.BanIP("127.0.0.1"))
+	assert.Equal(t, []string{"127.0.0.1"}, server.BannedIPs())
+	_, err := accept()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "banned")
+	assert.True(t, server.UnbanIP("127.0.0.1"))
+	assert.False(t, server.UnbanIP("127.0.0.1"))
+
+	server.BanPeer(client.Info.Id)
+	assert.Equal(t, []common.PeerId{client.Info.Id}, server.BannedPeers())
+	_, err = accept()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "banned")
+	assert.True(t, server.UnbanPeer(client.Info.Id))
+
+	conn, err := accept()
+	assert.Nil(t, err)
+	assert.Nil(t, server.CheckConnectedPeer(client.Info.Id, conn.RemoteAddr().String()))
+	server.BanPeer(client.Info.Id)
+	assert.NotNil(t, server.CheckConnectedPeer(client.Info.Id, conn.RemoteAddr().String()))
+	_ = conn.Close()
+}
+
 func TestConnectController_OutboundsCount(t *testing.T) {
 	maxOutboud := 5
 	server := NewNode(NewConnCtrlOption().MaxInBound(uint(maxOutboud * 2)))
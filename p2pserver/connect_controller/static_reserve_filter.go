@@ -20,21 +20,41 @@ package connect_controller
 import (
 	"net"
 	"sort"
+	"sync"
 )
 
 type StaticReserveFilter struct {
+	mutex sync.RWMutex
 	//format: host or ip
 	ReservedPeers []string
 }
 
 func NewStaticReserveFilter(peers []string) *StaticReserveFilter {
-	// put domain to the end
+	return &StaticReserveFilter{
+		ReservedPeers: sortReservedPeers(peers),
+	}
+}
+
+// put domain to the end
+func sortReservedPeers(peers []string) []string {
 	sort.Slice(peers, func(i, j int) bool {
 		return net.ParseIP(peers[i]) != nil
 	})
-	return &StaticReserveFilter{
-		ReservedPeers: peers,
-	}
+	return peers
+}
+
+// Update replaces the reserved peers, used when the reserved file is reloaded
+func (self *StaticReserveFilter) Update(peers []string) {
+	peers = sortReservedPeers(peers)
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.ReservedPeers = peers
+}
+
+func (self *StaticReserveFilter) reservedPeers() []string {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	return self.ReservedPeers
 }
 
 // remoteAddr format 192.168.1.1:61234
@@ -50,7 +70,7 @@ func (self *StaticReserveFilter) Contains(remoteIPPort string) bool {
 		return false
 	}
 	// we don't load domain in start because we consider domain's A/AAAA record may change sometimes
-	for _, curIPOrName := range self.ReservedPeers {
+	for _, curIPOrName := range self.reservedPeers() {
 		curIPs, err := net.LookupHost(curIPOrName)
 		if err != nil {
 			continue
@@ -0,0 +1,308 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mock
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	comm "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/p2pserver/common"
+	"github.com/ontio/ontology/p2pserver/message/types"
+	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/ontio/ontology/p2pserver/peer"
+)
+
+// MsgHandler receives the messages delivered to a node of MsgNetwork
+type MsgHandler func(from common.PeerId, msg types.Message)
+
+// MsgFilter rewrites the message sent between nodes into the messages
+// delivered, returns nothing to drop it, or more to duplicate or equivocate.
+// It is called with the network locked, so must not call the network.
+type MsgFilter func(from, to common.PeerId, msg types.Message) []types.Message
+
+// MsgNetwork connects the in-process nodes at message level, without the
+// handshakes and connections of netserver. Messages are serialized in wire
+// format, and delivered through the scheduler with the scripted faults. All
+// the random choices come from the seed, so a run can be reproduced.
+type MsgNetwork struct {
+	lock      sync.Mutex
+	rand      *rand.Rand
+	schedule  func(d time.Duration, f func())
+	nodes     map[common.PeerId]*msgNode
+	groups    map[common.PeerId]int // partition group, nodes of different groups are unreachable
+	down      map[common.PeerId]bool
+	dropRate  uint // percent
+	minDelay  time.Duration
+	maxDelay  time.Duration
+	filter    MsgFilter
+	delivered uint64
+	dropped   uint64
+}
+
+// NewMsgNetwork creates the network, schedule runs f after duration d, which
+// is usually driven by a simulated clock.
+func NewMsgNetwork(seed int64, schedule func(d time.Duration, f func())) *MsgNetwork {
+	return &MsgNetwork{
+		rand:     rand.New(rand.NewSource(seed)),
+		schedule: schedule,
+		nodes:    make(map[common.PeerId]*msgNode),
+		groups:   make(map[common.PeerId]int),
+		down:     make(map[common.PeerId]bool),
+	}
+}
+
+// NewNode adds a node to network, the returned P2P sends messages to the other nodes.
+func (n *MsgNetwork) NewNode(id common.PeerId, handler MsgHandler) p2p.P2P {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	node := &msgNode{
+		id:      id,
+		network: n,
+		handler: handler,
+		info:    &peer.PeerInfo{Id: id},
+	}
+	n.nodes[id] = node
+	return node
+}
+
+// Partition splits the nodes into groups, nodes not listed are in a group of their own.
+func (n *MsgNetwork) Partition(groups ...[]common.PeerId) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.groups = make(map[common.PeerId]int)
+	for i, group := range groups {
+		for _, id := range group {
+			n.groups[id] = i + 1
+		}
+	}
+	next := len(groups) + 1
+	for _, id := range n.sortedIds() {
+		if _, present := n.groups[id]; !present {
+			n.groups[id] = next
+			next++
+		}
+	}
+}
+
+// Heal removes the partition
+func (n *MsgNetwork) Heal() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.groups = make(map[common.PeerId]int)
+}
+
+// SetDown drops all the messages from and to the node
+func (n *MsgNetwork) SetDown(id common.PeerId, down bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.down[id] = down
+}
+
+// SetDropRate drops the percent of messages randomly
+func (n *MsgNetwork) SetDropRate(percent uint) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.dropRate = percent
+}
+
+// SetDelay delays each message randomly in [min, max], messages are reordered
+// if max is greater than min.
+func (n *MsgNetwork) SetDelay(min, max time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if max < min {
+		max = min
+	}
+	n.minDelay, n.maxDelay = min, max
+}
+
+// SetFilter rewrites or drops the messages sent, nil removes the filter
+func (n *MsgNetwork) SetFilter(filter MsgFilter) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.filter = filter
+}
+
+// Stats returns the number of messages delivered and dropped
+func (n *MsgNetwork) Stats() (delivered, dropped uint64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.delivered, n.dropped
+}
+
+func (n *MsgNetwork) sortedIds() []common.PeerId {
+	ids := make([]common.PeerId, 0, len(n.nodes))
+	for id := range n.nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].ToHexString() < ids[j].ToHexString()
+	})
+	return ids
+}
+
+func (n *MsgNetwork) reachable(from, to common.PeerId) bool {
+	if n.down[from] || n.down[to] {
+		return false
+	}
+	return n.groups[from] == n.groups[to]
+}
+
+func (n *MsgNetwork) send(from, to common.PeerId, msg types.Message) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	target := n.nodes[to]
+	if target == nil || from == to {
+		return
+	}
+	msgs := []types.Message{msg}
+	if n.filter != nil {
+		if msgs = n.filter(from, to, msg); len(msgs) == 0 {
+			n.dropped++
+			return
+		}
+	}
+	for _, msg := range msgs {
+		n.deliver(from, target, msg)
+	}
+}
+
+// deliver schedules the msg with the faults, must be called with network locked
+func (n *MsgNetwork) deliver(from common.PeerId, target *msgNode, msg types.Message) {
+	to := target.id
+	if !n.reachable(from, to) || (n.dropRate > 0 && uint(n.rand.Intn(100)) < n.dropRate) {
+		n.dropped++
+		return
+	}
+	delay := n.minDelay
+	if n.maxDelay > n.minDelay {
+		delay += time.Duration(n.rand.Int63n(int64(n.maxDelay - n.minDelay)))
+	}
+
+	// each receiver gets its own copy decoded from the wire format
+	sink := comm.NewZeroCopySink(nil)
+	types.WriteMessage(sink, msg)
+	data := sink.Bytes()
+	n.schedule(delay, func() {
+		n.lock.Lock()
+		ok := n.reachable(from, to)
+		if ok {
+			n.delivered++
+		} else {
+			n.dropped++
+		}
+		n.lock.Unlock()
+		if !ok {
+			return
+		}
+		received, _, err := types.ReadMessage(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		target.handler(from, received)
+	})
+}
+
+func (n *MsgNetwork) broadcast(from common.PeerId, msg types.Message) {
+	n.lock.Lock()
+	ids := n.sortedIds()
+	n.lock.Unlock()
+
+	for _, id := range ids {
+		n.send(from, id, msg)
+	}
+}
+
+type msgNode struct {
+	id      common.PeerId
+	network *MsgNetwork
+	handler MsgHandler
+	info    *peer.PeerInfo
+}
+
+var _ p2p.P2P = &msgNode{}
+
+func (self *msgNode) Connect(addr string) {}
+
+func (self *msgNode) GetHostInfo() *peer.PeerInfo {
+	return self.info
+}
+
+func (self *msgNode) GetID() common.PeerId {
+	return self.id
+}
+
+func (self *msgNode) GetNeighbors() []*peer.Peer {
+	return nil
+}
+
+func (self *msgNode) GetNeighborAddrs() []common.PeerAddr {
+	return nil
+}
+
+func (self *msgNode) GetConnectionCnt() uint32 {
+	self.network.lock.Lock()
+	defer self.network.lock.Unlock()
+
+	return uint32(len(self.network.nodes) - 1)
+}
+
+func (self *msgNode) GetMaxPeerBlockHeight() uint64 {
+	return 0
+}
+
+func (self *msgNode) GetPeer(id common.PeerId) *peer.Peer {
+	return nil
+}
+
+func (self *msgNode) SetHeight(uint64) {}
+
+func (self *msgNode) Send(p *peer.Peer, msg types.Message) error {
+	self.network.send(self.id, p.GetID(), msg)
+	return nil
+}
+
+func (self *msgNode) SendTo(p common.PeerId, msg types.Message) {
+	self.network.send(self.id, p, msg)
+}
+
+func (self *msgNode) GetOutConnRecordLen() uint {
+	return 0
+}
+
+func (self *msgNode) Broadcast(msg types.Message) {
+	self.network.broadcast(self.id, msg)
+}
+
+func (self *msgNode) IsOwnAddress(addr string) bool {
+	return false
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/p2pserver/common"
+	"github.com/ontio/ontology/p2pserver/message/msg_pack"
+	"github.com/ontio/ontology/p2pserver/message/types"
+	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgNetwork(t *testing.T) {
+	a := require.New(t)
+	var pending []func()
+	n := NewMsgNetwork(1, func(d time.Duration, f func()) {
+		pending = append(pending, f)
+	})
+	flush := func() {
+		for len(pending) > 0 {
+			f := pending[0]
+			pending = pending[1:]
+			f()
+		}
+	}
+
+	received := make(map[common.PeerId][]uint64)
+	var ids []common.PeerId
+	for i := uint64(1); i <= 3; i++ {
+		ids = append(ids, common.PseudoPeerIdFromUint64(i))
+	}
+	var nodes []p2p.P2P
+	for _, id := range ids {
+		id := id
+		nodes = append(nodes, n.NewNode(id, func(from common.PeerId, msg types.Message) {
+			received[id] = append(received[id], msg.(*types.Ping).Height)
+		}))
+	}
+
+	nodes[0].Broadcast(msgpack.NewPingMsg(1))
+	flush()
+	a.Empty(received[ids[0]], "no msg sent to self")
+	a.Equal([]uint64{1}, received[ids[1]])
+	a.Equal([]uint64{1}, received[ids[2]])
+
+	// msgs sent before partitioned are dropped
+	nodes[0].Broadcast(msgpack.NewPingMsg(2))
+	n.Partition([]common.PeerId{ids[0], ids[1]})
+	flush()
+	a.Equal([]uint64{1, 2}, received[ids[1]])
+	a.Equal([]uint64{1}, received[ids[2]])
+
+	n.Heal()
+	n.SetDown(ids[1], true)
+	nodes[0].Broadcast(msgpack.NewPingMsg(3))
+	flush()
+	a.Equal([]uint64{1, 2}, received[ids[1]])
+	a.Equal([]uint64{1, 3}, received[ids[2]])
+
+	n.SetDown(ids[1], false)
+	n.SetFilter(func(from, to common.PeerId, msg types.Message) []types.Message {
+		if to == ids[1] {
+			return nil
+		}
+		return []types.Message{msg, msgpack.NewPingMsg(msg.(*types.Ping).Height + 1)}
+	})
+	nodes[0].Broadcast(msgpack.NewPingMsg(4))
+	flush()
+	a.Equal([]uint64{1, 2}, received[ids[1]])
+	a.Equal([]uint64{1, 3, 4, 5}, received[ids[2]])
+
+	n.SetFilter(nil)
+	n.SetDropRate(100)
+	nodes[0].Broadcast(msgpack.NewPingMsg(6))
+	flush()
+	a.Equal([]uint64{1, 3, 4, 5}, received[ids[2]])
+
+	delivered, dropped := n.Stats()
+	a.Equal(uint64(6), delivered)
+	a.Equal(uint64(5), dropped)
+}
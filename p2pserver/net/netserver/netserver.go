@@ -216,6 +216,35 @@ func (this *NetServer) connect(addr string) error {
 	return nil
 }
 
+// AddPeer connects to the net address and returns the error if failed
+func (this *NetServer) AddPeer(addr string) error {
+	return this.connect(addr)
+}
+
+// DropPeers closes the connections with the neighbors matched and returns them
+func (this *NetServer) DropPeers(match func(p *peer.Peer) bool) []*peer.Peer {
+	var dropped []*peer.Peer
+	for _, p := range this.Np.GetNeighbors() {
+		if match(p) {
+			p.Close()
+			dropped = append(dropped, p)
+		}
+	}
+	return dropped
+}
+
+// DropUnacceptedPeers closes the connections with the neighbors which are
+// banned or not reserved any more
+func (this *NetServer) DropUnacceptedPeers() []*peer.Peer {
+	return this.DropPeers(func(p *peer.Peer) bool {
+		err := this.connCtrl.CheckConnectedPeer(p.GetID(), p.GetAddr())
+		if err != nil {
+			this.logger.Infof("[p2p] drop peer %s: %s", p.GetAddr(), err)
+		}
+		return err != nil
+	})
+}
+
 func (this *NetServer) notifyPeerConnected(p *peer.PeerInfo) {
 	this.protocol.HandleSystemMessage(this, p2p.PeerConnected{Info: p})
 }
@@ -19,6 +19,11 @@
 package p2pserver
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"strings"
 	"time"
 
@@ -32,6 +37,7 @@ import (
 	"github.com/ontio/ontology/p2pserver/connect_controller"
 	"github.com/ontio/ontology/p2pserver/net/netserver"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/ontio/ontology/p2pserver/peer"
 	"github.com/ontio/ontology/p2pserver/protocols"
 	"github.com/ontio/ontology/p2pserver/protocols/utils"
 )
@@ -40,6 +46,9 @@ import (
 type P2PServer struct {
 	network *netserver.NetServer
 	db      *ledger.Ledger
+
+	staticFilter *connect_controller.StaticReserveFilter // connections allowed
+	recFilter    *connect_controller.StaticReserveFilter // peers recommended
 }
 
 //NewServer return a new p2pserver according to the pubkey
@@ -56,7 +65,8 @@ func NewServer(acct *account.Account, txpool common2.TxPoolService) (*P2PServer,
 	}
 
 	staticFilter := connect_controller.NewStaticReserveFilter(rsv)
-	protocol := protocols.NewMsgHandler(acct, connect_controller.NewStaticReserveFilter(recRsv), db, txpool, common.NewGlobalLoggerWrapper())
+	recFilter := connect_controller.NewStaticReserveFilter(recRsv)
+	protocol := protocols.NewMsgHandler(acct, recFilter, db, txpool, common.NewGlobalLoggerWrapper())
 	reserved := protocol.GetReservedAddrFilter(len(rsv) != 0)
 	reservedPeers := p2p.CombineAddrFilter(staticFilter, reserved)
 	n, err := netserver.NewNetServer(protocol, conf, reservedPeers)
@@ -65,8 +75,10 @@ func NewServer(acct *account.Account, txpool common2.TxPoolService) (*P2PServer,
 	}
 
 	p := &P2PServer{
-		db:      db,
-		network: n,
+		db:           db,
+		network:      n,
+		staticFilter: staticFilter,
+		recFilter:    recFilter,
 	}
 
 	return p, nil
@@ -87,6 +99,97 @@ func (self *P2PServer) GetNetwork() p2p.P2P {
 	return self.network
 }
 
+// AddPeer connects to the peer at the net address
+func (self *P2PServer) AddPeer(addr string) error {
+	return self.network.AddPeer(addr)
+}
+
+// RemovePeer disconnects the peers with the id or the net address, the peer
+// may be connected again later unless it is banned
+func (self *P2PServer) RemovePeer(idOrAddr string) []string {
+	dropped := self.network.DropPeers(func(p *peer.Peer) bool {
+		id := p.GetID()
+		return id.ToHexString() == idOrAddr || p.GetAddr() == idOrAddr
+	})
+	return peerAddrs(dropped)
+}
+
+// BanIP disconnects and rejects the peers from the ip
+func (self *P2PServer) BanIP(ip string) ([]string, error) {
+	if err := self.network.ConnectController().BanIP(ip); err != nil {
+		return nil, err
+	}
+	return peerAddrs(self.network.DropUnacceptedPeers()), nil
+}
+
+// BanPeer disconnects and rejects the peer with the id
+func (self *P2PServer) BanPeer(id string) ([]string, error) {
+	kid, err := common.PeerIdFromHexString(id)
+	if err != nil {
+		return nil, err
+	}
+	self.network.ConnectController().BanPeer(kid)
+	return peerAddrs(self.network.DropUnacceptedPeers()), nil
+}
+
+// UnbanIP returns false if the ip is not banned
+func (self *P2PServer) UnbanIP(ip string) bool {
+	return self.network.ConnectController().UnbanIP(ip)
+}
+
+// UnbanPeer returns false if the peer id is not banned
+func (self *P2PServer) UnbanPeer(id string) (bool, error) {
+	kid, err := common.PeerIdFromHexString(id)
+	if err != nil {
+		return false, err
+	}
+	return self.network.ConnectController().UnbanPeer(kid), nil
+}
+
+// GetBanned returns the banned ips and peer ids
+func (self *P2PServer) GetBanned() (ips []string, ids []string) {
+	ctrl := self.network.ConnectController()
+	for _, id := range ctrl.BannedPeers() {
+		ids = append(ids, id.ToHexString())
+	}
+	return ctrl.BannedIPs(), ids
+}
+
+// ReloadReservedPeers reloads the reserved peers from the reserved file and
+// disconnects the peers not reserved any more. The mask peers are not reloaded.
+func (self *P2PServer) ReloadReservedPeers() ([]string, error) {
+	conf := config.DefConfig.P2PNode
+	if conf.ReservedPeersFile == "" {
+		return nil, errors.New("reserved peers file is not configured")
+	}
+	data, err := ioutil.ReadFile(conf.ReservedPeersFile)
+	if err != nil {
+		return nil, err
+	}
+	// Remove the UTF-8 Byte Order Mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	rsvCfg := &config.P2PRsvConfig{}
+	if err := json.Unmarshal(data, rsvCfg); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal %s error:%s", data, err)
+	}
+
+	self.recFilter.Update(rsvCfg.ReservedPeers)
+	if conf.ReservedPeersOnly {
+		self.staticFilter.Update(append([]string(nil), rsvCfg.ReservedPeers...))
+	}
+	log.Infof("[p2p] reserved peers reloaded: %v", rsvCfg.ReservedPeers)
+
+	return peerAddrs(self.network.DropUnacceptedPeers()), nil
+}
+
+func peerAddrs(peers []*peer.Peer) []string {
+	addrs := make([]string, 0, len(peers))
+	for _, p := range peers {
+		addrs = append(addrs, p.GetAddr())
+	}
+	return addrs
+}
+
 //WaitForPeersStart check whether enough peer linked in loop
 func (self *P2PServer) WaitForPeersStart() {
 	periodTime := config.DEFAULT_GEN_BLOCK_TIME / common.UPDATE_RATE_PER_BLOCK
@@ -115,7 +218,7 @@ func (self *P2PServer) reachMinConnection() bool {
 	case "dbft":
 	case "solo":
 		minCount = config.SOLO_MIN_NODE_NUM
-	case "vbft":
+	case "vbft", "sbft":
 		minCount = self.getVbftGovNodeCount()
 	}
 	return self.network.GetConnectionCnt()+1 >= minCount
@@ -20,8 +20,10 @@
 package common
 
 import (
+	"container/heap"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
@@ -70,16 +72,27 @@ func (self *VerifiedTx) GetAttrs() []*TXAttr {
 // enter the pool when they are valid from the network,
 // consensus or submitted. They exit the pool when they are included
 // in the ledger.
+//
+// The EIP155 transactions of a payer are either pending, whose nonces are
+// continuous from the account nonce and are ready to be packed, or queued,
+// which wait for the missing nonces and are promoted once the gap is filled.
 type TXPool struct {
 	sync.RWMutex
 	validTxMap map[common.Uint256]*VerifiedTx  // Transactions which have been verified
-	eipTxPool  map[common.Address]*txSortedMap // The tx pool that holds the valid transaction
+	eipTxPool  map[common.Address]*txSortedMap // The pending eip155 txs of each payer
+	eipTxQueue map[common.Address]*txSortedMap // The queued eip155 txs of each payer with nonce gap
+	queueBeats map[common.Address]time.Time    // The last time a tx of the payer is queued
+	evictables priceHeap                       // The txs which can be evicted by gas price, may contain stale ones
+	inEvicts   map[common.Uint256]bool         // The hashes of the txs in evictables
 }
 
 func NewTxPool() *TXPool {
 	return &TXPool{
 		validTxMap: make(map[common.Uint256]*VerifiedTx),
 		eipTxPool:  make(map[common.Address]*txSortedMap),
+		eipTxQueue: make(map[common.Address]*txSortedMap),
+		queueBeats: make(map[common.Address]time.Time),
+		inEvicts:   make(map[common.Uint256]bool),
 	}
 }
 
@@ -100,43 +113,273 @@ func (s *TXPool) NextNonce(addr common.Address) uint64 {
 	return uint64(l.Nonce + 1)
 }
 
-func (s *TXPool) getTxListByAddr(addr common.Address) *txSortedMap {
-	if _, ok := s.eipTxPool[addr]; !ok {
-		s.eipTxPool[addr] = newTxSortedMap()
+func getTxListByAddr(pool map[common.Address]*txSortedMap, addr common.Address) *txSortedMap {
+	if _, ok := pool[addr]; !ok {
+		pool[addr] = newTxSortedMap()
 	}
 
-	return s.eipTxPool[addr]
+	return pool[addr]
 }
 
-func (s *TXPool) addEIPTxPool(trans *types.Transaction) (replaced *types.Transaction, err errors.ErrCode) {
-	list := s.getTxListByAddr(trans.Payer)
+// adds the eip155 tx to the pending list of the payer if its nonce follows
+// the pending ones, otherwise to the queue.
+func (s *TXPool) addEIPTxPool(txEntry *VerifiedTx) (replaced *types.Transaction, err errors.ErrCode) {
+	trans := txEntry.Tx
+	nonce := uint64(trans.Nonce)
 
 	// does the same nonce exist?
-	old := list.Get(uint64(trans.Nonce))
-	if old == nil {
-		s.eipTxPool[trans.Payer].Put(trans)
+	for _, pool := range []map[common.Address]*txSortedMap{s.eipTxPool, s.eipTxQueue} {
+		list := pool[trans.Payer]
+		if list == nil {
+			continue
+		}
+		old := list.Get(nonce)
+		if old == nil {
+			continue
+		}
+		if trans.GasPrice > old.GasPrice*101/100 {
+			log.Infof("replace transaction %s with lower gas fee", old.Hash().ToHexString())
+			list.Put(trans)
+			return old, errors.ErrNoError
+		}
+		return nil, errors.ErrSameNonceExist
+	}
+
+	// the nonce of account when the tx is verified
+	next := txEntry.Nonce
+	if list := s.eipTxPool[trans.Payer]; list != nil {
+		next = uint64(list.LastElement().Nonce) + 1
+	}
+	if nonce > next {
+		getTxListByAddr(s.eipTxQueue, trans.Payer).Put(trans)
+		s.queueBeats[trans.Payer] = time.Now()
+		log.Infof("transaction %s queued, nonce: %d, expected: %d", trans.Hash().ToHexString(), nonce, next)
 		return nil, errors.ErrNoError
 	}
+	getTxListByAddr(s.eipTxPool, trans.Payer).Put(trans)
+	s.promoteQueueLocked(trans.Payer, uint64(s.eipTxPool[trans.Payer].LastElement().Nonce)+1)
+	return nil, errors.ErrNoError
+}
+
+// moves the queued txs of the payer starting from the next nonce to pending
+func (s *TXPool) promoteQueueLocked(payer common.Address, next uint64) {
+	queue := s.eipTxQueue[payer]
+	if queue == nil {
+		return
+	}
+	ready := queue.Ready(next)
+	if len(ready) != 0 {
+		pending := getTxListByAddr(s.eipTxPool, payer)
+		for _, tx := range ready {
+			pending.Put(tx)
+		}
+		log.Infof("promoted %d queued transactions of %s", len(ready), payer.ToBase58())
+	}
+	s.removeEmptyListLocked(payer)
+}
+
+// moves the pending txs of the payer above the nonce to the queue
+func (s *TXPool) demotePendingLocked(payer common.Address, nonce uint64) {
+	pending := s.eipTxPool[payer]
+	if pending == nil {
+		return
+	}
+	demoted := pending.Filter(func(tx *types.Transaction) bool {
+		return uint64(tx.Nonce) > nonce
+	})
+	if len(demoted) != 0 {
+		queue := getTxListByAddr(s.eipTxQueue, payer)
+		for _, tx := range demoted {
+			queue.Put(tx)
+		}
+		s.queueBeats[payer] = time.Now()
+	}
+	s.removeEmptyListLocked(payer)
+}
+
+func (s *TXPool) removeEmptyListLocked(payer common.Address) {
+	if list, ok := s.eipTxPool[payer]; ok && list.Len() == 0 {
+		delete(s.eipTxPool, payer)
+	}
+	if list, ok := s.eipTxQueue[payer]; ok && list.Len() == 0 {
+		delete(s.eipTxQueue, payer)
+		delete(s.queueBeats, payer)
+	}
+}
+
+// returns the eip155 tx of the payer with the highest nonce
+func (s *TXPool) lastEIPTxLocked(payer common.Address) *types.Transaction {
+	if list := s.eipTxQueue[payer]; list != nil && list.Len() != 0 {
+		return list.LastElement()
+	}
+	if list := s.eipTxPool[payer]; list != nil && list.Len() != 0 {
+		return list.LastElement()
+	}
+	return nil
+}
+
+// checks the configured limits of the pool for the transaction. If the pool
+// is full, returns the transaction with the lowest gas price to be evicted,
+// or rejects the transaction when it does not pay more than that one.
+func (tp *TXPool) checkLimitLocked(tx *types.Transaction) (*types.Transaction, errors.ErrCode) {
+	if _, ok := tp.validTxMap[tx.Hash()]; ok {
+		return nil, errors.ErrNoError
+	}
+	if tx.IsEipTx() {
+		count := 0
+		for _, pool := range []map[common.Address]*txSortedMap{tp.eipTxPool, tp.eipTxQueue} {
+			if list := pool[tx.Payer]; list != nil {
+				if list.Get(uint64(tx.Nonce)) != nil {
+					// replaces the transaction with the same nonce
+					return nil, errors.ErrNoError
+				}
+				count += list.Len()
+			}
+		}
+		slots := config.DefConfig.Common.TxPoolPayerSlots
+		if slots != 0 && uint(count) >= slots {
+			return nil, errors.ErrPayerTxPoolLimit
+		}
+	}
+
+	capacity := config.DefConfig.Common.TxPoolCapacity
+	if capacity == 0 || uint(len(tp.validTxMap)) < capacity {
+		return nil, errors.ErrNoError
+	}
+	cheapest := tp.cheapestTxLocked(tx)
+	if cheapest == nil || cheapest.GasPrice >= tx.GasPrice {
+		return nil, errors.ErrTxPoolUnderpriced
+	}
+	return cheapest, errors.ErrNoError
+}
+
+// returns the transaction with the lowest gas price which can be evicted for
+// the incoming tx. Only the highest nonce eip155 transaction of a payer can
+// be evicted, so that the remaining nonces are still continuous.
+func (tp *TXPool) cheapestTxLocked(tx *types.Transaction) *types.Transaction {
+	for len(tp.evictables) != 0 && !tp.isEvictableLocked(tp.evictables[0]) {
+		delete(tp.inEvicts, heap.Pop(&tp.evictables).(*types.Transaction).Hash())
+	}
+	if len(tp.evictables) == 0 {
+		return nil
+	}
+	cheapest := tp.evictables[0]
+	if !tx.IsEipTx() || !cheapest.IsEipTx() || cheapest.Payer != tx.Payer {
+		return cheapest
+	}
+	// the payer can not evict its own tx, which is its only one in the heap
+	heap.Pop(&tp.evictables)
+	next := tp.cheapestTxLocked(tx)
+	heap.Push(&tp.evictables, cheapest)
+	return next
+}
+
+// returns whether the tx in evictables is still in the pool and can be evicted
+func (tp *TXPool) isEvictableLocked(tx *types.Transaction) bool {
+	if _, ok := tp.validTxMap[tx.Hash()]; !ok {
+		return false
+	}
+	if !tx.IsEipTx() {
+		return true
+	}
+	last := tp.lastEIPTxLocked(tx.Payer)
+	return last != nil && last.Hash() == tx.Hash()
+}
+
+// evictables is not compacted while it has fewer stale txs
+const minEvictablesCompact = 64
+
+// adds the tx to evictables if it is not there. The stale txs are dropped
+// when they reach the top of the heap, or all at once when they outnumber the
+// txs in the pool.
+func (tp *TXPool) pushEvictableLocked(tx *types.Transaction) {
+	if tx == nil || tp.inEvicts[tx.Hash()] {
+		return
+	}
+	heap.Push(&tp.evictables, tx)
+	tp.inEvicts[tx.Hash()] = true
+	if len(tp.evictables) <= 2*len(tp.validTxMap)+minEvictablesCompact {
+		return
+	}
+	evictables := tp.evictables[:0]
+	for _, tx := range tp.evictables {
+		if tp.isEvictableLocked(tx) {
+			evictables = append(evictables, tx)
+		} else {
+			delete(tp.inEvicts, tx.Hash())
+		}
+	}
+	for i := len(evictables); i < len(tp.evictables); i++ {
+		tp.evictables[i] = nil
+	}
+	tp.evictables = evictables
+	heap.Init(&tp.evictables)
+}
+
+// adds the highest nonce eip155 tx of the payer to evictables, it must be
+// called after the txs of the payer are changed
+func (tp *TXPool) pushPayerEvictableLocked(payer common.Address) {
+	tp.pushEvictableLocked(tp.lastEIPTxLocked(payer))
+}
 
-	if trans.GasPrice > old.GasPrice*101/100 {
-		log.Infof("replace transaction %s with lower gas fee", old.Hash().ToHexString())
-		s.eipTxPool[trans.Payer].Put(trans)
-		return old, errors.ErrNoError
+// resets evictables when the pool is emptied
+func (tp *TXPool) resetEvictablesLocked() {
+	tp.evictables = nil
+	tp.inEvicts = make(map[common.Uint256]bool)
+}
+
+// removes a transaction from the pool, the pending eip155 txs of the payer
+// after it are moved to the queue. Returns false if the eip155 tx is not found.
+func (tp *TXPool) removeTxLocked(tx *types.Transaction) bool {
+	delete(tp.validTxMap, tx.Hash())
+	if !tx.IsEipTx() {
+		return true
+	}
+	nonce := uint64(tx.Nonce)
+	removed := false
+	if list, ok := tp.eipTxQueue[tx.Payer]; ok {
+		removed = list.Remove(nonce)
+	}
+	if list, ok := tp.eipTxPool[tx.Payer]; ok && list.Remove(nonce) {
+		removed = true
+		tp.demotePendingLocked(tx.Payer, nonce)
 	}
+	tp.removeEmptyListLocked(tx.Payer)
+	tp.pushPayerEvictableLocked(tx.Payer)
+	return removed
+}
 
-	return nil, errors.ErrSameNonceExist
+// CheckTxLimit checks whether the transaction can be accepted by the pool
+// under the configured capacity and per payer limit, which allows to reject
+// it before verification. It takes the write lock since the stale txs of
+// evictables are dropped while looking for the cheapest one.
+func (tp *TXPool) CheckTxLimit(tx *types.Transaction) errors.ErrCode {
+	tp.Lock()
+	defer tp.Unlock()
+	_, code := tp.checkLimitLocked(tx)
+	return code
 }
 
 // AddTxList adds a valid transaction to the transaction pool. If the
 // transaction is already in the pool, just return false. Parameter
 // txEntry includes transaction, fee, and verified information(height,
-// validator, error code).
+// validator, error code). If the pool is full, the transaction with the
+// lowest gas price is evicted for the one paying more.
 func (tp *TXPool) AddTxList(txEntry *VerifiedTx) errors.ErrCode {
 	tp.Lock()
 	defer tp.Unlock()
 	txHash := txEntry.Tx.Hash()
+	evicted, code := tp.checkLimitLocked(txEntry.Tx)
+	if !code.Success() {
+		return code
+	}
+	if evicted != nil {
+		tp.removeTxLocked(evicted)
+		log.Infof("tx %s evicted from the full pool by tx %s with higher gas price", evicted.Hash().ToHexString(),
+			txHash.ToHexString())
+	}
 	if txEntry.Tx.IsEipTx() {
-		repalced, code := tp.addEIPTxPool(txEntry.Tx)
+		repalced, code := tp.addEIPTxPool(txEntry)
 		if repalced != nil {
 			delete(tp.validTxMap, repalced.Hash())
 		}
@@ -151,6 +394,11 @@ func (tp *TXPool) AddTxList(txEntry *VerifiedTx) errors.ErrCode {
 	}
 
 	tp.validTxMap[txHash] = txEntry
+	if txEntry.Tx.IsEipTx() {
+		tp.pushPayerEvictableLocked(txEntry.Tx.Payer)
+	} else {
+		tp.pushEvictableLocked(txEntry.Tx)
+	}
 	return errors.ErrNoError
 }
 
@@ -159,17 +407,44 @@ func (s *TXPool) cleanEipTxPool(txs []*types.Transaction) []*types.Transaction {
 	var cleaned []*types.Transaction
 	for _, tx := range txs {
 		if tx.IsEipTx() {
-			if _, ok := s.eipTxPool[tx.Payer]; ok {
-				cleaned = append(cleaned, s.eipTxPool[tx.Payer].Forward(uint64(tx.Nonce+1))...)
-				if s.eipTxPool[tx.Payer].Len() == 0 {
-					delete(s.eipTxPool, tx.Payer)
+			next := uint64(tx.Nonce + 1)
+			for _, pool := range []map[common.Address]*txSortedMap{s.eipTxPool, s.eipTxQueue} {
+				if list, ok := pool[tx.Payer]; ok {
+					cleaned = append(cleaned, list.Forward(next)...)
 				}
 			}
+			s.removeEmptyListLocked(tx.Payer)
+			if list, ok := s.eipTxPool[tx.Payer]; ok {
+				next = uint64(list.LastElement().Nonce) + 1
+			}
+			s.promoteQueueLocked(tx.Payer, next)
 		}
 	}
 	return cleaned
 }
 
+// drops the queued txs of the payers which have not got new queued tx for
+// the configured lifetime
+func (s *TXPool) dropStaleQueueLocked() []*types.Transaction {
+	lifetime := time.Duration(config.DefConfig.Common.TxPoolQueueLifetime) * time.Second
+	if lifetime == 0 {
+		return nil
+	}
+	var dropped []*types.Transaction
+	for payer, beat := range s.queueBeats {
+		if time.Since(beat) < lifetime {
+			continue
+		}
+		if list, ok := s.eipTxQueue[payer]; ok {
+			dropped = append(dropped, list.flatten()...)
+		}
+		delete(s.eipTxQueue, payer)
+		delete(s.queueBeats, payer)
+		log.Infof("queued transactions of %s dropped for exceeding lifetime", payer.ToBase58())
+	}
+	return dropped
+}
+
 // cleans the transaction list included in the ledger.
 func (tp *TXPool) CleanTransactionList(txs []*types.Transaction) {
 	cleaned := 0
@@ -178,6 +453,7 @@ func (tp *TXPool) CleanTransactionList(txs []*types.Transaction) {
 	defer tp.Unlock()
 	cleanedEips := tp.cleanEipTxPool(txs)
 	txs = append(txs, cleanedEips...)
+	txs = append(txs, tp.dropStaleQueueLocked()...)
 	for _, tx := range txs {
 		if _, ok := tp.validTxMap[tx.Hash()]; ok {
 			delete(tp.validTxMap, tx.Hash())
@@ -185,6 +461,11 @@ func (tp *TXPool) CleanTransactionList(txs []*types.Transaction) {
 			log.Infof("transaction cleaned: %s", tx.Hash().ToHexString())
 		}
 	}
+	for _, tx := range txs {
+		if tx.IsEipTx() {
+			tp.pushPayerEvictableLocked(tx.Payer)
+		}
+	}
 
 	log.Infof("clean txes: total %d, cleaned %d, remains %d in TxPool", txsNum, cleaned, len(tp.validTxMap))
 }
@@ -278,12 +559,8 @@ func (tp *TXPool) GetTxPool(byCount bool, height uint32) ([]*VerifiedTx, []*type
 
 	tp.Lock()
 	for _, tx := range oldTxList {
-		delete(tp.validTxMap, tx.Hash())
-		if tx.IsEipTx() {
-			removed := tp.eipTxPool[tx.Payer].Remove(uint64(tx.Nonce))
-			if !removed {
-				log.Errorf("transaction not in eip pool: %s, impossible", tx.Hash().ToHexString())
-			}
+		if removed := tp.removeTxLocked(tx); !removed {
+			log.Errorf("transaction not in eip pool: %s, impossible", tx.Hash().ToHexString())
 		}
 
 		log.Infof("remove expired tx: %s from pool", tx.Hash().ToHexString())
@@ -379,21 +656,49 @@ func (tp *TXPool) RemoveTxsBelowGasPrice(gasPrice uint64) {
 	for _, txEntry := range tp.validTxMap {
 		tx := txEntry.Tx
 		if tx.GasPrice < gasPrice {
-			delete(tp.validTxMap, tx.Hash())
-			if tx.IsEipTx() {
-				tp.eipTxPool[tx.Payer].Remove(uint64(tx.Nonce))
-			}
+			tp.removeTxLocked(tx)
 			log.Infof("tx %s cleaned because of lower gas: %d, want: %d", tx.Hash().ToHexString(), txEntry.Tx.GasPrice, gasPrice)
 		}
 	}
 }
 
+// RemoveTx drops the transaction from the pool, returns false if not found
+func (tp *TXPool) RemoveTx(hash common.Uint256) bool {
+	tp.Lock()
+	defer tp.Unlock()
+	txEntry, ok := tp.validTxMap[hash]
+	if !ok {
+		return false
+	}
+	tp.removeTxLocked(txEntry.Tx)
+	log.Infof("transaction dropped: %s", hash.ToHexString())
+	return true
+}
+
+// Flush drops all the transactions in the pool, returns the number of them
+func (tp *TXPool) Flush() int {
+	tp.Lock()
+	defer tp.Unlock()
+
+	count := len(tp.validTxMap)
+	tp.validTxMap = make(map[common.Uint256]*VerifiedTx)
+	tp.eipTxPool = make(map[common.Address]*txSortedMap)
+	tp.eipTxQueue = make(map[common.Address]*txSortedMap)
+	tp.queueBeats = make(map[common.Address]time.Time)
+	tp.resetEvictablesLocked()
+	log.Infof("tx pool flushed, %d transactions dropped", count)
+	return count
+}
+
 // returns the remaining tx list to cleanup
 func (tp *TXPool) Remain() []*types.Transaction {
 	tp.Lock()
 	defer tp.Unlock()
 
 	tp.eipTxPool = make(map[common.Address]*txSortedMap) // clean all eip tx
+	tp.eipTxQueue = make(map[common.Address]*txSortedMap)
+	tp.queueBeats = make(map[common.Address]time.Time)
+	tp.resetEvictablesLocked()
 	txList := make([]*types.Transaction, 0, len(tp.validTxMap))
 	for _, txEntry := range tp.validTxMap {
 		txList = append(txList, txEntry.Tx)
@@ -403,3 +708,32 @@ func (tp *TXPool) Remain() []*types.Transaction {
 
 	return txList
 }
+
+// GetEIPTxCount returns the number of pending and queued eip155 transactions
+func (tp *TXPool) GetEIPTxCount() (pending int, queued int) {
+	tp.RLock()
+	defer tp.RUnlock()
+	for _, list := range tp.eipTxPool {
+		pending += list.Len()
+	}
+	for _, list := range tp.eipTxQueue {
+		queued += list.Len()
+	}
+	return pending, queued
+}
+
+// GetEIPTxContent returns the pending and queued eip155 transactions grouped
+// by payer and sorted by nonce
+func (tp *TXPool) GetEIPTxContent() (pending, queued map[common.Address]Transactions) {
+	tp.Lock()
+	defer tp.Unlock()
+	pending = make(map[common.Address]Transactions, len(tp.eipTxPool))
+	for payer, list := range tp.eipTxPool {
+		pending[payer] = append(Transactions(nil), list.flatten()...)
+	}
+	queued = make(map[common.Address]Transactions, len(tp.eipTxQueue))
+	for payer, list := range tp.eipTxQueue {
+		queued[payer] = append(Transactions(nil), list.flatten()...)
+	}
+	return pending, queued
+}
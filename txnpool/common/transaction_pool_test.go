@@ -19,12 +19,19 @@
 package common
 
 import (
+	"crypto/ecdsa"
+	"math/big"
 	"testing"
 	"time"
 
+	ethcomm "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -94,3 +101,115 @@ func TestTxPool(t *testing.T) {
 
 	txPool.CleanTransactionList([]*types.Transaction{txn})
 }
+
+func TestTxPoolRemoveAndFlush(t *testing.T) {
+	txPool := NewTxPool()
+	ret := txPool.AddTxList(&VerifiedTx{Tx: txn, VerifiedHeight: 10})
+	assert.True(t, ret.Success())
+
+	assert.True(t, txPool.RemoveTx(txn.Hash()))
+	assert.False(t, txPool.RemoveTx(txn.Hash()))
+	assert.Nil(t, txPool.GetTransaction(txn.Hash()))
+
+	ret = txPool.AddTxList(&VerifiedTx{Tx: txn, VerifiedHeight: 10})
+	assert.True(t, ret.Success())
+	assert.Equal(t, 1, txPool.Flush())
+	assert.Equal(t, 0, txPool.GetTransactionCount())
+}
+
+func genEIPTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, gasPrice int64) *types.Transaction {
+	tx := ethtypes.NewTransaction(nonce, ethcomm.HexToAddress("0x4592d8f8d7b001e72cb26a73e4fa1806a51ac79d"),
+		big.NewInt(1000000000), 21000, big.NewInt(gasPrice), nil)
+	signed, err := ethtypes.SignTx(tx, ethtypes.NewEIP155Signer(big.NewInt(12345)), key)
+	assert.Nil(t, err)
+	otx, err := types.TransactionFromEIP155(signed)
+	assert.Nil(t, err)
+	return otx
+}
+
+func genNeoTx(nonce uint32, gasPrice uint64) *types.Transaction {
+	mutable := &types.MutableTransaction{
+		TxType:   types.InvokeNeo,
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Payload:  &payload.InvokeCode{Code: []byte{}},
+	}
+	tx, _ := mutable.IntoImmutable()
+	return tx
+}
+
+// setTxPoolLimits sets the pool limits and returns the function restoring them
+func setTxPoolLimits(capacity, slots uint) func() {
+	common := config.DefConfig.Common
+	oldCapacity, oldSlots := common.TxPoolCapacity, common.TxPoolPayerSlots
+	common.TxPoolCapacity, common.TxPoolPayerSlots = capacity, slots
+	return func() {
+		common.TxPoolCapacity, common.TxPoolPayerSlots = oldCapacity, oldSlots
+	}
+}
+
+func TestTxPoolPayerSlots(t *testing.T) {
+	defer setTxPoolLimits(0, 2)()
+	key, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+
+	pool := NewTxPool()
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: genEIPTx(t, key, 0, 2500)}))
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: genEIPTx(t, key, 1, 2500)}))
+
+	tx := genEIPTx(t, key, 2, 2500)
+	assert.Equal(t, errors.ErrPayerTxPoolLimit, pool.CheckTxLimit(tx))
+	assert.Equal(t, errors.ErrPayerTxPoolLimit, pool.AddTxList(&VerifiedTx{Tx: tx}))
+	// replacing the tx with the same nonce is not limited
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: genEIPTx(t, key, 1, 3000)}))
+	assert.Equal(t, 2, pool.GetTransactionCount())
+}
+
+func TestTxPoolEvictLowestGasPrice(t *testing.T) {
+	defer setTxPoolLimits(2, 0)()
+	key, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+
+	pool := NewTxPool()
+	eip0 := genEIPTx(t, key, 0, 2500)
+	eip1 := genEIPTx(t, key, 1, 2000)
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: eip0}))
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: eip1}))
+
+	assert.Equal(t, errors.ErrTxPoolUnderpriced, pool.AddTxList(&VerifiedTx{Tx: genNeoTx(1, 1000)}))
+	// the payer can not evict its own tx
+	assert.Equal(t, errors.ErrTxPoolUnderpriced, pool.CheckTxLimit(genEIPTx(t, key, 2, 3000)))
+
+	// only the highest nonce of the payer can be evicted
+	neo := genNeoTx(2, 3000)
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: neo}))
+	assert.Nil(t, pool.GetTransaction(eip1.Hash()))
+	assert.NotNil(t, pool.GetTransaction(eip0.Hash()))
+	assert.NotNil(t, pool.GetTransaction(neo.Hash()))
+	assert.Equal(t, uint64(1), pool.NextNonce(eip0.Payer))
+
+	assert.Equal(t, errors.ErrTxPoolUnderpriced, pool.CheckTxLimit(genNeoTx(3, 2400)))
+	assert.Equal(t, 2, pool.GetTransactionCount())
+
+	// eip0 becomes the highest nonce of the payer after eip1 is evicted
+	neo2 := genNeoTx(4, 2600)
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: neo2}))
+	assert.Nil(t, pool.GetTransaction(eip0.Hash()))
+	assert.NotNil(t, pool.GetTransaction(neo.Hash()))
+	assert.NotNil(t, pool.GetTransaction(neo2.Hash()))
+
+	// the cleaned txs are dropped from the heap
+	pool.CleanTransactionList([]*types.Transaction{neo})
+	cheap := genNeoTx(5, 100)
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: cheap}))
+	assert.Equal(t, cheap.Hash(), pool.cheapestTxLocked(genNeoTx(6, 200)).Hash())
+
+	setTxPoolLimits(0, 0)
+	for i := 0; i < 2*minEvictablesCompact; i++ {
+		tx := genNeoTx(uint32(10+i), 3000)
+		assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: tx}))
+		assert.True(t, pool.RemoveTx(tx.Hash()))
+	}
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&VerifiedTx{Tx: genNeoTx(7, 3000)}))
+	assert.True(t, len(pool.evictables) <= 2*pool.GetTransactionCount()+minEvictablesCompact)
+}
@@ -45,6 +45,27 @@ func (h *nonceHeap) Pop() interface{} {
 	return x
 }
 
+// priceHeap is a heap.Interface implementation over transactions for retrieving
+// the transaction with the lowest gas price.
+type priceHeap Transactions
+
+func (h priceHeap) Len() int           { return len(h) }
+func (h priceHeap) Less(i, j int) bool { return h[i].GasPrice < h[j].GasPrice }
+func (h priceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *priceHeap) Push(x interface{}) {
+	*h = append(*h, x.(*types.Transaction))
+}
+
+func (h *priceHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*h = old[0 : n-1]
+	return x
+}
+
 type TxByNonce Transactions
 
 func (s TxByNonce) Len() int           { return len(s) }
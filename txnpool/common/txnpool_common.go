@@ -32,11 +32,12 @@ import (
 )
 
 const (
-	MAX_CAPACITY     = 100140      // The tx pool's capacity that holds the verified txs
 	MAX_PENDING_TXN  = 4096 * 10   // The max length of pending txs
 	MAX_LIMITATION   = 10000       // The length of pending tx from net and http
 	UPDATE_FREQUENCY = 100         // The frequency to update gas price from global params
 	MAX_TX_SIZE      = 1024 * 1024 // The max size of a transaction to prevent DOS attacks
+
+	TX_JOURNAL_FILE = "txpool_journal.dat" // The journal file of local txs in the chain data dir
 )
 
 // SenderType enumerates the kind of tx submitter
@@ -48,6 +49,17 @@ const (
 	HttpSender            // Http sends tx req
 )
 
+func (s SenderType) String() string {
+	switch s {
+	case NetSender:
+		return "net"
+	case HttpSender:
+		return "http"
+	default:
+		return "nil"
+	}
+}
+
 // CheckBlkResult contains a verifed tx list,
 // an unverified tx list and an old tx list
 // to be re-verifed
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"math/big"
 	"testing"
+	"time"
 
 	ethcomm "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -30,7 +31,10 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ontio/ontology/common"
 	sysconfig "github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/payload"
 	txtypes "github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/errors"
+	tc "github.com/ontio/ontology/txnpool/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -100,3 +104,67 @@ func Test_From(t *testing.T) {
 	assert.Equal(t, otx3.Payer, otx4.Payer)
 	assert.Equal(t, otx1.Payer, otx4.Payer)
 }
+
+func genNeoTxWithNonceAndPrice(nonce uint32, gp uint64) *txtypes.Transaction {
+	mutable := &txtypes.MutableTransaction{
+		TxType:   txtypes.InvokeNeo,
+		Nonce:    nonce,
+		GasPrice: gp,
+		Payload:  &payload.InvokeCode{Code: []byte{}},
+	}
+	tx, _ := mutable.IntoImmutable()
+	return tx
+}
+
+func Test_QueueFutureNonce(t *testing.T) {
+	initCfg()
+	pool := tc.NewTxPool()
+	eip0 := genTxWithNonceAndPrice(0, 2500)
+	eip1 := genTxWithNonceAndPrice(1, 2500)
+	eip2 := genTxWithNonceAndPrice(2, 2500)
+	eip3 := genTxWithNonceAndPrice(3, 2500)
+
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&tc.VerifiedTx{Tx: eip0}))
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&tc.VerifiedTx{Tx: eip2}))
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&tc.VerifiedTx{Tx: eip3}))
+	pending, queued := pool.GetEIPTxCount()
+	assert.Equal(t, 1, pending)
+	assert.Equal(t, 2, queued)
+	assert.Equal(t, uint64(1), pool.NextNonce(eip0.Payer))
+
+	// the queued txs are promoted once the gap is filled
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&tc.VerifiedTx{Tx: eip1}))
+	pending, queued = pool.GetEIPTxCount()
+	assert.Equal(t, 4, pending)
+	assert.Equal(t, 0, queued)
+	assert.Equal(t, uint64(4), pool.NextNonce(eip0.Payer))
+	txs, _ := pool.GetTxPool(false, 0)
+	assert.Equal(t, 4, len(txs))
+
+	pool.CleanTransactionList([]*txtypes.Transaction{eip0, eip1})
+	pendingTxs, queuedTxs := pool.GetEIPTxContent()
+	assert.Equal(t, 2, len(pendingTxs[eip0.Payer]))
+	assert.Equal(t, eip2.Hash(), pendingTxs[eip0.Payer][0].Hash())
+	assert.Empty(t, queuedTxs)
+}
+
+func Test_QueueLifetime(t *testing.T) {
+	initCfg()
+	common := sysconfig.DefConfig.Common
+	defer func(lifetime uint) {
+		common.TxPoolQueueLifetime = lifetime
+	}(common.TxPoolQueueLifetime)
+	common.TxPoolQueueLifetime = 1
+
+	pool := tc.NewTxPool()
+	eip2 := genTxWithNonceAndPrice(2, 2500)
+	assert.Equal(t, errors.ErrNoError, pool.AddTxList(&tc.VerifiedTx{Tx: eip2}))
+	_, queued := pool.GetEIPTxCount()
+	assert.Equal(t, 1, queued)
+
+	time.Sleep(1100 * time.Millisecond)
+	pool.CleanTransactionList(nil)
+	_, queued = pool.GetEIPTxCount()
+	assert.Equal(t, 0, queued)
+	assert.Nil(t, pool.GetTransaction(eip2.Hash()))
+}
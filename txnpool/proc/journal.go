@@ -0,0 +1,165 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	txtypes "github.com/ontio/ontology/core/types"
+)
+
+// txJournal is an append only file of the locally submitted transactions,
+// which are replayed to the tx pool when the node restarts.
+type txJournal struct {
+	lock   sync.Mutex
+	path   string
+	writer *os.File
+	txs    map[common.Uint256]*txtypes.Transaction // transactions recorded in the journal
+}
+
+func newTxJournal(path string) *txJournal {
+	return &txJournal{
+		path: path,
+		txs:  make(map[common.Uint256]*txtypes.Transaction),
+	}
+}
+
+// load reads all the transactions in the journal, a broken tail caused by
+// an unclean shutdown is ignored.
+func (self *txJournal) load() ([]*txtypes.Transaction, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	data, err := ioutil.ReadFile(self.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []*txtypes.Transaction
+	source := common.NewZeroCopySource(data)
+	for source.Len() > 0 {
+		raw, _, irregular, eof := source.NextVarBytes()
+		if irregular || eof {
+			log.Warnf("tx journal: broken record at offset %d, the rest is dropped", source.Pos())
+			break
+		}
+		tx, err := txtypes.TransactionFromRawBytes(raw)
+		if err != nil {
+			log.Warnf("tx journal: invalid transaction: %s", err)
+			continue
+		}
+		if _, ok := self.txs[tx.Hash()]; ok {
+			continue
+		}
+		self.txs[tx.Hash()] = tx
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// insert appends the transaction to the journal if it is not recorded.
+func (self *txJournal) insert(tx *txtypes.Transaction) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.writer == nil {
+		return fmt.Errorf("tx journal is not opened")
+	}
+	if _, ok := self.txs[tx.Hash()]; ok {
+		return nil
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarBytes(tx.ToArray())
+	if _, err := self.writer.Write(sink.Bytes()); err != nil {
+		return err
+	}
+	self.txs[tx.Hash()] = tx
+	return nil
+}
+
+// rotate regenerates the journal with the transactions accepted by keep,
+// and reopens it for appending. keep is called without holding the lock, the
+// transactions inserted meanwhile are all kept.
+func (self *txJournal) rotate(keep func(tx *txtypes.Transaction) bool) error {
+	self.lock.Lock()
+	txs := make([]*txtypes.Transaction, 0, len(self.txs))
+	for _, tx := range self.txs {
+		txs = append(txs, tx)
+	}
+	self.lock.Unlock()
+
+	dropped := make(map[common.Uint256]bool)
+	for _, tx := range txs {
+		if !keep(tx) {
+			dropped[tx.Hash()] = true
+		}
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.writer != nil {
+		if err := self.writer.Close(); err != nil {
+			return err
+		}
+		self.writer = nil
+	}
+
+	sink := common.NewZeroCopySink(nil)
+	for hash, tx := range self.txs {
+		if dropped[hash] {
+			delete(self.txs, hash)
+			continue
+		}
+		sink.WriteVarBytes(tx.ToArray())
+	}
+	if err := ioutil.WriteFile(self.path+".new", sink.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(self.path+".new", self.path); err != nil {
+		return err
+	}
+	writer, err := os.OpenFile(self.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	self.writer = writer
+	log.Infof("tx journal: regenerated with %d transactions", len(self.txs))
+	return nil
+}
+
+// close closes the journal file.
+func (self *txJournal) close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.writer == nil {
+		return nil
+	}
+	err := self.writer.Close()
+	self.writer = nil
+	return err
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package proc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	txtypes "github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+
+	journal := newTxJournal(path)
+	txs, err := journal.load()
+	assert.Nil(t, err)
+	assert.Empty(t, txs)
+	assert.Nil(t, journal.rotate(func(*txtypes.Transaction) bool { return true }))
+
+	tx1 := genNeoTxWithNonceAndPrice(1, 500)
+	tx2 := genNeoTxWithNonceAndPrice(2, 500)
+	assert.Nil(t, journal.insert(tx1))
+	assert.Nil(t, journal.insert(tx2))
+	assert.Nil(t, journal.insert(tx1))
+	assert.Nil(t, journal.close())
+
+	// simulates an unclean shutdown in the middle of a record
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = file.Write([]byte{0xfd, 0xff})
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	journal = newTxJournal(path)
+	txs, err = journal.load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(txs))
+
+	assert.Nil(t, journal.rotate(func(tx *txtypes.Transaction) bool { return tx.Hash() != tx1.Hash() }))
+	assert.Nil(t, journal.close())
+
+	journal = newTxJournal(path)
+	txs, err = journal.load()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, tx2.Hash(), txs[0].Hash())
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package proc
+
+import (
+	"strconv"
+
+	"github.com/ontio/ontology/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	rejectedTxMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_txpool_rejected_txs_total",
+		Help: "ontology transactions rejected by tx pool",
+	}, []string{"code", "reason"})
+
+	pendingTxsDesc = prom.NewDesc("ontology_txpool_pending_txs",
+		"ontology transactions being verified in tx pool", nil, nil)
+	verifiedTxsDesc = prom.NewDesc("ontology_txpool_verified_txs",
+		"ontology verified transactions in tx pool", nil, nil)
+)
+
+func init() {
+	prom.MustRegister(rejectedTxMetric)
+}
+
+func countRejectedTx(err errors.ErrCode) {
+	if err != errors.ErrNoError {
+		rejectedTxMetric.WithLabelValues(strconv.Itoa(int(err)), err.Error()).Inc()
+	}
+}
+
+// txPoolCollector reports the transaction counts of tx pool server when scraped
+type txPoolCollector struct {
+	server *TXPoolServer
+}
+
+func (self *txPoolCollector) Describe(ch chan<- *prom.Desc) {
+	ch <- pendingTxsDesc
+	ch <- verifiedTxsDesc
+}
+
+func (self *txPoolCollector) Collect(ch chan<- prom.Metric) {
+	ch <- prom.MustNewConstMetric(pendingTxsDesc, prom.GaugeValue, float64(self.server.getPendingListSize()))
+	ch <- prom.MustNewConstMetric(verifiedTxsDesc, prom.GaugeValue, float64(self.server.getTransactionCount()))
+}
+
+// RegisterMetrics registers the transaction counts of tx pool server to the default prometheus registry
+func (s *TXPoolServer) RegisterMetrics() error {
+	return prom.Register(&txPoolCollector{server: s})
+}
@@ -59,6 +59,7 @@ func isBalanceEnough(address common.Address, gas uint64) bool {
 }
 
 func replyTxResult(txResultCh chan *tc.TxResult, hash common.Uint256, err errors.ErrCode, desc string) {
+	countRejectedTx(err)
 	if txResultCh != nil {
 		result := &tc.TxResult{
 			Err:  err,
@@ -120,10 +121,10 @@ func (ta *TxPoolService) handleTransaction(sender tc.SenderType, txn *tx.Transac
 		return
 	}
 
-	if ta.server.getTransactionCount() >= tc.MAX_CAPACITY {
-		log.Debugf("handleTransaction: transaction pool is full for tx %x", txn.Hash())
+	if errCode := ta.server.checkTxLimit(txn); !errCode.Success() {
+		log.Debugf("handleTransaction: transaction %x rejected by tx pool limit: %s", txn.Hash(), errCode.Error())
 
-		replyTxResult(txResultCh, txn.Hash(), errors.ErrTxPoolFull, "transaction pool is full")
+		replyTxResult(txResultCh, txn.Hash(), errCode, errCode.Error())
 		return
 	}
 
@@ -29,7 +29,9 @@ import (
 	ethtype "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ontio/ontology-eventbus/actor"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/tracing"
 	"github.com/ontio/ontology/core/ledger"
 	txtypes "github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
@@ -39,6 +41,7 @@ import (
 	"github.com/ontio/ontology/validator/stateful"
 	"github.com/ontio/ontology/validator/stateless"
 	"github.com/ontio/ontology/validator/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type serverPendingTx struct {
@@ -46,6 +49,7 @@ type serverPendingTx struct {
 	sender         tc.SenderType        // Indicate which sender tx is from
 	ch             chan *tc.TxResult    // channel to send tx result
 	checkingStatus *tc.CheckingStatus
+	span           trace.Span // traces the verification of tx
 }
 
 // TXPoolServer contains all api to external modules
@@ -59,6 +63,7 @@ type TXPoolServer struct {
 	slots                 chan struct{} // The limited slots for the new transaction
 	height                uint32        // The current block height
 	gasPrice              uint64        // Gas price to enforce for acceptance into the pool
+	localGasPrice         uint64        // Local gas price floor, changed by SetGasPrice
 	disablePreExec        bool          // Disbale PreExecute a transaction
 	disableBroadcastNetTx bool          // Disable broadcast tx from network
 
@@ -66,6 +71,7 @@ type TXPoolServer struct {
 	stateful  *stateful.ValidatorPool
 	rspCh     chan *types.CheckResponse // The channel of verified response
 	stopCh    chan bool                 // stop routine
+	journal   *txJournal                // The journal of local txs, nil if disabled
 }
 
 // NewTxPoolServer creates a new tx pool server to schedule workers to
@@ -81,7 +87,8 @@ func NewTxPoolServer(disablePreExec, disableBroadcastNetTx bool) *TXPoolServer {
 		s.slots <- struct{}{}
 	}
 
-	s.gasPrice = getGasPriceConfig()
+	s.localGasPrice = config.DefConfig.Common.GasPrice
+	s.gasPrice = getGasPriceConfig(s.localGasPrice)
 	log.Infof("tx pool: the current local gas price is %d", s.gasPrice)
 
 	s.disablePreExec = disablePreExec
@@ -147,7 +154,8 @@ func (s *TXPoolServer) movePendingTxToPool(txEntry *tc.VerifiedTx) { //solve the
 
 	errCode := s.txPool.AddTxList(txEntry)
 	s.removePendingTxLocked(txEntry.Tx.Hash(), errCode)
-	log.Infof("tx moved from pending pool to tx pool: %s, err: %s", txEntry.Tx.Hash().ToHexString(), errCode.Error())
+	txHash := txEntry.Tx.Hash().ToHexString()
+	log.WithFields(log.Fields{log.FIELD_TXHASH: txHash}).Infof("tx moved from pending pool to tx pool: %s, err: %s", txHash, errCode.Error())
 }
 
 // removes a transaction from the pending list
@@ -158,7 +166,8 @@ func (s *TXPoolServer) removePendingTx(hash common.Uint256, err errors.ErrCode)
 	s.mu.Lock()
 	s.removePendingTxLocked(hash, err)
 	s.mu.Unlock()
-	log.Infof("transaction removed from pending pool: %s, err: %s", hash.ToHexString(), err.Error())
+	txHash := hash.ToHexString()
+	log.WithFields(log.Fields{log.FIELD_TXHASH: txHash}).Infof("transaction removed from pending pool: %s, err: %s", txHash, err.Error())
 }
 
 func (s *TXPoolServer) broadcastTx(pt *serverPendingTx) {
@@ -173,8 +182,14 @@ func (s *TXPoolServer) broadcastTx(pt *serverPendingTx) {
 func (s *TXPoolServer) handleRemovedPendingTx(pt *serverPendingTx, err errors.ErrCode) {
 	if err == errors.ErrNoError {
 		s.broadcastTx(pt)
+		if pt.sender == tc.HttpSender && s.journal != nil {
+			if err := s.journal.insert(pt.tx); err != nil {
+				log.Errorf("failed to journal local tx %s: %s", pt.tx.Hash().ToHexString(), err)
+			}
+		}
 	}
 
+	tracing.EndTxSpan(pt.span, err)
 	replyTxResult(pt.ch, pt.tx.Hash(), err, err.Error())
 }
 
@@ -215,6 +230,7 @@ func (s *TXPoolServer) setPendingTx(tx *txtypes.Transaction, sender tc.SenderTyp
 			PassedStateful:  0,
 			CheckHeight:     0,
 		},
+		span: tracing.StartTxSpan(tx.Hash(), "txpool.VerifyTx", tracing.ATTR_SENDER.String(sender.String())),
 	}
 
 	s.allPendingTxs[tx.Hash()] = pt
@@ -228,7 +244,8 @@ func (s *TXPoolServer) startTxVerify(tx *txtypes.Transaction, sender tc.SenderTy
 		return false
 	}
 
-	log.Infof("transaction added to pending pool: %s", tx.Hash().ToHexString())
+	txHash := tx.Hash().ToHexString()
+	log.WithFields(log.Fields{log.FIELD_TXHASH: txHash}).Infof("transaction added to pending pool: %s", txHash)
 
 	if tx := s.getTransaction(tx.Hash()); tx != nil {
 		log.Debugf("verifyTx: transaction %x already in the txn pool", tx.Hash())
@@ -241,6 +258,51 @@ func (s *TXPoolServer) startTxVerify(tx *txtypes.Transaction, sender tc.SenderTy
 	return true
 }
 
+// StartJournal replays the local transactions recorded in the journal at
+// path, and regenerates the journal every rejournal interval to drop the
+// transactions which are already on chain or invalid.
+func (s *TXPoolServer) StartJournal(path string, rejournal time.Duration) error {
+	journal := newTxJournal(path)
+	txs, err := journal.load()
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		s.startTxVerify(tx, tc.HttpSender, nil)
+	}
+	log.Infof("tx pool: %d transactions replayed from journal", len(txs))
+
+	if err := journal.rotate(s.isTxAlive); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.journal = journal
+	s.mu.Unlock()
+
+	go s.rejournal(journal, rejournal)
+	return nil
+}
+
+func (s *TXPoolServer) rejournal(journal *txJournal, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := journal.rotate(s.isTxAlive); err != nil {
+				log.Errorf("failed to rotate tx journal: %s", err)
+			}
+		}
+	}
+}
+
+// isTxAlive checks whether the transaction is still in the pending list or the tx pool
+func (s *TXPoolServer) isTxAlive(tx *txtypes.Transaction) bool {
+	return s.GetPendingTx(tx.Hash()) != nil || s.getTransaction(tx.Hash()) != nil
+}
+
 // GetPID returns an actor pid with the actor type, If the type
 // doesn't exist, return nil.
 func (s *TXPoolServer) GetPID() *actor.PID {
@@ -260,6 +322,13 @@ func (s *TXPoolServer) Stop() {
 	close(s.rspCh)
 	close(s.stopCh)
 	close(s.slots)
+	s.mu.Lock()
+	if s.journal != nil {
+		if err := s.journal.close(); err != nil {
+			log.Errorf("failed to close tx journal: %s", err)
+		}
+	}
+	s.mu.Unlock()
 }
 
 // returns a transaction with the transaction hash.
@@ -317,18 +386,7 @@ func (s *TXPoolServer) cleanTransactionList(txs []*txtypes.Transaction, height u
 
 	// Check whether to update the gas price and remove txs below the threshold
 	if height%tc.UPDATE_FREQUENCY == 0 {
-		gasPrice := getGasPriceConfig()
-		s.mu.Lock()
-		oldGasPrice := s.gasPrice
-		s.gasPrice = gasPrice
-		s.mu.Unlock()
-		if oldGasPrice != gasPrice {
-			log.Infof("tx pool price threshold updated from %d to %d", oldGasPrice, gasPrice)
-		}
-
-		if oldGasPrice < gasPrice {
-			s.txPool.RemoveTxsBelowGasPrice(gasPrice)
-		}
+		s.updateGasPrice()
 	}
 
 	// Cleanup tx pool
@@ -344,6 +402,63 @@ func (s *TXPoolServer) cleanTransactionList(txs []*txtypes.Transaction, height u
 	}
 }
 
+// updates the gas price enforced and removes txs below it if raised
+func (s *TXPoolServer) updateGasPrice() uint64 {
+	s.mu.Lock()
+	gasPrice := getGasPriceConfig(s.localGasPrice)
+	oldGasPrice := s.gasPrice
+	s.gasPrice = gasPrice
+	s.mu.Unlock()
+	if oldGasPrice != gasPrice {
+		log.Infof("tx pool price threshold updated from %d to %d", oldGasPrice, gasPrice)
+	}
+
+	if oldGasPrice < gasPrice {
+		s.txPool.RemoveTxsBelowGasPrice(gasPrice)
+	}
+	return gasPrice
+}
+
+// SetGasPrice changes the local gas price floor, the gas price enforced is
+// still the bigger one between it and the global one. Returns the enforced one.
+func (s *TXPoolServer) SetGasPrice(gasPrice uint64) uint64 {
+	s.mu.Lock()
+	s.localGasPrice = gasPrice
+	s.mu.Unlock()
+	return s.updateGasPrice()
+}
+
+// DropTransaction removes the transaction from the pool and the journal,
+// returns false if it is not in the pool.
+func (s *TXPoolServer) DropTransaction(hash common.Uint256) bool {
+	if !s.txPool.RemoveTx(hash) {
+		return false
+	}
+	s.rotateJournal()
+	return true
+}
+
+// FlushTransactions removes all the transactions from the pool and the
+// journal, returns the number of them. The pending ones in verification are
+// not affected.
+func (s *TXPoolServer) FlushTransactions() int {
+	count := s.txPool.Flush()
+	s.rotateJournal()
+	return count
+}
+
+func (s *TXPoolServer) rotateJournal() {
+	s.mu.RLock()
+	journal := s.journal
+	s.mu.RUnlock()
+	if journal == nil {
+		return
+	}
+	if err := journal.rotate(s.isTxAlive); err != nil {
+		log.Errorf("failed to rotate tx journal: %s", err)
+	}
+}
+
 // getTxStatusReq returns a transaction's status with the transaction hash.
 func (s *TXPoolServer) getTxStatusReq(hash common.Uint256) *tc.TxStatus {
 	if ret := s.GetPendingTx(hash); ret != nil {
@@ -356,6 +471,11 @@ func (s *TXPoolServer) getTxStatusReq(hash common.Uint256) *tc.TxStatus {
 	return s.txPool.GetTxStatus(hash)
 }
 
+// checkTxLimit checks the capacity and the per payer limit of the transaction pool.
+func (s *TXPoolServer) checkTxLimit(tx *txtypes.Transaction) errors.ErrCode {
+	return s.txPool.CheckTxLimit(tx)
+}
+
 // getTransactionCount returns the tx size of the transaction pool.
 func (s *TXPoolServer) getTransactionCount() int {
 	return s.txPool.GetTransactionCount()
@@ -545,6 +665,33 @@ func (s *TXPoolServer) PendingEIPTransactions() []*ethtype.Transaction {
 	return ret
 }
 
+// EIPTxPoolStatus returns the number of pending and queued eip155 transactions in the tx pool
+func (s *TXPoolServer) EIPTxPoolStatus() (pending int, queued int) {
+	return s.txPool.GetEIPTxCount()
+}
+
+// EIPTxPoolContent returns the pending and queued eip155 transactions in the tx pool grouped by sender
+func (s *TXPoolServer) EIPTxPoolContent() (pending, queued map[ethcomm.Address][]*ethtype.Transaction) {
+	pendingTxs, queuedTxs := s.txPool.GetEIPTxContent()
+	return toEIPTxContent(pendingTxs), toEIPTxContent(queuedTxs)
+}
+
+func toEIPTxContent(content map[common.Address]tc.Transactions) map[ethcomm.Address][]*ethtype.Transaction {
+	ret := make(map[ethcomm.Address][]*ethtype.Transaction, len(content))
+	for payer, txs := range content {
+		eipTxs := make([]*ethtype.Transaction, 0, len(txs))
+		for _, tx := range txs {
+			eipTx, err := tx.GetEIP155Tx()
+			if err != nil {
+				continue
+			}
+			eipTxs = append(eipTxs, eipTx)
+		}
+		ret[ethcomm.Address(payer)] = eipTxs
+	}
+	return ret
+}
+
 func (s *TXPoolServer) PendingTransactionsByHash(target ethcomm.Hash) *ethtype.Transaction {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
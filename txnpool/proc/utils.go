@@ -23,7 +23,6 @@ import (
 	"strconv"
 
 	"github.com/ontio/ontology/common"
-	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/ledger"
 	httpcom "github.com/ontio/ontology/http/base/common"
@@ -69,16 +68,16 @@ func getGlobalGasPrice() (uint64, error) {
 	return gasPrice, nil
 }
 
-// getGasPriceConfig returns the bigger one between global and cmd configured
-func getGasPriceConfig() uint64 {
+// getGasPriceConfig returns the bigger one between global and local configured
+func getGasPriceConfig(localGasPrice uint64) uint64 {
 	globalGasPrice, err := getGlobalGasPrice()
 	if err != nil {
 		log.Info(err)
 		return 0
 	}
 
-	if globalGasPrice < config.DefConfig.Common.GasPrice {
-		return config.DefConfig.Common.GasPrice
+	if globalGasPrice < localGasPrice {
+		return localGasPrice
 	}
 	return globalGasPrice
 }
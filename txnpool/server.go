@@ -25,6 +25,7 @@ import (
 
 	"github.com/ontio/ontology-eventbus/actor"
 	"github.com/ontio/ontology-eventbus/mailbox"
+	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/events"
 	"github.com/ontio/ontology/events/message"
 	tc "github.com/ontio/ontology/txnpool/common"
@@ -63,6 +64,9 @@ func StartTxnPoolServer(disablePreExec, disableBroadcastNetTx bool) (*tp.TXPoolS
 		return nil, err
 	}
 	s.RegisterActor(txPoolPid)
+	if err := s.RegisterMetrics(); err != nil {
+		log.Warnf("register tx pool metrics error: %s", err)
+	}
 
 	// Subscribe the block complete event
 	var sub = events.NewActorSubscriber(txPoolPid)
@@ -21,6 +21,7 @@ package stateful
 import (
 	ethcomm "github.com/ethereum/go-ethereum/common"
 	"github.com/gammazero/workerpool"
+	"github.com/ontio/ontology/common/tracing"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
@@ -37,6 +38,7 @@ func NewValidatorPool(maxWorkers int) *ValidatorPool {
 
 func (self *ValidatorPool) SubmitVerifyTask(tx *types.Transaction, rspCh chan<- *vatypes.CheckResponse) {
 	task := func() {
+		span := tracing.StartTxSpan(tx.Hash(), "validator.Stateful")
 		height := ledger.DefLedger.GetCurrentBlockHeight()
 
 		errCode := errors.ErrNoError
@@ -64,6 +66,7 @@ func (self *ValidatorPool) SubmitVerifyTask(tx *types.Transaction, rspCh chan<-
 				response.Nonce = ethacct.Nonce
 			}
 		}
+		tracing.EndTxSpan(span, response.ErrCode)
 
 		rspCh <- response
 	}
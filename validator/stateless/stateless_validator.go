@@ -22,6 +22,7 @@ import (
 	"github.com/ontio/ontology/core/types"
 
 	"github.com/gammazero/workerpool"
+	"github.com/ontio/ontology/common/tracing"
 	"github.com/ontio/ontology/core/validation"
 	vatypes "github.com/ontio/ontology/validator/types"
 )
@@ -36,7 +37,9 @@ func NewValidatorPool(maxWorkers int) *ValidatorPool {
 
 func (self *ValidatorPool) SubmitVerifyTask(tx *types.Transaction, rspCh chan<- *vatypes.CheckResponse) {
 	task := func() {
+		span := tracing.StartTxSpan(tx.Hash(), "validator.Stateless")
 		errCode := validation.VerifyTransaction(tx)
+		tracing.EndTxSpan(span, errCode)
 		response := &vatypes.CheckResponse{
 			ErrCode: errCode,
 			Hash:    tx.Hash(),
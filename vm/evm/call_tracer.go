@@ -0,0 +1,241 @@
+// Copyright (C) 2021 The Ontology Authors
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package evm
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	errors2 "github.com/ontio/ontology/vm/evm/errors"
+)
+
+// CallFrame is a call made during execution, in the output format of the callTracer
+type CallFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Calls   []*CallFrame   `json:"calls,omitempty"`
+
+	gasIn   uint64
+	gasCost uint64
+	gasSet  bool
+	outOff  uint64
+	outLen  uint64
+}
+
+// CallTracer is a Tracer collecting the call tree of a transaction, it follows the callTracer of go-ethereum,
+// inner calls are tracked through the call opcodes and the change of depth.
+type CallTracer struct {
+	callstack []*CallFrame
+	descended bool
+	root      *CallFrame
+}
+
+// NewCallTracer returns a new call tracer
+func NewCallTracer() *CallTracer {
+	return &CallTracer{callstack: []*CallFrame{{}}}
+}
+
+// CaptureStart implements the Tracer interface to record the top level call.
+func (t *CallTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	typ := CALL.String()
+	if create {
+		typ = CREATE.String()
+	}
+	t.root = &CallFrame{
+		Type:  typ,
+		From:  from,
+		To:    to,
+		Value: (*hexutil.Big)(new(big.Int).Set(value)),
+		Gas:   hexutil.Uint64(gas),
+		Input: common.CopyBytes(input),
+	}
+}
+
+// CaptureState implements the Tracer interface to track the inner calls.
+func (t *CallTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory,
+	stack *Stack, rStack *ReturnStack, rData []byte, contract *Contract, depth int, err error) {
+	if err != nil {
+		t.CaptureFault(env, pc, op, gas, cost, memory, stack, rStack, contract, depth, err)
+		return
+	}
+	switch op {
+	case CREATE, CREATE2:
+		inOff := stack.Back(1).Uint64()
+		inLen := stack.Back(2).Uint64()
+		t.callstack = append(t.callstack, &CallFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			Input:   memorySlice(memory, inOff, inLen),
+			Value:   (*hexutil.Big)(stack.Back(0).ToBig()),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return
+	case SELFDESTRUCT:
+		left := t.callstack[len(t.callstack)-1]
+		left.Calls = append(left.Calls, &CallFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			To:      common.Address(stack.Back(0).Bytes20()),
+			Value:   (*hexutil.Big)(env.StateDB.GetBalance(contract.Address())),
+			Gas:     hexutil.Uint64(gas),
+			GasUsed: hexutil.Uint64(cost),
+			Input:   []byte{},
+		})
+		return
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		to := common.Address(stack.Back(1).Bytes20())
+		if _, isPrecompile := env.precompile(to); isPrecompile {
+			return
+		}
+		off := 1
+		if op == DELEGATECALL || op == STATICCALL {
+			off = 0
+		}
+		inOff := stack.Back(2 + off).Uint64()
+		inLen := stack.Back(3 + off).Uint64()
+		call := &CallFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			To:      to,
+			Input:   memorySlice(memory, inOff, inLen),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  stack.Back(4 + off).Uint64(),
+			outLen:  stack.Back(5 + off).Uint64(),
+		}
+		if op != DELEGATECALL && op != STATICCALL {
+			call.Value = (*hexutil.Big)(stack.Back(2).ToBig())
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return
+	}
+
+	// the first step of the inner call, record the gas it is given
+	if t.descended {
+		if depth >= len(t.callstack) {
+			call := t.callstack[len(t.callstack)-1]
+			call.Gas = hexutil.Uint64(gas)
+			call.gasSet = true
+		}
+		t.descended = false
+	}
+	if op == REVERT {
+		t.callstack[len(t.callstack)-1].Error = errors2.ErrExecutionReverted.Error()
+		return
+	}
+	// returned from the inner call
+	if depth == len(t.callstack)-1 {
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stack.Back(0)
+		if call.Type == CREATE.String() || call.Type == CREATE2.String() {
+			call.GasUsed = hexutil.Uint64(call.gasIn - call.gasCost - gas)
+			if ret.Sign() != 0 {
+				call.To = common.Address(ret.Bytes20())
+				call.Output = env.StateDB.GetCode(call.To)
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else {
+			if call.gasSet {
+				call.GasUsed = hexutil.Uint64(call.gasIn - call.gasCost + uint64(call.Gas) - gas)
+			}
+			if ret.Sign() != 0 {
+				call.Output = memorySlice(memory, call.outOff, call.outLen)
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		left := t.callstack[len(t.callstack)-1]
+		left.Calls = append(left.Calls, call)
+	}
+}
+
+// CaptureFault implements the Tracer interface to record the error of the failed call.
+func (t *CallTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory,
+	stack *Stack, rStack *ReturnStack, contract *Contract, depth int, err error) {
+	if len(t.callstack) <= 1 {
+		return
+	}
+	call := t.callstack[len(t.callstack)-1]
+	if call.Error != "" {
+		return
+	}
+	call.Error = err.Error()
+	// a failed call consumes all the gas given to it
+	call.GasUsed = call.Gas
+	if call.Type == CREATE.String() || call.Type == CREATE2.String() {
+		call.GasUsed = hexutil.Uint64(call.gasIn - call.gasCost)
+	}
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	left := t.callstack[len(t.callstack)-1]
+	left.Calls = append(left.Calls, call)
+}
+
+// CaptureEnd implements the Tracer interface to finalize the top level call.
+func (t *CallTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	if t.root == nil {
+		return
+	}
+	t.root.GasUsed = hexutil.Uint64(gasUsed)
+	t.root.Output = common.CopyBytes(output)
+	if err != nil {
+		t.root.Error = err.Error()
+		if !errors.Is(err, errors2.ErrExecutionReverted) || len(output) == 0 {
+			t.root.Output = nil
+		}
+	}
+}
+
+// Result returns the call tree, nil if nothing is executed
+func (t *CallTracer) Result() *CallFrame {
+	if t.root == nil {
+		return nil
+	}
+	t.root.Calls = t.callstack[0].Calls
+	if t.root.Error == "" && t.callstack[0].Error != "" {
+		t.root.Error = t.callstack[0].Error
+	}
+	return t.root
+}
+
+func memorySlice(memory *Memory, offset, size uint64) []byte {
+	data := memory.Data()
+	if size == 0 || offset >= uint64(len(data)) {
+		return []byte{}
+	}
+	end := offset + size
+	if end > uint64(len(data)) || end < offset {
+		end = uint64(len(data))
+	}
+	return common.CopyBytes(data[offset:end])
+}
@@ -428,6 +428,41 @@ func TestReturnSubShallow(t *testing.T) {
 	}
 }
 
+func TestCallTracer(t *testing.T) {
+	db := storage.NewCacheDB(overlaydb.NewOverlayDB(leveldbstore.NewMemLevelDBStore()))
+	statedb := storage.NewStateDB(db, common.Hash{}, common.Hash{}, ong.OngBalanceHandle{})
+	caller, callee := common.HexToAddress("0xaa"), common.HexToAddress("0xbb")
+	// the caller calls the callee, which reverts
+	statedb.SetCode(caller, []byte{
+		byte(evm.PUSH1), 0, byte(evm.PUSH1), 0, byte(evm.PUSH1), 0, byte(evm.PUSH1), 0,
+		byte(evm.PUSH1), 0, byte(evm.PUSH1), 0xbb, byte(evm.PUSH2), 0xff, 0xff,
+		byte(evm.CALL), byte(evm.POP), byte(evm.STOP),
+	})
+	statedb.SetCode(callee, []byte{byte(evm.PUSH1), 0, byte(evm.PUSH1), 0, byte(evm.REVERT)})
+	tracer := evm.NewCallTracer()
+	_, _, err := Call(caller, nil, &Config{State: statedb,
+		GasLimit:    100000,
+		ChainConfig: params.AllEthashProtocolChanges,
+		EVMConfig: evm.Config{
+			Debug:  true,
+			Tracer: tracer,
+		}})
+	require.NoError(t, err)
+
+	result := tracer.Result()
+	require.NotNil(t, result)
+	require.Equal(t, "CALL", result.Type)
+	require.Equal(t, caller, result.To)
+	require.Empty(t, result.Error)
+	require.Len(t, result.Calls, 1)
+	inner := result.Calls[0]
+	require.Equal(t, "CALL", inner.Type)
+	require.Equal(t, caller, inner.From)
+	require.Equal(t, callee, inner.To)
+	require.Equal(t, "execution reverted", inner.Error)
+	require.True(t, inner.GasUsed > 0 && inner.GasUsed <= inner.Gas)
+}
+
 // disabled -- only used for generating markdown
 func DisabledTestReturnCases(t *testing.T) {
 	cfg := &Config{